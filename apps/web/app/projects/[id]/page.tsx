@@ -4,15 +4,16 @@ import { useEffect, useState } from 'react';
 import { useParams, useRouter } from 'next/navigation';
 import Navigation from '@/components/Navigation';
 import { apiClient } from '@/lib/api-client';
-import type { Project, Task, LogEntry } from '@/lib/types';
+import type { Project, Task, LogEntry, TaskStatus } from '@/lib/types';
 
 export default function ProjectPage() {
   const params = useParams();
   const router = useRouter();
-  const projectId = parseInt(params.id as string);
+  const projectId = params.id as string;
 
   const [project, setProject] = useState<Project | null>(null);
   const [tasks, setTasks] = useState<Task[]>([]);
+  const [statuses, setStatuses] = useState<TaskStatus[]>([]);
   const [logEntries, setLogEntries] = useState<LogEntry[]>([]);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState('');
@@ -21,22 +22,24 @@ export default function ProjectPage() {
   const [showNewTask, setShowNewTask] = useState(false);
   const [newTaskTitle, setNewTaskTitle] = useState('');
   const [newTaskDescription, setNewTaskDescription] = useState('');
-  const [newTaskStatus, setNewTaskStatus] = useState('todo');
+  const [newTaskStatus, setNewTaskStatus] = useState('');
 
   // New log entry form
   const [showNewLog, setShowNewLog] = useState(false);
   const [newLogContent, setNewLogContent] = useState('');
-  const [selectedTaskId, setSelectedTaskId] = useState<number | undefined>();
+  const [selectedTaskId, setSelectedTaskId] = useState<string | undefined>();
 
   const loadProjectData = async () => {
     try {
-      const [projectData, tasksData, logsData] = await Promise.all([
+      const [projectData, tasksData, logsData, workflowData] = await Promise.all([
         apiClient.getProject(projectId),
         apiClient.getTasks(projectId),
         apiClient.getLogEntries(projectId),
+        apiClient.getWorkflow(projectId),
       ]);
       setProject(projectData);
       setTasks(tasksData);
+      setStatuses(workflowData.statuses);
       setLogEntries(logsData);
     } catch {
       setError('Failed to load project data');
@@ -58,11 +61,11 @@ export default function ProjectPage() {
         project_id: projectId,
         title: newTaskTitle,
         description: newTaskDescription,
-        status: newTaskStatus,
+        status: newTaskStatus || undefined,
       });
       setNewTaskTitle('');
       setNewTaskDescription('');
-      setNewTaskStatus('todo');
+      setNewTaskStatus('');
       setShowNewTask(false);
       loadProjectData();
     } catch {
@@ -89,7 +92,7 @@ export default function ProjectPage() {
     }
   };
 
-  const handleUpdateTaskStatus = async (taskId: number, newStatus: string) => {
+  const handleUpdateTaskStatus = async (taskId: string, newStatus: string) => {
     try {
       const task = tasks.find((t) => t.id === taskId);
       if (task) {
@@ -105,6 +108,22 @@ export default function ProjectPage() {
     }
   };
 
+  // A task can stay where it is or move to any status its current one allows.
+  const statusOptions = (task: Task) => {
+    const current = statuses.find((s) => s.key === task.status);
+    const options = statuses.filter(
+      (s) =>
+        s.key === task.status ||
+        !current ||
+        current.allowed_transitions === null ||
+        current.allowed_transitions.includes(s.key)
+    );
+    if (!current) {
+      options.unshift({ key: task.status, name: task.status } as TaskStatus);
+    }
+    return options;
+  };
+
   if (loading) {
     return (
       <div className="min-h-screen bg-gray-50">
@@ -209,9 +228,12 @@ export default function ProjectPage() {
                         value={newTaskStatus}
                         onChange={(e) => setNewTaskStatus(e.target.value)}
                       >
-                        <option value="todo">To Do</option>
-                        <option value="in_progress">In Progress</option>
-                        <option value="done">Done</option>
+                        <option value="">Default</option>
+                        {statuses.map((status) => (
+                          <option key={status.key} value={status.key}>
+                            {status.name}
+                          </option>
+                        ))}
                       </select>
                     </div>
                     <div className="flex justify-end space-x-3">
@@ -250,9 +272,11 @@ export default function ProjectPage() {
                         onChange={(e) => handleUpdateTaskStatus(task.id, e.target.value)}
                         className="ml-4 border border-gray-300 rounded-md shadow-sm py-1 px-2 text-sm focus:outline-none focus:ring-blue-500 focus:border-blue-500"
                       >
-                        <option value="todo">To Do</option>
-                        <option value="in_progress">In Progress</option>
-                        <option value="done">Done</option>
+                        {statusOptions(task).map((status) => (
+                          <option key={status.key} value={status.key}>
+                            {status.name}
+                          </option>
+                        ))}
                       </select>
                     </div>
                   </div>
@@ -286,7 +310,7 @@ export default function ProjectPage() {
                         id="log-task"
                         className="mt-1 block w-full border border-gray-300 rounded-md shadow-sm py-2 px-3 focus:outline-none focus:ring-blue-500 focus:border-blue-500 sm:text-sm"
                         value={selectedTaskId || ''}
-                        onChange={(e) => setSelectedTaskId(e.target.value ? parseInt(e.target.value) : undefined)}
+                        onChange={(e) => setSelectedTaskId(e.target.value || undefined)}
                       >
                         <option value="">No task</option>
                         {tasks.map((task) => (
@@ -2,12 +2,14 @@ import type {
   User,
   Project,
   Task,
+  Workflow,
   LogEntry,
   RegisterData,
   LoginData,
   CreateProjectData,
   CreateTaskData,
   CreateLogEntryData,
+  UpdateWorkflowData,
 } from './types';
 
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
@@ -74,11 +76,12 @@ class ApiClient {
   }
 
   // Projects endpoints
-  async getProjects(): Promise<Project[]> {
-    return this.request<Project[]>('/api/projects');
+  async getProjects(archived = false): Promise<Project[]> {
+    const query = archived ? '?archived=true' : '';
+    return this.request<Project[]>(`/api/projects${query}`);
   }
 
-  async getProject(id: number): Promise<Project> {
+  async getProject(id: string): Promise<Project> {
     return this.request<Project>(`/api/projects/${id}`);
   }
 
@@ -89,26 +92,49 @@ class ApiClient {
     });
   }
 
-  async updateProject(id: number, data: Partial<CreateProjectData>): Promise<Project> {
+  async updateProject(id: string, data: Partial<CreateProjectData>): Promise<Project> {
     return this.request<Project>(`/api/projects/${id}`, {
       method: 'PUT',
       body: JSON.stringify(data),
     });
   }
 
-  async deleteProject(id: number): Promise<void> {
+  async deleteProject(id: string): Promise<void> {
     return this.request<void>(`/api/projects/${id}`, {
       method: 'DELETE',
     });
   }
 
+  async archiveProject(id: string): Promise<Project> {
+    return this.request<Project>(`/api/projects/${id}/archive`, {
+      method: 'POST',
+    });
+  }
+
+  async unarchiveProject(id: string): Promise<Project> {
+    return this.request<Project>(`/api/projects/${id}/unarchive`, {
+      method: 'POST',
+    });
+  }
+
+  async getWorkflow(projectId: string): Promise<Workflow> {
+    return this.request<Workflow>(`/api/projects/${projectId}/workflow`);
+  }
+
+  async updateWorkflow(projectId: string, data: UpdateWorkflowData): Promise<Workflow> {
+    return this.request<Workflow>(`/api/projects/${projectId}/workflow`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  }
+
   // Tasks endpoints
-  async getTasks(projectId?: number): Promise<Task[]> {
+  async getTasks(projectId?: string): Promise<Task[]> {
     const query = projectId ? `?project_id=${projectId}` : '';
     return this.request<Task[]>(`/api/tasks${query}`);
   }
 
-  async getTask(id: number): Promise<Task> {
+  async getTask(id: string): Promise<Task> {
     return this.request<Task>(`/api/tasks/${id}`);
   }
 
@@ -119,26 +145,26 @@ class ApiClient {
     });
   }
 
-  async updateTask(id: number, data: Partial<CreateTaskData>): Promise<Task> {
+  async updateTask(id: string, data: Partial<CreateTaskData>): Promise<Task> {
     return this.request<Task>(`/api/tasks/${id}`, {
       method: 'PUT',
       body: JSON.stringify(data),
     });
   }
 
-  async deleteTask(id: number): Promise<void> {
+  async deleteTask(id: string): Promise<void> {
     return this.request<void>(`/api/tasks/${id}`, {
       method: 'DELETE',
     });
   }
 
   // Log entries endpoints
-  async getLogEntries(projectId?: number): Promise<LogEntry[]> {
+  async getLogEntries(projectId?: string): Promise<LogEntry[]> {
     const query = projectId ? `?project_id=${projectId}` : '';
     return this.request<LogEntry[]>(`/api/log-entries${query}`);
   }
 
-  async getLogEntry(id: number): Promise<LogEntry> {
+  async getLogEntry(id: string): Promise<LogEntry> {
     return this.request<LogEntry>(`/api/log-entries/${id}`);
   }
 
@@ -149,14 +175,14 @@ class ApiClient {
     });
   }
 
-  async updateLogEntry(id: number, data: Partial<CreateLogEntryData>): Promise<LogEntry> {
+  async updateLogEntry(id: string, data: Partial<CreateLogEntryData>): Promise<LogEntry> {
     return this.request<LogEntry>(`/api/log-entries/${id}`, {
       method: 'PUT',
       body: JSON.stringify(data),
     });
   }
 
-  async deleteLogEntry(id: number): Promise<void> {
+  async deleteLogEntry(id: string): Promise<void> {
     return this.request<void>(`/api/log-entries/${id}`, {
       method: 'DELETE',
     });
@@ -1,5 +1,5 @@
 export interface User {
-  id: number;
+  id: string;
   email: string;
   name: string;
   created_at: string;
@@ -7,32 +7,60 @@ export interface User {
 }
 
 export interface Project {
-  id: number;
-  user_id: number;
+  id: string;
+  user_id: string;
+  workspace_id: string;
   name: string;
   description: string;
+  client_id?: string;
+  hourly_rate_cents?: number;
+  currency: string;
+  archived_at?: string;
   created_at: string;
   updated_at: string;
 }
 
 export interface Task {
-  id: number;
-  user_id: number;
-  project_id: number;
+  id: string;
+  user_id: string;
+  project_id: string;
   title: string;
   description: string;
-  status: 'todo' | 'in_progress' | 'done';
+  // One of the project's workflow status keys.
+  status: string;
   created_at: string;
   updated_at: string;
 }
 
+export type StatusCategory = 'open' | 'active' | 'closed';
+
+export interface TaskStatus {
+  id: string;
+  project_id: string;
+  key: string;
+  name: string;
+  category: StatusCategory;
+  position: number;
+  // null allows a move to any status.
+  allowed_transitions: string[] | null;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface Workflow {
+  project_id: string;
+  statuses: TaskStatus[];
+}
+
 export interface LogEntry {
-  id: number;
-  user_id: number;
-  task_id?: number;
-  project_id?: number;
+  id: string;
+  user_id: string;
+  task_id?: string;
+  project_id?: string;
   content: string;
   log_date: string;
+  duration_minutes?: number;
+  billable: boolean;
   created_at: string;
   updated_at: string;
 }
@@ -54,15 +82,26 @@ export interface CreateProjectData {
 }
 
 export interface CreateTaskData {
-  project_id: number;
+  project_id: string;
   title: string;
   description: string;
   status?: string;
 }
 
 export interface CreateLogEntryData {
-  task_id?: number;
-  project_id?: number;
+  task_id?: string;
+  project_id?: string;
   content: string;
   log_date?: string;
 }
+
+export interface WorkflowStatusData {
+  key: string;
+  name: string;
+  category: StatusCategory;
+  allowed_transitions?: string[] | null;
+}
+
+export interface UpdateWorkflowData {
+  statuses: WorkflowStatusData[];
+}
@@ -6,14 +6,16 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
+	// Embed the timezone database, which the runtime image does not ship,
+	// for user timezones.
+	_ "time/tzdata"
 
 	"github.com/chrispotter/makerlog/services/api/internal/database"
-	"github.com/chrispotter/makerlog/services/api/internal/handlers"
-	"github.com/chrispotter/makerlog/services/api/internal/middleware"
-	"github.com/go-chi/chi/v5"
-	chimiddleware "github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
+	"github.com/chrispotter/makerlog/services/api/internal/jobs"
+	"github.com/chrispotter/makerlog/services/api/internal/mail"
+	"github.com/chrispotter/makerlog/services/api/internal/server"
 	"github.com/gorilla/sessions"
 	_ "github.com/lib/pq"
 )
@@ -24,6 +26,24 @@ func main() {
 	sessionSecret := getEnv("SESSION_SECRET", "your-secret-key-change-this-in-production")
 	port := getEnv("PORT", "8080")
 	frontendURL := getEnv("FRONTEND_URL", "http://localhost:3000")
+	apiURL := getEnv("API_URL", "http://localhost:"+port)
+	recurrenceInterval := getEnvDuration("RECURRENCE_INTERVAL", time.Hour)
+	recurrenceLookahead := getEnvDuration("RECURRENCE_LOOKAHEAD", 7*24*time.Hour)
+	trashRetention := getEnvDuration("TRASH_RETENTION", 30*24*time.Hour)
+	trashPurgeInterval := getEnvDuration("TRASH_PURGE_INTERVAL", time.Hour)
+	revisionLimit := getEnvInt("REVISION_LIMIT", database.DefaultRevisionLimit)
+	invitationTTL := getEnvDuration("INVITATION_TTL", 7*24*time.Hour)
+	idempotencyTTL := getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+	mailDir := getEnv("MAIL_DIR", "mail")
+	mailFrom := getEnv("MAIL_FROM", "noreply@localhost")
+	dueReminderInterval := getEnvDuration("DUE_REMINDER_INTERVAL", time.Hour)
+	dueReminderLookahead := getEnvDuration("DUE_REMINDER_LOOKAHEAD", 24*time.Hour)
+	notificationDigestInterval := getEnvDuration("NOTIFICATION_DIGEST_INTERVAL", 24*time.Hour)
+	digestInterval := getEnvDuration("DIGEST_INTERVAL", 15*time.Minute)
+	digestHour := getEnvHour("DIGEST_HOUR", 17)
+	smtpAddr := getEnv("SMTP_ADDR", "")
+	smtpUsername := getEnv("SMTP_USERNAME", "")
+	smtpPassword := getEnv("SMTP_PASSWORD", "")
 
 	// Warn if using default session secret
 	if sessionSecret == "your-secret-key-change-this-in-production" {
@@ -63,71 +83,41 @@ func main() {
 
 	// Setup database queries
 	queries := database.New(db)
+	queries.SetRevisionLimit(revisionLimit)
 
-	// Setup handlers
-	authHandler := handlers.NewAuthHandler(queries, sessionStore)
-	projectHandler := handlers.NewProjectHandler(queries)
-	taskHandler := handlers.NewTaskHandler(queries)
-	logEntryHandler := handlers.NewLogEntryHandler(queries)
-
-	// Setup router
-	r := chi.NewRouter()
-
-	// Middleware
-	r.Use(chimiddleware.Logger)
-	r.Use(chimiddleware.Recoverer)
-	r.Use(chimiddleware.Timeout(60 * time.Second))
-
-	// CORS configuration
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{frontendURL},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
-
-	// Public routes
-	r.Post("/api/auth/register", authHandler.Register)
-	r.Post("/api/auth/login", authHandler.Login)
-
-	// Protected routes
-	r.Group(func(r chi.Router) {
-		r.Use(middleware.Auth(sessionStore))
-
-		// Auth routes
-		r.Post("/api/auth/logout", authHandler.Logout)
-		r.Get("/api/auth/me", authHandler.Me)
-
-		// Projects routes
-		r.Get("/api/projects", projectHandler.List)
-		r.Post("/api/projects", projectHandler.Create)
-		r.Get("/api/projects/{id}", projectHandler.Get)
-		r.Put("/api/projects/{id}", projectHandler.Update)
-		r.Delete("/api/projects/{id}", projectHandler.Delete)
-
-		// Tasks routes
-		r.Get("/api/tasks", taskHandler.List)
-		r.Post("/api/tasks", taskHandler.Create)
-		r.Get("/api/tasks/{id}", taskHandler.Get)
-		r.Put("/api/tasks/{id}", taskHandler.Update)
-		r.Delete("/api/tasks/{id}", taskHandler.Delete)
-
-		// Log entries routes
-		r.Get("/api/log-entries", logEntryHandler.List)
-		r.Post("/api/log-entries", logEntryHandler.Create)
-		r.Get("/api/log-entries/{id}", logEntryHandler.Get)
-		r.Put("/api/log-entries/{id}", logEntryHandler.Update)
-		r.Delete("/api/log-entries/{id}", logEntryHandler.Delete)
-
-		// Today route - get today's log entries
-		r.Get("/api/today", logEntryHandler.Today)
+	// Outgoing email goes through SMTP when configured, and is written to
+	// files otherwise
+	var mailer mail.Mailer = mail.NewFileMailer(mailDir, mailFrom)
+	if smtpAddr != "" {
+		mailer = mail.NewSMTPMailer(smtpAddr, smtpUsername, smtpPassword, mailFrom)
+	}
+
+	// Start background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	jobs.Start(jobsCtx,
+		jobs.RecurringTasks(queries, recurrenceInterval, recurrenceLookahead),
+		jobs.PurgeTrash(queries, trashPurgeInterval, trashRetention),
+		jobs.DueReminders(queries, dueReminderInterval, dueReminderLookahead),
+		jobs.NotificationDigest(queries, mailer, notificationDigestInterval),
+		jobs.EmailDigests(queries, mailer, digestInterval, digestHour),
+		jobs.PurgeIdempotencyKeys(queries, time.Hour),
+	)
+
+	r := server.NewRouter(server.Config{
+		Queries:        queries,
+		SessionStore:   sessionStore,
+		Mailer:         mailer,
+		FrontendURL:    frontendURL,
+		APIURL:         apiURL,
+		TrashRetention: trashRetention,
+		InvitationTTL:  invitationTTL,
+		IdempotencyTTL: idempotencyTTL,
 	})
 
 	// Start server with timeouts
 	log.Printf("Server starting on port %s", port)
-	server := &http.Server{
+	httpServer := &http.Server{
 		Addr:              ":" + port,
 		Handler:           r,
 		ReadTimeout:       15 * time.Second,
@@ -135,7 +125,7 @@ func main() {
 		ReadHeaderTimeout: 5 * time.Second,
 		IdleTimeout:       60 * time.Second,
 	}
-	if err := server.ListenAndServe(); err != nil {
+	if err := httpServer.ListenAndServe(); err != nil {
 		log.Fatal("Server failed to start:", err)
 	}
 }
@@ -146,3 +136,48 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvDuration reads a duration such as "30m" or "168h", falling back when
+// the variable is unset or invalid.
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("WARNING: Invalid %s %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return duration
+}
+
+// getEnvInt reads a positive integer, falling back when the variable is unset
+// or invalid.
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("WARNING: Invalid %s %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// getEnvHour reads an hour of the day, 0 to 23, falling back when the
+// variable is unset or invalid.
+func getEnvHour(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	hour, err := strconv.Atoi(value)
+	if err != nil || hour < 0 || hour > 23 {
+		log.Printf("WARNING: Invalid %s %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return hour
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestGetEnv(t *testing.T) {
@@ -64,3 +65,74 @@ func TestGetEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestGetEnvDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected time.Duration
+	}{
+		{name: "unset", envValue: "", expected: time.Hour},
+		{name: "valid", envValue: "15m", expected: 15 * time.Minute},
+		{name: "invalid", envValue: "soon", expected: time.Hour},
+		{name: "not positive", envValue: "0s", expected: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_DURATION", tt.envValue)
+
+			if result := getEnvDuration("TEST_DURATION", time.Hour); result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetEnvInt(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected int
+	}{
+		{name: "unset", envValue: "", expected: 50},
+		{name: "valid", envValue: "10", expected: 10},
+		{name: "invalid", envValue: "many", expected: 50},
+		{name: "not positive", envValue: "0", expected: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_INT", tt.envValue)
+
+			if result := getEnvInt("TEST_INT", 50); result != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetEnvHour(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected int
+	}{
+		{name: "unset", envValue: "", expected: 17},
+		{name: "midnight", envValue: "0", expected: 0},
+		{name: "last hour", envValue: "23", expected: 23},
+		{name: "past the day", envValue: "24", expected: 17},
+		{name: "negative", envValue: "-1", expected: 17},
+		{name: "invalid", envValue: "noon", expected: 17},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_HOUR", tt.envValue)
+
+			if result := getEnvHour("TEST_HOUR", 17); result != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/mail"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/chrispotter/makerlog/services/api/internal/server"
+	"github.com/gorilla/sessions"
+	_ "github.com/lib/pq"
+)
+
+// testEnv points the CLI at an empty config directory.
+func testEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MAKERLOG_SERVER", "")
+	t.Setenv("MAKERLOG_TOKEN", "")
+	t.Setenv("MAKERLOG_PASSWORD", "")
+}
+
+// runCLI runs the CLI at the time given and returns its exit status and
+// output.
+func runCLI(t *testing.T, now time.Time, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	a := &app{
+		stdin:  strings.NewReader(stdin),
+		stdout: &stdout,
+		stderr: &stderr,
+		http:   http.DefaultClient,
+		now:    func() time.Time { return now },
+	}
+	return a.run(args), stdout.String(), stderr.String()
+}
+
+func TestParseInterspersedFlags(t *testing.T) {
+	a := &app{stderr: &bytes.Buffer{}}
+	fs := a.flags("log", "log <content>")
+	project := projectFlag(fs)
+	positional, err := a.parse(fs, []string{"fixed", "-p", "api", "the", "--", "-x", "test"}, 1, -1)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := strings.Join(positional, " "); got != "fixed the -x test" {
+		t.Errorf("positional = %q", got)
+	}
+	if *project != "api" {
+		t.Errorf("project = %q, want api", *project)
+	}
+
+	fs = a.flags("whoami", "whoami")
+	if _, err := a.parse(fs, []string{"extra"}, 0, 0); err != errUsage {
+		t.Errorf("extra argument: err = %v, want errUsage", err)
+	}
+}
+
+func TestParseMinutes(t *testing.T) {
+	tests := map[string]int{"45": 45, "90m": 90, "1h30m": 90, "2h": 120, "1h29m40s": 90}
+	for value, want := range tests {
+		got, err := parseMinutes(value)
+		if err != nil || got != want {
+			t.Errorf("parseMinutes(%q) = %d, %v, want %d", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "0", "-5", "30s", "soon"} {
+		if _, err := parseMinutes(value); err == nil {
+			t.Errorf("parseMinutes(%q) succeeded", value)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	a := &app{now: func() time.Time { return time.Date(2025, 3, 1, 9, 0, 0, 0, time.Local) }}
+	tests := map[string]string{
+		"today":      "2025-03-01",
+		"yesterday":  "2025-02-28",
+		"tomorrow":   "2025-03-02",
+		"2024-12-31": "2024-12-31",
+	}
+	for value, want := range tests {
+		if got, err := a.parseDate(value); err != nil || got != want {
+			t.Errorf("parseDate(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	if _, err := a.parseDate("31/12/2024"); err == nil {
+		t.Error("parseDate accepted 31/12/2024")
+	}
+}
+
+func TestResolveID(t *testing.T) {
+	ids := []string{"0a1b2c3d-0000-4000-8000-000000000001", "0a1b9999-0000-4000-8000-000000000002"}
+	if id, err := resolveID("task", "0A1B2", ids); err != nil || id != ids[0] {
+		t.Errorf("unique prefix: %q, %v", id, err)
+	}
+	if _, err := resolveID("task", "0a1b", ids); err == nil || !strings.Contains(err.Error(), "more than one") {
+		t.Errorf("ambiguous prefix: %v", err)
+	}
+	if _, err := resolveID("task", "ffff", ids); err == nil {
+		t.Error("unknown prefix resolved")
+	}
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	testEnv(t)
+	var cfg config
+	if ok, err := loadFile(configFile, &cfg); ok || err != nil {
+		t.Fatalf("loadFile before save = %v, %v", ok, err)
+	}
+	if err := saveFile(configFile, config{Server: "http://example.test", Token: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := loadFile(configFile, &cfg); !ok || err != nil {
+		t.Fatalf("loadFile after save = %v, %v", ok, err)
+	}
+	if cfg.Server != "http://example.test" || cfg.Token != "secret" {
+		t.Errorf("config = %+v", cfg)
+	}
+	dir, err := configDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dir + "/" + configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		t.Errorf("config file mode = %v, want it private", perm)
+	}
+}
+
+// stubAPI fakes the endpoints the login, log and timer commands use, and
+// records the log entries posted and the access tokens revoked.
+type stubAPI struct {
+	posted  []models.CreateLogEntryRequest
+	revoked []string
+}
+
+func (s *stubAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api/auth/login" {
+		http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "session-1"})
+		writeTestJSON(w, models.User{ID: "u1", Email: "ada@example.com", Name: "Ada"})
+		return
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil && cookie.Value == "session-1" {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/access-tokens":
+			w.WriteHeader(http.StatusCreated)
+			writeTestJSON(w, models.AccessToken{ID: "33333333-0000-4000-8000-000000000000", Name: "makerlog-cli", Scope: models.ScopeWrite, Token: "token-1"})
+		case r.Method == http.MethodPost && r.URL.Path == "/api/auth/logout":
+			writeTestJSON(w, map[string]string{"message": "Logged out successfully"})
+		default:
+			http.Error(w, "The CLI should use its access token", http.StatusBadRequest)
+		}
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token-1" || len(s.revoked) > 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/access-tokens/"):
+		s.revoked = append(s.revoked, strings.TrimPrefix(r.URL.Path, "/api/access-tokens/"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/projects":
+		projects := []models.Project{{ID: "11111111-0000-4000-8000-000000000000", Name: "API"}}
+		if r.URL.Query().Get("archived") == "true" {
+			projects = []models.Project{}
+		}
+		writeTestJSON(w, projects)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/log-entries":
+		var req models.CreateLogEntryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		s.posted = append(s.posted, req)
+		w.WriteHeader(http.StatusCreated)
+		writeTestJSON(w, models.LogEntry{ID: "22222222-0000-4000-8000-000000000000", Content: req.Content})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeTestJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		panic(err)
+	}
+}
+
+func TestLoginLogAndTimer(t *testing.T) {
+	testEnv(t)
+	api := &stubAPI{}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.Local)
+
+	if code, _, stderr := runCLI(t, now, "", "whoami", "--server", srv.URL); code != 1 || !strings.Contains(stderr, "not signed in") {
+		t.Errorf("whoami signed out = %d, %q", code, stderr)
+	}
+	code, stdout, stderr := runCLI(t, now, "ada@example.com\nhunter2\n", "login", "--server", srv.URL)
+	if code != 0 || !strings.Contains(stdout, "Signed in") {
+		t.Fatalf("login = %d, %q, %q", code, stdout, stderr)
+	}
+
+	// The saved login is used from here on.
+	code, stdout, stderr = runCLI(t, now, "", "log", "fixed", "the", "build", "-p", "api", "--duration", "1h15m", "--date", "yesterday")
+	if code != 0 || !strings.Contains(stdout, "Logged 22222222") {
+		t.Fatalf("log = %d, %q, %q", code, stdout, stderr)
+	}
+	got := api.posted[0]
+	if got.Content != "fixed the build" || got.LogDate != "2025-02-28" || *got.DurationMinutes != 75 || *got.ProjectID != "11111111-0000-4000-8000-000000000000" {
+		t.Errorf("posted %+v", got)
+	}
+
+	if code, _, stderr = runCLI(t, now, "", "timer", "start", "reviewing", "-p", "API"); code != 0 {
+		t.Fatalf("timer start = %d, %q", code, stderr)
+	}
+	if code, _, stderr = runCLI(t, now, "", "timer", "start"); code != 1 || !strings.Contains(stderr, "already running") {
+		t.Errorf("second timer start = %d, %q", code, stderr)
+	}
+	code, stdout, _ = runCLI(t, now.Add(40*time.Minute), "", "timer", "--json")
+	var running timer
+	if err := json.Unmarshal([]byte(stdout), &running); code != 0 || err != nil || running.Note != "reviewing" {
+		t.Errorf("timer status = %d, %q", code, stdout)
+	}
+	code, stdout, stderr = runCLI(t, now.Add(40*time.Minute+20*time.Second), "", "timer", "stop", "--billable")
+	if code != 0 || !strings.Contains(stdout, "Logged 40m") {
+		t.Fatalf("timer stop = %d, %q, %q", code, stdout, stderr)
+	}
+	got = api.posted[1]
+	if got.Content != "reviewing" || got.LogDate != "2025-03-01" || *got.DurationMinutes != 40 || !got.Billable {
+		t.Errorf("posted %+v", got)
+	}
+	if code, stdout, _ = runCLI(t, now, "", "timer", "status"); code != 0 || !strings.Contains(stdout, "No timer") {
+		t.Errorf("timer status after stop = %d, %q", code, stdout)
+	}
+
+	if code, _, _ = runCLI(t, now, "", "logout"); code != 0 {
+		t.Errorf("logout = %d", code)
+	}
+	if len(api.revoked) != 1 || api.revoked[0] != "33333333-0000-4000-8000-000000000000" {
+		t.Errorf("revoked %v, want the token from login", api.revoked)
+	}
+	if code, _, _ = runCLI(t, now, "", "log", "more"); code != 1 {
+		t.Errorf("log after logout = %d, want 1", code)
+	}
+}
+
+// TestEndToEnd drives the CLI against the real API. It needs a migrated
+// database, so it only runs when MAKERLOG_TEST_DATABASE_URL is set.
+func TestEndToEnd(t *testing.T) {
+	dbURL := os.Getenv("MAKERLOG_TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("MAKERLOG_TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+	testEnv(t)
+
+	srv := httptest.NewServer(server.NewRouter(server.Config{
+		Queries:        database.New(db),
+		SessionStore:   sessions.NewCookieStore([]byte("test-secret")),
+		Mailer:         mail.NewFileMailer(t.TempDir(), "noreply@localhost"),
+		FrontendURL:    "http://localhost:3000",
+		APIURL:         "http://localhost:8080",
+		TrashRetention: time.Hour,
+		InvitationTTL:  time.Hour,
+	}))
+	defer srv.Close()
+
+	email := "cli-" + time.Now().Format("20060102150405.000000000") + "@example.com"
+	body, err := json.Marshal(models.RegisterRequest{Email: email, Password: "correct horse", Name: "CLI"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(srv.URL+"/api/auth/register", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("register = %d", resp.StatusCode)
+	}
+
+	now := time.Now()
+	steps := [][]string{
+		{"login", "--server", srv.URL, "--email", email, "--password", "correct horse"},
+		{"whoami"},
+		{"projects", "create", "CLI test"},
+		{"tasks", "create", "write the docs", "-p", "cli test", "--due", "today"},
+		{"log", "started the docs", "-p", "CLI test", "--duration", "30m"},
+		{"today"},
+		{"projects"},
+	}
+	for _, args := range steps {
+		if code, stdout, stderr := runCLI(t, now, "", args...); code != 0 {
+			t.Fatalf("%v = %d\n%s%s", args, code, stdout, stderr)
+		}
+	}
+
+	code, stdout, stderr := runCLI(t, now, "", "tasks", "--json")
+	var tasks []models.Task
+	if err := json.Unmarshal([]byte(stdout), &tasks); code != 0 || err != nil || len(tasks) != 1 {
+		t.Fatalf("tasks = %d, %q, %q", code, stdout, stderr)
+	}
+	if code, stdout, stderr = runCLI(t, now, "", "tasks", "done", shortID(tasks[0].ID)); code != 0 {
+		t.Fatalf("tasks done = %d, %q, %q", code, stdout, stderr)
+	}
+	if code, stdout, _ = runCLI(t, now, "", "tasks", "--json"); code != 0 || strings.TrimSpace(stdout) != "[]" {
+		t.Errorf("open tasks after done = %q", stdout)
+	}
+	if code, stdout, _ = runCLI(t, now, "", "entries"); code != 0 || !strings.Contains(stdout, "started the docs") {
+		t.Errorf("entries = %q", stdout)
+	}
+
+	// Logging out revokes the saved access token, so it stops working even
+	// when passed with --token.
+	var cfg config
+	if _, err := loadFile(configFile, &cfg); err != nil || cfg.Token == "" {
+		t.Fatalf("config = %+v, %v", cfg, err)
+	}
+	if code, _, stderr = runCLI(t, now, "", "logout"); code != 0 {
+		t.Fatalf("logout = %d, %q", code, stderr)
+	}
+	if code, _, stderr = runCLI(t, now, "", "whoami", "--server", srv.URL, "--token", cfg.Token); code != 1 || !strings.Contains(stderr, "Unauthorized") {
+		t.Errorf("whoami with revoked token = %d, %q", code, stderr)
+	}
+}
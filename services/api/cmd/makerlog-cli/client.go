@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+// sessionCookie is the cookie the API keeps its session in. The CLI only
+// uses a session while logging in, to create an access token.
+const sessionCookie = "makerlog-session"
+
+// client calls the REST API as the user whose personal access token it
+// holds, or with session while logging in.
+type client struct {
+	server  string
+	token   string
+	session string
+	http    *http.Client
+}
+
+// apiError is an error response from the API.
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server responded %d %s", e.Status, http.StatusText(e.Status))
+	}
+	return e.Message
+}
+
+// send makes a request with body encoded as JSON, if it is not nil, and
+// turns error statuses into an *apiError.
+func (c *client) send(method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.server+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.session != "" {
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: c.session})
+	} else if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		// An unreadable message leaves the status to describe the error.
+		message, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		closeBody(resp)
+		if err != nil {
+			message = nil
+		}
+		return nil, &apiError{Status: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+	return resp, nil
+}
+
+// do makes a request and decodes the JSON response into out, unless out is
+// nil.
+func (c *client) do(method, path string, body, out interface{}) error {
+	resp, err := c.send(method, path, body)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// login signs in, creates a personal access token with the name given and
+// signs out again, so only the token is left.
+func (c *client) login(email, password, name string, user interface{}) (*models.AccessToken, error) {
+	resp, err := c.send(http.MethodPost, "/api/auth/login", map[string]string{"email": email, "password": password})
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+	if err := json.NewDecoder(resp.Body).Decode(user); err != nil {
+		return nil, err
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == sessionCookie {
+			c.session = cookie.Value
+		}
+	}
+	if c.session == "" {
+		return nil, fmt.Errorf("server did not start a session")
+	}
+	defer func() { c.session = "" }()
+
+	var token models.AccessToken
+	if err := c.do(http.MethodPost, "/api/access-tokens", models.CreateAccessTokenRequest{Name: name}, &token); err != nil {
+		return nil, fmt.Errorf("creating access token: %w", err)
+	}
+	if err := c.do(http.MethodPost, "/api/auth/logout", nil, nil); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func closeBody(resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		_ = err
+	}
+}
@@ -0,0 +1,1038 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/google/uuid"
+	"golang.org/x/term"
+)
+
+// subcommand splits off the subcommand in args if it is one of names, and
+// otherwise returns the first name, the default, and args untouched.
+func subcommand(args []string, names ...string) (string, []string) {
+	if len(args) > 0 {
+		for _, name := range names {
+			if args[0] == name {
+				return name, args[1:]
+			}
+		}
+	}
+	return names[0], args
+}
+
+func (a *app) login(args []string) error {
+	fs := a.flags("login", "login [--email EMAIL] [--password PASSWORD]")
+	email := fs.String("email", "", "email address (prompted for if missing)")
+	password := fs.String("password", "", "password (default $MAKERLOG_PASSWORD, prompted for if missing)")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	in := bufio.NewReader(a.stdin)
+	if *email == "" {
+		printf(a.stderr, "Email: ")
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("reading email: %w", err)
+		}
+		*email = strings.TrimSpace(line)
+	}
+	if *password == "" {
+		*password = os.Getenv("MAKERLOG_PASSWORD")
+	}
+	if *password == "" {
+		printf(a.stderr, "Password: ")
+		if f, ok := a.stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+			secret, err := term.ReadPassword(int(f.Fd()))
+			printf(a.stderr, "\n")
+			if err != nil {
+				return fmt.Errorf("reading password: %w", err)
+			}
+			*password = string(secret)
+		} else {
+			line, err := in.ReadString('\n')
+			if err != nil && line == "" {
+				return fmt.Errorf("reading password: %w", err)
+			}
+			*password = strings.TrimRight(line, "\r\n")
+		}
+	}
+
+	name := "makerlog-cli"
+	if host, err := os.Hostname(); err == nil && host != "" {
+		name += " on " + host
+	}
+	var user models.User
+	token, err := a.client.login(*email, *password, name, &user)
+	if err != nil {
+		return err
+	}
+	a.cfg.Server = a.client.server
+	a.cfg.Token = token.Token
+	a.cfg.TokenID = token.ID
+	if err := saveFile(configFile, a.cfg); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	return a.output(user, func(w io.Writer) {
+		printf(w, "Signed in to %s as %s <%s>\n", a.client.server, user.Name, user.Email)
+	})
+}
+
+func (a *app) logout(args []string) error {
+	fs := a.flags("logout", "logout")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	// Revoke the token saved at login, unless another was given for this
+	// run. A token that is already gone needs no revoking.
+	if a.cfg.TokenID != "" && a.client.token == a.cfg.Token {
+		if err := a.client.do(http.MethodDelete, "/api/access-tokens/"+a.cfg.TokenID, nil, nil); err != nil {
+			var apiErr *apiError
+			if !errors.As(err, &apiErr) || (apiErr.Status != http.StatusUnauthorized && apiErr.Status != http.StatusNotFound) {
+				return err
+			}
+		}
+	}
+	a.cfg.Token = ""
+	a.cfg.TokenID = ""
+	if err := saveFile(configFile, a.cfg); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	if !a.json {
+		printf(a.stdout, "Signed out\n")
+	}
+	return nil
+}
+
+func (a *app) whoami(args []string) error {
+	fs := a.flags("whoami", "whoami")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	var user models.User
+	if err := a.client.do(http.MethodGet, "/api/auth/me", nil, &user); err != nil {
+		return err
+	}
+	return a.output(user, func(w io.Writer) {
+		printf(w, "%s <%s> on %s\n", user.Name, user.Email, a.client.server)
+	})
+}
+
+// todayView is the JSON of the today command.
+type todayView struct {
+	Date       string            `json:"date"`
+	LogEntries []models.LogEntry `json:"log_entries"`
+	Overdue    []models.Task     `json:"overdue"`
+	Due        []models.Task     `json:"due"`
+	Timer      *timer            `json:"timer,omitempty"`
+}
+
+func (a *app) today(args []string) error {
+	fs := a.flags("today", "today")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	view := todayView{Date: a.now().Format("2006-01-02")}
+	if err := a.client.do(http.MethodGet, "/api/today", nil, &view.LogEntries); err != nil {
+		return err
+	}
+	if err := a.client.do(http.MethodGet, "/api/tasks?due=overdue", nil, &view.Overdue); err != nil {
+		return err
+	}
+	if err := a.client.do(http.MethodGet, "/api/tasks/upcoming?days=1", nil, &view.Due); err != nil {
+		return err
+	}
+	var running timer
+	if ok, err := loadFile(timerFile, &running); err != nil {
+		return err
+	} else if ok {
+		view.Timer = &running
+	}
+	names, err := a.projectNames()
+	if err != nil {
+		return err
+	}
+
+	return a.output(view, func(w io.Writer) {
+		printf(w, "Today, %s\n", a.now().Format("Monday January 2"))
+		printf(w, "\nLog\n")
+		if len(view.LogEntries) == 0 {
+			printf(w, "  Nothing logged yet\n")
+		}
+		for _, logEntry := range view.LogEntries {
+			printf(w, "  - %s\n", describeLogEntry(logEntry, names))
+		}
+		if len(view.Overdue)+len(view.Due) > 0 {
+			printf(w, "\nDue\n")
+			for _, task := range view.Overdue {
+				printf(w, "  ! %s (%s, due %s)\n", task.Title, names[task.ProjectID], task.DueDate.Format("Jan 2"))
+			}
+			for _, task := range view.Due {
+				printf(w, "  - %s (%s)\n", task.Title, names[task.ProjectID])
+			}
+		}
+		if view.Timer != nil {
+			printf(w, "\nTimer\n  %s\n", a.describeTimer(view.Timer, names))
+		}
+	})
+}
+
+func (a *app) logCreate(args []string) error {
+	fs := a.flags("log", "log <content> [-p project] [-t task] [--date DATE] [--duration DURATION] [--billable]")
+	project := projectFlag(fs)
+	task := fs.String("t", "", "task ID")
+	date := fs.String("date", "today", "day the work was done: YYYY-MM-DD, today or yesterday")
+	duration := fs.String("duration", "", "time spent, e.g. 90m, 1h30m or 45")
+	billable := fs.Bool("billable", false, "mark the time billable")
+	positional, err := a.parse(fs, args, 1, -1)
+	if err != nil {
+		return err
+	}
+
+	req := models.CreateLogEntryRequest{Content: strings.Join(positional, " "), Billable: *billable}
+	if req.LogDate, err = a.parseDate(*date); err != nil {
+		return err
+	}
+	if *duration != "" {
+		minutes, err := parseMinutes(*duration)
+		if err != nil {
+			return err
+		}
+		req.DurationMinutes = &minutes
+	}
+	if *project != "" {
+		id, err := a.resolveProject(*project)
+		if err != nil {
+			return err
+		}
+		req.ProjectID = &id
+	}
+	if *task != "" {
+		id, err := a.resolveTask(*task)
+		if err != nil {
+			return err
+		}
+		req.TaskID = &id
+	}
+
+	var logEntry models.LogEntry
+	if err := a.client.do(http.MethodPost, "/api/log-entries", req, &logEntry); err != nil {
+		return err
+	}
+	return a.output(logEntry, func(w io.Writer) {
+		printf(w, "Logged %s\n", shortID(logEntry.ID))
+	})
+}
+
+func (a *app) entries(args []string) error {
+	name, args := subcommand(args, "list", "edit", "delete")
+	switch name {
+	case "edit":
+		return a.entriesEdit(args)
+	case "delete":
+		return a.entriesDelete(args)
+	}
+
+	fs := a.flags("entries list", "entries list [-p project] [--limit N]")
+	project := projectFlag(fs)
+	limit := fs.Int("limit", 20, "show at most this many entries, newest first (0 for all)")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	path := "/api/log-entries"
+	if *project != "" {
+		id, err := a.resolveProject(*project)
+		if err != nil {
+			return err
+		}
+		path += "?project_id=" + url.QueryEscape(id)
+	}
+	logEntries := []models.LogEntry{}
+	if err := a.client.do(http.MethodGet, path, nil, &logEntries); err != nil {
+		return err
+	}
+	if *limit > 0 && len(logEntries) > *limit {
+		logEntries = logEntries[:*limit]
+	}
+	names, err := a.projectNames()
+	if err != nil {
+		return err
+	}
+	return a.output(logEntries, func(w io.Writer) {
+		rows := [][]string{{"ID", "DATE", "PROJECT", "TIME", "CONTENT"}}
+		for _, logEntry := range logEntries {
+			project, minutes := "", ""
+			if logEntry.ProjectID != nil {
+				project = names[*logEntry.ProjectID]
+			}
+			if logEntry.DurationMinutes != nil {
+				minutes = formatMinutes(*logEntry.DurationMinutes)
+			}
+			rows = append(rows, []string{
+				shortID(logEntry.ID), logEntry.LogDate.Format("2006-01-02"), project, minutes, firstLine(logEntry.Content),
+			})
+		}
+		table(w, rows)
+	})
+}
+
+func (a *app) entriesEdit(args []string) error {
+	fs := a.flags("entries edit", "entries edit <id> [--content TEXT] [-p project] [-t task] [--date DATE] [--duration DURATION] [--billable=BOOL]")
+	content := fs.String("content", "", "new content")
+	project := projectFlag(fs)
+	task := fs.String("t", "", "task ID, or none to unlink it")
+	date := fs.String("date", "", "day the work was done: YYYY-MM-DD, today or yesterday")
+	duration := fs.String("duration", "", "time spent, or none to clear it")
+	billable := fs.Bool("billable", false, "whether the time is billable")
+	positional, err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	id, err := a.resolveLogEntry(positional[0])
+	if err != nil {
+		return err
+	}
+
+	patch := map[string]interface{}{}
+	set := setFlags(fs)
+	if set["content"] {
+		patch["content"] = *content
+	}
+	if set["p"] || set["project"] {
+		if patch["project_id"], err = a.optionalRef(*project, a.resolveProject); err != nil {
+			return err
+		}
+	}
+	if set["t"] {
+		if patch["task_id"], err = a.optionalRef(*task, a.resolveTask); err != nil {
+			return err
+		}
+	}
+	if set["date"] {
+		if patch["log_date"], err = a.parseDate(*date); err != nil {
+			return err
+		}
+	}
+	if set["duration"] {
+		patch["duration_minutes"] = nil
+		if *duration != "none" {
+			minutes, err := parseMinutes(*duration)
+			if err != nil {
+				return err
+			}
+			patch["duration_minutes"] = minutes
+		}
+	}
+	if set["billable"] {
+		patch["billable"] = *billable
+	}
+	if len(patch) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	var logEntry models.LogEntry
+	if err := a.client.do(http.MethodPatch, "/api/log-entries/"+id, patch, &logEntry); err != nil {
+		return err
+	}
+	return a.output(logEntry, func(w io.Writer) {
+		printf(w, "Updated %s\n", shortID(logEntry.ID))
+	})
+}
+
+func (a *app) entriesDelete(args []string) error {
+	fs := a.flags("entries delete", "entries delete <id>")
+	positional, err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	id, err := a.resolveLogEntry(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := a.client.do(http.MethodDelete, "/api/log-entries/"+id, nil, nil); err != nil {
+		return err
+	}
+	if !a.json {
+		printf(a.stdout, "Deleted %s; restore it from the trash in the web app\n", shortID(id))
+	}
+	return nil
+}
+
+func (a *app) projects(args []string) error {
+	name, args := subcommand(args, "list", "create", "edit", "delete")
+	switch name {
+	case "create":
+		return a.projectsCreate(args)
+	case "edit":
+		return a.projectsEdit(args)
+	case "delete":
+		return a.projectsDelete(args)
+	}
+
+	fs := a.flags("projects list", "projects list [--archived]")
+	archived := fs.Bool("archived", false, "list archived projects instead")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	path := "/api/projects"
+	if *archived {
+		path += "?archived=true"
+	}
+	projects := []models.Project{}
+	if err := a.client.do(http.MethodGet, path, nil, &projects); err != nil {
+		return err
+	}
+	return a.output(projects, func(w io.Writer) {
+		rows := [][]string{{"ID", "NAME", "DESCRIPTION"}}
+		for _, project := range projects {
+			rows = append(rows, []string{shortID(project.ID), project.Name, firstLine(project.Description)})
+		}
+		table(w, rows)
+	})
+}
+
+func (a *app) projectsCreate(args []string) error {
+	fs := a.flags("projects create", "projects create <name> [--description TEXT] [--workspace ID]")
+	description := fs.String("description", "", "what the project is about")
+	workspace := fs.String("workspace", "", "workspace ID (default your personal workspace)")
+	positional, err := a.parse(fs, args, 1, -1)
+	if err != nil {
+		return err
+	}
+	req := models.CreateProjectRequest{Name: strings.Join(positional, " "), Description: *description}
+	if *workspace != "" {
+		req.WorkspaceID = workspace
+	}
+	var project models.Project
+	if err := a.client.do(http.MethodPost, "/api/projects", req, &project); err != nil {
+		return err
+	}
+	return a.output(project, func(w io.Writer) {
+		printf(w, "Created project %s (%s)\n", project.Name, shortID(project.ID))
+	})
+}
+
+func (a *app) projectsEdit(args []string) error {
+	fs := a.flags("projects edit", "projects edit <project> [--name NAME] [--description TEXT]")
+	name := fs.String("name", "", "new name")
+	description := fs.String("description", "", "new description")
+	positional, err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	id, err := a.resolveProject(positional[0])
+	if err != nil {
+		return err
+	}
+	patch := map[string]interface{}{}
+	set := setFlags(fs)
+	if set["name"] {
+		patch["name"] = *name
+	}
+	if set["description"] {
+		patch["description"] = *description
+	}
+	if len(patch) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	var project models.Project
+	if err := a.client.do(http.MethodPatch, "/api/projects/"+id, patch, &project); err != nil {
+		return err
+	}
+	return a.output(project, func(w io.Writer) {
+		printf(w, "Updated project %s\n", project.Name)
+	})
+}
+
+func (a *app) projectsDelete(args []string) error {
+	fs := a.flags("projects delete", "projects delete <project>")
+	positional, err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	id, err := a.resolveProject(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := a.client.do(http.MethodDelete, "/api/projects/"+id, nil, nil); err != nil {
+		return err
+	}
+	if !a.json {
+		printf(a.stdout, "Deleted project %s; restore it from the trash in the web app\n", shortID(id))
+	}
+	return nil
+}
+
+func (a *app) tasks(args []string) error {
+	name, args := subcommand(args, "list", "create", "edit", "done", "delete")
+	switch name {
+	case "create":
+		return a.tasksCreate(args)
+	case "edit":
+		return a.tasksEdit(args)
+	case "done":
+		return a.tasksDone(args)
+	case "delete":
+		return a.tasksDelete(args)
+	}
+
+	fs := a.flags("tasks list", "tasks list [-p project] [--priority PRIORITY] [--due overdue|this_week] [--all]")
+	project := projectFlag(fs)
+	priority := fs.String("priority", "", "only tasks of this priority")
+	due := fs.String("due", "", "only open tasks due: overdue or this_week")
+	all := fs.Bool("all", false, "include completed tasks")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	query := url.Values{}
+	if *project != "" {
+		id, err := a.resolveProject(*project)
+		if err != nil {
+			return err
+		}
+		query.Set("project_id", id)
+	}
+	if *priority != "" {
+		query.Set("priority", *priority)
+	}
+	if *due != "" {
+		query.Set("due", *due)
+	}
+	path := "/api/tasks"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var listed []models.Task
+	if err := a.client.do(http.MethodGet, path, nil, &listed); err != nil {
+		return err
+	}
+	tasks := []models.Task{}
+	for _, task := range listed {
+		if *all || task.CompletedAt == nil {
+			tasks = append(tasks, task)
+		}
+	}
+	names, err := a.projectNames()
+	if err != nil {
+		return err
+	}
+	return a.output(tasks, func(w io.Writer) {
+		rows := [][]string{{"ID", "PROJECT", "STATUS", "PRIORITY", "DUE", "TITLE"}}
+		for _, task := range tasks {
+			due := ""
+			if task.DueDate != nil {
+				due = task.DueDate.Format("2006-01-02")
+			}
+			rows = append(rows, []string{shortID(task.ID), names[task.ProjectID], task.Status, task.Priority, due, task.Title})
+		}
+		table(w, rows)
+	})
+}
+
+func (a *app) tasksCreate(args []string) error {
+	fs := a.flags("tasks create", "tasks create <title> -p project [--description TEXT] [--status STATUS] [--priority PRIORITY] [--due DATE]")
+	project := projectFlag(fs)
+	description := fs.String("description", "", "details of the task")
+	status := fs.String("status", "", "status key (default the project's first status)")
+	priority := fs.String("priority", "", "low, medium, high or urgent (default medium)")
+	due := fs.String("due", "", "due date: YYYY-MM-DD, today or tomorrow")
+	positional, err := a.parse(fs, args, 1, -1)
+	if err != nil {
+		return err
+	}
+	if *project == "" {
+		printf(a.stderr, "makerlog: tasks create needs a project (-p)\n")
+		return errUsage
+	}
+	projectID, err := a.resolveProject(*project)
+	if err != nil {
+		return err
+	}
+	req := models.CreateTaskRequest{
+		ProjectID:   projectID,
+		Title:       strings.Join(positional, " "),
+		Description: *description,
+		Status:      *status,
+		Priority:    *priority,
+	}
+	if *due != "" {
+		date, err := a.parseDate(*due)
+		if err != nil {
+			return err
+		}
+		req.DueDate = &date
+	}
+	var task models.Task
+	if err := a.client.do(http.MethodPost, "/api/tasks", req, &task); err != nil {
+		return err
+	}
+	return a.output(task, func(w io.Writer) {
+		printf(w, "Created task %s\n", shortID(task.ID))
+	})
+}
+
+func (a *app) tasksEdit(args []string) error {
+	fs := a.flags("tasks edit", "tasks edit <id> [--title TEXT] [--description TEXT] [--status STATUS] [--priority PRIORITY] [--due DATE|none]")
+	title := fs.String("title", "", "new title")
+	description := fs.String("description", "", "new description")
+	status := fs.String("status", "", "new status key")
+	priority := fs.String("priority", "", "low, medium, high or urgent")
+	due := fs.String("due", "", "due date: YYYY-MM-DD, today, tomorrow or none")
+	positional, err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	id, err := a.resolveTask(positional[0])
+	if err != nil {
+		return err
+	}
+	patch := map[string]interface{}{}
+	set := setFlags(fs)
+	if set["title"] {
+		patch["title"] = *title
+	}
+	if set["description"] {
+		patch["description"] = *description
+	}
+	if set["status"] {
+		patch["status"] = *status
+	}
+	if set["priority"] {
+		patch["priority"] = *priority
+	}
+	if set["due"] {
+		patch["due_date"] = nil
+		if *due != "none" {
+			if patch["due_date"], err = a.parseDate(*due); err != nil {
+				return err
+			}
+		}
+	}
+	if len(patch) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	var task models.Task
+	if err := a.client.do(http.MethodPatch, "/api/tasks/"+id, patch, &task); err != nil {
+		return err
+	}
+	return a.output(task, func(w io.Writer) {
+		printf(w, "Updated task %s\n", shortID(task.ID))
+	})
+}
+
+// tasksDone moves a task to the first closed status of its project's
+// workflow.
+func (a *app) tasksDone(args []string) error {
+	fs := a.flags("tasks done", "tasks done <id> [--force]")
+	force := fs.Bool("force", false, "complete the task even if tasks blocking it are open")
+	positional, err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	id, err := a.resolveTask(positional[0])
+	if err != nil {
+		return err
+	}
+	var task models.Task
+	if err := a.client.do(http.MethodGet, "/api/tasks/"+id, nil, &task); err != nil {
+		return err
+	}
+	var workflow models.Workflow
+	if err := a.client.do(http.MethodGet, "/api/projects/"+task.ProjectID+"/workflow", nil, &workflow); err != nil {
+		return err
+	}
+	status := ""
+	for _, s := range workflow.Statuses {
+		if s.Category == models.StatusCategoryClosed {
+			status = s.Key
+			break
+		}
+	}
+	if status == "" {
+		return fmt.Errorf("the project's workflow has no closed status")
+	}
+
+	path := "/api/tasks/" + id
+	if *force {
+		path += "?force=true"
+	}
+	if err := a.client.do(http.MethodPatch, path, map[string]string{"status": status}, &task); err != nil {
+		return err
+	}
+	return a.output(task, func(w io.Writer) {
+		printf(w, "Done: %s\n", task.Title)
+	})
+}
+
+func (a *app) tasksDelete(args []string) error {
+	fs := a.flags("tasks delete", "tasks delete <id>")
+	positional, err := a.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	id, err := a.resolveTask(positional[0])
+	if err != nil {
+		return err
+	}
+	if err := a.client.do(http.MethodDelete, "/api/tasks/"+id, nil, nil); err != nil {
+		return err
+	}
+	if !a.json {
+		printf(a.stdout, "Deleted task %s; restore it from the trash in the web app\n", shortID(id))
+	}
+	return nil
+}
+
+func (a *app) timer(args []string) error {
+	name, args := subcommand(args, "status", "start", "stop", "cancel")
+	switch name {
+	case "start":
+		return a.timerStart(args)
+	case "stop":
+		return a.timerStop(args)
+	case "cancel":
+		return a.timerCancel(args)
+	}
+
+	fs := a.flags("timer status", "timer status")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	var running timer
+	ok, err := loadFile(timerFile, &running)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return a.output(nil, func(w io.Writer) {
+			printf(w, "No timer running\n")
+		})
+	}
+	names, err := a.projectNames()
+	if err != nil {
+		return err
+	}
+	return a.output(running, func(w io.Writer) {
+		printf(w, "%s\n", a.describeTimer(&running, names))
+	})
+}
+
+func (a *app) timerStart(args []string) error {
+	fs := a.flags("timer start", "timer start [note] [-p project] [-t task]")
+	project := projectFlag(fs)
+	task := fs.String("t", "", "task ID")
+	positional, err := a.parse(fs, args, 0, -1)
+	if err != nil {
+		return err
+	}
+	var running timer
+	if ok, err := loadFile(timerFile, &running); err != nil {
+		return err
+	} else if ok {
+		return fmt.Errorf("a timer is already running since %s; stop or cancel it first", running.StartedAt.Local().Format("15:04"))
+	}
+
+	running = timer{Note: strings.Join(positional, " "), StartedAt: a.now()}
+	if *project != "" {
+		id, err := a.resolveProject(*project)
+		if err != nil {
+			return err
+		}
+		running.ProjectID = &id
+	}
+	if *task != "" {
+		id, err := a.resolveTask(*task)
+		if err != nil {
+			return err
+		}
+		running.TaskID = &id
+	}
+	if err := saveFile(timerFile, running); err != nil {
+		return err
+	}
+	return a.output(running, func(w io.Writer) {
+		printf(w, "Timer started at %s\n", running.StartedAt.Format("15:04"))
+	})
+}
+
+// timerStop logs the time since the timer started, rounded to the minute,
+// on the day it started, and clears the timer.
+func (a *app) timerStop(args []string) error {
+	fs := a.flags("timer stop", "timer stop [content] [--billable]")
+	billable := fs.Bool("billable", false, "mark the time billable")
+	positional, err := a.parse(fs, args, 0, -1)
+	if err != nil {
+		return err
+	}
+	var running timer
+	ok, err := loadFile(timerFile, &running)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no timer running")
+	}
+	content := firstNonEmpty(strings.Join(positional, " "), running.Note)
+	if content == "" {
+		printf(a.stderr, "makerlog: say what you did: makerlog timer stop <content>\n")
+		return errUsage
+	}
+
+	minutes := int(math.Round(a.now().Sub(running.StartedAt).Minutes()))
+	if minutes < 1 {
+		minutes = 1
+	}
+	req := models.CreateLogEntryRequest{
+		ProjectID:       running.ProjectID,
+		TaskID:          running.TaskID,
+		Content:         content,
+		LogDate:         running.StartedAt.Local().Format("2006-01-02"),
+		DurationMinutes: &minutes,
+		Billable:        *billable,
+	}
+	var logEntry models.LogEntry
+	if err := a.client.do(http.MethodPost, "/api/log-entries", req, &logEntry); err != nil {
+		return err
+	}
+	if err := removeFile(timerFile); err != nil {
+		return err
+	}
+	return a.output(logEntry, func(w io.Writer) {
+		printf(w, "Logged %s (%s)\n", formatMinutes(minutes), shortID(logEntry.ID))
+	})
+}
+
+func (a *app) timerCancel(args []string) error {
+	fs := a.flags("timer cancel", "timer cancel")
+	if _, err := a.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if err := removeFile(timerFile); err != nil {
+		return err
+	}
+	if !a.json {
+		printf(a.stdout, "Timer cancelled\n")
+	}
+	return nil
+}
+
+func (a *app) describeTimer(t *timer, names map[string]string) string {
+	elapsed := int(a.now().Sub(t.StartedAt).Minutes())
+	description := "Running for " + formatMinutes(elapsed)
+	if t.ProjectID != nil {
+		description += " on " + names[*t.ProjectID]
+	}
+	if t.Note != "" {
+		description += ": " + t.Note
+	}
+	return description
+}
+
+func describeLogEntry(logEntry models.LogEntry, names map[string]string) string {
+	description := firstLine(logEntry.Content)
+	if logEntry.ProjectID != nil {
+		description = "[" + names[*logEntry.ProjectID] + "] " + description
+	}
+	if logEntry.DurationMinutes != nil {
+		description += " (" + formatMinutes(*logEntry.DurationMinutes) + ")"
+	}
+	return description
+}
+
+// projectFlag adds -p, and its long form --project, for a project name or
+// ID.
+func projectFlag(fs *flag.FlagSet) *string {
+	project := new(string)
+	fs.StringVar(project, "p", "", "project name or ID")
+	fs.StringVar(project, "project", "", "project name or ID")
+	return project
+}
+
+// setFlags returns the names of the flags given on the command line, so
+// edits only send the fields asked for.
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// projectNames maps the IDs of the user's projects, archived ones
+// included, to their names.
+func (a *app) projectNames() (map[string]string, error) {
+	names := map[string]string{}
+	for _, path := range []string{"/api/projects", "/api/projects?archived=true"} {
+		var projects []models.Project
+		if err := a.client.do(http.MethodGet, path, nil, &projects); err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			names[project.ID] = project.Name
+		}
+	}
+	return names, nil
+}
+
+// resolveProject finds a project by name, ignoring case, or by ID or a
+// unique prefix of it.
+func (a *app) resolveProject(ref string) (string, error) {
+	names, err := a.projectNames()
+	if err != nil {
+		return "", err
+	}
+	var byName []string
+	for id, name := range names {
+		if strings.EqualFold(name, ref) {
+			byName = append(byName, id)
+		}
+	}
+	switch len(byName) {
+	case 1:
+		return byName[0], nil
+	case 0:
+	default:
+		return "", fmt.Errorf("more than one project is called %q; use its ID", ref)
+	}
+	ids := make([]string, 0, len(names))
+	for id := range names {
+		ids = append(ids, id)
+	}
+	return resolveID("project", ref, ids)
+}
+
+// resolveTask finds a task by ID or a unique prefix of it, among the tasks
+// the user can see.
+func (a *app) resolveTask(ref string) (string, error) {
+	if _, err := uuid.Parse(ref); err == nil {
+		return ref, nil
+	}
+	var tasks []models.Task
+	if err := a.client.do(http.MethodGet, "/api/tasks", nil, &tasks); err != nil {
+		return "", err
+	}
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return resolveID("task", ref, ids)
+}
+
+// resolveLogEntry finds one of the user's log entries by ID or a unique
+// prefix of it.
+func (a *app) resolveLogEntry(ref string) (string, error) {
+	if _, err := uuid.Parse(ref); err == nil {
+		return ref, nil
+	}
+	var logEntries []models.LogEntry
+	if err := a.client.do(http.MethodGet, "/api/log-entries", nil, &logEntries); err != nil {
+		return "", err
+	}
+	ids := make([]string, len(logEntries))
+	for i, logEntry := range logEntries {
+		ids[i] = logEntry.ID
+	}
+	return resolveID("log entry", ref, ids)
+}
+
+// optionalRef resolves ref, or returns nil, to clear the field, if it is
+// "none".
+func (a *app) optionalRef(ref string, resolve func(string) (string, error)) (interface{}, error) {
+	if ref == "none" {
+		return nil, nil
+	}
+	return resolve(ref)
+}
+
+// resolveID returns the ID that is ref or starts with it.
+func resolveID(kind, ref string, ids []string) (string, error) {
+	ref = strings.ToLower(ref)
+	var matches []string
+	for _, id := range ids {
+		if id == ref {
+			return id, nil
+		}
+		if strings.HasPrefix(id, ref) {
+			matches = append(matches, id)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no %s matches %q", kind, ref)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("%q matches more than one %s; give more of the ID", ref, kind)
+}
+
+// parseDate turns YYYY-MM-DD, today, yesterday or tomorrow into
+// YYYY-MM-DD.
+func (a *app) parseDate(value string) (string, error) {
+	now := a.now()
+	switch value {
+	case "today":
+		return now.Format("2006-01-02"), nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1).Format("2006-01-02"), nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1).Format("2006-01-02"), nil
+	}
+	if _, err := time.Parse("2006-01-02", value); err != nil {
+		return "", fmt.Errorf("invalid date %q, use YYYY-MM-DD, today, yesterday or tomorrow", value)
+	}
+	return value, nil
+}
+
+// parseMinutes reads a duration such as 1h30m, or a plain number of
+// minutes.
+func parseMinutes(value string) (int, error) {
+	if minutes, err := strconv.Atoi(value); err == nil && minutes > 0 {
+		return minutes, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < time.Minute {
+		return 0, fmt.Errorf("invalid duration %q, use e.g. 45, 90m or 1h30m", value)
+	}
+	return int(math.Round(d.Minutes())), nil
+}
+
+// formatMinutes formats minutes as e.g. 1h 30m.
+func formatMinutes(minutes int) string {
+	switch {
+	case minutes < 60:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%dh", minutes/60)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
+
+// shortID is the part of an ID tables show, which is enough to refer back
+// to it.
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	configFile = "config.json"
+	timerFile  = "timer.json"
+
+	defaultServer = "http://localhost:8080"
+)
+
+// config is what the CLI remembers between runs. Token is the personal
+// access token created at login, and TokenID its ID, to revoke it at
+// logout.
+type config struct {
+	Server  string `json:"server,omitempty"`
+	Token   string `json:"token,omitempty"`
+	TokenID string `json:"token_id,omitempty"`
+}
+
+// timer is a running timer. Stopping it logs the time since StartedAt.
+type timer struct {
+	ProjectID *string   `json:"project_id,omitempty"`
+	TaskID    *string   `json:"task_id,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// configDir is the makerlog directory under the user's config directory,
+// which is $XDG_CONFIG_HOME, or ~/.config, on Linux.
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "makerlog"), nil
+}
+
+// loadFile decodes the named file of the config directory into v. It
+// reports false, leaving v alone, if the file does not exist.
+func loadFile(name string, v interface{}) (bool, error) {
+	dir, err := configDir()
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+// saveFile writes v to the named file of the config directory, readable
+// only by the user since it may hold the access token.
+func saveFile(name string, v interface{}) error {
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, name+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.Remove(tmp.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			_ = err
+		}
+	}()
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		if closeErr := tmp.Close(); closeErr != nil {
+			return errors.Join(err, closeErr)
+		}
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+// removeFile deletes the named file of the config directory, if it exists.
+func removeFile(name string) error {
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,217 @@
+// Command makerlog-cli is a terminal client for the Makerlog API:
+//
+//	makerlog login
+//	makerlog log "fixed the flaky test" -p api
+//	makerlog today
+//
+// Run it without arguments for the list of commands.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage: makerlog <command> [arguments] [flags]
+
+Commands:
+  login                       Sign in and save an access token for this machine
+  logout                      Revoke the saved access token and forget it
+  whoami                      Show the signed-in user
+  today                       Show today's log, tasks due and the running timer
+  log <content>               Log what you did (-p project, -t task, --date, --duration, --billable)
+  entries [list|edit|delete]  Manage log entries
+  projects [list|create|edit|delete]
+                              Manage projects
+  tasks [list|create|edit|done|delete]
+                              Manage tasks
+  timer [start|stop|status|cancel]
+                              Time what you are doing and log it when you stop
+
+Every command takes --json to print the API's JSON, --server to pick the
+API (also MAKERLOG_SERVER) and --token to use a personal access token instead
+of the saved login (also MAKERLOG_TOKEN). Projects can be given by name or ID,
+and everything else by ID or a unique prefix of it.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// errUsage is returned for bad invocations, after the flag package or the
+// command has explained what is wrong.
+var errUsage = errors.New("usage")
+
+// app is one run of the CLI.
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	http   *http.Client
+	now    func() time.Time
+
+	// Set by the global flags.
+	json   bool
+	server string
+	token  string
+
+	cfg    config
+	client *client
+}
+
+// run runs the command in args and returns the exit status: 0 on success,
+// 1 on failure and 2 on bad usage.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	a := &app{
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+		http:   &http.Client{Timeout: 30 * time.Second},
+		now:    time.Now,
+	}
+	return a.run(args)
+}
+
+var commands = map[string]func(a *app, args []string) error{
+	"login":    (*app).login,
+	"logout":   (*app).logout,
+	"whoami":   (*app).whoami,
+	"today":    (*app).today,
+	"log":      (*app).logCreate,
+	"entries":  (*app).entries,
+	"projects": (*app).projects,
+	"tasks":    (*app).tasks,
+	"timer":    (*app).timer,
+}
+
+func (a *app) run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printf(a.stdout, "%s", usage)
+		return 0
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		printf(a.stderr, "makerlog: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	if _, err := loadFile(configFile, &a.cfg); err != nil {
+		printf(a.stderr, "makerlog: reading config: %v\n", err)
+		return 1
+	}
+	err := command(a, args[1:])
+	var apiErr *apiError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized:
+		printf(a.stderr, "makerlog: not signed in; run makerlog login or pass --token\n")
+	default:
+		printf(a.stderr, "makerlog: %v\n", err)
+	}
+	return 1
+}
+
+// flags returns a flag set for the command with the global flags on it.
+func (a *app) flags(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		printf(a.stderr, "Usage: makerlog %s\n", usage)
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&a.json, "json", false, "print JSON")
+	fs.StringVar(&a.server, "server", "", "API address (default $MAKERLOG_SERVER, the saved server or "+defaultServer+")")
+	fs.StringVar(&a.token, "token", "", "personal access token (default $MAKERLOG_TOKEN or the saved login)")
+	return fs
+}
+
+// parse parses flags wherever they appear among the arguments, so that
+// `log "text" -p api` works, and returns the remaining arguments. Arguments
+// after "--" are never flags.
+func (a *app) parse(fs *flag.FlagSet, args []string, minArgs, maxArgs int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, errUsage
+		}
+		rest := fs.Args()
+		if parsed := args[:len(args)-len(rest)]; len(parsed) > 0 && parsed[len(parsed)-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	if len(positional) < minArgs || (maxArgs >= 0 && len(positional) > maxArgs) {
+		fs.Usage()
+		return nil, errUsage
+	}
+	a.connect()
+	return positional, nil
+}
+
+// connect sets up the API client. Flags win over the environment, which
+// wins over the saved config.
+func (a *app) connect() {
+	server := firstNonEmpty(a.server, os.Getenv("MAKERLOG_SERVER"), a.cfg.Server, defaultServer)
+	token := firstNonEmpty(a.token, os.Getenv("MAKERLOG_TOKEN"), a.cfg.Token)
+	a.client = &client{server: strings.TrimSuffix(server, "/"), token: token, http: a.http}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// printf writes to the terminal, where a failed write has nowhere left to
+// be reported.
+func printf(w io.Writer, format string, args ...interface{}) {
+	if _, err := fmt.Fprintf(w, format, args...); err != nil {
+		_ = err
+	}
+}
+
+// output prints v as JSON with --json, and otherwise calls human.
+func (a *app) output(v interface{}, human func(w io.Writer)) error {
+	if a.json {
+		encoder := json.NewEncoder(a.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	human(a.stdout)
+	return nil
+}
+
+// table writes rows of tab-separated cells in aligned columns.
+func table(w io.Writer, rows [][]string) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, row := range rows {
+		printf(tw, "%s\n", strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		_ = err
+	}
+}
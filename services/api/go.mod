@@ -1,6 +1,6 @@
 module github.com/chrispotter/makerlog/services/api
 
-go 1.24.0
+go 1.24.1
 
 toolchain go1.24.11
 
@@ -9,8 +9,17 @@ require (
 	github.com/go-chi/cors v1.2.2
 	github.com/google/uuid v1.6.0
 	github.com/gorilla/sessions v1.4.0
+	github.com/graph-gophers/graphql-go v1.9.0
+	github.com/jung-kurt/gofpdf v1.16.2
+	github.com/ledongthuc/pdf v0.0.0-20250511090121-5959a4027728
 	github.com/lib/pq v1.10.9
+	github.com/vektah/gqlparser/v2 v2.5.31
 	golang.org/x/crypto v0.46.0
+	golang.org/x/term v0.38.0
 )
 
-require github.com/gorilla/securecookie v1.1.2 // indirect
+require (
+	github.com/agnivade/levenshtein v1.2.1 // indirect
+	github.com/gorilla/securecookie v1.1.2 // indirect
+	golang.org/x/sys v0.39.0 // indirect
+)
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+const accessTokenColumns = `id, name, scope, expires_at, last_used_at, created_at`
+
+func scanAccessToken(row rowScanner, token *models.AccessToken) error {
+	return row.Scan(&token.ID, &token.Name, &token.Scope, &token.ExpiresAt, &token.LastUsedAt, &token.CreatedAt)
+}
+
+// CreateAccessToken stores a personal access token for the user. The caller
+// keeps the token; only its hash is stored.
+func (q *Queries) CreateAccessToken(ctx context.Context, userID, name, scope, tokenHash string, expiresAt *time.Time) (*models.AccessToken, error) {
+	var token models.AccessToken
+	err := scanAccessToken(q.db.QueryRowContext(ctx, `
+		INSERT INTO access_tokens (user_id, name, scope, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING `+accessTokenColumns,
+		userID, name, scope, tokenHash, expiresAt), &token)
+	return &token, err
+}
+
+// ListAccessTokens returns the user's access tokens, expired ones included,
+// newest first.
+func (q *Queries) ListAccessTokens(ctx context.Context, userID string) ([]models.AccessToken, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+accessTokenColumns+`
+		FROM access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	tokens := []models.AccessToken{}
+	for rows.Next() {
+		var token models.AccessToken
+		if err := scanAccessToken(rows, &token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// DeleteAccessToken revokes one of the user's access tokens. It returns
+// sql.ErrNoRows if they have no such token.
+func (q *Queries) DeleteAccessToken(ctx context.Context, id, userID string) error {
+	result, err := q.db.ExecContext(ctx, `DELETE FROM access_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UseAccessToken returns the user and scope of the unexpired access token
+// with the hash, recording that it was used, or an empty user ID if there
+// is no such token.
+func (q *Queries) UseAccessToken(ctx context.Context, tokenHash string) (userID, scope string, err error) {
+	err = q.db.QueryRowContext(ctx, `
+		UPDATE access_tokens SET last_used_at = NOW()
+		WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING user_id, scope
+	`, tokenHash).Scan(&userID, &scope)
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+	return userID, scope, err
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// LastTaskPosition returns the highest rank in a project's status column, or
+// "" for an empty column. excludeID leaves out the task being moved.
+func (q *Queries) LastTaskPosition(ctx context.Context, projectID, status, userID, excludeID string) (string, error) {
+	var position sql.NullString
+	err := q.db.QueryRowContext(ctx, `
+		SELECT MAX(position)
+		FROM tasks
+		WHERE project_id = $1 AND status = $2 AND `+taskAccess("tasks.project_id", "$3")+` AND deleted_at IS NULL AND id::text <> $4
+	`, projectID, status, userID, excludeID).Scan(&position)
+	return position.String, err
+}
+
+// NextTaskPosition returns the rank that directly follows position in the
+// column, or "" when nothing does. excludeID leaves out the task being moved.
+func (q *Queries) NextTaskPosition(ctx context.Context, projectID, status, userID, position, excludeID string) (string, error) {
+	var next sql.NullString
+	err := q.db.QueryRowContext(ctx, `
+		SELECT MIN(position)
+		FROM tasks
+		WHERE project_id = $1 AND status = $2 AND `+taskAccess("tasks.project_id", "$3")+` AND deleted_at IS NULL AND position > $4 AND id::text <> $5
+	`, projectID, status, userID, position, excludeID).Scan(&next)
+	return next.String, err
+}
+
+// PreviousTaskPosition returns the rank that directly precedes position in
+// the column, or "" when nothing does. excludeID leaves out the task being
+// moved.
+func (q *Queries) PreviousTaskPosition(ctx context.Context, projectID, status, userID, position, excludeID string) (string, error) {
+	var previous sql.NullString
+	err := q.db.QueryRowContext(ctx, `
+		SELECT MAX(position)
+		FROM tasks
+		WHERE project_id = $1 AND status = $2 AND `+taskAccess("tasks.project_id", "$3")+` AND deleted_at IS NULL AND position < $4 AND id::text <> $5
+	`, projectID, status, userID, position, excludeID).Scan(&previous)
+	return previous.String, err
+}
+
+// RebalanceTaskPositions rewrites the ranks of a column with evenly spaced
+// keys, keeping the current order. It is only needed when concurrent inserts
+// left two tasks with the same rank. Tasks whose rank changes get a new
+// version, so that clients holding the old order see it has changed.
+// excludeID leaves out the task being moved, which gets its new rank, and
+// version, from the move itself.
+func (q *Queries) RebalanceTaskPositions(ctx context.Context, projectID, status, userID, excludeID string) error {
+	_, err := q.db.ExecContext(ctx, `
+		UPDATE tasks t
+		SET position = lpad(r.rn::text, 8, '0') || 'i', version = version + 1, updated_at = NOW()
+		FROM (
+			SELECT id, row_number() OVER (ORDER BY position, created_at) AS rn
+			FROM tasks
+			WHERE project_id = $1 AND status = $2 AND `+taskAccess("tasks.project_id", "$3")+` AND deleted_at IS NULL AND id::text <> $4
+		) r
+		WHERE t.id = r.id AND t.position <> lpad(r.rn::text, 8, '0') || 'i'
+	`, projectID, status, userID, excludeID)
+	return err
+}
+
+// MoveTask sets the task's status and rank in a single-row update, honoring
+// ifMatch like UpdateTask.
+func (q *Queries) MoveTask(ctx context.Context, id, userID, status, position string, ifMatch []int64) (*models.Task, error) {
+	var task models.Task
+	err := scanTask(q.revisedUpdate(ctx, models.RevisionEntityTask, taskColumns, "",
+		`status = $1, position = $2, `+fmt.Sprintf(completedAtSQL, "tasks.project_id", "$1")+`,
+			version = version + 1, updated_at = NOW()`,
+		`id = $3 AND `+taskAccess("tasks.project_id", "$4")+` AND deleted_at IS NULL AND ($5::bigint[] IS NULL OR version = ANY($5))`,
+		[]interface{}{status, position, id, userID, pq.Array(ifMatch)}, userID), &task)
+	if err == sql.ErrNoRows {
+		return nil, q.versionConflict(ctx, "tasks", id, userID, ifMatch)
+	}
+	return &task, err
+}
+
+// ListBoardTasks returns a project's tasks in board order: by status, then by
+// rank within the status.
+func (q *Queries) ListBoardTasks(ctx context.Context, projectID, userID string) ([]models.Task, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE project_id = $1 AND `+taskAccess("tasks.project_id", "$2")+` AND deleted_at IS NULL
+		ORDER BY status, position, created_at
+	`, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectTasks(rows)
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+// GetCalendarFeed returns the user's calendar feed, or nil if they have
+// none.
+func (q *Queries) GetCalendarFeed(ctx context.Context, userID string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := q.db.QueryRowContext(ctx, `
+		SELECT created_at, last_used_at FROM calendar_feeds WHERE user_id = $1
+	`, userID).Scan(&feed.CreatedAt, &feed.LastUsedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &feed, err
+}
+
+// SetCalendarFeed gives the user a calendar feed with the token hash,
+// replacing the token of any feed they had, which then stops working.
+func (q *Queries) SetCalendarFeed(ctx context.Context, userID, tokenHash string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := q.db.QueryRowContext(ctx, `
+		INSERT INTO calendar_feeds (user_id, token_hash, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET token_hash = EXCLUDED.token_hash, created_at = NOW(), last_used_at = NULL
+		RETURNING created_at, last_used_at
+	`, userID, tokenHash).Scan(&feed.CreatedAt, &feed.LastUsedAt)
+	return &feed, err
+}
+
+// DeleteCalendarFeed turns off the user's calendar feed. It returns
+// sql.ErrNoRows if they had none.
+func (q *Queries) DeleteCalendarFeed(ctx context.Context, userID string) error {
+	result, err := q.db.ExecContext(ctx, `DELETE FROM calendar_feeds WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UseCalendarFeed returns the user who owns the feed with the token hash,
+// recording that it was read, or nil if no feed has that token.
+func (q *Queries) UseCalendarFeed(ctx context.Context, tokenHash string) (*models.User, error) {
+	var user models.User
+	err := q.db.QueryRowContext(ctx, `
+		WITH feed AS (
+			UPDATE calendar_feeds SET last_used_at = NOW()
+			WHERE token_hash = $1
+			RETURNING user_id
+		)
+		SELECT u.id, u.email, u.name, u.created_at, u.updated_at
+		FROM feed JOIN users u ON u.id = feed.user_id
+	`, tokenHash).Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &user, err
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+const clientColumns = `id, workspace_id, name, email, created_at, updated_at`
+
+func scanClient(row rowScanner, client *models.Client) error {
+	return row.Scan(&client.ID, &client.WorkspaceID, &client.Name, &client.Email, &client.CreatedAt, &client.UpdatedAt)
+}
+
+// clientAccess is true when user is a member of the client's workspace.
+func clientAccess(user string) string {
+	return `workspace_id IN (SELECT wm.workspace_id FROM workspace_members wm WHERE wm.user_id = ` + user + `)`
+}
+
+// ListClients returns the clients of the user's workspaces by name. A
+// non-nil workspaceID limits them to that workspace.
+func (q *Queries) ListClients(ctx context.Context, userID string, workspaceID *string) ([]models.Client, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+clientColumns+`
+		FROM clients
+		WHERE `+clientAccess("$1")+` AND ($2::uuid IS NULL OR workspace_id = $2)
+		ORDER BY name, created_at
+	`, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	clients := []models.Client{}
+	for rows.Next() {
+		var client models.Client
+		if err := scanClient(rows, &client); err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, rows.Err()
+}
+
+// GetClient returns the client, or nil if the user is not a member of its
+// workspace.
+func (q *Queries) GetClient(ctx context.Context, id, userID string) (*models.Client, error) {
+	var client models.Client
+	err := scanClient(q.db.QueryRowContext(ctx, `
+		SELECT `+clientColumns+` FROM clients WHERE id = $1 AND `+clientAccess("$2"),
+		id, userID), &client)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &client, err
+}
+
+// CreateClient adds a client to the workspace. The caller checks the user
+// may do so.
+func (q *Queries) CreateClient(ctx context.Context, workspaceID, name string, email *string) (*models.Client, error) {
+	var client models.Client
+	err := scanClient(q.db.QueryRowContext(ctx, `
+		INSERT INTO clients (workspace_id, name, email, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING `+clientColumns,
+		workspaceID, name, email), &client)
+	return &client, err
+}
+
+// UpdateClient replaces the client's name and email. It returns nil if the
+// user cannot see the client.
+func (q *Queries) UpdateClient(ctx context.Context, id, userID, name string, email *string) (*models.Client, error) {
+	var client models.Client
+	err := scanClient(q.db.QueryRowContext(ctx, `
+		UPDATE clients SET name = $3, email = $4, updated_at = NOW()
+		WHERE id = $1 AND `+clientAccess("$2")+`
+		RETURNING `+clientColumns,
+		id, userID, name, email), &client)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &client, err
+}
+
+// DeleteClient removes the client, unlinking its projects. It returns
+// sql.ErrNoRows if the user cannot see the client.
+func (q *Queries) DeleteClient(ctx context.Context, id, userID string) error {
+	result, err := q.db.ExecContext(ctx, `DELETE FROM clients WHERE id = $1 AND `+clientAccess("$2"), id, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
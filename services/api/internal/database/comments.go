@@ -0,0 +1,244 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+// ErrInvalidParent is returned when replying to a comment that is not on the
+// same log entry or has been deleted.
+var ErrInvalidParent = errors.New("invalid parent comment")
+
+// logEntryCountColumns follows logEntryColumns in list queries over
+// log_entries, counting each entry's comments and reactions in the same
+// query.
+const logEntryCountColumns = `(
+		SELECT COUNT(*) FROM comments c WHERE c.log_entry_id = log_entries.id AND c.deleted_at IS NULL
+	), (
+		SELECT jsonb_object_agg(emoji, n) FROM (
+			SELECT r.emoji, COUNT(*) AS n FROM reactions r WHERE r.log_entry_id = log_entries.id GROUP BY r.emoji
+		) counts
+	)`
+
+// scanLogEntryWithCounts scans a row selected with logEntryColumns and
+// logEntryCountColumns.
+func scanLogEntryWithCounts(row rowScanner, logEntry *models.LogEntry) error {
+	var comments int
+	var reactions []byte
+	err := row.Scan(
+		&logEntry.ID, &logEntry.UserID, &logEntry.TaskID, &logEntry.ProjectID, &logEntry.Content, &logEntry.LogDate, &logEntry.DurationMinutes, &logEntry.Billable, &logEntry.DeletedAt, &logEntry.Version, &logEntry.CreatedAt, &logEntry.UpdatedAt,
+		&comments, &reactions,
+	)
+	if err != nil {
+		return err
+	}
+	logEntry.CommentCount = &comments
+	logEntry.Reactions = map[string]int{}
+	if reactions != nil {
+		return json.Unmarshal(reactions, &logEntry.Reactions)
+	}
+	return nil
+}
+
+const commentColumns = `c.id, c.log_entry_id, c.parent_id, c.user_id, u.name, c.content, c.deleted_at, c.created_at, c.updated_at`
+
+func scanComment(row rowScanner, comment *models.Comment) error {
+	return row.Scan(
+		&comment.ID, &comment.LogEntryID, &comment.ParentID, &comment.UserID, &comment.UserName, &comment.Content, &comment.DeletedAt, &comment.CreatedAt, &comment.UpdatedAt,
+	)
+}
+
+// ListComments returns the comments on the log entry, oldest first, if the
+// user can see the entry.
+func (q *Queries) ListComments(ctx context.Context, logEntryID, userID string) ([]models.Comment, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		JOIN log_entries le ON le.id = c.log_entry_id
+		WHERE c.log_entry_id = $1 AND `+logEntryAccess("le", "$2")+` AND le.deleted_at IS NULL
+		ORDER BY c.created_at, c.id
+	`, logEntryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		var comment models.Comment
+		if err := scanComment(rows, &comment); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// GetComment returns the comment, or nil if the user cannot see its log
+// entry.
+func (q *Queries) GetComment(ctx context.Context, id, userID string) (*models.Comment, error) {
+	var comment models.Comment
+	err := scanComment(q.db.QueryRowContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		JOIN log_entries le ON le.id = c.log_entry_id
+		WHERE c.id = $1 AND `+logEntryAccess("le", "$2")+` AND le.deleted_at IS NULL
+	`, id, userID), &comment)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &comment, err
+}
+
+// CreateComment adds the user's comment to a log entry they can see. It
+// returns ErrInvalidParent if parentID is not a live comment on the same
+// entry.
+func (q *Queries) CreateComment(ctx context.Context, logEntryID string, parentID *string, userID, content string) (*models.Comment, error) {
+	var comment models.Comment
+	err := scanComment(q.db.QueryRowContext(ctx, `
+		WITH c AS (
+			INSERT INTO comments (log_entry_id, parent_id, user_id, content, created_at, updated_at)
+			SELECT le.id, $2, $3, $4, NOW(), NOW()
+			FROM log_entries le
+			WHERE le.id = $1 AND `+logEntryAccess("le", "$3")+` AND le.deleted_at IS NULL
+				AND ($2::uuid IS NULL OR EXISTS (
+					SELECT 1 FROM comments p WHERE p.id = $2 AND p.log_entry_id = le.id AND p.deleted_at IS NULL
+				))
+			RETURNING *
+		)
+		SELECT `+commentColumns+`
+		FROM c JOIN users u ON u.id = c.user_id
+	`, logEntryID, parentID, userID, content), &comment)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidParent
+	}
+	return &comment, err
+}
+
+// UpdateComment changes the content of the user's own comment. It returns
+// nil if the user wrote no such live comment.
+func (q *Queries) UpdateComment(ctx context.Context, id, userID, content string) (*models.Comment, error) {
+	var comment models.Comment
+	err := scanComment(q.db.QueryRowContext(ctx, `
+		WITH c AS (
+			UPDATE comments SET content = $3, updated_at = NOW()
+			WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+			RETURNING *
+		)
+		SELECT `+commentColumns+`
+		FROM c JOIN users u ON u.id = c.user_id
+	`, id, userID, content), &comment)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &comment, err
+}
+
+// DeleteComment clears the comment and marks it deleted, keeping its replies
+// in place. The caller checks the user may do so. It returns sql.ErrNoRows
+// if the comment is already deleted.
+func (q *Queries) DeleteComment(ctx context.Context, id string) error {
+	result, err := q.db.ExecContext(ctx, `
+		UPDATE comments SET content = '', deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+const reactionColumns = `r.log_entry_id, r.user_id, u.name, r.emoji, r.created_at`
+
+func scanReaction(row rowScanner, reaction *models.Reaction) error {
+	return row.Scan(&reaction.LogEntryID, &reaction.UserID, &reaction.UserName, &reaction.Emoji, &reaction.CreatedAt)
+}
+
+// ListReactions returns the reactions on the log entry, oldest first, if the
+// user can see the entry.
+func (q *Queries) ListReactions(ctx context.Context, logEntryID, userID string) ([]models.Reaction, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+reactionColumns+`
+		FROM reactions r
+		JOIN users u ON u.id = r.user_id
+		JOIN log_entries le ON le.id = r.log_entry_id
+		WHERE r.log_entry_id = $1 AND `+logEntryAccess("le", "$2")+` AND le.deleted_at IS NULL
+		ORDER BY r.created_at, r.emoji
+	`, logEntryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	reactions := []models.Reaction{}
+	for rows.Next() {
+		var reaction models.Reaction
+		if err := scanReaction(rows, &reaction); err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, reaction)
+	}
+	return reactions, rows.Err()
+}
+
+// AddReaction records the user's emoji on a log entry they can see. Adding
+// it again is a no-op. It returns nil if the user cannot see the entry.
+func (q *Queries) AddReaction(ctx context.Context, logEntryID, userID, emoji string) (*models.Reaction, error) {
+	var reaction models.Reaction
+	err := scanReaction(q.db.QueryRowContext(ctx, `
+		WITH inserted AS (
+			INSERT INTO reactions (log_entry_id, user_id, emoji, created_at)
+			SELECT le.id, $2, $3, NOW()
+			FROM log_entries le
+			WHERE le.id = $1 AND `+logEntryAccess("le", "$2")+` AND le.deleted_at IS NULL
+			ON CONFLICT (log_entry_id, user_id, emoji) DO UPDATE SET emoji = EXCLUDED.emoji
+			RETURNING *
+		)
+		SELECT `+reactionColumns+`
+		FROM inserted r JOIN users u ON u.id = r.user_id
+	`, logEntryID, userID, emoji), &reaction)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &reaction, err
+}
+
+// RemoveReaction takes back the user's emoji, returning sql.ErrNoRows if
+// they had not reacted with it.
+func (q *Queries) RemoveReaction(ctx context.Context, logEntryID, userID, emoji string) error {
+	result, err := q.db.ExecContext(ctx, `
+		DELETE FROM reactions WHERE log_entry_id = $1 AND user_id = $2 AND emoji = $3
+	`, logEntryID, userID, emoji)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// IdempotentRequest is a request made with an Idempotency-Key. StatusCode is
+// zero while it is in progress; after that it holds the response to replay.
+type IdempotentRequest struct {
+	Fingerprint string
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+}
+
+// ClaimIdempotencyKey claims the user's key for a request with the given
+// fingerprint, for ttl, holding it for the request to run for lease. A
+// request with the same fingerprint takes over a key whose holder has not
+// finished within its lease. If the key is taken it returns the request that
+// took it instead, or nil if that request was released meanwhile.
+func (q *Queries) ClaimIdempotencyKey(ctx context.Context, userID, key, fingerprint string, ttl, lease time.Duration) (*IdempotentRequest, bool, error) {
+	if _, err := q.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND expires_at < NOW()
+	`, userID, key); err != nil {
+		return nil, false, err
+	}
+
+	result, err := q.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (user_id, key, fingerprint, created_at, expires_at, locked_until)
+		VALUES ($1, $2, $3, NOW(), NOW() + $4 * INTERVAL '1 second', NOW() + $5 * INTERVAL '1 second')
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at, locked_until = EXCLUDED.locked_until
+		WHERE idempotency_keys.status_code IS NULL
+			AND idempotency_keys.locked_until < NOW()
+			AND idempotency_keys.fingerprint = EXCLUDED.fingerprint
+	`, userID, key, fingerprint, ttl.Seconds(), lease.Seconds())
+	if err != nil {
+		return nil, false, err
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 1 {
+		return nil, rows == 1, err
+	}
+
+	var request IdempotentRequest
+	var status sql.NullInt64
+	var header []byte
+	err = q.db.QueryRowContext(ctx, `
+		SELECT fingerprint, status_code, headers, body FROM idempotency_keys WHERE user_id = $1 AND key = $2
+	`, userID, key).Scan(&request.Fingerprint, &status, &header, &request.Body)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	request.StatusCode = int(status.Int64)
+	if header != nil {
+		if err := json.Unmarshal(header, &request.Header); err != nil {
+			return nil, false, err
+		}
+	}
+	return &request, false, nil
+}
+
+// SaveIdempotentResponse stores the response to the request holding the
+// user's key. If the key was taken over, the first response stored wins.
+func (q *Queries) SaveIdempotentResponse(ctx context.Context, userID, key string, statusCode int, header http.Header, body []byte) error {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	_, err = q.db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET status_code = $3, headers = $4, body = $5
+		WHERE user_id = $1 AND key = $2 AND status_code IS NULL
+	`, userID, key, statusCode, data, body)
+	return err
+}
+
+// ReleaseIdempotencyKey frees the user's key, unless a response was stored
+// for it, so the request can be retried.
+func (q *Queries) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	_, err := q.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code IS NULL
+	`, userID, key)
+	return err
+}
+
+// PurgeIdempotencyKeys deletes expired keys and returns how many there were.
+func (q *Queries) PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,215 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+const invitationColumns = `id, workspace_id, project_id, email, role, invited_by, expires_at, accepted_at, accepted_by, created_at`
+
+func scanInvitation(row rowScanner, invitation *models.Invitation) error {
+	return row.Scan(
+		&invitation.ID, &invitation.WorkspaceID, &invitation.ProjectID, &invitation.Email, &invitation.Role, &invitation.InvitedBy,
+		&invitation.ExpiresAt, &invitation.AcceptedAt, &invitation.AcceptedBy, &invitation.CreatedAt,
+	)
+}
+
+// roleRankSQL orders roles from least to most privileged.
+const roleRankSQL = `array_position(ARRAY['viewer', 'member', 'admin', 'owner']::varchar[], %s)`
+
+// CreateInvitation stores an invitation to the workspace or the project,
+// exactly one of which is set. The caller keeps the token; only its hash is
+// stored.
+func (q *Queries) CreateInvitation(ctx context.Context, workspaceID, projectID, email *string, role, tokenHash, invitedBy string, expiresAt time.Time) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := scanInvitation(q.db.QueryRowContext(ctx, `
+		INSERT INTO invitations (workspace_id, project_id, email, role, token_hash, invited_by, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING `+invitationColumns,
+		workspaceID, projectID, email, role, tokenHash, invitedBy, expiresAt), &invitation)
+	return &invitation, err
+}
+
+// ListPendingInvitations returns the invitations to the workspace or project
+// in column ("workspace_id" or "project_id") that are neither accepted nor
+// expired, newest first.
+func (q *Queries) ListPendingInvitations(ctx context.Context, column, id string) ([]models.Invitation, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+invitationColumns+`
+		FROM invitations
+		WHERE `+column+` = $1 AND accepted_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	invitations := []models.Invitation{}
+	for rows.Next() {
+		var invitation models.Invitation
+		if err := scanInvitation(rows, &invitation); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+// GetInvitation returns the invitation, or nil if there is none. The caller
+// checks the user may see it.
+func (q *Queries) GetInvitation(ctx context.Context, id string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := scanInvitation(q.db.QueryRowContext(ctx, `
+		SELECT `+invitationColumns+` FROM invitations WHERE id = $1
+	`, id), &invitation)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &invitation, err
+}
+
+// GetPendingInvitation returns the invitation with the token hash if it can
+// still be accepted, or nil.
+func (q *Queries) GetPendingInvitation(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := scanInvitation(q.db.QueryRowContext(ctx, `
+		SELECT `+invitationColumns+`
+		FROM invitations
+		WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > NOW()
+	`, tokenHash), &invitation)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &invitation, err
+}
+
+// RevokeInvitation deletes a pending invitation, returning sql.ErrNoRows if
+// there is no such invitation or it has been accepted.
+func (q *Queries) RevokeInvitation(ctx context.Context, id string) error {
+	result, err := q.db.ExecContext(ctx, `DELETE FROM invitations WHERE id = $1 AND accepted_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AcceptInvitation marks the invitation with the token hash as used by the
+// user and gives them its role. A user who already has a higher role keeps
+// it. It returns nil if the invitation has expired or was already used.
+func (q *Queries) AcceptInvitation(ctx context.Context, tokenHash, userID string) (*models.Invitation, error) {
+	tx, err := q.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			_ = err
+		}
+	}()
+
+	// The update claims the token, so a second accept finds nothing.
+	var invitation models.Invitation
+	err = scanInvitation(tx.QueryRowContext(ctx, `
+		UPDATE invitations SET accepted_at = NOW(), accepted_by = $2
+		WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > NOW()
+		RETURNING `+invitationColumns,
+		tokenHash, userID), &invitation)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if invitation.WorkspaceID != nil {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO workspace_members (workspace_id, user_id, role, created_at, updated_at)
+			VALUES ($1, $2, $3, NOW(), NOW())
+			ON CONFLICT (workspace_id, user_id) DO UPDATE
+			SET role = EXCLUDED.role, updated_at = NOW()
+			WHERE `+fmt.Sprintf(roleRankSQL, "EXCLUDED.role")+` > `+fmt.Sprintf(roleRankSQL, "workspace_members.role")+`
+		`, *invitation.WorkspaceID, userID, invitation.Role)
+	} else {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO project_members (project_id, user_id, role, created_at, updated_at)
+			VALUES ($1, $2, $3, NOW(), NOW())
+			ON CONFLICT (project_id, user_id) DO UPDATE
+			SET role = EXCLUDED.role, updated_at = NOW()
+			WHERE `+fmt.Sprintf(roleRankSQL, "EXCLUDED.role")+` > `+fmt.Sprintf(roleRankSQL, "project_members.role")+`
+		`, *invitation.ProjectID, userID, invitation.Role)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+const projectMemberColumns = `pm.project_id, pm.user_id, u.email, u.username, u.name, pm.role, pm.created_at, pm.updated_at`
+
+// ListProjectMembers returns the members of the project itself, not those
+// who see it through its workspace.
+func (q *Queries) ListProjectMembers(ctx context.Context, projectID string) ([]models.ProjectMember, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+projectMemberColumns+`
+		FROM project_members pm JOIN users u ON u.id = pm.user_id
+		WHERE pm.project_id = $1
+		ORDER BY `+fmt.Sprintf(roleRankSQL, "pm.role")+` DESC, u.name, u.email
+	`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	members := []models.ProjectMember{}
+	for rows.Next() {
+		var member models.ProjectMember
+		if err := rows.Scan(
+			&member.ProjectID, &member.UserID, &member.Email, &member.Username, &member.Name, &member.Role, &member.CreatedAt, &member.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// RemoveProjectMember takes the user out of the project, returning
+// sql.ErrNoRows if they are not a project member.
+func (q *Queries) RemoveProjectMember(ctx context.Context, projectID, userID string) error {
+	result, err := q.db.ExecContext(ctx, `DELETE FROM project_members WHERE project_id = $1 AND user_id = $2`, projectID, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
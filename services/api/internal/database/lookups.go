@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// Lookups of many rows at once by ID, for the GraphQL API to fetch the
+// relations of a whole page of results in one query. Rows the user cannot
+// see are left out, as are deleted ones.
+
+// GetUsersByIDs returns the users among ids that the user shares a
+// workspace or project with, the user included.
+func (q *Queries) GetUsersByIDs(ctx context.Context, ids []string, userID string) ([]models.User, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE id = ANY($1) AND (id = $2
+			OR id IN (
+				SELECT wm.user_id FROM workspace_members wm
+				WHERE wm.workspace_id IN (SELECT own.workspace_id FROM workspace_members own WHERE own.user_id = $2)
+			)
+			OR id IN (
+				SELECT pm.user_id FROM project_members pm WHERE `+taskAccess("pm.project_id", "$2")+`
+			))
+	`, pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// GetProjectsByIDs returns the projects among ids, archived ones included.
+func (q *Queries) GetProjectsByIDs(ctx context.Context, ids []string, userID string) ([]models.Project, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+projectColumns+`
+		FROM projects WHERE id = ANY($1) AND `+projectAccess("projects", "$2")+` AND deleted_at IS NULL
+	`, pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	var projects []models.Project
+	for rows.Next() {
+		var project models.Project
+		if err := scanProject(rows, &project); err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+// GetTasksByIDs returns the tasks among ids.
+func (q *Queries) GetTasksByIDs(ctx context.Context, ids []string, userID string) ([]models.Task, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks WHERE id = ANY($1) AND `+taskAccess("tasks.project_id", "$2")+` AND deleted_at IS NULL
+	`, pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectTasks(rows)
+}
+
+// ListTasksByProjectIDs returns the tasks of the projects, newest first.
+func (q *Queries) ListTasksByProjectIDs(ctx context.Context, projectIDs []string, userID string) ([]models.Task, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks WHERE project_id = ANY($1) AND `+taskAccess("tasks.project_id", "$2")+` AND deleted_at IS NULL
+		ORDER BY created_at DESC
+	`, pq.Array(projectIDs), userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectTasks(rows)
+}
+
+// ListTasksByParentIDs returns the direct subtasks of the tasks, oldest
+// first.
+func (q *Queries) ListTasksByParentIDs(ctx context.Context, parentIDs []string, userID string) ([]models.Task, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks WHERE parent_task_id = ANY($1) AND `+taskAccess("tasks.project_id", "$2")+` AND deleted_at IS NULL
+		ORDER BY created_at
+	`, pq.Array(parentIDs), userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectTasks(rows)
+}
+
+// ListLogEntriesByProjectIDs returns the log entries of the projects, with
+// their comment and reaction counts, in the order of ListLogEntries.
+func (q *Queries) ListLogEntriesByProjectIDs(ctx context.Context, projectIDs []string, userID string) ([]models.LogEntry, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+logEntryColumns+`, `+logEntryCountColumns+`
+		FROM log_entries WHERE project_id = ANY($1) AND `+logEntryAccess("log_entries", "$2")+` AND deleted_at IS NULL
+		ORDER BY log_date DESC, created_at DESC
+	`, pq.Array(projectIDs), userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectLogEntriesWithCounts(rows)
+}
+
+// ListLogEntriesByTaskIDs returns the log entries of the tasks, with their
+// comment and reaction counts, in the order of ListLogEntries.
+func (q *Queries) ListLogEntriesByTaskIDs(ctx context.Context, taskIDs []string, userID string) ([]models.LogEntry, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+logEntryColumns+`, `+logEntryCountColumns+`
+		FROM log_entries WHERE task_id = ANY($1) AND `+logEntryAccess("log_entries", "$2")+` AND deleted_at IS NULL
+		ORDER BY log_date DESC, created_at DESC
+	`, pq.Array(taskIDs), userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectLogEntriesWithCounts(rows)
+}
+
+// collectLogEntriesWithCounts scans and closes rows selected with
+// logEntryColumns and logEntryCountColumns.
+func collectLogEntriesWithCounts(rows *sql.Rows) ([]models.LogEntry, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	var logEntries []models.LogEntry
+	for rows.Next() {
+		var logEntry models.LogEntry
+		if err := scanLogEntryWithCounts(rows, &logEntry); err != nil {
+			return nil, err
+		}
+		logEntries = append(logEntries, logEntry)
+	}
+	return logEntries, rows.Err()
+}
@@ -0,0 +1,255 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/lib/pq"
+)
+
+const notificationColumns = `id, user_id, type, actor_id, task_id, log_entry_id, comment_id, message, read_at, created_at`
+
+func scanNotification(row rowScanner, notification *models.Notification) error {
+	return row.Scan(
+		&notification.ID, &notification.UserID, &notification.Type, &notification.ActorID, &notification.TaskID,
+		&notification.LogEntryID, &notification.CommentID, &notification.Message, &notification.ReadAt, &notification.CreatedAt,
+	)
+}
+
+// insertNotifications wraps a query selecting, in order, the recipient,
+// actor, task, log entry, comment, message and dedupe key of new
+// notifications of the given type. It routes each one by the recipient's
+// preferences, drops those delivered nowhere and skips any already sent.
+func insertNotifications(notificationType, source string) string {
+	return fmt.Sprintf(`
+		INSERT INTO notifications (user_id, type, actor_id, task_id, log_entry_id, comment_id, message, dedupe_key, in_app, email, created_at)
+		SELECT n.user_id, '%[1]s', n.actor_id, n.task_id, n.log_entry_id, n.comment_id, n.message, n.dedupe_key,
+			COALESCE(np.in_app, TRUE), COALESCE(np.email, FALSE), NOW()
+		FROM (%[2]s) AS n (user_id, actor_id, task_id, log_entry_id, comment_id, message, dedupe_key)
+		LEFT JOIN notification_preferences np ON np.user_id = n.user_id AND np.type = '%[1]s'
+		WHERE COALESCE(np.in_app, TRUE) OR COALESCE(np.email, FALSE)
+		ON CONFLICT (user_id, dedupe_key) DO NOTHING
+	`, notificationType, source)
+}
+
+// NotifyComment tells the author of a log entry about a comment someone else
+// left on it.
+func (q *Queries) NotifyComment(ctx context.Context, comment *models.Comment) error {
+	_, err := q.db.ExecContext(ctx, insertNotifications(models.NotificationComment, `
+		SELECT le.user_id, actor.id, NULL::uuid, le.id, c.id,
+			actor.name || ' commented on your log entry: ' || left(c.content, 140), 'comment:' || c.id
+		FROM comments c
+		JOIN log_entries le ON le.id = c.log_entry_id
+		JOIN users actor ON actor.id = c.user_id
+		WHERE c.id = $1 AND le.user_id <> c.user_id AND `+logEntryAccess("le", "le.user_id")+`
+	`), comment.ID)
+	return err
+}
+
+// NotifyMentions tells the users mentioned in a log entry, by their
+// lower-cased usernames, that the actor mentioned them. Only users who
+// can see the entry are notified, each once per entry.
+func (q *Queries) NotifyMentions(ctx context.Context, logEntryID, actorID string, handles []string) error {
+	if len(handles) == 0 {
+		return nil
+	}
+	_, err := q.db.ExecContext(ctx, insertNotifications(models.NotificationMention, `
+		SELECT u.id, actor.id, NULL::uuid, le.id, NULL::uuid,
+			actor.name || ' mentioned you in a log entry: ' || left(le.content, 140), 'mention:' || le.id
+		FROM log_entries le
+		JOIN users actor ON actor.id = $2
+		JOIN users u ON lower(u.username) = ANY($3)
+		WHERE le.id = $1 AND le.deleted_at IS NULL AND u.id <> actor.id AND `+logEntryAccess("le", "u.id")+`
+	`), logEntryID, actorID, pq.Array(handles))
+	return err
+}
+
+// CreateDueReminders reminds each task's creator of open tasks due before
+// the given date, once per due date. It returns how many reminders it
+// created.
+func (q *Queries) CreateDueReminders(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, insertNotifications(models.NotificationTaskDue, `
+		SELECT t.user_id, NULL::uuid, t.id, NULL::uuid, NULL::uuid,
+			'Task "' || t.title || '" is due ' || to_char(t.due_date, 'YYYY-MM-DD'), 'task_due:' || t.id || ':' || t.due_date
+		FROM tasks t
+		JOIN projects p ON p.id = t.project_id
+		WHERE t.due_date < $1 AND t.completed_at IS NULL AND t.deleted_at IS NULL
+			AND p.archived_at IS NULL AND p.deleted_at IS NULL
+			AND `+taskAccess("t.project_id", "t.user_id")+`
+	`), before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListNotifications returns the user's in-app notifications, newest first.
+func (q *Queries) ListNotifications(ctx context.Context, userID string, unreadOnly bool, limit int) ([]models.Notification, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+notificationColumns+`
+		FROM notifications
+		WHERE user_id = $1 AND in_app AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, userID, unreadOnly, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var notification models.Notification
+		if err := scanNotification(rows, &notification); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+// MarkNotificationRead marks one of the user's notifications read, keeping
+// the time it was first read. It returns nil if there is no such
+// notification.
+func (q *Queries) MarkNotificationRead(ctx context.Context, id, userID string) (*models.Notification, error) {
+	var notification models.Notification
+	err := scanNotification(q.db.QueryRowContext(ctx, `
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2 AND in_app
+		RETURNING `+notificationColumns,
+		id, userID), &notification)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &notification, err
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user read
+// and returns how many there were.
+func (q *Queries) MarkAllNotificationsRead(ctx context.Context, userID string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, `
+		UPDATE notifications SET read_at = NOW()
+		WHERE user_id = $1 AND in_app AND read_at IS NULL
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetNotificationPreferences returns the user's preference for every
+// notification type, with the defaults for those never set.
+func (q *Queries) GetNotificationPreferences(ctx context.Context, userID string) ([]models.NotificationPreference, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT t.type, COALESCE(np.in_app, TRUE), COALESCE(np.email, FALSE)
+		FROM unnest($2::varchar[]) WITH ORDINALITY AS t (type, position)
+		LEFT JOIN notification_preferences np ON np.user_id = $1 AND np.type = t.type
+		ORDER BY t.position
+	`, userID, pq.Array(models.NotificationTypes))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	var preferences []models.NotificationPreference
+	for rows.Next() {
+		var preference models.NotificationPreference
+		if err := rows.Scan(&preference.Type, &preference.InApp, &preference.Email); err != nil {
+			return nil, err
+		}
+		preferences = append(preferences, preference)
+	}
+	return preferences, rows.Err()
+}
+
+// SetNotificationPreferences stores the given preferences, leaving other
+// types as they are, and returns them all.
+func (q *Queries) SetNotificationPreferences(ctx context.Context, userID string, preferences []models.NotificationPreference) ([]models.NotificationPreference, error) {
+	tx, err := q.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			_ = err
+		}
+	}()
+
+	for _, preference := range preferences {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO notification_preferences (user_id, type, in_app, email, updated_at)
+			VALUES ($1, $2, $3, $4, NOW())
+			ON CONFLICT (user_id, type) DO UPDATE
+			SET in_app = EXCLUDED.in_app, email = EXCLUDED.email, updated_at = NOW()
+		`, userID, preference.Type, preference.InApp, preference.Email); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return q.GetNotificationPreferences(ctx, userID)
+}
+
+// NotificationEmail is the set of notifications owed to one user by email.
+type NotificationEmail struct {
+	Email         string
+	Name          string
+	Notifications []models.Notification
+}
+
+// ClaimEmailNotifications marks every notification waiting for the email
+// digest as emailed and returns them grouped by recipient. Claiming first
+// means concurrent runs never send the same notification twice; one whose
+// email then fails to send is not retried.
+func (q *Queries) ClaimEmailNotifications(ctx context.Context) ([]NotificationEmail, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		WITH claimed AS (
+			UPDATE notifications SET emailed_at = NOW()
+			WHERE email AND emailed_at IS NULL
+			RETURNING `+notificationColumns+`
+		)
+		SELECT u.email, u.name, claimed.*
+		FROM claimed JOIN users u ON u.id = claimed.user_id
+		ORDER BY claimed.user_id, claimed.created_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	var emails []NotificationEmail
+	for rows.Next() {
+		var email, name string
+		var notification models.Notification
+		err := rows.Scan(
+			&email, &name,
+			&notification.ID, &notification.UserID, &notification.Type, &notification.ActorID, &notification.TaskID,
+			&notification.LogEntryID, &notification.CommentID, &notification.Message, &notification.ReadAt, &notification.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(emails); n == 0 || emails[n-1].Notifications[0].UserID != notification.UserID {
+			emails = append(emails, NotificationEmail{Email: email, Name: name})
+		}
+		last := &emails[len(emails)-1]
+		last.Notifications = append(last.Notifications, notification)
+	}
+	return emails, rows.Err()
+}
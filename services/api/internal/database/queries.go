@@ -1,94 +1,226 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/lib/pq"
 )
 
+// ErrVersionMismatch is returned by conditional writes when the row exists but
+// its version is not one of the versions the caller expected.
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// ErrIDTaken is returned when creating a row under a client-chosen ID that
+// is already in use.
+var ErrIDTaken = errors.New("id already in use")
+
 type Queries struct {
-	db *sql.DB
+	db dbtx
+	// conn is the database the queries run on, nil if they run in a
+	// transaction.
+	conn          *sql.DB
+	revisionLimit int
 }
 
 func New(db *sql.DB) *Queries {
-	return &Queries{db: db}
+	q := &Queries{conn: db, revisionLimit: DefaultRevisionLimit}
+	if db != nil {
+		// A nil *sql.DB would make a non-nil dbtx.
+		q.db = db
+	}
+	return q
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const (
+	projectColumns  = `id, user_id, workspace_id, name, description, client_id, hourly_rate_cents, currency, archived_at, deleted_at, version, created_at, updated_at`
+	taskColumns     = `id, user_id, project_id, parent_task_id, title, description, status, priority, position, due_date, completed_at, recurrence_rule, recurrence_series_id, recurrence_index, deleted_at, version, created_at, updated_at`
+	logEntryColumns = `id, user_id, task_id, project_id, content, log_date, duration_minutes, billable, deleted_at, version, created_at, updated_at`
+)
+
+func scanProject(row rowScanner, project *models.Project) error {
+	return row.Scan(
+		&project.ID, &project.UserID, &project.WorkspaceID, &project.Name, &project.Description, &project.ClientID, &project.HourlyRateCents, &project.Currency, &project.ArchivedAt, &project.DeletedAt, &project.Version, &project.CreatedAt, &project.UpdatedAt,
+	)
+}
+
+func scanTask(row rowScanner, task *models.Task) error {
+	return row.Scan(
+		&task.ID, &task.UserID, &task.ProjectID, &task.ParentTaskID, &task.Title, &task.Description, &task.Status, &task.Priority, &task.Position, &task.DueDate, &task.CompletedAt, &task.RecurrenceRule, &task.RecurrenceSeriesID, &task.RecurrenceIndex, &task.DeletedAt, &task.Version, &task.CreatedAt, &task.UpdatedAt,
+	)
+}
+
+func scanLogEntry(row rowScanner, logEntry *models.LogEntry) error {
+	return row.Scan(
+		&logEntry.ID, &logEntry.UserID, &logEntry.TaskID, &logEntry.ProjectID, &logEntry.Content, &logEntry.LogDate, &logEntry.DurationMinutes, &logEntry.Billable, &logEntry.DeletedAt, &logEntry.Version, &logEntry.CreatedAt, &logEntry.UpdatedAt,
+	)
 }
 
 // User queries
-func (q *Queries) CreateUser(email, passwordHash, name string) (*models.User, error) {
+
+const userColumns = `id, email, username, password_hash, name, created_at, updated_at`
+
+func scanUser(row rowScanner, user *models.User) error {
+	return row.Scan(&user.ID, &user.Email, &user.Username, &user.PasswordHash, &user.Name, &user.CreatedAt, &user.UpdatedAt)
+}
+
+// ErrUsernameTaken is returned when another user already has the username,
+// in any case.
+var ErrUsernameTaken = errors.New("username taken")
+
+// isUsernameTaken reports whether err is a clash on the username index.
+func isUsernameTaken(err error) bool {
+	var pqErr *pq.Error
+	return isUniqueViolation(err) && errors.As(err, &pqErr) && pqErr.Constraint == "idx_users_username"
+}
+
+// CreateUser inserts the user along with their personal workspace.
+func (q *Queries) CreateUser(ctx context.Context, email, username, passwordHash, name string) (*models.User, error) {
 	var user models.User
-	err := q.db.QueryRow(`
-		INSERT INTO users (email, password_hash, name, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		RETURNING id, email, password_hash, name, created_at, updated_at
-	`, email, passwordHash, name).Scan(
-		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.CreatedAt, &user.UpdatedAt,
-	)
+	err := scanUser(q.db.QueryRowContext(ctx, `
+		WITH new_user AS (
+			INSERT INTO users (email, username, password_hash, name, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, NOW(), NOW())
+			RETURNING `+userColumns+`
+		), workspace AS (
+			INSERT INTO workspaces (name, personal_user_id, created_at, updated_at)
+			SELECT 'Personal', id, NOW(), NOW() FROM new_user
+			RETURNING id, personal_user_id
+		), membership AS (
+			INSERT INTO workspace_members (workspace_id, user_id, role, created_at, updated_at)
+			SELECT id, personal_user_id, 'owner', NOW(), NOW() FROM workspace
+		)
+		SELECT `+userColumns+` FROM new_user
+	`, email, username, passwordHash, name), &user)
+	if isUsernameTaken(err) {
+		return nil, ErrUsernameTaken
+	}
 	return &user, err
 }
 
-func (q *Queries) GetUserByEmail(email string) (*models.User, error) {
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	err := q.db.QueryRow(`
-		SELECT id, email, password_hash, name, created_at, updated_at
+	err := scanUser(q.db.QueryRowContext(ctx, `
+		SELECT `+userColumns+`
 		FROM users WHERE email = $1
-	`, email).Scan(
-		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.CreatedAt, &user.UpdatedAt,
-	)
+	`, email), &user)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return &user, err
 }
 
-func (q *Queries) GetUserByID(id string) (*models.User, error) {
+func (q *Queries) GetUserByID(ctx context.Context, id string) (*models.User, error) {
 	var user models.User
-	err := q.db.QueryRow(`
-		SELECT id, email, password_hash, name, created_at, updated_at
+	err := scanUser(q.db.QueryRowContext(ctx, `
+		SELECT `+userColumns+`
 		FROM users WHERE id = $1
-	`, id).Scan(
-		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &user.CreatedAt, &user.UpdatedAt,
-	)
+	`, id), &user)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &user, err
+}
+
+// FreeUsername returns base if no user has it, in any case, or else base
+// followed by the lowest number from 2 up that makes it free.
+func (q *Queries) FreeUsername(ctx context.Context, base string) (string, error) {
+	var username string
+	err := q.db.QueryRowContext(ctx, `
+		SELECT candidate
+		FROM (
+			SELECT $1::text AS candidate, 1 AS n
+			UNION ALL
+			SELECT $1::text || n, n FROM generate_series(2, 10000) AS n
+		) c
+		WHERE NOT EXISTS (SELECT 1 FROM users WHERE lower(username) = lower(c.candidate))
+		ORDER BY n
+		LIMIT 1
+	`, base).Scan(&username)
+	return username, err
+}
+
+// UpdateUsername changes the user's username, returning ErrUsernameTaken if
+// someone else has it.
+func (q *Queries) UpdateUsername(ctx context.Context, id, username string) (*models.User, error) {
+	var user models.User
+	err := scanUser(q.db.QueryRowContext(ctx, `
+		UPDATE users SET username = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+userColumns, id, username), &user)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
+	if isUsernameTaken(err) {
+		return nil, ErrUsernameTaken
+	}
 	return &user, err
 }
 
 // Project queries
-func (q *Queries) CreateProject(userID string, name, description string) (*models.Project, error) {
+// CreateProject inserts the project into the workspace together with the
+// default workflow. userID is recorded as the project's creator; id is the
+// client's choice of ID, or nil for a new one.
+func (q *Queries) CreateProject(ctx context.Context, id *string, userID, workspaceID string, name, description string) (*models.Project, error) {
+	var keys, names, categories []string
+	for _, status := range DefaultWorkflow {
+		keys = append(keys, status.Key)
+		names = append(names, status.Name)
+		categories = append(categories, status.Category)
+	}
+
 	var project models.Project
-	err := q.db.QueryRow(`
-		INSERT INTO projects (user_id, name, description, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		RETURNING id, user_id, name, description, created_at, updated_at
-	`, userID, name, description).Scan(
-		&project.ID, &project.UserID, &project.Name, &project.Description, &project.CreatedAt, &project.UpdatedAt,
-	)
+	err := scanProject(q.db.QueryRowContext(ctx, `
+		WITH project AS (
+			INSERT INTO projects (id, user_id, workspace_id, name, description, created_at, updated_at)
+			VALUES (COALESCE($8::uuid, uuid_generate_v4()), $1, $7, $2, $3, NOW(), NOW())
+			RETURNING `+projectColumns+`
+		), workflow AS (
+			INSERT INTO project_statuses (project_id, key, name, category, position, created_at, updated_at)
+			SELECT project.id, s.key, s.name, s.category, s.position, NOW(), NOW()
+			FROM project, unnest($4::text[], $5::text[], $6::text[]) WITH ORDINALITY AS s(key, name, category, position)
+		)
+		SELECT `+projectColumns+` FROM project`,
+		userID, name, description, pq.Array(keys), pq.Array(names), pq.Array(categories), workspaceID, id), &project)
+	if isUniqueViolation(err) {
+		return nil, ErrIDTaken
+	}
 	return &project, err
 }
 
-func (q *Queries) GetProject(id, userID string) (*models.Project, error) {
+func (q *Queries) GetProject(ctx context.Context, id, userID string) (*models.Project, error) {
 	var project models.Project
-	err := q.db.QueryRow(`
-		SELECT id, user_id, name, description, created_at, updated_at
-		FROM projects WHERE id = $1 AND user_id = $2
-	`, id, userID).Scan(
-		&project.ID, &project.UserID, &project.Name, &project.Description, &project.CreatedAt, &project.UpdatedAt,
-	)
+	err := scanProject(q.db.QueryRowContext(ctx, `
+		SELECT `+projectColumns+`
+		FROM projects WHERE id = $1 AND `+projectAccess("projects", "$2")+` AND deleted_at IS NULL
+	`, id, userID), &project)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return &project, err
 }
 
-func (q *Queries) ListProjects(userID string) ([]models.Project, error) {
-	rows, err := q.db.Query(`
-		SELECT id, user_id, name, description, created_at, updated_at
-		FROM projects WHERE user_id = $1
+// ListProjects returns the active projects of the user's workspaces, or with
+// archived set only the archived ones. A non-nil workspaceID limits them to
+// that workspace.
+func (q *Queries) ListProjects(ctx context.Context, userID string, workspaceID *string, archived bool) ([]models.Project, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+projectColumns+`
+		FROM projects
+		WHERE `+projectAccess("projects", "$1")+` AND deleted_at IS NULL AND (archived_at IS NOT NULL) = $2
+			AND ($3::uuid IS NULL OR workspace_id = $3)
 		ORDER BY created_at DESC
-	`, userID)
+	`, userID, archived, workspaceID)
 	if err != nil {
 		return nil, err
 	}
@@ -102,9 +234,7 @@ func (q *Queries) ListProjects(userID string) ([]models.Project, error) {
 	var projects []models.Project
 	for rows.Next() {
 		var project models.Project
-		if err := rows.Scan(
-			&project.ID, &project.UserID, &project.Name, &project.Description, &project.CreatedAt, &project.UpdatedAt,
-		); err != nil {
+		if err := scanProject(rows, &project); err != nil {
 			return nil, err
 		}
 		projects = append(projects, project)
@@ -112,84 +242,199 @@ func (q *Queries) ListProjects(userID string) ([]models.Project, error) {
 	return projects, rows.Err()
 }
 
-func (q *Queries) UpdateProject(id, userID string, name, description string) (*models.Project, error) {
+// ProjectNames maps the ID of every project the user can see, archived ones
+// included, to its name.
+func (q *Queries) ProjectNames(ctx context.Context, userID string) (map[string]string, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT id, name FROM projects WHERE `+projectAccess("projects", "$1")+` AND deleted_at IS NULL
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	names := map[string]string{}
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+	return names, rows.Err()
+}
+
+// UpdateProject replaces the project's name and description. A non-nil ifMatch
+// limits the update to those versions and yields ErrVersionMismatch otherwise.
+func (q *Queries) UpdateProject(ctx context.Context, id, userID string, name, description string, ifMatch []int64) (*models.Project, error) {
 	var project models.Project
-	err := q.db.QueryRow(`
+	err := scanProject(q.db.QueryRowContext(ctx, `
 		UPDATE projects
-		SET name = $1, description = $2, updated_at = NOW()
-		WHERE id = $3 AND user_id = $4
-		RETURNING id, user_id, name, description, created_at, updated_at
-	`, name, description, id, userID).Scan(
-		&project.ID, &project.UserID, &project.Name, &project.Description, &project.CreatedAt, &project.UpdatedAt,
-	)
+		SET name = $1, description = $2, version = version + 1, updated_at = NOW()
+		WHERE id = $3 AND `+projectAccess("projects", "$4")+` AND deleted_at IS NULL AND ($5::bigint[] IS NULL OR version = ANY($5))
+		RETURNING `+projectColumns,
+		name, description, id, userID, pq.Array(ifMatch)), &project)
 	if err == sql.ErrNoRows {
-		return nil, nil
+		return nil, q.versionConflict(ctx, "projects", id, userID, ifMatch)
 	}
 	return &project, err
 }
 
-func (q *Queries) DeleteProject(id, userID string) error {
-	result, err := q.db.Exec(`
-		DELETE FROM projects WHERE id = $1 AND user_id = $2
-	`, id, userID)
-	if err != nil {
-		return err
+// ProjectPatch lists the project columns to change; nil fields are left as is.
+type ProjectPatch struct {
+	Name        *string
+	Description *string
+	// ClientID and HourlyRateCents are only changed when their Set flag is
+	// true, and a nil value then clears them.
+	SetClientID     bool
+	ClientID        *string
+	SetHourlyRate   bool
+	HourlyRateCents *int64
+	Currency        *string
+}
+
+// PatchProject updates only the columns set in patch. With an empty patch it
+// behaves like GetProject, still honoring ifMatch.
+func (q *Queries) PatchProject(ctx context.Context, id, userID string, patch ProjectPatch, ifMatch []int64) (*models.Project, error) {
+	var set setClause
+	set.addIf("name", patch.Name)
+	set.addIf("description", patch.Description)
+	if patch.SetClientID {
+		set.add("client_id", patch.ClientID)
 	}
-	rows, err := result.RowsAffected()
+	if patch.SetHourlyRate {
+		set.add("hourly_rate_cents", patch.HourlyRateCents)
+	}
+	set.addIf("currency", patch.Currency)
+	if set.empty() {
+		project, err := q.GetProject(ctx, id, userID)
+		if err != nil || project == nil {
+			return project, err
+		}
+		return project, checkVersion(project.Version, ifMatch)
+	}
+
+	var project models.Project
+	err := scanProject(q.db.QueryRowContext(ctx, fmt.Sprintf(`
+		UPDATE projects
+		SET %s, version = version + 1, updated_at = NOW()
+		WHERE id = $%d AND %s AND deleted_at IS NULL AND ($%d::bigint[] IS NULL OR version = ANY($%d))
+		RETURNING %s
+	`, set.String(), set.next(), projectAccess("projects", fmt.Sprintf("$%d", set.next()+1)), set.next()+2, set.next()+2, projectColumns),
+		append(set.args, id, userID, pq.Array(ifMatch))...), &project)
+	if err == sql.ErrNoRows {
+		return nil, q.versionConflict(ctx, "projects", id, userID, ifMatch)
+	}
+	return &project, err
+}
+
+// DeleteProject moves the project to the trash together with its tasks and
+// log entries, which share its deleted_at so that restoring the project
+// brings back exactly those. It returns sql.ErrNoRows if the project does
+// not exist and ErrVersionMismatch if ifMatch is set and does not match.
+func (q *Queries) DeleteProject(ctx context.Context, id, userID string, ifMatch []int64) error {
+	result, err := q.db.ExecContext(ctx, `
+		WITH project AS (
+			UPDATE projects
+			SET deleted_at = NOW(), version = version + 1, updated_at = NOW()
+			WHERE id = $1 AND `+projectAccess("projects", "$2")+` AND deleted_at IS NULL AND ($3::bigint[] IS NULL OR version = ANY($3))
+			RETURNING id, deleted_at
+		), project_tasks AS (
+			UPDATE tasks SET deleted_at = project.deleted_at
+			FROM project WHERE tasks.project_id = project.id AND tasks.deleted_at IS NULL
+		), project_log_entries AS (
+			UPDATE log_entries SET deleted_at = project.deleted_at
+			FROM project WHERE log_entries.project_id = project.id AND log_entries.deleted_at IS NULL
+		)
+		SELECT id FROM project
+	`, id, userID, pq.Array(ifMatch))
 	if err != nil {
 		return err
 	}
-	if rows == 0 {
-		return sql.ErrNoRows
-	}
-	return nil
+	return q.deleteResult(ctx, result, "projects", id, userID, ifMatch)
 }
 
 // Task queries
-func (q *Queries) CreateTask(userID, projectID string, title, description, status string) (*models.Task, error) {
+
+// CreateTaskParams holds the columns of a new task. An empty Priority uses the
+// column default; Position is the task's rank in its board column. A task
+// with a RecurrenceRule starts a new series as its first occurrence. ID is
+// the client's choice of ID, or nil for a new one.
+type CreateTaskParams struct {
+	ID             *string
+	UserID         string
+	ProjectID      string
+	ParentTaskID   *string
+	Title          string
+	Description    string
+	Status         string
+	Priority       string
+	Position       string
+	DueDate        *time.Time
+	RecurrenceRule *string
+}
+
+func (q *Queries) CreateTask(ctx context.Context, params CreateTaskParams) (*models.Task, error) {
 	var task models.Task
-	err := q.db.QueryRow(`
-		INSERT INTO tasks (user_id, project_id, title, description, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
-		RETURNING id, user_id, project_id, title, description, status, created_at, updated_at
-	`, userID, projectID, title, description, status).Scan(
-		&task.ID, &task.UserID, &task.ProjectID, &task.Title, &task.Description, &task.Status, &task.CreatedAt, &task.UpdatedAt,
-	)
+	err := scanTask(q.db.QueryRowContext(ctx, `
+		INSERT INTO tasks (id, user_id, project_id, parent_task_id, title, description, status, priority, position, due_date, completed_at,
+			recurrence_rule, recurrence_series_id, recurrence_index, created_at, updated_at)
+		VALUES (COALESCE($11::uuid, uuid_generate_v4()), $1, $2, $3, $4, $5, $6, COALESCE(NULLIF($7, ''), 'medium'), $8, $9, CASE WHEN `+fmt.Sprintf(closedStatusSQL, "$2::uuid", "$6")+` THEN NOW() END,
+			$10::text, CASE WHEN $10::text IS NOT NULL THEN uuid_generate_v4() END, CASE WHEN $10::text IS NOT NULL THEN 1 END, NOW(), NOW())
+		RETURNING `+taskColumns,
+		params.UserID, params.ProjectID, params.ParentTaskID, params.Title, params.Description, params.Status, params.Priority, params.Position, params.DueDate,
+		params.RecurrenceRule, params.ID), &task)
+	if isUniqueViolation(err) {
+		return nil, ErrIDTaken
+	}
 	return &task, err
 }
 
-func (q *Queries) GetTask(id, userID string) (*models.Task, error) {
+func (q *Queries) GetTask(ctx context.Context, id, userID string) (*models.Task, error) {
 	var task models.Task
-	err := q.db.QueryRow(`
-		SELECT id, user_id, project_id, title, description, status, created_at, updated_at
-		FROM tasks WHERE id = $1 AND user_id = $2
-	`, id, userID).Scan(
-		&task.ID, &task.UserID, &task.ProjectID, &task.Title, &task.Description, &task.Status, &task.CreatedAt, &task.UpdatedAt,
-	)
+	err := scanTask(q.db.QueryRowContext(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks WHERE id = $1 AND `+taskAccess("tasks.project_id", "$2")+` AND deleted_at IS NULL
+	`, id, userID), &task)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return &task, err
 }
 
-func (q *Queries) ListTasks(userID string, projectID *string) ([]models.Task, error) {
-	var rows *sql.Rows
-	var err error
-
-	if projectID != nil {
-		rows, err = q.db.Query(`
-			SELECT id, user_id, project_id, title, description, status, created_at, updated_at
-			FROM tasks WHERE user_id = $1 AND project_id = $2
-			ORDER BY created_at DESC
-		`, userID, *projectID)
-	} else {
-		rows, err = q.db.Query(`
-			SELECT id, user_id, project_id, title, description, status, created_at, updated_at
-			FROM tasks WHERE user_id = $1
-			ORDER BY created_at DESC
-		`, userID)
-	}
+// TaskFilter narrows ListTasks. Due date bounds only match unfinished tasks
+// with a due date; DueFrom is inclusive and DueBefore exclusive.
+type TaskFilter struct {
+	WorkspaceID *string
+	ProjectID   *string
+	Priority    *string
+	DueFrom     *time.Time
+	DueBefore   *time.Time
+	// CreatedBy limits the tasks to those the user created.
+	CreatedBy *string
+	// Completed, when set, keeps only finished or only open tasks.
+	Completed       *bool
+	CompletedFrom   *time.Time
+	CompletedBefore *time.Time
+	// StatusCategory keeps the tasks whose status is in that category of
+	// their project's workflow.
+	StatusCategory *string
+	// Blocked keeps the open tasks that wait on an unfinished blocker or sit
+	// in a status with the key blocked.
+	Blocked bool
+}
 
+// ListTasks returns the tasks of the user's workspaces, newest first. Tasks of
+// archived projects are left out unless ProjectID asks for one. When a due
+// date bound is set the tasks are ordered by due date and then by priority
+// instead.
+func (q *Queries) ListTasks(ctx context.Context, userID string, filter TaskFilter) ([]models.Task, error) {
+	query, args := listTasksQuery(userID, filter)
+	rows, err := q.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -202,9 +447,7 @@ func (q *Queries) ListTasks(userID string, projectID *string) ([]models.Task, er
 	var tasks []models.Task
 	for rows.Next() {
 		var task models.Task
-		if err := rows.Scan(
-			&task.ID, &task.UserID, &task.ProjectID, &task.Title, &task.Description, &task.Status, &task.CreatedAt, &task.UpdatedAt,
-		); err != nil {
+		if err := scanTask(rows, &task); err != nil {
 			return nil, err
 		}
 		tasks = append(tasks, task)
@@ -212,80 +455,286 @@ func (q *Queries) ListTasks(userID string, projectID *string) ([]models.Task, er
 	return tasks, rows.Err()
 }
 
-func (q *Queries) UpdateTask(id, userID string, title, description, status string) (*models.Task, error) {
+// listTasksQuery builds the SELECT of ListTasks and its arguments.
+func listTasksQuery(userID string, filter TaskFilter) (string, []interface{}) {
+	var where whereClause
+	where.cond(taskAccess("project_id", fmt.Sprintf("$%d", where.next())), userID)
+	where.raw("deleted_at IS NULL")
+	if filter.WorkspaceID != nil {
+		where.add("project_id IN (SELECT id FROM projects WHERE workspace_id = $%d)", *filter.WorkspaceID)
+	}
+	if filter.ProjectID != nil {
+		where.add("project_id = $%d", *filter.ProjectID)
+	} else {
+		where.raw("project_id NOT IN (SELECT id FROM projects WHERE archived_at IS NOT NULL)")
+	}
+	if filter.Priority != nil {
+		where.add("priority = $%d", *filter.Priority)
+	}
+	orderBy := "created_at DESC"
+	if filter.DueFrom != nil || filter.DueBefore != nil {
+		where.raw("due_date IS NOT NULL AND completed_at IS NULL")
+		orderBy = "due_date ASC, " + priorityRankSQL + ", created_at ASC"
+	}
+	if filter.DueFrom != nil {
+		where.add("due_date >= $%d", *filter.DueFrom)
+	}
+	if filter.DueBefore != nil {
+		where.add("due_date < $%d", *filter.DueBefore)
+	}
+	if filter.CreatedBy != nil {
+		where.add("user_id = $%d", *filter.CreatedBy)
+	}
+	if filter.Completed != nil {
+		where.add("(completed_at IS NOT NULL) = $%d", *filter.Completed)
+	}
+	if filter.CompletedFrom != nil {
+		where.add("completed_at >= $%d", *filter.CompletedFrom)
+	}
+	if filter.CompletedBefore != nil {
+		where.add("completed_at < $%d", *filter.CompletedBefore)
+	}
+	if filter.StatusCategory != nil {
+		where.add(`EXISTS (
+			SELECT 1 FROM project_statuses ps
+			WHERE ps.project_id = tasks.project_id AND ps.key = tasks.status AND ps.category = $%d
+		)`, *filter.StatusCategory)
+	}
+	if filter.Blocked {
+		where.raw(`completed_at IS NULL AND (status = 'blocked' OR EXISTS (
+			SELECT 1 FROM task_dependencies d JOIN tasks b ON b.id = d.blocked_by_id
+			WHERE d.task_id = tasks.id AND b.completed_at IS NULL AND b.deleted_at IS NULL
+		))`)
+	}
+
+	return `
+		SELECT ` + taskColumns + `
+		FROM tasks WHERE ` + where.String() + `
+		ORDER BY ` + orderBy, where.args
+}
+
+// priorityRankSQL sorts the most pressing priority first.
+const priorityRankSQL = `array_position(ARRAY['urgent', 'high', 'medium', 'low']::varchar[], priority)`
+
+// UpdateTask replaces the task's title, description and status. A non-nil
+// ifMatch limits the update to those versions and yields ErrVersionMismatch
+// otherwise.
+func (q *Queries) UpdateTask(ctx context.Context, id, userID string, title, description, status string, ifMatch []int64) (*models.Task, error) {
 	var task models.Task
-	err := q.db.QueryRow(`
-		UPDATE tasks
-		SET title = $1, description = $2, status = $3, updated_at = NOW()
-		WHERE id = $4 AND user_id = $5
-		RETURNING id, user_id, project_id, title, description, status, created_at, updated_at
-	`, title, description, status, id, userID).Scan(
-		&task.ID, &task.UserID, &task.ProjectID, &task.Title, &task.Description, &task.Status, &task.CreatedAt, &task.UpdatedAt,
-	)
+	err := scanTask(q.revisedUpdate(ctx, models.RevisionEntityTask, taskColumns, "",
+		`title = $1, description = $2, status = $3, `+fmt.Sprintf(completedAtSQL, "tasks.project_id", "$3")+`,
+			version = version + 1, updated_at = NOW()`,
+		`id = $4 AND `+taskAccess("tasks.project_id", "$5")+` AND deleted_at IS NULL AND ($6::bigint[] IS NULL OR version = ANY($6))`,
+		[]interface{}{title, description, status, id, userID, pq.Array(ifMatch)}, userID), &task)
 	if err == sql.ErrNoRows {
-		return nil, nil
+		return nil, q.versionConflict(ctx, "tasks", id, userID, ifMatch)
 	}
 	return &task, err
 }
 
-func (q *Queries) DeleteTask(id, userID string) error {
-	result, err := q.db.Exec(`
-		DELETE FROM tasks WHERE id = $1 AND user_id = $2
-	`, id, userID)
+// TaskPatch lists the task columns to change; nil fields are left as is.
+// Setting ProjectID moves the task, and its subtasks, to another project.
+// ParentTaskID, DueDate and RecurrenceRule are nullable, so they are only
+// written when the matching Set flag is true. Giving a one-off task a rule
+// starts a new series; clearing the rule ends the series.
+type TaskPatch struct {
+	ProjectID         *string
+	SetParentTaskID   bool
+	ParentTaskID      *string
+	Title             *string
+	Description       *string
+	Status            *string
+	Priority          *string
+	SetDueDate        bool
+	DueDate           *time.Time
+	SetRecurrenceRule bool
+	RecurrenceRule    *string
+}
+
+// PatchTask updates only the columns set in patch. With an empty patch it
+// behaves like GetTask, still honoring ifMatch. A new parent that is the
+// task itself or one of its subtasks yields ErrParentCycle; the check and
+// the update run in one transaction under lockTaskTree.
+func (q *Queries) PatchTask(ctx context.Context, id, userID string, patch TaskPatch, ifMatch []int64) (*models.Task, error) {
+	if !patch.SetParentTaskID || patch.ParentTaskID == nil {
+		return q.patchTask(ctx, id, userID, patch, ifMatch)
+	}
+
+	tx, err := q.Begin(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	rows, err := result.RowsAffected()
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			_ = err
+		}
+	}()
+	qtx := q.WithTx(tx)
+	var projectID string
+	err = qtx.db.QueryRowContext(ctx, `SELECT project_id FROM tasks WHERE id = $1`, *patch.ParentTaskID).Scan(&projectID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err := qtx.lockTaskTree(ctx, projectID); err != nil {
+		return nil, err
+	}
+	loop, err := qtx.IsInSubtree(ctx, *patch.ParentTaskID, id, userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if rows == 0 {
-		return sql.ErrNoRows
+	if loop {
+		return nil, ErrParentCycle
 	}
-	return nil
+	task, err := qtx.patchTask(ctx, id, userID, patch, ifMatch)
+	if err != nil {
+		return nil, err
+	}
+	return task, tx.Commit()
+}
+
+func (q *Queries) patchTask(ctx context.Context, id, userID string, patch TaskPatch, ifMatch []int64) (*models.Task, error) {
+	var set setClause
+	set.addIf("project_id", patch.ProjectID)
+	if patch.SetParentTaskID {
+		set.add("parent_task_id", patch.ParentTaskID)
+	}
+	set.addIf("title", patch.Title)
+	set.addIf("description", patch.Description)
+	if patch.Status != nil {
+		set.add("status", *patch.Status)
+		project := "tasks.project_id"
+		if patch.ProjectID != nil {
+			project = "$1"
+		}
+		set.raw(fmt.Sprintf(completedAtSQL, project, fmt.Sprintf("$%d", len(set.args))))
+	}
+	set.addIf("priority", patch.Priority)
+	if patch.SetDueDate {
+		set.add("due_date", patch.DueDate)
+	}
+	if patch.SetRecurrenceRule {
+		set.add("recurrence_rule", patch.RecurrenceRule)
+		if patch.RecurrenceRule != nil {
+			set.raw("recurrence_series_id = COALESCE(recurrence_series_id, uuid_generate_v4())")
+			set.raw("recurrence_index = COALESCE(recurrence_index, 1)")
+		}
+	}
+	if set.empty() {
+		task, err := q.GetTask(ctx, id, userID)
+		if err != nil || task == nil {
+			return task, err
+		}
+		return task, checkVersion(task.Version, ifMatch)
+	}
+
+	// Subtasks follow their parent when it moves to another project, taking
+	// the closest status the target's workflow has. The CTE runs even if
+	// the main UPDATE matches nothing, so it repeats the main row's
+	// conditions. project_id is always the first SET argument.
+	moveSubtasks := ""
+	if patch.ProjectID != nil {
+		mappedStatus := fmt.Sprintf(mapStatusSQL, "$1")
+		moveSubtasks = fmt.Sprintf(`
+		subtree AS (
+			SELECT id FROM tasks WHERE parent_task_id = $%[1]d AND deleted_at IS NULL
+			UNION
+			SELECT t.id FROM tasks t JOIN subtree st ON t.parent_task_id = st.id WHERE t.deleted_at IS NULL
+		), moved AS (
+			UPDATE tasks
+			SET project_id = $1, status = %[4]s, %[5]s,
+				version = version + 1, updated_at = NOW()
+			WHERE id IN (SELECT id FROM subtree) AND EXISTS (
+				SELECT 1 FROM tasks root WHERE root.id = $%[1]d AND %[6]s
+					AND ($%[3]d::bigint[] IS NULL OR root.version = ANY($%[3]d))
+			)
+		),`, set.next(), set.next()+1, set.next()+2, mappedStatus, fmt.Sprintf(completedAtSQL, "$1", mappedStatus),
+			taskAccess("root.project_id", fmt.Sprintf("$%d", set.next()+1)))
+	}
+
+	var task models.Task
+	err := scanTask(q.revisedUpdate(ctx, models.RevisionEntityTask, taskColumns, moveSubtasks,
+		set.String()+", version = version + 1, updated_at = NOW()",
+		fmt.Sprintf("id = $%d AND %s AND deleted_at IS NULL AND ($%d::bigint[] IS NULL OR version = ANY($%d))",
+			set.next(), taskAccess("tasks.project_id", fmt.Sprintf("$%d", set.next()+1)), set.next()+2, set.next()+2),
+		append(set.args, id, userID, pq.Array(ifMatch)), userID), &task)
+	if err == sql.ErrNoRows {
+		return nil, q.versionConflict(ctx, "tasks", id, userID, ifMatch)
+	}
+	return &task, err
+}
+
+// DeleteTask moves the task and its subtasks to the trash, sharing one
+// deleted_at so they are restored together. It returns sql.ErrNoRows if the
+// task does not exist and ErrVersionMismatch if ifMatch is set and does not
+// match.
+func (q *Queries) DeleteTask(ctx context.Context, id, userID string, ifMatch []int64) error {
+	result, err := q.db.ExecContext(ctx, `
+		WITH RECURSIVE task AS (
+			UPDATE tasks
+			SET deleted_at = NOW(), version = version + 1, updated_at = NOW()
+			WHERE id = $1 AND `+taskAccess("tasks.project_id", "$2")+` AND deleted_at IS NULL AND ($3::bigint[] IS NULL OR version = ANY($3))
+			RETURNING id, deleted_at
+		), subtree AS (
+			SELECT t.id FROM tasks t JOIN task ON t.parent_task_id = task.id WHERE t.deleted_at IS NULL
+			UNION
+			SELECT t.id FROM tasks t JOIN subtree st ON t.parent_task_id = st.id WHERE t.deleted_at IS NULL
+		), subtasks AS (
+			UPDATE tasks SET deleted_at = task.deleted_at
+			FROM task WHERE tasks.id IN (SELECT id FROM subtree)
+		)
+		SELECT id FROM task
+	`, id, userID, pq.Array(ifMatch))
+	if err != nil {
+		return err
+	}
+	return q.deleteResult(ctx, result, "tasks", id, userID, ifMatch)
 }
 
 // Log entry queries
-func (q *Queries) CreateLogEntry(userID string, taskID, projectID *string, content string, logDate time.Time) (*models.LogEntry, error) {
+
+// CreateLogEntry inserts the entry under id, the client's choice of ID, or a
+// new one if id is nil.
+func (q *Queries) CreateLogEntry(ctx context.Context, id *string, userID string, taskID, projectID *string, content string, logDate time.Time, durationMinutes *int, billable bool) (*models.LogEntry, error) {
 	var logEntry models.LogEntry
-	err := q.db.QueryRow(`
-		INSERT INTO log_entries (user_id, task_id, project_id, content, log_date, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
-		RETURNING id, user_id, task_id, project_id, content, log_date, created_at, updated_at
-	`, userID, taskID, projectID, content, logDate).Scan(
-		&logEntry.ID, &logEntry.UserID, &logEntry.TaskID, &logEntry.ProjectID, &logEntry.Content, &logEntry.LogDate, &logEntry.CreatedAt, &logEntry.UpdatedAt,
-	)
+	err := scanLogEntry(q.db.QueryRowContext(ctx, `
+		INSERT INTO log_entries (id, user_id, task_id, project_id, content, log_date, duration_minutes, billable, created_at, updated_at)
+		VALUES (COALESCE($8::uuid, uuid_generate_v4()), $1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING `+logEntryColumns,
+		userID, taskID, projectID, content, logDate, durationMinutes, billable, id), &logEntry)
+	if isUniqueViolation(err) {
+		return nil, ErrIDTaken
+	}
 	return &logEntry, err
 }
 
-func (q *Queries) GetLogEntry(id, userID string) (*models.LogEntry, error) {
+func (q *Queries) GetLogEntry(ctx context.Context, id, userID string) (*models.LogEntry, error) {
 	var logEntry models.LogEntry
-	err := q.db.QueryRow(`
-		SELECT id, user_id, task_id, project_id, content, log_date, created_at, updated_at
-		FROM log_entries WHERE id = $1 AND user_id = $2
-	`, id, userID).Scan(
-		&logEntry.ID, &logEntry.UserID, &logEntry.TaskID, &logEntry.ProjectID, &logEntry.Content, &logEntry.LogDate, &logEntry.CreatedAt, &logEntry.UpdatedAt,
-	)
+	err := scanLogEntry(q.db.QueryRowContext(ctx, `
+		SELECT `+logEntryColumns+`
+		FROM log_entries WHERE id = $1 AND `+logEntryAccess("log_entries", "$2")+` AND deleted_at IS NULL
+	`, id, userID), &logEntry)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	return &logEntry, err
 }
 
-func (q *Queries) ListLogEntries(userID string, projectID *string) ([]models.LogEntry, error) {
+// ListLogEntries returns the entries the user can see, with their comment and
+// reaction counts.
+func (q *Queries) ListLogEntries(ctx context.Context, userID string, projectID *string) ([]models.LogEntry, error) {
 	var rows *sql.Rows
 	var err error
 
 	if projectID != nil {
-		rows, err = q.db.Query(`
-			SELECT id, user_id, task_id, project_id, content, log_date, created_at, updated_at
-			FROM log_entries WHERE user_id = $1 AND project_id = $2
+		rows, err = q.db.QueryContext(ctx, `
+			SELECT `+logEntryColumns+`, `+logEntryCountColumns+`
+			FROM log_entries WHERE `+logEntryAccess("log_entries", "$1")+` AND project_id = $2 AND deleted_at IS NULL
 			ORDER BY log_date DESC, created_at DESC
 		`, userID, *projectID)
 	} else {
-		rows, err = q.db.Query(`
-			SELECT id, user_id, task_id, project_id, content, log_date, created_at, updated_at
-			FROM log_entries WHERE user_id = $1
+		rows, err = q.db.QueryContext(ctx, `
+			SELECT `+logEntryColumns+`, `+logEntryCountColumns+`
+			FROM log_entries WHERE `+logEntryAccess("log_entries", "$1")+` AND deleted_at IS NULL
 			ORDER BY log_date DESC, created_at DESC
 		`, userID)
 	}
@@ -302,9 +751,7 @@ func (q *Queries) ListLogEntries(userID string, projectID *string) ([]models.Log
 	var logEntries []models.LogEntry
 	for rows.Next() {
 		var logEntry models.LogEntry
-		if err := rows.Scan(
-			&logEntry.ID, &logEntry.UserID, &logEntry.TaskID, &logEntry.ProjectID, &logEntry.Content, &logEntry.LogDate, &logEntry.CreatedAt, &logEntry.UpdatedAt,
-		); err != nil {
+		if err := scanLogEntryWithCounts(rows, &logEntry); err != nil {
 			return nil, err
 		}
 		logEntries = append(logEntries, logEntry)
@@ -312,11 +759,13 @@ func (q *Queries) ListLogEntries(userID string, projectID *string) ([]models.Log
 	return logEntries, rows.Err()
 }
 
-func (q *Queries) GetTodayLogEntries(userID string, date time.Time) ([]models.LogEntry, error) {
-	rows, err := q.db.Query(`
-		SELECT id, user_id, task_id, project_id, content, log_date, created_at, updated_at
+// GetTodayLogEntries returns the entries the user wrote for the date, with
+// their comment and reaction counts.
+func (q *Queries) GetTodayLogEntries(ctx context.Context, userID string, date time.Time) ([]models.LogEntry, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+logEntryColumns+`, `+logEntryCountColumns+`
 		FROM log_entries
-		WHERE user_id = $1 AND DATE(log_date) = DATE($2)
+		WHERE user_id = $1 AND DATE(log_date) = DATE($2) AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`, userID, date)
 	if err != nil {
@@ -331,9 +780,66 @@ func (q *Queries) GetTodayLogEntries(userID string, date time.Time) ([]models.Lo
 	var logEntries []models.LogEntry
 	for rows.Next() {
 		var logEntry models.LogEntry
-		if err := rows.Scan(
-			&logEntry.ID, &logEntry.UserID, &logEntry.TaskID, &logEntry.ProjectID, &logEntry.Content, &logEntry.LogDate, &logEntry.CreatedAt, &logEntry.UpdatedAt,
-		); err != nil {
+		if err := scanLogEntryWithCounts(rows, &logEntry); err != nil {
+			return nil, err
+		}
+		logEntries = append(logEntries, logEntry)
+	}
+	return logEntries, rows.Err()
+}
+
+// ListLogEntriesBetween returns the entries the user wrote for the dates
+// from through to, oldest first.
+func (q *Queries) ListLogEntriesBetween(ctx context.Context, userID string, from, to time.Time) ([]models.LogEntry, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+logEntryColumns+`
+		FROM log_entries
+		WHERE user_id = $1 AND DATE(log_date) BETWEEN DATE($2) AND DATE($3) AND deleted_at IS NULL
+		ORDER BY log_date, created_at
+	`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	var logEntries []models.LogEntry
+	for rows.Next() {
+		var logEntry models.LogEntry
+		if err := scanLogEntry(rows, &logEntry); err != nil {
+			return nil, err
+		}
+		logEntries = append(logEntries, logEntry)
+	}
+	return logEntries, rows.Err()
+}
+
+// ListProjectLogEntriesBetween returns the entries of the project the user
+// can see for the dates from through to, oldest first, whoever wrote them.
+func (q *Queries) ListProjectLogEntriesBetween(ctx context.Context, userID, projectID string, from, to time.Time) ([]models.LogEntry, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+logEntryColumns+`
+		FROM log_entries
+		WHERE `+logEntryAccess("log_entries", "$1")+` AND project_id = $2
+			AND DATE(log_date) BETWEEN DATE($3) AND DATE($4) AND deleted_at IS NULL
+		ORDER BY log_date, created_at
+	`, userID, projectID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	var logEntries []models.LogEntry
+	for rows.Next() {
+		var logEntry models.LogEntry
+		if err := scanLogEntry(rows, &logEntry); err != nil {
 			return nil, err
 		}
 		logEntries = append(logEntries, logEntry)
@@ -341,35 +847,208 @@ func (q *Queries) GetTodayLogEntries(userID string, date time.Time) ([]models.Lo
 	return logEntries, rows.Err()
 }
 
-func (q *Queries) UpdateLogEntry(id, userID string, content string, logDate time.Time) (*models.LogEntry, error) {
+// UpdateLogEntry replaces the entry's content and date. A non-nil ifMatch
+// limits the update to those versions and yields ErrVersionMismatch otherwise.
+func (q *Queries) UpdateLogEntry(ctx context.Context, id, userID string, content string, logDate time.Time, ifMatch []int64) (*models.LogEntry, error) {
 	var logEntry models.LogEntry
-	err := q.db.QueryRow(`
-		UPDATE log_entries
-		SET content = $1, log_date = $2, updated_at = NOW()
-		WHERE id = $3 AND user_id = $4
-		RETURNING id, user_id, task_id, project_id, content, log_date, created_at, updated_at
-	`, content, logDate, id, userID).Scan(
-		&logEntry.ID, &logEntry.UserID, &logEntry.TaskID, &logEntry.ProjectID, &logEntry.Content, &logEntry.LogDate, &logEntry.CreatedAt, &logEntry.UpdatedAt,
-	)
+	err := scanLogEntry(q.revisedUpdate(ctx, models.RevisionEntityLogEntry, logEntryColumns, "",
+		`content = $1, log_date = $2, version = version + 1, updated_at = NOW()`,
+		`id = $3 AND `+logEntryAccess("log_entries", "$4")+` AND deleted_at IS NULL AND ($5::bigint[] IS NULL OR version = ANY($5))`,
+		[]interface{}{content, logDate, id, userID, pq.Array(ifMatch)}, userID), &logEntry)
 	if err == sql.ErrNoRows {
-		return nil, nil
+		return nil, q.versionConflict(ctx, "log_entries", id, userID, ifMatch)
+	}
+	return &logEntry, err
+}
+
+// LogEntryPatch lists the log entry columns to change; nil fields are left as
+// is. TaskID and ProjectID are nullable, so they are only written when the
+// matching Set flag is true, and a nil value then clears the link.
+type LogEntryPatch struct {
+	Content      *string
+	LogDate      *time.Time
+	SetTaskID    bool
+	TaskID       *string
+	SetProjectID bool
+	ProjectID    *string
+	// SetDuration changes DurationMinutes, where nil clears it.
+	SetDuration     bool
+	DurationMinutes *int
+	Billable        *bool
+}
+
+// PatchLogEntry updates only the columns set in patch. With an empty patch it
+// behaves like GetLogEntry, still honoring ifMatch.
+func (q *Queries) PatchLogEntry(ctx context.Context, id, userID string, patch LogEntryPatch, ifMatch []int64) (*models.LogEntry, error) {
+	var set setClause
+	set.addIf("content", patch.Content)
+	if patch.LogDate != nil {
+		set.add("log_date", *patch.LogDate)
+	}
+	if patch.SetTaskID {
+		set.add("task_id", patch.TaskID)
+	}
+	if patch.SetProjectID {
+		set.add("project_id", patch.ProjectID)
+	}
+	if patch.SetDuration {
+		set.add("duration_minutes", patch.DurationMinutes)
+	}
+	if patch.Billable != nil {
+		set.add("billable", *patch.Billable)
+	}
+	if set.empty() {
+		logEntry, err := q.GetLogEntry(ctx, id, userID)
+		if err != nil || logEntry == nil {
+			return logEntry, err
+		}
+		return logEntry, checkVersion(logEntry.Version, ifMatch)
+	}
+
+	var logEntry models.LogEntry
+	err := scanLogEntry(q.revisedUpdate(ctx, models.RevisionEntityLogEntry, logEntryColumns, "",
+		set.String()+", version = version + 1, updated_at = NOW()",
+		fmt.Sprintf("id = $%d AND %s AND deleted_at IS NULL AND ($%d::bigint[] IS NULL OR version = ANY($%d))",
+			set.next(), logEntryAccess("log_entries", fmt.Sprintf("$%d", set.next()+1)), set.next()+2, set.next()+2),
+		append(set.args, id, userID, pq.Array(ifMatch)), userID), &logEntry)
+	if err == sql.ErrNoRows {
+		return nil, q.versionConflict(ctx, "log_entries", id, userID, ifMatch)
 	}
 	return &logEntry, err
 }
 
-func (q *Queries) DeleteLogEntry(id, userID string) error {
-	result, err := q.db.Exec(`
-		DELETE FROM log_entries WHERE id = $1 AND user_id = $2
-	`, id, userID)
+// DeleteLogEntry moves the entry to the trash, returning sql.ErrNoRows if it
+// does not exist and ErrVersionMismatch if ifMatch is set and does not match.
+func (q *Queries) DeleteLogEntry(ctx context.Context, id, userID string, ifMatch []int64) error {
+	result, err := q.db.ExecContext(ctx, `
+		UPDATE log_entries
+		SET deleted_at = NOW(), version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND `+logEntryAccess("log_entries", "$2")+` AND deleted_at IS NULL AND ($3::bigint[] IS NULL OR version = ANY($3))
+	`, id, userID, pq.Array(ifMatch))
 	if err != nil {
 		return err
 	}
+	return q.deleteResult(ctx, result, "log_entries", id, userID, ifMatch)
+}
+
+// versionConflict explains why a conditional write touched no rows: if the
+// row exists the version must not have matched, otherwise it is simply gone.
+// The table name always comes from code.
+func (q *Queries) versionConflict(ctx context.Context, table, id, userID string, ifMatch []int64) error {
+	if ifMatch == nil {
+		return nil
+	}
+	var exists bool
+	err := q.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = $1 AND `+tableAccess(table, "$2")+` AND deleted_at IS NULL)
+	`, id, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+func (q *Queries) deleteResult(ctx context.Context, result sql.Result, table, id, userID string, ifMatch []int64) error {
 	rows, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 	if rows == 0 {
+		if err := q.versionConflict(ctx, table, id, userID, ifMatch); err != nil {
+			return err
+		}
 		return sql.ErrNoRows
 	}
 	return nil
 }
+
+// isUniqueViolation reports whether err is Postgres refusing a duplicate
+// key.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// checkVersion applies ifMatch to a row that was read rather than written.
+func checkVersion(version int64, ifMatch []int64) error {
+	if ifMatch == nil {
+		return nil
+	}
+	for _, v := range ifMatch {
+		if v == version {
+			return nil
+		}
+	}
+	return ErrVersionMismatch
+}
+
+// setClause builds the SET list of a partial UPDATE. Column names always come
+// from code, never from the request; values are passed as placeholders.
+type setClause struct {
+	cols []string
+	args []interface{}
+}
+
+func (s *setClause) add(col string, value interface{}) {
+	s.args = append(s.args, value)
+	s.cols = append(s.cols, fmt.Sprintf("%s = $%d", col, len(s.args)))
+}
+
+func (s *setClause) addIf(col string, value *string) {
+	if value != nil {
+		s.add(col, *value)
+	}
+}
+
+// raw appends an expression that refers to earlier placeholders.
+func (s *setClause) raw(expr string) {
+	s.cols = append(s.cols, expr)
+}
+
+func (s *setClause) empty() bool {
+	return len(s.cols) == 0
+}
+
+// next returns the placeholder index following the SET arguments.
+func (s *setClause) next() int {
+	return len(s.args) + 1
+}
+
+func (s *setClause) String() string {
+	return strings.Join(s.cols, ", ")
+}
+
+// whereClause builds an AND-ed WHERE condition. Each condition passed to add
+// holds a single %d verb for its placeholder index; one that refers to its
+// placeholder more than once is rendered with next and passed to cond.
+type whereClause struct {
+	conds []string
+	args  []interface{}
+}
+
+func (w *whereClause) add(cond string, value interface{}) {
+	w.cond(fmt.Sprintf(cond, w.next()), value)
+}
+
+// cond adds a condition whose placeholder, the one next gave, is already
+// filled in.
+func (w *whereClause) cond(cond string, value interface{}) {
+	w.args = append(w.args, value)
+	w.conds = append(w.conds, cond)
+}
+
+// next returns the index of the placeholder for the next argument.
+func (w *whereClause) next() int {
+	return len(w.args) + 1
+}
+
+func (w *whereClause) raw(cond string) {
+	w.conds = append(w.conds, cond)
+}
+
+func (w *whereClause) String() string {
+	return strings.Join(w.conds, " AND ")
+}
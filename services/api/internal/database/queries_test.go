@@ -1,7 +1,9 @@
 package database
 
 import (
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -14,3 +16,81 @@ func TestNew(t *testing.T) {
 		t.Error("Expected db to be nil when passed nil")
 	}
 }
+
+func TestSetClause(t *testing.T) {
+	name := "New name"
+	var set setClause
+	if !set.empty() {
+		t.Error("Expected new set clause to be empty")
+	}
+
+	set.addIf("name", &name)
+	set.addIf("description", nil)
+	set.add("task_id", nil)
+
+	if set.empty() {
+		t.Fatal("Expected set clause to have columns")
+	}
+	if got := set.String(); got != "name = $1, task_id = $2" {
+		t.Errorf("Unexpected SET list: %s", got)
+	}
+	if len(set.args) != 2 {
+		t.Errorf("Expected 2 args, got %d", len(set.args))
+	}
+	if set.next() != 3 {
+		t.Errorf("Expected next placeholder 3, got %d", set.next())
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	if err := checkVersion(3, nil); err != nil {
+		t.Errorf("Expected unconditional check to pass, got %v", err)
+	}
+	if err := checkVersion(3, []int64{2, 3}); err != nil {
+		t.Errorf("Expected matching version to pass, got %v", err)
+	}
+	if err := checkVersion(3, []int64{}); err != ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch for empty list, got %v", err)
+	}
+	if err := checkVersion(3, []int64{2}); err != ErrVersionMismatch {
+		t.Errorf("Expected ErrVersionMismatch, got %v", err)
+	}
+}
+
+func TestWhereClause(t *testing.T) {
+	var where whereClause
+	where.add("user_id = $%d", "user-123")
+	where.raw("status <> 'done'")
+	where.add("priority = $%d", "high")
+
+	if got := where.String(); got != "user_id = $1 AND status <> 'done' AND priority = $2" {
+		t.Errorf("Unexpected WHERE condition: %s", got)
+	}
+	if len(where.args) != 2 {
+		t.Errorf("Expected 2 args, got %d", len(where.args))
+	}
+}
+
+func TestListTasksQuery(t *testing.T) {
+	workspaceID, priority, category := "workspace-1", "high", "closed"
+	due := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	query, args := listTasksQuery("user-123", TaskFilter{
+		WorkspaceID:    &workspaceID,
+		Priority:       &priority,
+		DueFrom:        &due,
+		StatusCategory: &category,
+	})
+
+	if strings.Contains(query, "%!") {
+		t.Errorf("Expected every placeholder to be filled in, got:\n%s", query)
+	}
+	if !strings.Contains(query, "wm.user_id = $1") || !strings.Contains(query, "pm.user_id = $1") {
+		t.Errorf("Expected the access check to use $1 for the user, got:\n%s", query)
+	}
+	if !strings.Contains(query, "ps.category = $5") {
+		t.Errorf("Expected the last filter to use $5, got:\n%s", query)
+	}
+	if len(args) != 5 || args[0] != "user-123" {
+		t.Errorf("Unexpected args: %v", args)
+	}
+}
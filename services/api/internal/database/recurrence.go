@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/chrispotter/makerlog/services/api/internal/rank"
+	"github.com/chrispotter/makerlog/services/api/internal/recurrence"
+)
+
+// CreateNextOccurrence inserts the occurrence that follows prev in its
+// series: a task with prev's details in its project's default status, due on
+// the rule's next date and placed at the end of its board column. Occurrences due after notAfter are
+// not created; a nil notAfter has no limit.
+//
+// It returns nil when prev does not recur, its series has ended or the
+// occurrence already exists, so it is safe to call more than once.
+func (q *Queries) CreateNextOccurrence(ctx context.Context, prev *models.Task, notAfter *time.Time) (*models.Task, error) {
+	if prev.RecurrenceRule == nil || prev.RecurrenceSeriesID == nil || prev.RecurrenceIndex == nil || prev.DueDate == nil {
+		return nil, nil
+	}
+	rule, err := recurrence.Parse(*prev.RecurrenceRule)
+	if err != nil {
+		return nil, err
+	}
+	dueDate, ok := rule.Next(*prev.DueDate, *prev.RecurrenceIndex)
+	if !ok || (notAfter != nil && dueDate.After(*notAfter)) {
+		return nil, nil
+	}
+
+	workflow, err := q.GetWorkflow(ctx, prev.ProjectID, prev.UserID)
+	if err != nil || workflow == nil {
+		return nil, err
+	}
+	status := workflow.DefaultStatus()
+	last, err := q.LastTaskPosition(ctx, prev.ProjectID, status, prev.UserID, "")
+	if err != nil {
+		return nil, err
+	}
+	position, err := rank.Between(last, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var task models.Task
+	err = scanTask(q.db.QueryRowContext(ctx, `
+		INSERT INTO tasks (user_id, project_id, parent_task_id, title, description, status, priority, position, due_date,
+			recurrence_rule, recurrence_series_id, recurrence_index, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
+		ON CONFLICT (recurrence_series_id, recurrence_index) WHERE recurrence_series_id IS NOT NULL DO NOTHING
+		RETURNING `+taskColumns,
+		prev.UserID, prev.ProjectID, prev.ParentTaskID, prev.Title, prev.Description, status, prev.Priority, position, dueDate,
+		prev.RecurrenceRule, prev.RecurrenceSeriesID, *prev.RecurrenceIndex+1), &task)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &task, err
+}
+
+// ListRecurrenceHeads returns the latest occurrence of every recurring
+// series, across all users, that still has a rule. The scheduler extends
+// each series from there. Deleting the latest occurrence, or archiving or
+// deleting its project, pauses the series.
+func (q *Queries) ListRecurrenceHeads(ctx context.Context) ([]models.Task, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+taskColumns+`
+		FROM (
+			SELECT DISTINCT ON (recurrence_series_id) *
+			FROM tasks
+			WHERE recurrence_series_id IS NOT NULL
+			ORDER BY recurrence_series_id, recurrence_index DESC
+		) heads
+		WHERE recurrence_rule IS NOT NULL AND due_date IS NOT NULL AND deleted_at IS NULL
+			AND project_id IN (SELECT id FROM projects WHERE archived_at IS NULL AND deleted_at IS NULL)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return collectTasks(rows)
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// DefaultRevisionLimit is how many revisions are kept per task or log entry
+// unless SetRevisionLimit says otherwise.
+const DefaultRevisionLimit = 50
+
+// revisionEntity describes an entity with a revision history: its table and
+// the tracked fields, as a jsonb expression over a row of the table.
+type revisionEntity struct {
+	table string
+	data  string
+}
+
+var revisionEntities = map[string]revisionEntity{
+	models.RevisionEntityTask: {
+		table: "tasks",
+		data: `jsonb_build_object(
+			'title', tasks.title, 'description', tasks.description, 'status', tasks.status,
+			'priority', tasks.priority, 'due_date', tasks.due_date, 'project_id', tasks.project_id,
+			'parent_task_id', tasks.parent_task_id, 'recurrence_rule', tasks.recurrence_rule
+		)`,
+	},
+	models.RevisionEntityLogEntry: {
+		table: "log_entries",
+		data: `jsonb_build_object(
+			'content', log_entries.content, 'log_date', log_entries.log_date,
+			'task_id', log_entries.task_id, 'project_id', log_entries.project_id,
+			'duration_minutes', log_entries.duration_minutes, 'billable', log_entries.billable
+		)`,
+	},
+}
+
+const revisionColumns = `id, entity_type, entity_id, user_id, version, data, changed_fields, created_at`
+
+func scanRevision(row rowScanner, revision *models.Revision) error {
+	var data []byte
+	if err := row.Scan(
+		&revision.ID, &revision.EntityType, &revision.EntityID, &revision.UserID, &revision.Version, &data, pq.Array(&revision.ChangedFields), &revision.CreatedAt,
+	); err != nil {
+		return err
+	}
+	revision.Data = data
+	return nil
+}
+
+// SetRevisionLimit sets how many revisions are kept per task or log entry.
+// Older ones are dropped as new ones are written.
+func (q *Queries) SetRevisionLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	q.revisionLimit = limit
+}
+
+// revisedUpdate runs an UPDATE of a single task or log entry that records the
+// values it replaces as a revision by userID, in the same statement. where
+// picks the row and set is the SET list; both use args as placeholders. ctes
+// are extra common table expressions, each followed by a comma, to run
+// first. A revision is only written when a tracked field changes, and it
+// pushes out the oldest revisions beyond the limit. The row comes back with
+// the entity's columns.
+func (q *Queries) revisedUpdate(ctx context.Context, entityType, columns, ctes, set, where string, args []interface{}, userID string) *sql.Row {
+	entity := revisionEntities[entityType]
+	limit := q.revisionLimit
+	if limit == 0 {
+		limit = DefaultRevisionLimit
+	}
+	next := len(args) + 1
+	return q.db.QueryRowContext(ctx, fmt.Sprintf(`
+		WITH RECURSIVE %[1]s previous AS (
+			SELECT id AS previous_id, version AS previous_version, %[4]s AS previous_data
+			FROM %[2]s
+			WHERE %[5]s
+			FOR UPDATE
+		), updated AS (
+			UPDATE %[2]s
+			SET %[6]s
+			FROM previous
+			WHERE %[2]s.id = previous.previous_id
+			RETURNING %[3]s, previous_version, previous_data, %[4]s AS current_data
+		), revision AS (
+			INSERT INTO revisions (entity_type, entity_id, user_id, version, data, changed_fields, created_at)
+			SELECT '%[7]s', id, $%[8]d::uuid, previous_version, previous_data,
+				ARRAY(SELECT key FROM jsonb_each(previous_data) WHERE value IS DISTINCT FROM current_data -> key ORDER BY key),
+				NOW()
+			FROM updated
+			WHERE previous_data IS DISTINCT FROM current_data
+			RETURNING entity_id
+		), pruned AS (
+			DELETE FROM revisions WHERE id IN (
+				SELECT r.id FROM revisions r JOIN revision ON r.entity_id = revision.entity_id
+				WHERE r.entity_type = '%[7]s'
+				ORDER BY r.created_at DESC, r.version DESC
+				OFFSET $%[9]d
+			)
+		)
+		SELECT %[3]s FROM updated
+	`, ctes, entity.table, columns, entity.data, where, set, entityType, next, next+1),
+		append(args, userID, limit-1)...)
+}
+
+// ListRevisions returns the revisions of a task or log entry the user can see, newest
+// first.
+func (q *Queries) ListRevisions(ctx context.Context, entityType, entityID, userID string) ([]models.Revision, error) {
+	entity := revisionEntities[entityType]
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+revisionColumns+`
+		FROM revisions
+		WHERE entity_type = $1 AND entity_id = $2 AND EXISTS (
+			SELECT 1 FROM `+entity.table+` WHERE id = $2 AND `+tableAccess(entity.table, "$3")+` AND deleted_at IS NULL
+		)
+		ORDER BY created_at DESC, version DESC
+	`, entityType, entityID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	revisions := []models.Revision{}
+	for rows.Next() {
+		var revision models.Revision
+		if err := scanRevision(rows, &revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// GetRevision returns one revision of a task or log entry the user can see, or nil if
+// there is no such revision.
+func (q *Queries) GetRevision(ctx context.Context, entityType, entityID, revisionID, userID string) (*models.Revision, error) {
+	entity := revisionEntities[entityType]
+	var revision models.Revision
+	err := scanRevision(q.db.QueryRowContext(ctx, `
+		SELECT `+revisionColumns+`
+		FROM revisions
+		WHERE id = $1 AND entity_type = $2 AND entity_id = $3 AND EXISTS (
+			SELECT 1 FROM `+entity.table+` WHERE id = $3 AND `+tableAccess(entity.table, "$4")+` AND deleted_at IS NULL
+		)
+	`, revisionID, entityType, entityID, userID), &revision)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// CurrentRevisionData returns the tracked fields of a task or log entry the
+// user can see as they are now, in the form revisions store them, or nil if the
+// entity does not exist.
+func (q *Queries) CurrentRevisionData(ctx context.Context, entityType, entityID, userID string) (json.RawMessage, error) {
+	entity := revisionEntities[entityType]
+	var data []byte
+	err := q.db.QueryRowContext(ctx, `
+		SELECT `+entity.data+`
+		FROM `+entity.table+`
+		WHERE id = $1 AND `+tableAccess(entity.table, "$2")+` AND deleted_at IS NULL
+	`, entityID, userID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return data, err
+}
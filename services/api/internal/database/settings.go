@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+// GetUserSettings returns the user's settings, or the defaults if they have
+// never changed them.
+func (q *Queries) GetUserSettings(ctx context.Context, userID string) (*models.UserSettings, error) {
+	var settings models.UserSettings
+	err := q.db.QueryRowContext(ctx, `
+		SELECT timezone, digest, updated_at FROM user_settings WHERE user_id = $1
+	`, userID).Scan(&settings.Timezone, &settings.Digest, &settings.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return &models.UserSettings{Timezone: "UTC", Digest: models.DigestOff}, nil
+	}
+	return &settings, err
+}
+
+// UpdateUserSettings changes the settings that are non-nil and returns the
+// result.
+func (q *Queries) UpdateUserSettings(ctx context.Context, userID string, timezone, digest *string) (*models.UserSettings, error) {
+	var settings models.UserSettings
+	err := q.db.QueryRowContext(ctx, `
+		INSERT INTO user_settings (user_id, timezone, digest, updated_at)
+		VALUES ($1, COALESCE($2, 'UTC'), COALESCE($3, 'off'), NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET timezone = COALESCE($2, user_settings.timezone), digest = COALESCE($3, user_settings.digest), updated_at = NOW()
+		RETURNING timezone, digest, updated_at
+	`, userID, timezone, digest).Scan(&settings.Timezone, &settings.Digest, &settings.UpdatedAt)
+	return &settings, err
+}
+
+// DigestSubscriber is a user who opted in to the email digest.
+type DigestSubscriber struct {
+	User         models.User
+	Settings     models.UserSettings
+	LastDigestOn *time.Time
+}
+
+// ListDigestSubscribers returns every user with a daily or weekly digest.
+func (q *Queries) ListDigestSubscribers(ctx context.Context) ([]DigestSubscriber, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT u.id, u.email, u.name, u.created_at, u.updated_at, s.timezone, s.digest, s.updated_at, s.last_digest_on
+		FROM user_settings s JOIN users u ON u.id = s.user_id
+		WHERE s.digest <> 'off'
+		ORDER BY u.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	var subscribers []DigestSubscriber
+	for rows.Next() {
+		var s DigestSubscriber
+		err := rows.Scan(
+			&s.User.ID, &s.User.Email, &s.User.Name, &s.User.CreatedAt, &s.User.UpdatedAt,
+			&s.Settings.Timezone, &s.Settings.Digest, &s.Settings.UpdatedAt, &s.LastDigestOn,
+		)
+		if err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, s)
+	}
+	return subscribers, rows.Err()
+}
+
+// ClaimDigest records that the user's digest for the local date is being
+// sent. It returns false if a digest for that date or a later one was
+// already claimed, so concurrent runs send it once. A claim whose digest
+// could not be sent is given back with ReleaseDigest.
+func (q *Queries) ClaimDigest(ctx context.Context, userID string, date time.Time) (bool, error) {
+	result, err := q.db.ExecContext(ctx, `
+		UPDATE user_settings SET last_digest_on = $2
+		WHERE user_id = $1 AND (last_digest_on IS NULL OR last_digest_on < $2)
+	`, userID, date)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+// ReleaseDigest undoes ClaimDigest for the date, putting back previous, the
+// date of the digest sent before it, so that the next run tries again.
+func (q *Queries) ReleaseDigest(ctx context.Context, userID string, date time.Time, previous *time.Time) error {
+	_, err := q.db.ExecContext(ctx, `
+		UPDATE user_settings SET last_digest_on = $3
+		WHERE user_id = $1 AND last_digest_on = $2
+	`, userID, date, previous)
+	return err
+}
@@ -0,0 +1,221 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+// syncItem is one row of a sync page, placed by its sequence number.
+type syncItem struct {
+	seq int64
+	add func(changes *models.SyncChanges)
+}
+
+// seqScanner reads a leading sync_seq column before handing the rest of the
+// row to a scan function.
+type seqScanner struct {
+	row rowScanner
+	seq *int64
+}
+
+func (s seqScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append([]interface{}{s.seq}, dest...)...)
+}
+
+// ListChangesSince returns the projects, tasks and log entries the user can
+// see that were written after the cursor since, and the deletions, at most
+// limit items in all. So that no later page can turn up a number below the
+// returned cursor, it notes the last number handed out, waits for the
+// writes in flight in the user's scopes to finish, and leaves out anything
+// numbered later; see migration 024. Writes elsewhere do not hold it up.
+func (q *Queries) ListChangesSince(ctx context.Context, userID string, since int64, limit int) (*models.SyncChanges, error) {
+	var until int64
+	if err := q.db.QueryRowContext(ctx, `
+		SELECT CASE WHEN is_called THEN last_value ELSE 0 END FROM sync_seq
+	`).Scan(&until); err != nil {
+		return nil, err
+	}
+	scopes, err := q.syncScopes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		if _, err := q.db.ExecContext(ctx, `SELECT sync_wait($1)`, scope); err != nil {
+			return nil, err
+		}
+	}
+
+	// Each query fetches one more than the page holds, so a full page is
+	// noticed even when it all comes from one table.
+	var items []syncItem
+	collect := func(query string, scan func(rows *sql.Rows) (syncItem, error)) error {
+		rows, err := q.db.QueryContext(ctx, query, since, userID, limit+1, until)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := rows.Close(); err != nil {
+				_ = err
+			}
+		}()
+		for rows.Next() {
+			item, err := scan(rows)
+			if err != nil {
+				return err
+			}
+			items = append(items, item)
+		}
+		return rows.Err()
+	}
+
+	err = collect(`
+		SELECT sync_seq, `+projectColumns+` FROM projects
+		WHERE sync_seq > $1 AND sync_seq <= $4 AND `+projectAccess("projects", "$2")+`
+		ORDER BY sync_seq LIMIT $3
+	`, func(rows *sql.Rows) (syncItem, error) {
+		var item syncItem
+		var project models.Project
+		if err := scanProject(seqScanner{rows, &item.seq}, &project); err != nil {
+			return item, err
+		}
+		item.add = func(changes *models.SyncChanges) {
+			if project.DeletedAt != nil {
+				changes.Deleted = append(changes.Deleted, models.SyncDeletion{Type: models.SyncProject, ID: project.ID})
+				return
+			}
+			changes.Projects = append(changes.Projects, project)
+		}
+		return item, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = collect(`
+		SELECT sync_seq, `+taskColumns+` FROM tasks
+		WHERE sync_seq > $1 AND sync_seq <= $4 AND `+taskAccess("tasks.project_id", "$2")+`
+		ORDER BY sync_seq LIMIT $3
+	`, func(rows *sql.Rows) (syncItem, error) {
+		var item syncItem
+		var task models.Task
+		if err := scanTask(seqScanner{rows, &item.seq}, &task); err != nil {
+			return item, err
+		}
+		item.add = func(changes *models.SyncChanges) {
+			if task.DeletedAt != nil {
+				changes.Deleted = append(changes.Deleted, models.SyncDeletion{Type: models.SyncTask, ID: task.ID})
+				return
+			}
+			changes.Tasks = append(changes.Tasks, task)
+		}
+		return item, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = collect(`
+		SELECT sync_seq, `+logEntryColumns+` FROM log_entries
+		WHERE sync_seq > $1 AND sync_seq <= $4 AND `+logEntryAccess("log_entries", "$2")+`
+		ORDER BY sync_seq LIMIT $3
+	`, func(rows *sql.Rows) (syncItem, error) {
+		var item syncItem
+		var logEntry models.LogEntry
+		if err := scanLogEntry(seqScanner{rows, &item.seq}, &logEntry); err != nil {
+			return item, err
+		}
+		item.add = func(changes *models.SyncChanges) {
+			if logEntry.DeletedAt != nil {
+				changes.Deleted = append(changes.Deleted, models.SyncDeletion{Type: models.SyncLogEntry, ID: logEntry.ID})
+				return
+			}
+			changes.LogEntries = append(changes.LogEntries, logEntry)
+		}
+		return item, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = collect(`
+		SELECT sync_seq, entity_type, entity_id FROM sync_tombstones
+		WHERE sync_seq > $1 AND sync_seq <= $4
+			AND (user_id = $2
+				OR workspace_id IN (SELECT wm.workspace_id FROM workspace_members wm WHERE wm.user_id = $2)
+				OR project_id IN (SELECT pm.project_id FROM project_members pm WHERE pm.user_id = $2))
+		ORDER BY sync_seq LIMIT $3
+	`, func(rows *sql.Rows) (syncItem, error) {
+		var item syncItem
+		var deletion models.SyncDeletion
+		if err := rows.Scan(&item.seq, &deletion.Type, &deletion.ID); err != nil {
+			return item, err
+		}
+		item.add = func(changes *models.SyncChanges) {
+			changes.Deleted = append(changes.Deleted, deletion)
+		}
+		return item, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return syncPage(items, since, limit), nil
+}
+
+// syncScopes returns the scopes whose writes the user can see: the
+// workspaces they belong to or have a project in, and their own log entries
+// outside any project.
+func (q *Queries) syncScopes(ctx context.Context, userID string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT wm.workspace_id::text FROM workspace_members wm WHERE wm.user_id = $1
+		UNION
+		SELECT p.workspace_id::text FROM project_members pm JOIN projects p ON p.id = pm.project_id WHERE pm.user_id = $1
+		UNION
+		SELECT 'user:' || $1::text
+		ORDER BY 1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	var scopes []string
+	for rows.Next() {
+		var scope string
+		if err := rows.Scan(&scope); err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, rows.Err()
+}
+
+// syncPage orders the items by sequence number and keeps the first limit of
+// them. The cursor is the number of the last item kept, or since if there
+// are none.
+func syncPage(items []syncItem, since int64, limit int) *models.SyncChanges {
+	changes := &models.SyncChanges{
+		Cursor:     since,
+		Projects:   []models.Project{},
+		Tasks:      []models.Task{},
+		LogEntries: []models.LogEntry{},
+		Deleted:    []models.SyncDeletion{},
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].seq < items[j].seq })
+	if len(items) > limit {
+		items = items[:limit]
+		changes.HasMore = true
+	}
+	for _, item := range items {
+		item.add(changes)
+		changes.Cursor = item.seq
+	}
+	return changes
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+func TestSyncPage(t *testing.T) {
+	project := func(seq int64, id string) syncItem {
+		return syncItem{seq: seq, add: func(changes *models.SyncChanges) {
+			changes.Projects = append(changes.Projects, models.Project{ID: id})
+		}}
+	}
+	deletion := func(seq int64, id string) syncItem {
+		return syncItem{seq: seq, add: func(changes *models.SyncChanges) {
+			changes.Deleted = append(changes.Deleted, models.SyncDeletion{Type: models.SyncTask, ID: id})
+		}}
+	}
+
+	changes := syncPage([]syncItem{project(7, "b"), deletion(5, "t"), project(3, "a")}, 2, 10)
+	if changes.Cursor != 7 || changes.HasMore {
+		t.Errorf("Expected cursor 7 and no more, got %d, %v", changes.Cursor, changes.HasMore)
+	}
+	if len(changes.Projects) != 2 || changes.Projects[0].ID != "a" || changes.Projects[1].ID != "b" {
+		t.Errorf("Expected projects in sequence order, got %+v", changes.Projects)
+	}
+	if len(changes.Deleted) != 1 || changes.Deleted[0].ID != "t" {
+		t.Errorf("Expected one deletion, got %+v", changes.Deleted)
+	}
+
+	changes = syncPage([]syncItem{project(9, "c"), project(4, "a"), deletion(6, "t")}, 2, 2)
+	if changes.Cursor != 6 || !changes.HasMore {
+		t.Errorf("Expected cursor 6 with more, got %d, %v", changes.Cursor, changes.HasMore)
+	}
+	if len(changes.Projects) != 1 || len(changes.Deleted) != 1 {
+		t.Errorf("Expected the page to stop at the limit, got %+v", changes)
+	}
+
+	changes = syncPage(nil, 42, 10)
+	if changes.Cursor != 42 || changes.Tasks == nil || changes.Deleted == nil {
+		t.Errorf("Expected an empty page to keep the cursor and list nothing, got %+v", changes)
+	}
+}
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// ErrDependencyCycle is returned when a new blocked-by edge would make a task
+// (transitively) wait on itself.
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// ErrParentCycle is returned when a new parent would make a task a subtask
+// of itself or of one of its subtasks.
+var ErrParentCycle = errors.New("parent cycle")
+
+// ListSubtasks returns every descendant of the task, parents before children.
+// The walk carries its path so that it ends even if the tree has a loop.
+func (q *Queries) ListSubtasks(ctx context.Context, id, userID string) ([]models.Task, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		WITH RECURSIVE subtree AS (
+			SELECT id, 1 AS depth, ARRAY[$1::uuid, id] AS path
+			FROM tasks WHERE parent_task_id = $1 AND `+taskAccess("tasks.project_id", "$2")+` AND deleted_at IS NULL
+			UNION ALL
+			SELECT t.id, st.depth + 1, st.path || t.id
+			FROM tasks t JOIN subtree st ON t.parent_task_id = st.id
+			WHERE t.deleted_at IS NULL AND t.id <> ALL(st.path)
+		)
+		SELECT `+taskColumns+`
+		FROM tasks JOIN subtree USING (id)
+		ORDER BY subtree.depth, tasks.created_at
+	`, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectTasks(rows)
+}
+
+// IsInSubtree reports whether candidate is rootID itself or one of its
+// descendants. Making such a task the parent of rootID would create a loop.
+func (q *Queries) IsInSubtree(ctx context.Context, candidate, rootID, userID string) (bool, error) {
+	var inSubtree bool
+	err := q.db.QueryRowContext(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_task_id FROM tasks WHERE id = $1 AND `+taskAccess("tasks.project_id", "$3")+`
+			UNION
+			SELECT t.id, t.parent_task_id FROM tasks t JOIN ancestors a ON t.id = a.parent_task_id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)
+	`, candidate, rootID, userID).Scan(&inSubtree)
+	return inSubtree, err
+}
+
+// lockTaskTree takes the lock that changes of parent within the project
+// hold while they check for loops and write, so that two of them cannot
+// each pass the check and together close a loop. A subtask is always in
+// its parent's project, so loops cannot span projects. It must run in a
+// transaction, which holds the lock until it ends.
+func (q *Queries) lockTaskTree(ctx context.Context, projectID string) error {
+	_, err := q.db.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('task_tree:' || $1))`, projectID)
+	return err
+}
+
+// lockTaskDependencies is lockTaskTree for blocked-by edges. Edges may join
+// tasks of different projects and workspaces, and a cycle can run through
+// tasks that neither of two racing requests touches, so the lock covers
+// the whole graph. Only adding an edge takes it.
+func (q *Queries) lockTaskDependencies(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('task_dependencies'))`)
+	return err
+}
+
+// AddTaskDependency records that taskID is blocked by blockedByID. Both tasks
+// must already be known to be visible to the user. Adding an existing edge is a
+// no-op; an edge that closes a cycle yields ErrDependencyCycle. The check and
+// the insert run in one transaction under lockTaskDependencies.
+func (q *Queries) AddTaskDependency(ctx context.Context, taskID, blockedByID string) (*models.TaskDependency, error) {
+	tx, err := q.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			_ = err
+		}
+	}()
+	qtx := q.WithTx(tx)
+	if err := qtx.lockTaskDependencies(ctx); err != nil {
+		return nil, err
+	}
+
+	var cycle bool
+	err = qtx.db.QueryRowContext(ctx, `
+		WITH RECURSIVE upstream AS (
+			SELECT blocked_by_id FROM task_dependencies WHERE task_id = $2
+			UNION
+			SELECT d.blocked_by_id FROM task_dependencies d JOIN upstream u ON d.task_id = u.blocked_by_id
+		)
+		SELECT $1 = $2 OR EXISTS (SELECT 1 FROM upstream WHERE blocked_by_id = $1)
+	`, taskID, blockedByID).Scan(&cycle)
+	if err != nil {
+		return nil, err
+	}
+	if cycle {
+		return nil, ErrDependencyCycle
+	}
+
+	var dependency models.TaskDependency
+	err = qtx.db.QueryRowContext(ctx, `
+		INSERT INTO task_dependencies (task_id, blocked_by_id, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (task_id, blocked_by_id) DO UPDATE SET task_id = EXCLUDED.task_id
+		RETURNING task_id, blocked_by_id, created_at
+	`, taskID, blockedByID).Scan(&dependency.TaskID, &dependency.BlockedByID, &dependency.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &dependency, tx.Commit()
+}
+
+// RemoveTaskDependency deletes the edge, returning sql.ErrNoRows if the user
+// has no such dependency.
+func (q *Queries) RemoveTaskDependency(ctx context.Context, taskID, blockedByID, userID string) error {
+	result, err := q.db.ExecContext(ctx, `
+		DELETE FROM task_dependencies d
+		USING tasks t
+		WHERE d.task_id = $1 AND d.blocked_by_id = $2 AND t.id = d.task_id AND `+taskAccess("t.project_id", "$3")+`
+	`, taskID, blockedByID, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CountOpenBlockers returns how many of the task's direct blockers are not
+// finished, that is not in a closed status.
+func (q *Queries) CountOpenBlockers(ctx context.Context, taskID, userID string) (int, error) {
+	var count int
+	err := q.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM task_dependencies d
+		JOIN tasks b ON b.id = d.blocked_by_id
+		WHERE d.task_id = $1 AND `+taskAccess("b.project_id", "$2")+` AND b.completed_at IS NULL AND b.deleted_at IS NULL
+	`, taskID, userID).Scan(&count)
+	return count, err
+}
+
+// GetTaskDependencyGraph returns the edges reachable from the task in either
+// direction, along with every task they mention other than the task itself.
+// Edges to tasks in the trash are left out.
+func (q *Queries) GetTaskDependencyGraph(ctx context.Context, taskID, userID string) (*models.TaskGraph, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		WITH RECURSIVE upstream AS (
+			SELECT task_id, blocked_by_id, created_at FROM task_dependencies WHERE task_id = $1
+			UNION
+			SELECT d.task_id, d.blocked_by_id, d.created_at
+			FROM task_dependencies d JOIN upstream u ON d.task_id = u.blocked_by_id
+		), downstream AS (
+			SELECT task_id, blocked_by_id, created_at FROM task_dependencies WHERE blocked_by_id = $1
+			UNION
+			SELECT d.task_id, d.blocked_by_id, d.created_at
+			FROM task_dependencies d JOIN downstream dn ON d.blocked_by_id = dn.task_id
+		)
+		SELECT e.task_id, e.blocked_by_id, e.created_at
+		FROM (SELECT * FROM upstream UNION SELECT * FROM downstream) e
+		JOIN tasks t ON t.id = e.task_id
+		JOIN tasks b ON b.id = e.blocked_by_id
+		WHERE `+taskAccess("t.project_id", "$2")+` AND `+taskAccess("b.project_id", "$2")+`
+			AND t.deleted_at IS NULL AND b.deleted_at IS NULL
+		ORDER BY e.created_at
+	`, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	graph := &models.TaskGraph{Tasks: []models.Task{}, Edges: []models.TaskDependency{}}
+	seen := map[string]bool{taskID: true}
+	var ids []string
+	for rows.Next() {
+		var edge models.TaskDependency
+		if err := rows.Scan(&edge.TaskID, &edge.BlockedByID, &edge.CreatedAt); err != nil {
+			return nil, err
+		}
+		graph.Edges = append(graph.Edges, edge)
+		for _, id := range []string{edge.TaskID, edge.BlockedByID} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return graph, nil
+	}
+
+	taskRows, err := q.db.QueryContext(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks WHERE id = ANY($1) AND `+taskAccess("tasks.project_id", "$2")+`
+		ORDER BY created_at
+	`, pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := collectTasks(taskRows)
+	if err != nil {
+		return nil, err
+	}
+	graph.Tasks = append(graph.Tasks, tasks...)
+	return graph, nil
+}
+
+// collectTasks scans and closes rows selected with taskColumns.
+func collectTasks(rows *sql.Rows) ([]models.Task, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	var tasks []models.Task
+	for rows.Next() {
+		var task models.Task
+		if err := scanTask(rows, &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+// TimesheetFilter narrows a timesheet to a workspace or a client.
+type TimesheetFilter struct {
+	WorkspaceID *string
+	ClientID    *string
+}
+
+// ListTimesheetRows totals the time on the log entries the user can see
+// dated from through to, per project and week, with each project's client
+// and current rate. Entries without a duration or a project are left out.
+// Amounts are left for the caller to work out.
+func (q *Queries) ListTimesheetRows(ctx context.Context, userID string, from, to time.Time, filter TimesheetFilter) ([]models.TimesheetRow, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT p.client_id, c.name, p.id, p.name, to_char(date_trunc('week', le.log_date), 'YYYY-MM-DD') AS week,
+			SUM(le.duration_minutes), COALESCE(SUM(le.duration_minutes) FILTER (WHERE le.billable), 0),
+			p.hourly_rate_cents, p.currency
+		FROM log_entries le
+		JOIN projects p ON p.id = le.project_id
+		LEFT JOIN clients c ON c.id = p.client_id
+		WHERE `+logEntryAccess("le", "$1")+` AND le.deleted_at IS NULL AND p.deleted_at IS NULL
+			AND le.duration_minutes IS NOT NULL
+			AND DATE(le.log_date) BETWEEN DATE($2) AND DATE($3)
+			AND ($4::uuid IS NULL OR p.workspace_id = $4)
+			AND ($5::uuid IS NULL OR p.client_id = $5)
+		GROUP BY p.client_id, c.name, p.id, p.name, week, p.hourly_rate_cents, p.currency
+		ORDER BY c.name NULLS LAST, p.name, p.id, week
+	`, userID, from, to, filter.WorkspaceID, filter.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	timesheetRows := []models.TimesheetRow{}
+	for rows.Next() {
+		var row models.TimesheetRow
+		err := rows.Scan(
+			&row.ClientID, &row.ClientName, &row.ProjectID, &row.ProjectName, &row.Week,
+			&row.Minutes, &row.BillableMinutes, &row.HourlyRateCents, &row.Currency,
+		)
+		if err != nil {
+			return nil, err
+		}
+		timesheetRows = append(timesheetRows, row)
+	}
+	return timesheetRows, rows.Err()
+}
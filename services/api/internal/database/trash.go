@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// ErrParentDeleted is returned when restoring an item whose project or parent
+// task is still in the trash.
+var ErrParentDeleted = errors.New("parent is in the trash")
+
+// SetProjectArchived archives or unarchives the project, honoring ifMatch like
+// UpdateProject. Archiving an archived project keeps its archived_at.
+func (q *Queries) SetProjectArchived(ctx context.Context, id, userID string, archived bool, ifMatch []int64) (*models.Project, error) {
+	var project models.Project
+	err := scanProject(q.db.QueryRowContext(ctx, `
+		UPDATE projects
+		SET archived_at = CASE WHEN $1 THEN COALESCE(archived_at, NOW()) END,
+			version = version + 1, updated_at = NOW()
+		WHERE id = $2 AND `+projectAccess("projects", "$3")+` AND deleted_at IS NULL AND ($4::bigint[] IS NULL OR version = ANY($4))
+		RETURNING `+projectColumns,
+		archived, id, userID, pq.Array(ifMatch)), &project)
+	if err == sql.ErrNoRows {
+		return nil, q.versionConflict(ctx, "projects", id, userID, ifMatch)
+	}
+	return &project, err
+}
+
+// ListTrash returns the deleted items the user can see, most recently deleted
+// first.
+// Items deleted along with their project or parent task are left out.
+func (q *Queries) ListTrash(ctx context.Context, userID string) (*models.Trash, error) {
+	trash := &models.Trash{Projects: []models.Project{}, Tasks: []models.Task{}, LogEntries: []models.LogEntry{}}
+
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+projectColumns+`
+		FROM projects
+		WHERE `+projectAccess("projects", "$1")+` AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+	for rows.Next() {
+		var project models.Project
+		if err := scanProject(rows, &project); err != nil {
+			return nil, err
+		}
+		trash.Projects = append(trash.Projects, project)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	taskRows, err := q.db.QueryContext(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE `+taskAccess("tasks.project_id", "$1")+` AND deleted_at IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM projects p WHERE p.id = tasks.project_id AND p.deleted_at = tasks.deleted_at)
+			AND NOT EXISTS (SELECT 1 FROM tasks parent WHERE parent.id = tasks.parent_task_id AND parent.deleted_at = tasks.deleted_at)
+		ORDER BY deleted_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	if trash.Tasks, err = collectTasks(taskRows); err != nil {
+		return nil, err
+	}
+	if trash.Tasks == nil {
+		trash.Tasks = []models.Task{}
+	}
+
+	entryRows, err := q.db.QueryContext(ctx, `
+		SELECT `+logEntryColumns+`
+		FROM log_entries
+		WHERE `+logEntryAccess("log_entries", "$1")+` AND deleted_at IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM projects p WHERE p.id = log_entries.project_id AND p.deleted_at = log_entries.deleted_at)
+		ORDER BY deleted_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := entryRows.Close(); err != nil {
+			_ = err
+		}
+	}()
+	for entryRows.Next() {
+		var logEntry models.LogEntry
+		if err := scanLogEntry(entryRows, &logEntry); err != nil {
+			return nil, err
+		}
+		trash.LogEntries = append(trash.LogEntries, logEntry)
+	}
+	return trash, entryRows.Err()
+}
+
+// RestoreProject takes the project out of the trash along with the tasks and
+// log entries deleted with it. It returns nil if the project is not in the
+// trash.
+func (q *Queries) RestoreProject(ctx context.Context, id, userID string) (*models.Project, error) {
+	var project models.Project
+	err := scanProject(q.db.QueryRowContext(ctx, `
+		WITH deleted AS (
+			SELECT deleted_at FROM projects WHERE id = $1 AND `+projectAccess("projects", "$2")+` AND deleted_at IS NOT NULL
+		), project_tasks AS (
+			UPDATE tasks SET deleted_at = NULL
+			WHERE project_id = $1 AND deleted_at = (SELECT deleted_at FROM deleted)
+		), project_log_entries AS (
+			UPDATE log_entries SET deleted_at = NULL
+			WHERE project_id = $1 AND deleted_at = (SELECT deleted_at FROM deleted)
+		)
+		UPDATE projects
+		SET deleted_at = NULL, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND `+projectAccess("projects", "$2")+` AND deleted_at IS NOT NULL
+		RETURNING `+projectColumns,
+		id, userID), &project)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &project, err
+}
+
+// RestoreTask takes the task out of the trash along with the subtasks deleted
+// with it. It returns nil if the task is not in the trash and
+// ErrParentDeleted while its project or parent task still is.
+func (q *Queries) RestoreTask(ctx context.Context, id, userID string) (*models.Task, error) {
+	var parentDeleted sql.NullBool
+	err := q.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM projects p WHERE p.id = t.project_id AND p.deleted_at IS NOT NULL)
+			OR EXISTS (SELECT 1 FROM tasks parent WHERE parent.id = t.parent_task_id AND parent.deleted_at IS NOT NULL)
+		FROM tasks t
+		WHERE t.id = $1 AND `+taskAccess("t.project_id", "$2")+` AND t.deleted_at IS NOT NULL
+	`, id, userID).Scan(&parentDeleted)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if parentDeleted.Bool {
+		return nil, ErrParentDeleted
+	}
+
+	var task models.Task
+	err = scanTask(q.db.QueryRowContext(ctx, `
+		WITH RECURSIVE deleted AS (
+			SELECT deleted_at FROM tasks WHERE id = $1 AND `+taskAccess("tasks.project_id", "$2")+` AND deleted_at IS NOT NULL
+		), subtree AS (
+			SELECT t.id FROM tasks t
+			WHERE t.parent_task_id = $1 AND t.deleted_at = (SELECT deleted_at FROM deleted)
+			UNION
+			SELECT t.id FROM tasks t JOIN subtree st ON t.parent_task_id = st.id
+			WHERE t.deleted_at = (SELECT deleted_at FROM deleted)
+		), subtasks AS (
+			UPDATE tasks SET deleted_at = NULL WHERE id IN (SELECT id FROM subtree)
+		)
+		UPDATE tasks
+		SET deleted_at = NULL, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND `+taskAccess("tasks.project_id", "$2")+` AND deleted_at IS NOT NULL
+		RETURNING `+taskColumns,
+		id, userID), &task)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &task, err
+}
+
+// RestoreLogEntry takes the entry out of the trash. It returns nil if the
+// entry is not in the trash and ErrParentDeleted while its project still is.
+func (q *Queries) RestoreLogEntry(ctx context.Context, id, userID string) (*models.LogEntry, error) {
+	var logEntry models.LogEntry
+	err := scanLogEntry(q.db.QueryRowContext(ctx, `
+		UPDATE log_entries
+		SET deleted_at = NULL, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND `+logEntryAccess("log_entries", "$2")+` AND deleted_at IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM projects p WHERE p.id = log_entries.project_id AND p.deleted_at IS NOT NULL)
+		RETURNING `+logEntryColumns,
+		id, userID), &logEntry)
+	if err != sql.ErrNoRows {
+		return &logEntry, err
+	}
+
+	var inTrash bool
+	err = q.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM log_entries WHERE id = $1 AND `+logEntryAccess("log_entries", "$2")+` AND deleted_at IS NOT NULL)
+	`, id, userID).Scan(&inTrash)
+	if err != nil || !inTrash {
+		return nil, err
+	}
+	return nil, ErrParentDeleted
+}
+
+// TrashedItemProject returns the project of a task or log entry in the trash,
+// table being "tasks" or "log_entries". found is false if the user has no
+// such item in the trash; projectID is nil for a log entry without a project.
+func (q *Queries) TrashedItemProject(ctx context.Context, table, id, userID string) (projectID *string, found bool, err error) {
+	err = q.db.QueryRowContext(ctx, `
+		SELECT project_id FROM `+table+`
+		WHERE id = $1 AND `+tableAccess(table, "$2")+` AND deleted_at IS NOT NULL
+	`, id, userID).Scan(&projectID)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	return projectID, err == nil, err
+}
+
+// PurgeTrash permanently deletes every item, of any user, that was moved to
+// the trash before cutoff, along with its revisions. It returns how many
+// items were removed.
+func (q *Queries) PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	var purged int64
+	// Children first, so nothing purged early is still referenced by a
+	// row that is kept.
+	for _, table := range []string{"log_entries", "tasks", "projects"} {
+		result, err := q.db.ExecContext(ctx, `DELETE FROM `+table+` WHERE deleted_at < $1`, cutoff)
+		if err != nil {
+			return purged, err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+		purged += rows
+	}
+
+	// Revisions have no foreign key to their entity, so drop the ones
+	// left without one.
+	for entityType, entity := range revisionEntities {
+		_, err := q.db.ExecContext(ctx, `
+			DELETE FROM revisions r
+			WHERE r.entity_type = $1 AND NOT EXISTS (SELECT 1 FROM `+entity.table+` e WHERE e.id = r.entity_id)
+		`, entityType)
+		if err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// dbtx runs statements, on the database or in a transaction.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Tx is a transaction begun with Begin. Begun from queries that already run
+// in a transaction, it is a savepoint, so it still commits or rolls back as
+// a unit without ending the transaction around it.
+type Tx struct {
+	dbtx
+	commit, rollback func() error
+	done             bool
+}
+
+// Begin starts a transaction, or a savepoint in queries from WithTx. The
+// transaction is rolled back if ctx is done before it commits.
+func (q *Queries) Begin(ctx context.Context) (*Tx, error) {
+	if q.conn == nil {
+		if _, err := q.db.ExecContext(ctx, `SAVEPOINT nested`); err != nil {
+			return nil, err
+		}
+		return &Tx{
+			dbtx: q.db,
+			commit: func() error {
+				_, err := q.db.ExecContext(ctx, `RELEASE SAVEPOINT nested`)
+				return err
+			},
+			rollback: func() error {
+				if _, err := q.db.ExecContext(ctx, `ROLLBACK TO SAVEPOINT nested`); err != nil {
+					return err
+				}
+				_, err := q.db.ExecContext(ctx, `RELEASE SAVEPOINT nested`)
+				return err
+			},
+		}, nil
+	}
+
+	tx, err := q.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{dbtx: tx, commit: tx.Commit, rollback: tx.Rollback}, nil
+}
+
+// WithTx returns queries that run in tx, so that several of them commit or
+// roll back together.
+func (q *Queries) WithTx(tx *Tx) *Queries {
+	return &Queries{db: tx.dbtx, revisionLimit: q.revisionLimit}
+}
+
+// Commit commits the transaction. Like Rollback, it returns sql.ErrTxDone
+// once either has been called.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+	return tx.commit()
+}
+
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+	return tx.rollback()
+}
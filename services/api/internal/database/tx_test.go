@@ -0,0 +1,28 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestTxEndsOnce(t *testing.T) {
+	var commits, rollbacks int
+	tx := &Tx{
+		commit:   func() error { commits++; return nil },
+		rollback: func() error { rollbacks++; return nil },
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Expected commit to succeed, got %v", err)
+	}
+	// A deferred rollback after commit must not undo anything.
+	if err := tx.Rollback(); err != sql.ErrTxDone {
+		t.Errorf("Expected sql.ErrTxDone rolling back a committed tx, got %v", err)
+	}
+	if err := tx.Commit(); err != sql.ErrTxDone {
+		t.Errorf("Expected sql.ErrTxDone committing twice, got %v", err)
+	}
+	if commits != 1 || rollbacks != 0 {
+		t.Errorf("Expected one commit and no rollback, got %d and %d", commits, rollbacks)
+	}
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/lib/pq"
+)
+
+// DefaultWorkflow is the workflow every new project starts with.
+var DefaultWorkflow = []models.TaskStatus{
+	{Key: "todo", Name: "To Do", Category: models.StatusCategoryOpen},
+	{Key: "in_progress", Name: "In Progress", Category: models.StatusCategoryActive},
+	{Key: "done", Name: "Done", Category: models.StatusCategoryClosed},
+}
+
+// StatusInUseError is returned by ReplaceWorkflow when it would drop a status
+// that tasks are still in.
+type StatusInUseError struct {
+	Status string
+	Tasks  int
+}
+
+func (e *StatusInUseError) Error() string {
+	return fmt.Sprintf("status %s is used by %d tasks", e.Status, e.Tasks)
+}
+
+const taskStatusColumns = `id, project_id, key, name, category, position, allowed_transitions, created_at, updated_at`
+
+func scanTaskStatus(row rowScanner, status *models.TaskStatus) error {
+	return row.Scan(
+		&status.ID, &status.ProjectID, &status.Key, &status.Name, &status.Category, &status.Position, pq.Array(&status.AllowedTransitions), &status.CreatedAt, &status.UpdatedAt,
+	)
+}
+
+// closedStatusSQL is true when the status in the second expression is a
+// closed status of the project in the first.
+const closedStatusSQL = `EXISTS (
+	SELECT 1 FROM project_statuses ps
+	WHERE ps.project_id = %s AND ps.key = %s AND ps.category = 'closed'
+)`
+
+// completedAtSQL keeps completed_at in step with a task's new status: stamped
+// the first time the task reaches a closed status of its project, cleared if
+// it is reopened. It takes the project and status expressions.
+const completedAtSQL = `completed_at = CASE WHEN ` + closedStatusSQL + ` THEN COALESCE(completed_at, NOW()) END`
+
+// mapStatusSQL picks the status a task keeps when it moves to the project in
+// the first expression: the same key if that project has it, otherwise the
+// target's first status in the same category, otherwise the target's first
+// status. The task's row supplies its current project and status.
+const mapStatusSQL = `COALESCE(
+	(SELECT ps.key FROM project_statuses ps WHERE ps.project_id = %[1]s AND ps.key = tasks.status),
+	(SELECT ps.key FROM project_statuses ps
+		WHERE ps.project_id = %[1]s AND ps.category = (
+			SELECT cur.category FROM project_statuses cur WHERE cur.project_id = tasks.project_id AND cur.key = tasks.status
+		)
+		ORDER BY ps.position LIMIT 1),
+	(SELECT ps.key FROM project_statuses ps WHERE ps.project_id = %[1]s ORDER BY ps.position LIMIT 1),
+	tasks.status
+)`
+
+// GetWorkflow returns the project's statuses in order, or nil if the user has
+// no such project.
+func (q *Queries) GetWorkflow(ctx context.Context, projectID, userID string) (*models.Workflow, error) {
+	var exists bool
+	err := q.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM projects WHERE id = $1 AND `+projectAccess("projects", "$2")+` AND deleted_at IS NULL)
+	`, projectID, userID).Scan(&exists)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+taskStatusColumns+`
+		FROM project_statuses
+		WHERE project_id = $1
+		ORDER BY position
+	`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	workflow := &models.Workflow{ProjectID: projectID, Statuses: []models.TaskStatus{}}
+	for rows.Next() {
+		var status models.TaskStatus
+		if err := scanTaskStatus(rows, &status); err != nil {
+			return nil, err
+		}
+		workflow.Statuses = append(workflow.Statuses, status)
+	}
+	return workflow, rows.Err()
+}
+
+// ReplaceWorkflow sets the project's statuses to the given ones, in order,
+// keeping the IDs of statuses whose key survives. It fails with
+// *StatusInUseError rather than drop a status that tasks are in, and
+// re-stamps completed_at on tasks whose status changed category. It returns
+// nil if the user has no such project.
+func (q *Queries) ReplaceWorkflow(ctx context.Context, projectID, userID string, statuses []models.TaskStatus) (*models.Workflow, error) {
+	tx, err := q.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			_ = err
+		}
+	}()
+
+	// Lock the project so two edits of the same workflow apply in turn.
+	var id string
+	err = tx.QueryRowContext(ctx, `
+		SELECT id FROM projects WHERE id = $1 AND `+projectAccess("projects", "$2")+` AND deleted_at IS NULL FOR UPDATE
+	`, projectID, userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(statuses))
+	for i, status := range statuses {
+		keys[i] = status.Key
+	}
+
+	inUse := StatusInUseError{}
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, COUNT(*)
+		FROM tasks
+		WHERE project_id = $1 AND deleted_at IS NULL AND NOT (status = ANY($2))
+		GROUP BY status
+		ORDER BY status
+		LIMIT 1
+	`, projectID, pq.Array(keys)).Scan(&inUse.Status, &inUse.Tasks)
+	if err == nil {
+		return nil, &inUse
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM project_statuses WHERE project_id = $1 AND NOT (key = ANY($2))
+	`, projectID, pq.Array(keys)); err != nil {
+		return nil, err
+	}
+	for i, status := range statuses {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO project_statuses (project_id, key, name, category, position, allowed_transitions, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+			ON CONFLICT (project_id, key) DO UPDATE
+			SET name = EXCLUDED.name, category = EXCLUDED.category, position = EXCLUDED.position,
+				allowed_transitions = EXCLUDED.allowed_transitions, updated_at = NOW()
+		`, projectID, status.Key, status.Name, status.Category, i+1, pq.Array(status.AllowedTransitions)); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE tasks
+		SET `+fmt.Sprintf(completedAtSQL, "tasks.project_id", "tasks.status")+`,
+			version = version + 1, updated_at = NOW()
+		WHERE project_id = $1 AND (completed_at IS NOT NULL) <> `+fmt.Sprintf(closedStatusSQL, "tasks.project_id", "tasks.status")+`
+	`, projectID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return q.GetWorkflow(ctx, projectID, userID)
+}
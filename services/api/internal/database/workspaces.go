@@ -0,0 +1,390 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+var (
+	// ErrLastOwner is returned when a change would leave a workspace without
+	// an owner.
+	ErrLastOwner = errors.New("workspace needs an owner")
+	// ErrAlreadyMember is returned when adding a user who already belongs
+	// to the workspace.
+	ErrAlreadyMember = errors.New("already a member")
+	// ErrWorkspaceNotEmpty is returned when deleting a workspace that still
+	// has projects.
+	ErrWorkspaceNotEmpty = errors.New("workspace has projects")
+)
+
+// Access rules. A project is visible to the members of its workspace and to
+// its own project members, and its tasks with it. A log entry filed under a
+// project is visible the same way; one without a project stays private to its
+// author. What a member may change depends on their role, which the handlers
+// check.
+//
+// Each helper takes column and user expressions and returns a condition.
+
+// projectAccess is true when user may see the project in table, a table name
+// or alias.
+func projectAccess(table, user string) string {
+	return fmt.Sprintf(`(%[1]s.workspace_id IN (SELECT wm.workspace_id FROM workspace_members wm WHERE wm.user_id = %[2]s)
+		OR %[1]s.id IN (SELECT pm.project_id FROM project_members pm WHERE pm.user_id = %[2]s))`, table, user)
+}
+
+// taskAccess is true when user may see the project in projectCol.
+func taskAccess(projectCol, user string) string {
+	return fmt.Sprintf(`%[1]s IN (
+		SELECT ap.id FROM projects ap JOIN workspace_members wm ON wm.workspace_id = ap.workspace_id
+		WHERE wm.user_id = %[2]s
+		UNION ALL
+		SELECT pm.project_id FROM project_members pm WHERE pm.user_id = %[2]s
+	)`, projectCol, user)
+}
+
+// logEntryAccess is true when user may see the log entry in table, a table
+// name or alias.
+func logEntryAccess(table, user string) string {
+	return fmt.Sprintf(`CASE WHEN %[1]s.project_id IS NULL THEN %[1]s.user_id = %[2]s ELSE %[3]s END`,
+		table, user, taskAccess(table+".project_id", user))
+}
+
+// tableAccess gives the access condition for a row of the table.
+func tableAccess(table, user string) string {
+	switch table {
+	case "projects":
+		return projectAccess(table, user)
+	case "tasks":
+		return taskAccess("tasks.project_id", user)
+	default:
+		return logEntryAccess(table, user)
+	}
+}
+
+const workspaceColumns = `w.id, w.name, w.personal_user_id IS NOT NULL, wm.role, w.created_at, w.updated_at`
+
+func scanWorkspace(row rowScanner, workspace *models.Workspace) error {
+	return row.Scan(
+		&workspace.ID, &workspace.Name, &workspace.Personal, &workspace.Role, &workspace.CreatedAt, &workspace.UpdatedAt,
+	)
+}
+
+const workspaceMemberColumns = `wm.workspace_id, wm.user_id, u.email, u.username, u.name, wm.role, wm.created_at, wm.updated_at`
+
+func scanWorkspaceMember(row rowScanner, member *models.WorkspaceMember) error {
+	return row.Scan(
+		&member.WorkspaceID, &member.UserID, &member.Email, &member.Username, &member.Name, &member.Role, &member.CreatedAt, &member.UpdatedAt,
+	)
+}
+
+// CreateWorkspace creates a shared workspace owned by the user.
+func (q *Queries) CreateWorkspace(ctx context.Context, userID, name string) (*models.Workspace, error) {
+	var workspace models.Workspace
+	err := scanWorkspace(q.db.QueryRowContext(ctx, `
+		WITH w AS (
+			INSERT INTO workspaces (name, created_at, updated_at)
+			VALUES ($1, NOW(), NOW())
+			RETURNING *
+		), wm AS (
+			INSERT INTO workspace_members (workspace_id, user_id, role, created_at, updated_at)
+			SELECT id, $2, 'owner', NOW(), NOW() FROM w
+			RETURNING role
+		)
+		SELECT `+workspaceColumns+` FROM w, wm
+	`, name, userID), &workspace)
+	return &workspace, err
+}
+
+// ListWorkspaces returns the workspaces the user belongs to, the personal one
+// first.
+func (q *Queries) ListWorkspaces(ctx context.Context, userID string) ([]models.Workspace, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+workspaceColumns+`
+		FROM workspaces w JOIN workspace_members wm ON wm.workspace_id = w.id
+		WHERE wm.user_id = $1
+		ORDER BY w.personal_user_id IS NULL, w.name, w.created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	workspaces := []models.Workspace{}
+	for rows.Next() {
+		var workspace models.Workspace
+		if err := scanWorkspace(rows, &workspace); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, workspace)
+	}
+	return workspaces, rows.Err()
+}
+
+// GetWorkspace returns the workspace with the user's role in it, or nil if
+// the user is not a member.
+func (q *Queries) GetWorkspace(ctx context.Context, id, userID string) (*models.Workspace, error) {
+	var workspace models.Workspace
+	err := scanWorkspace(q.db.QueryRowContext(ctx, `
+		SELECT `+workspaceColumns+`
+		FROM workspaces w JOIN workspace_members wm ON wm.workspace_id = w.id
+		WHERE w.id = $1 AND wm.user_id = $2
+	`, id, userID), &workspace)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &workspace, err
+}
+
+// GetPersonalWorkspace returns the user's personal workspace.
+func (q *Queries) GetPersonalWorkspace(ctx context.Context, userID string) (*models.Workspace, error) {
+	var workspace models.Workspace
+	err := scanWorkspace(q.db.QueryRowContext(ctx, `
+		SELECT `+workspaceColumns+`
+		FROM workspaces w JOIN workspace_members wm ON wm.workspace_id = w.id
+		WHERE w.personal_user_id = $1 AND wm.user_id = $1
+	`, userID), &workspace)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &workspace, err
+}
+
+// RenameWorkspace changes the workspace's name. The caller checks the user
+// may do so; userID only picks the role returned.
+func (q *Queries) RenameWorkspace(ctx context.Context, id, userID, name string) (*models.Workspace, error) {
+	var workspace models.Workspace
+	err := scanWorkspace(q.db.QueryRowContext(ctx, `
+		WITH w AS (
+			UPDATE workspaces SET name = $1, updated_at = NOW()
+			WHERE id = $2
+			RETURNING *
+		)
+		SELECT `+workspaceColumns+`
+		FROM w JOIN workspace_members wm ON wm.workspace_id = w.id
+		WHERE wm.user_id = $3
+	`, name, id, userID), &workspace)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return &workspace, err
+}
+
+// DeleteWorkspace deletes a shared workspace, along with the projects left in
+// its trash. It returns ErrWorkspaceNotEmpty while it still has other
+// projects and sql.ErrNoRows if there is no such shared workspace.
+func (q *Queries) DeleteWorkspace(ctx context.Context, id string) error {
+	var empty bool
+	err := q.db.QueryRowContext(ctx, `
+		SELECT NOT EXISTS (SELECT 1 FROM projects WHERE workspace_id = $1 AND deleted_at IS NULL)
+	`, id).Scan(&empty)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return ErrWorkspaceNotEmpty
+	}
+
+	result, err := q.db.ExecContext(ctx, `
+		DELETE FROM workspaces
+		WHERE id = $1 AND personal_user_id IS NULL
+			AND NOT EXISTS (SELECT 1 FROM projects WHERE workspace_id = $1 AND deleted_at IS NULL)
+	`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// WorkspaceRole returns the user's role in the workspace, or "" if they are
+// not a member.
+func (q *Queries) WorkspaceRole(ctx context.Context, workspaceID, userID string) (string, error) {
+	var role string
+	err := q.db.QueryRowContext(ctx, `
+		SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// ProjectAccess is what a user may do with a project: the higher of their
+// roles in its workspace and in the project itself, and whether the project is
+// archived or in the trash.
+type ProjectAccess struct {
+	WorkspaceID string
+	Role        string
+	ArchivedAt  *time.Time
+	DeletedAt   *time.Time
+}
+
+// GetProjectAccess returns the user's access to the project, including one in
+// the trash, or nil if the user is neither a member of its workspace nor of
+// the project.
+func (q *Queries) GetProjectAccess(ctx context.Context, projectID, userID string) (*ProjectAccess, error) {
+	var access ProjectAccess
+	var workspaceRole, projectRole sql.NullString
+	err := q.db.QueryRowContext(ctx, `
+		SELECT p.workspace_id, wm.role, pm.role, p.archived_at, p.deleted_at
+		FROM projects p
+		LEFT JOIN workspace_members wm ON wm.workspace_id = p.workspace_id AND wm.user_id = $2
+		LEFT JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = $2
+		WHERE p.id = $1 AND (wm.role IS NOT NULL OR pm.role IS NOT NULL)
+	`, projectID, userID).Scan(&access.WorkspaceID, &workspaceRole, &projectRole, &access.ArchivedAt, &access.DeletedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	access.Role = workspaceRole.String
+	if models.RoleAtLeast(projectRole.String, access.Role) {
+		access.Role = projectRole.String
+	}
+	return &access, err
+}
+
+// ListWorkspaceMembers returns the workspace's members, owners first.
+func (q *Queries) ListWorkspaceMembers(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT `+workspaceMemberColumns+`
+		FROM workspace_members wm JOIN users u ON u.id = wm.user_id
+		WHERE wm.workspace_id = $1
+		ORDER BY array_position(ARRAY['owner', 'admin', 'member', 'viewer']::varchar[], wm.role), u.name, u.email
+	`, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			_ = err
+		}
+	}()
+
+	members := []models.WorkspaceMember{}
+	for rows.Next() {
+		var member models.WorkspaceMember
+		if err := scanWorkspaceMember(rows, &member); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// AddWorkspaceMember gives the user a role in the workspace. It returns
+// ErrAlreadyMember if they have one.
+func (q *Queries) AddWorkspaceMember(ctx context.Context, workspaceID, userID, role string) (*models.WorkspaceMember, error) {
+	var member models.WorkspaceMember
+	err := scanWorkspaceMember(q.db.QueryRowContext(ctx, `
+		WITH wm AS (
+			INSERT INTO workspace_members (workspace_id, user_id, role, created_at, updated_at)
+			VALUES ($1, $2, $3, NOW(), NOW())
+			ON CONFLICT (workspace_id, user_id) DO NOTHING
+			RETURNING *
+		)
+		SELECT `+workspaceMemberColumns+`
+		FROM wm JOIN users u ON u.id = wm.user_id
+	`, workspaceID, userID, role), &member)
+	if err == sql.ErrNoRows {
+		return nil, ErrAlreadyMember
+	}
+	return &member, err
+}
+
+// UpdateWorkspaceMember changes the member's role. It returns nil if the user
+// is not a member and ErrLastOwner when demoting the only owner.
+func (q *Queries) UpdateWorkspaceMember(ctx context.Context, workspaceID, userID, role string) (*models.WorkspaceMember, error) {
+	var member *models.WorkspaceMember
+	err := q.changeMembership(ctx, workspaceID, userID, role != models.RoleOwner, func(tx *Tx) error {
+		var updated models.WorkspaceMember
+		err := scanWorkspaceMember(tx.QueryRowContext(ctx, `
+			WITH wm AS (
+				UPDATE workspace_members SET role = $3, updated_at = NOW()
+				WHERE workspace_id = $1 AND user_id = $2
+				RETURNING *
+			)
+			SELECT `+workspaceMemberColumns+`
+			FROM wm JOIN users u ON u.id = wm.user_id
+		`, workspaceID, userID, role), &updated)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		member = &updated
+		return err
+	})
+	return member, err
+}
+
+// RemoveWorkspaceMember takes the user out of the workspace. It returns
+// sql.ErrNoRows if the user is not a member and ErrLastOwner when removing
+// the only owner.
+func (q *Queries) RemoveWorkspaceMember(ctx context.Context, workspaceID, userID string) error {
+	return q.changeMembership(ctx, workspaceID, userID, true, func(tx *Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2
+		`, workspaceID, userID)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// changeMembership runs change in a transaction that holds the workspace's
+// lock, so concurrent changes cannot remove every owner between them. With
+// dropsOwner set, change must not take away the user's ownership if they are
+// the only owner.
+func (q *Queries) changeMembership(ctx context.Context, workspaceID, userID string, dropsOwner bool, change func(tx *Tx) error) error {
+	tx, err := q.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			_ = err
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM workspaces WHERE id = $1 FOR UPDATE`, workspaceID); err != nil {
+		return err
+	}
+	if dropsOwner {
+		var lastOwner bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(bool_and(user_id = $2), false)
+			FROM workspace_members WHERE workspace_id = $1 AND role = 'owner'
+		`, workspaceID, userID).Scan(&lastOwner)
+		if err != nil {
+			return err
+		}
+		if lastOwner {
+			return ErrLastOwner
+		}
+	}
+
+	if err := change(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
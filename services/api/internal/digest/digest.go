@@ -0,0 +1,203 @@
+// Package digest builds and renders the email summary of a user's work over
+// a day or a week.
+package digest
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	htmltemplate "html/template"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/mail"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+// WeeklyDay is the day weekly digests go out, summing up the week before.
+const WeeklyDay = time.Friday
+
+// maxOpenTasks caps how many open tasks a digest lists.
+const maxOpenTasks = 20
+
+//go:embed templates
+var templateFiles embed.FS
+
+var funcs = map[string]interface{}{
+	"date": func(t time.Time) string { return t.Format("Mon, Jan 2") },
+}
+
+var (
+	textTemplate = template.Must(template.New("digest.txt").Funcs(funcs).ParseFS(templateFiles, "templates/digest.txt"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("digest.html").Funcs(funcs).ParseFS(templateFiles, "templates/digest.html"))
+)
+
+// Digest is a user's work over the dates From through To.
+type Digest struct {
+	Name      string
+	Period    string // daily or weekly
+	From      time.Time
+	To        time.Time
+	Projects  []Section
+	Completed []Task
+	Open      []Task
+	// MoreOpen counts the open tasks left out of Open.
+	MoreOpen int
+}
+
+// Section holds the log entries of one project. Project is empty for
+// entries outside any project.
+type Section struct {
+	Project string
+	Entries []Entry
+}
+
+type Entry struct {
+	Date    time.Time
+	Content string
+}
+
+type Task struct {
+	Title   string
+	Project string
+	DueDate *time.Time
+}
+
+// Empty reports whether nothing was logged or completed in the period.
+func (d *Digest) Empty() bool {
+	return len(d.Projects) == 0 && len(d.Completed) == 0
+}
+
+// Range returns the dates a digest sent on the date today covers: that day
+// for a daily digest and the seven days ending with it for a weekly one.
+func Range(period string, today time.Time) (from, to time.Time) {
+	if period == models.DigestWeekly {
+		return today.AddDate(0, 0, -6), today
+	}
+	return today, today
+}
+
+// Due reports whether a digest is due at the user's local time now: daily
+// digests from the hour on every day, weekly ones from the hour on WeeklyDay.
+func Due(period string, now time.Time, hour int) bool {
+	if now.Hour() < hour {
+		return false
+	}
+	switch period {
+	case models.DigestDaily:
+		return true
+	case models.DigestWeekly:
+		return now.Weekday() == WeeklyDay
+	}
+	return false
+}
+
+// Build gathers the user's digest for the period ending today, where now
+// is in the user's timezone. Log entries are those the user wrote; tasks
+// are those the user created.
+func Build(ctx context.Context, queries *database.Queries, user *models.User, period string, now time.Time) (*Digest, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from, to := Range(period, today)
+
+	logEntries, err := queries.ListLogEntriesBetween(ctx, user.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tasks count as completed in the period by the user's clock.
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, now.Location())
+	end := time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, now.Location())
+	completed, open := true, false
+	completedTasks, err := queries.ListTasks(ctx, user.ID, database.TaskFilter{
+		CreatedBy: &user.ID, Completed: &completed, CompletedFrom: &start, CompletedBefore: &end,
+	})
+	if err != nil {
+		return nil, err
+	}
+	openTasks, err := queries.ListTasks(ctx, user.ID, database.TaskFilter{CreatedBy: &user.ID, Completed: &open})
+	if err != nil {
+		return nil, err
+	}
+
+	projectNames, err := queries.ProjectNames(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	d := assemble(logEntries, completedTasks, openTasks, projectNames)
+	d.Name = user.Name
+	d.Period = period
+	d.From = from
+	d.To = to
+	return d, nil
+}
+
+// assemble groups log entries by project name, with entries outside any
+// project last, and lists open tasks by due date, undated ones last.
+func assemble(logEntries []models.LogEntry, completedTasks, openTasks []models.Task, projectNames map[string]string) *Digest {
+	d := &Digest{}
+
+	sections := map[string]*Section{}
+	var names []string
+	for _, logEntry := range logEntries {
+		name := ""
+		if logEntry.ProjectID != nil {
+			name = projectNames[*logEntry.ProjectID]
+		}
+		section, ok := sections[name]
+		if !ok {
+			section = &Section{Project: name}
+			sections[name] = section
+			names = append(names, name)
+		}
+		section.Entries = append(section.Entries, Entry{Date: logEntry.LogDate, Content: logEntry.Content})
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i] == "" || names[j] == "" {
+			return names[j] == ""
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		d.Projects = append(d.Projects, *sections[name])
+	}
+
+	for _, task := range completedTasks {
+		d.Completed = append(d.Completed, Task{Title: task.Title, Project: projectNames[task.ProjectID]})
+	}
+
+	sort.SliceStable(openTasks, func(i, j int) bool {
+		a, b := openTasks[i].DueDate, openTasks[j].DueDate
+		return a != nil && (b == nil || a.Before(*b))
+	})
+	for i, task := range openTasks {
+		if i == maxOpenTasks {
+			d.MoreOpen = len(openTasks) - maxOpenTasks
+			break
+		}
+		d.Open = append(d.Open, Task{Title: task.Title, Project: projectNames[task.ProjectID], DueDate: task.DueDate})
+	}
+	return d
+}
+
+// Subject returns the email subject of the digest.
+func (d *Digest) Subject() string {
+	if d.Period == models.DigestWeekly {
+		return "Your week on Makerlog: " + d.From.Format("Jan 2") + " to " + d.To.Format("Jan 2")
+	}
+	return "Your day on Makerlog: " + d.To.Format("Mon, Jan 2")
+}
+
+// Render renders the digest as an email to the address.
+func Render(d *Digest, to string) (mail.Message, error) {
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, d); err != nil {
+		return mail.Message{}, err
+	}
+	if err := htmlTemplate.Execute(&html, d); err != nil {
+		return mail.Message{}, err
+	}
+	return mail.Message{To: to, Subject: d.Subject(), Text: text.String(), HTML: html.String()}, nil
+}
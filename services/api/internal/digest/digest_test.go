@@ -0,0 +1,155 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestRange(t *testing.T) {
+	from, to := Range(models.DigestDaily, day(15))
+	if !from.Equal(day(15)) || !to.Equal(day(15)) {
+		t.Errorf("daily Range = %v to %v, want the day itself", from, to)
+	}
+	from, to = Range(models.DigestWeekly, day(15))
+	if !from.Equal(day(9)) || !to.Equal(day(15)) {
+		t.Errorf("weekly Range = %v to %v, want Mar 9 to Mar 15", from, to)
+	}
+}
+
+func TestDue(t *testing.T) {
+	friday := time.Date(2024, time.March, 15, 17, 30, 0, 0, time.UTC)
+	tests := []struct {
+		period string
+		now    time.Time
+		want   bool
+	}{
+		{models.DigestDaily, friday, true},
+		{models.DigestDaily, friday.Add(-time.Hour), false},
+		{models.DigestDaily, friday.AddDate(0, 0, 1), true},
+		{models.DigestWeekly, friday, true},
+		{models.DigestWeekly, friday.AddDate(0, 0, 1), false},
+		{models.DigestOff, friday, false},
+	}
+
+	for _, tt := range tests {
+		if got := Due(tt.period, tt.now, 17); got != tt.want {
+			t.Errorf("Due(%q, %v) = %v, want %v", tt.period, tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	due := func(d int) *time.Time { t := day(d); return &t }
+	names := map[string]string{"p1": "Website", "p2": "API"}
+
+	d := assemble(
+		[]models.LogEntry{
+			{ProjectID: ptr("p1"), Content: "Fixed the header", LogDate: day(14)},
+			{Content: "Read a paper", LogDate: day(14)},
+			{ProjectID: ptr("p2"), Content: "Added pagination", LogDate: day(15)},
+			{ProjectID: ptr("p1"), Content: "Shipped the footer", LogDate: day(15)},
+		},
+		[]models.Task{{Title: "Pagination", ProjectID: "p2"}},
+		[]models.Task{
+			{Title: "Someday", ProjectID: "p1"},
+			{Title: "Later", ProjectID: "p1", DueDate: due(20)},
+			{Title: "Soon", ProjectID: "p2", DueDate: due(16)},
+		},
+		names,
+	)
+
+	var sections []string
+	for _, section := range d.Projects {
+		sections = append(sections, section.Project)
+	}
+	if got := strings.Join(sections, ","); got != "API,Website," {
+		t.Errorf("Expected sections API, Website and no project, got %q", got)
+	}
+	if len(d.Projects[1].Entries) != 2 {
+		t.Errorf("Expected 2 Website entries, got %d", len(d.Projects[1].Entries))
+	}
+	if len(d.Completed) != 1 || d.Completed[0].Project != "API" {
+		t.Errorf("Unexpected completed tasks %+v", d.Completed)
+	}
+
+	var open []string
+	for _, task := range d.Open {
+		open = append(open, task.Title)
+	}
+	if got := strings.Join(open, ","); got != "Soon,Later,Someday" {
+		t.Errorf("Expected open tasks by due date, got %q", got)
+	}
+}
+
+func TestAssembleCapsOpenTasks(t *testing.T) {
+	open := make([]models.Task, maxOpenTasks+3)
+	d := assemble(nil, nil, open, nil)
+	if len(d.Open) != maxOpenTasks || d.MoreOpen != 3 {
+		t.Errorf("Expected %d open tasks and 3 more, got %d and %d", maxOpenTasks, len(d.Open), d.MoreOpen)
+	}
+}
+
+func TestRender(t *testing.T) {
+	d := &Digest{
+		Name:   "Ada",
+		Period: models.DigestWeekly,
+		From:   day(9),
+		To:     day(15),
+		Projects: []Section{
+			{Project: "Website", Entries: []Entry{{Date: day(14), Content: "Fixed <script> escaping"}}},
+		},
+		Completed: []Task{{Title: "Footer", Project: "Website"}},
+		Open:      []Task{{Title: "Docs"}},
+	}
+
+	msg, err := Render(d, "ada@example.com")
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if msg.To != "ada@example.com" || msg.Subject != "Your week on Makerlog: Mar 9 to Mar 15" {
+		t.Errorf("Unexpected message header %q, %q", msg.To, msg.Subject)
+	}
+
+	wantText := `Hi Ada,
+
+Here is what you got done from Sat, Mar 9 to Fri, Mar 15.
+
+Website
+- Thu, Mar 14: Fixed <script> escaping
+
+Completed tasks
+- Footer (Website)
+
+Still open
+- Docs
+
+You get this email because you turned on the weekly digest in your Makerlog settings.
+`
+	if msg.Text != wantText {
+		t.Errorf("Text = %q, want %q", msg.Text, wantText)
+	}
+	if !strings.Contains(msg.HTML, "Fixed &lt;script&gt; escaping") {
+		t.Errorf("Expected HTML to escape content, got %s", msg.HTML)
+	}
+}
+
+func TestRenderEmpty(t *testing.T) {
+	msg, err := Render(&Digest{Name: "Ada", Period: models.DigestDaily, From: day(15), To: day(15)}, "ada@example.com")
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.Contains(msg.Text, "Nothing was logged or completed today.") {
+		t.Errorf("Expected an empty digest note, got %q", msg.Text)
+	}
+	if msg.Subject != "Your day on Makerlog: Fri, Mar 15" {
+		t.Errorf("Unexpected subject %q", msg.Subject)
+	}
+}
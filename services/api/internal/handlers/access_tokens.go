@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// maxAccessTokenDays is the longest an expiring access token may last.
+const maxAccessTokenDays = 365
+
+// AccessTokenHandler manages the user's personal access tokens.
+type AccessTokenHandler struct {
+	queries *database.Queries
+}
+
+func NewAccessTokenHandler(queries *database.Queries) *AccessTokenHandler {
+	return &AccessTokenHandler{queries: queries}
+}
+
+// List returns the user's access tokens, without the tokens themselves.
+func (h *AccessTokenHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.queries.ListAccessTokens(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to list access tokens", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, tokens)
+}
+
+// Create gives the user a new access token. The response holds the token,
+// which cannot be retrieved again.
+func (h *AccessTokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateAccessTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Access token name is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Name) > 100 {
+		http.Error(w, "Access token name must be at most 100 characters", http.StatusBadRequest)
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = models.ScopeWrite
+	}
+	if req.Scope != models.ScopeRead && req.Scope != models.ScopeWrite {
+		http.Error(w, "Invalid scope, must be read or write", http.StatusBadRequest)
+		return
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		if *req.ExpiresInDays < 1 || *req.ExpiresInDays > maxAccessTokenDays {
+			http.Error(w, "expires_in_days must be between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		at := time.Now().AddDate(0, 0, *req.ExpiresInDays)
+		expiresAt = &at
+	}
+
+	token, hash, err := newSecretToken()
+	if err != nil {
+		http.Error(w, "Failed to create access token", http.StatusInternalServerError)
+		return
+	}
+	accessToken, err := h.queries.CreateAccessToken(r.Context(), userID, req.Name, req.Scope, hash, expiresAt)
+	if err != nil {
+		http.Error(w, "Failed to create access token", http.StatusInternalServerError)
+		return
+	}
+	accessToken.Token = token
+
+	setNoStore(w)
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, accessToken)
+}
+
+// Delete revokes an access token, which stops working at once.
+func (h *AccessTokenHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid access token ID format", http.StatusBadRequest)
+		return
+	}
+
+	err := h.queries.DeleteAccessToken(r.Context(), id, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Access token not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete access token", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
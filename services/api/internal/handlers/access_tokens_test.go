@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCreateAccessTokenValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "invalid body", body: `not json`, want: "Invalid request body"},
+		{name: "missing name", body: `{"name": "  "}`, want: "Access token name is required"},
+		{name: "long name", body: `{"name": "` + strings.Repeat("a", 101) + `"}`, want: "at most 100 characters"},
+		{name: "unknown scope", body: `{"name": "CI", "scope": "admin"}`, want: "Invalid scope"},
+		{name: "no expiry days", body: `{"name": "CI", "expires_in_days": 0}`, want: "expires_in_days must be between 1 and 365"},
+		{name: "too many expiry days", body: `{"name": "CI", "expires_in_days": 366}`, want: "expires_in_days must be between 1 and 365"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/access-tokens", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			signedIn(t, NewAccessTokenHandler(nil).Create).ServeHTTP(rec, req)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("Expected 400 %q, got %d %q", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
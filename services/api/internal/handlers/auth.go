@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"regexp"
+	"strings"
 
 	"github.com/chrispotter/makerlog/services/api/internal/database"
 	"github.com/chrispotter/makerlog/services/api/internal/middleware"
@@ -37,7 +41,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if user already exists
-	existingUser, err := h.queries.GetUserByEmail(req.Email)
+	existingUser, err := h.queries.GetUserByEmail(r.Context(), req.Email)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -47,6 +51,36 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check the invitation before creating the account, so a bad link does
+	// not leave the user registered without the access they expected
+	var inviteHash string
+	if req.InviteToken != "" {
+		inviteHash = hashSecretToken(req.InviteToken)
+		invitation, err := h.queries.GetPendingInvitation(r.Context(), inviteHash)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if invitation == nil {
+			http.Error(w, "Invitation is invalid, used or expired", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Pick the username, made from the email address unless one is given
+	username := req.Username
+	if username != "" && !validUsername(username) {
+		http.Error(w, invalidUsername, http.StatusBadRequest)
+		return
+	}
+	if username == "" {
+		username, err = h.queries.FreeUsername(r.Context(), usernameFromEmail(req.Email))
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -55,12 +89,25 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create user
-	user, err := h.queries.CreateUser(req.Email, string(hashedPassword), req.Name)
+	user, err := h.queries.CreateUser(r.Context(), req.Email, username, string(hashedPassword), req.Name)
+	if errors.Is(err, database.ErrUsernameTaken) {
+		http.Error(w, "Username already taken", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to create user", http.StatusInternalServerError)
 		return
 	}
 
+	// Accept the invitation. It can only have been used up since the check
+	// above by a concurrent request, which is not worth failing the
+	// registration over.
+	if inviteHash != "" {
+		if invitation, err := h.queries.AcceptInvitation(r.Context(), inviteHash, user.ID); err != nil || invitation == nil {
+			log.Printf("Failed to accept invitation for new user %s: %v", user.ID, err)
+		}
+	}
+
 	// Create session
 	session, err := h.sessionStore.Get(r, "makerlog-session")
 	if err != nil {
@@ -90,7 +137,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get user by email
-	user, err := h.queries.GetUserByEmail(req.Email)
+	user, err := h.queries.GetUserByEmail(r.Context(), req.Email)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -145,7 +192,7 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.queries.GetUserByID(userID)
+	user, err := h.queries.GetUserByID(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -157,3 +204,73 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, user)
 }
+
+// Update changes the user's own account. Only the username can change.
+func (h *AuthHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Username == nil {
+		h.Me(w, r)
+		return
+	}
+	if !validUsername(*req.Username) {
+		http.Error(w, invalidUsername, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.queries.UpdateUsername(r.Context(), userID, *req.Username)
+	if errors.Is(err, database.ErrUsernameTaken) {
+		http.Error(w, "Username already taken", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, user)
+}
+
+const invalidUsername = "Invalid username, must be 1 to 50 letters, digits or . _ + -, starting with a letter or digit and not ending in . _ -"
+
+// usernamePattern matches the handles that mentionPattern finds whole: a
+// trailing . _ or - reads as punctuation after the mention.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9._+-]*[A-Za-z0-9+])?$`)
+
+func validUsername(username string) bool {
+	return len(username) <= 50 && usernamePattern.MatchString(username)
+}
+
+// usernameFromEmail makes a username from the part of an email address
+// before the @, dropping the characters a username cannot have.
+func usernameFromEmail(email string) string {
+	local, _, _ := strings.Cut(email, "@")
+	username := strings.Map(func(r rune) rune {
+		if r < 128 && (r == '.' || r == '_' || r == '+' || r == '-' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z') {
+			return r
+		}
+		return -1
+	}, local)
+	username = strings.TrimLeft(username, "._+-")
+	if len(username) > 40 {
+		username = username[:40]
+	}
+	username = strings.TrimRight(username, "._-")
+	if username == "" {
+		return "user"
+	}
+	return username
+}
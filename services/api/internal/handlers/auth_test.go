@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/chrispotter/makerlog/services/api/internal/models"
@@ -155,3 +156,34 @@ func TestSessionStoreCreation(t *testing.T) {
 		t.Error("Expected session store to be created")
 	}
 }
+
+func TestValidUsername(t *testing.T) {
+	for _, username := range []string{"a", "ada", "Ada.Lovelace", "jane_doe", "c++", "x-1"} {
+		if !validUsername(username) {
+			t.Errorf("Expected %q to be valid", username)
+		}
+		// Whatever is valid must be found whole in a mention.
+		if got := parseMentions("@" + username); len(got) != 1 || got[0] != strings.ToLower(username) {
+			t.Errorf("Expected @%s to mention %q, got %v", username, strings.ToLower(username), got)
+		}
+	}
+	for _, username := range []string{"", "_ada", "ada.", "ada-", "ada lovelace", "ada@example.com", strings.Repeat("a", 51)} {
+		if validUsername(username) {
+			t.Errorf("Expected %q to be invalid", username)
+		}
+	}
+}
+
+func TestUsernameFromEmail(t *testing.T) {
+	for email, want := range map[string]string{
+		"ada@example.com":          "ada",
+		"Ada.Lovelace@example.com": "Ada.Lovelace",
+		"_ada_@example.com":        "ada",
+		"josé@example.com":         "jos",
+		"!!!@example.com":          "user",
+	} {
+		if got := usernameFromEmail(email); got != want || !validUsername(got) {
+			t.Errorf("usernameFromEmail(%q) = %q, want %q", email, got, want)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/google/uuid"
+)
+
+// maxBatchOperations caps the operations in one batch, and the IDs in one
+// bulk request.
+const maxBatchOperations = 500
+
+// BatchHandler runs many writes to projects, tasks and log entries in one
+// transaction. Each is made with the same checks as the REST API, run on
+// the transaction, so it is validated and authorized alike.
+type BatchHandler struct {
+	queries *database.Queries
+}
+
+func NewBatchHandler(queries *database.Queries) *BatchHandler {
+	return &BatchHandler{queries: queries}
+}
+
+// Run runs the operations in order. An atomic batch stops at the first
+// failure and commits nothing, answering 422 with the failed operation's
+// index; a best-effort one undoes only the failed operations. ?force=true
+// applies to every operation.
+func (h *BatchHandler) Run(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = models.BatchAtomic
+	}
+	if req.Mode != models.BatchAtomic && req.Mode != models.BatchBestEffort {
+		http.Error(w, "Invalid mode, must be atomic or best_effort", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) > maxBatchOperations {
+		http.Error(w, "Too many operations, at most "+strconv.Itoa(maxBatchOperations)+" per batch", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.queries.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to start batch", http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			_ = err
+		}
+	}()
+	queries := h.queries.WithTx(tx)
+	stores := itemStores(queries)
+
+	response := models.BatchResponse{Results: []models.BatchResult{}}
+	for i, op := range req.Operations {
+		if req.Mode == models.BatchAtomic {
+			result := runBatchOperation(r.Context(), userID, stores, op, forced(r))
+			response.Results = append(response.Results, result)
+			if result.Error != "" {
+				response.FailedIndex = &i
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				writeJSON(w, response)
+				return
+			}
+			continue
+		}
+
+		step, err := queries.Begin(r.Context())
+		if err != nil {
+			http.Error(w, "Failed to run batch", http.StatusInternalServerError)
+			return
+		}
+		result := runBatchOperation(r.Context(), userID, stores, op, forced(r))
+		if result.Error == "" {
+			err = step.Commit()
+		} else {
+			err = step.Rollback()
+		}
+		if err != nil {
+			http.Error(w, "Failed to run batch", http.StatusInternalServerError)
+			return
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit batch", http.StatusInternalServerError)
+		return
+	}
+	response.Committed = true
+
+	writeJSON(w, response)
+}
+
+// runBatchOperation runs op on the store for its type.
+func runBatchOperation(ctx context.Context, userID string, stores map[string]itemStore, op models.BatchOperation, force bool) models.BatchResult {
+	fail := func(message string) models.BatchResult {
+		return models.BatchResult{Status: http.StatusBadRequest, Error: message}
+	}
+
+	store, ok := stores[op.Type]
+	if !ok {
+		return fail("Invalid type, must be project, task or log_entry")
+	}
+	var data map[string]json.RawMessage
+	if op.Op == "create" || op.Op == "update" {
+		if err := json.Unmarshal(op.Data, &data); err != nil || data == nil {
+			return fail("Invalid data, must be a JSON object")
+		}
+	}
+	if op.Op != "create" && op.ID == "" {
+		return fail("id is required")
+	}
+	if op.ID != "" {
+		if _, err := uuid.Parse(op.ID); err != nil {
+			return fail("Invalid id format")
+		}
+	}
+	var versions []int64
+	if op.Version != nil {
+		versions = []int64{*op.Version}
+	}
+
+	var written *item
+	var err error
+	status := http.StatusOK
+	switch op.Op {
+	case "create":
+		if op.ID != "" {
+			id, marshalErr := json.Marshal(op.ID)
+			if marshalErr != nil {
+				return fail("Invalid id format")
+			}
+			data["id"] = id
+		}
+		body, marshalErr := json.Marshal(data)
+		if marshalErr != nil {
+			return fail("Invalid data, must be a JSON object")
+		}
+		written, err = store.createItem(ctx, userID, body)
+		status = http.StatusCreated
+	case "update":
+		body, marshalErr := json.Marshal(data)
+		if marshalErr != nil {
+			return fail("Invalid data, must be a JSON object")
+		}
+		written, err = store.patchItem(ctx, userID, op.ID, body, versions, force)
+	case "delete":
+		err = store.deleteItem(ctx, userID, op.ID, versions)
+		status = http.StatusNoContent
+	default:
+		return fail("Invalid op, must be create, update or delete")
+	}
+
+	if err != nil {
+		result := models.BatchResult{}
+		result.Status, result.Error = errorStatus(err)
+		return result
+	}
+	result := models.BatchResult{Status: status}
+	if written != nil {
+		result.Record = recordJSON(written)
+	}
+	return result
+}
+
+// CompleteTasks moves the tasks to a closed status of their project's
+// workflow, the first one unless a task is in one already. Like a PATCH,
+// it refuses tasks with open blockers unless ?force=true.
+func (h *BatchHandler) CompleteTasks(w http.ResponseWriter, r *http.Request) {
+	var req models.BulkTaskRequest
+	if !decodeBulk(w, r, &req, &req.IDs) {
+		return
+	}
+
+	h.bulk(w, r, req.IDs, func(queries *database.Queries, userID, id string) (interface{}, error) {
+		tasks := NewTaskHandler(queries)
+		task, err := tasks.get(r.Context(), userID, id)
+		if err != nil {
+			return nil, err
+		}
+		workflow, err := queries.GetWorkflow(r.Context(), task.ProjectID, userID)
+		if err != nil {
+			return nil, internalError("Failed to get workflow")
+		}
+		if workflow == nil {
+			return nil, notFound("Project not found")
+		}
+
+		status := task.Status
+		if !workflow.IsClosed(status) {
+			status = ""
+			for _, candidate := range workflow.Statuses {
+				if candidate.Category == models.StatusCategoryClosed {
+					status = candidate.Key
+					break
+				}
+			}
+		}
+		if status == "" {
+			return nil, conflict("Project workflow has no closed status")
+		}
+		return tasks.patch(r.Context(), userID, id, database.TaskPatch{Status: &status}, nil, forced(r))
+	})
+}
+
+// MoveLogEntries moves the log entries to a project, or out of any.
+func (h *BatchHandler) MoveLogEntries(w http.ResponseWriter, r *http.Request) {
+	var req models.MoveLogEntriesRequest
+	if !decodeBulk(w, r, &req, &req.IDs) {
+		return
+	}
+	if req.ProjectID != nil {
+		if _, err := uuid.Parse(*req.ProjectID); err != nil {
+			http.Error(w, "Invalid project_id format", http.StatusBadRequest)
+			return
+		}
+	}
+	patch := database.LogEntryPatch{SetProjectID: true, ProjectID: req.ProjectID}
+
+	h.bulk(w, r, req.IDs, func(queries *database.Queries, userID, id string) (interface{}, error) {
+		return NewLogEntryHandler(queries).patch(r.Context(), userID, id, patch, nil)
+	})
+}
+
+// decodeBulk decodes a bulk request into req and checks the IDs it filled
+// in. It writes the error response and returns false when they are not
+// acceptable.
+func decodeBulk(w http.ResponseWriter, r *http.Request, req interface{}, ids *[]string) bool {
+	if _, ok := middleware.GetUserID(r.Context()); !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return false
+	}
+	if len(*ids) == 0 || len(*ids) > maxBatchOperations {
+		http.Error(w, "ids must list 1 to "+strconv.Itoa(maxBatchOperations)+" items", http.StatusBadRequest)
+		return false
+	}
+	for _, id := range *ids {
+		if _, err := uuid.Parse(id); err != nil {
+			http.Error(w, "Invalid id format: "+id, http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+// bulk applies apply to each ID in one transaction and responds with the
+// items written. If one fails, nothing is written and the response is its
+// error, naming the ID.
+func (h *BatchHandler) bulk(w http.ResponseWriter, r *http.Request, ids []string, apply func(queries *database.Queries, userID, id string) (interface{}, error)) {
+	userID, _ := middleware.GetUserID(r.Context())
+	tx, err := h.queries.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			_ = err
+		}
+	}()
+	queries := h.queries.WithTx(tx)
+
+	records := []interface{}{}
+	for _, id := range ids {
+		record, err := apply(queries, userID, id)
+		if err != nil {
+			status, message := errorStatus(err)
+			http.Error(w, id+": "+message, status)
+			return
+		}
+		records = append(records, record)
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit changes", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, records)
+}
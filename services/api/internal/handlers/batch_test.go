@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/gorilla/sessions"
+)
+
+// signedIn returns handler behind the Auth middleware, serving requests as
+// if user-1 were signed in.
+func signedIn(t *testing.T, handler http.HandlerFunc) http.Handler {
+	store := sessions.NewCookieStore([]byte("test-secret-key-32-bytes-long!!!"))
+	authenticated := middleware.Auth(store, nil)(handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := store.New(r, "makerlog-session")
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		session.Values["user_id"] = "user-1"
+		saved := httptest.NewRecorder()
+		if err := session.Save(r, saved); err != nil {
+			t.Fatalf("Failed to save session: %v", err)
+		}
+		for _, cookie := range saved.Result().Cookies() {
+			r.AddCookie(cookie)
+		}
+		authenticated.ServeHTTP(w, r)
+	})
+}
+
+func TestRunBatchOperation(t *testing.T) {
+	const id = "550e8400-e29b-41d4-a716-446655440000"
+	version := func(v int64) *int64 { return &v }
+	fresh := func() *fakeItems {
+		return &fakeItems{items: map[string]*fakeItem{id: {ID: id, Title: "Server", Version: 3}}}
+	}
+
+	tests := []struct {
+		name   string
+		items  *fakeItems
+		op     models.BatchOperation
+		status int
+		failed bool
+		title  string // the item's title afterwards, empty if deleted
+	}{
+		{
+			name:   "create under the given ID",
+			items:  &fakeItems{items: map[string]*fakeItem{}},
+			op:     models.BatchOperation{Op: "create", ID: id, Data: json.RawMessage(`{"title":"New"}`)},
+			status: http.StatusCreated,
+			title:  "New",
+		},
+		{
+			name:   "update",
+			items:  fresh(),
+			op:     models.BatchOperation{Op: "update", ID: id, Data: json.RawMessage(`{"title":"Changed"}`)},
+			status: http.StatusOK,
+			title:  "Changed",
+		},
+		{
+			name:   "update from the current version",
+			items:  fresh(),
+			op:     models.BatchOperation{Op: "update", ID: id, Version: version(3), Data: json.RawMessage(`{"title":"Changed"}`)},
+			status: http.StatusOK,
+			title:  "Changed",
+		},
+		{
+			name:   "update from an old version",
+			items:  fresh(),
+			op:     models.BatchOperation{Op: "update", ID: id, Version: version(2), Data: json.RawMessage(`{"title":"Changed"}`)},
+			status: http.StatusPreconditionFailed,
+			failed: true,
+			title:  "Server",
+		},
+		{
+			name:   "delete",
+			items:  fresh(),
+			op:     models.BatchOperation{Op: "delete", ID: id},
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "invalid create",
+			items:  &fakeItems{items: map[string]*fakeItem{}},
+			op:     models.BatchOperation{Op: "create", Data: json.RawMessage(`{}`)},
+			status: http.StatusBadRequest,
+			failed: true,
+		},
+		{
+			name:   "missing item",
+			items:  &fakeItems{items: map[string]*fakeItem{}},
+			op:     models.BatchOperation{Op: "delete", ID: id},
+			status: http.StatusNotFound,
+			failed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stores := map[string]itemStore{models.SyncTask: tt.items}
+			tt.op.Type = models.SyncTask
+			result := runBatchOperation(context.Background(), "user-1", stores, tt.op, false)
+
+			if result.Status != tt.status || (result.Error != "") != tt.failed {
+				t.Errorf("Expected %d (failed %v), got %d %q", tt.status, tt.failed, result.Status, result.Error)
+			}
+			if !tt.failed && tt.status != http.StatusNoContent && len(result.Record) == 0 {
+				t.Error("Expected the written record")
+			}
+			title := ""
+			if item := tt.items.items[id]; item != nil {
+				title = item.Title
+			}
+			if title != tt.title {
+				t.Errorf("Expected title %q afterwards, got %q", tt.title, title)
+			}
+		})
+	}
+}
+
+func TestRunBatchOperationRejectsInvalidOperations(t *testing.T) {
+	stores := map[string]itemStore{models.SyncTask: &fakeItems{items: map[string]*fakeItem{}}}
+	for _, op := range []models.BatchOperation{
+		{Op: "create", Type: "comment", Data: json.RawMessage(`{"title":"A"}`)},
+		{Op: "merge", Type: models.SyncTask, ID: "550e8400-e29b-41d4-a716-446655440000"},
+		{Op: "update", Type: models.SyncTask, Data: json.RawMessage(`{"title":"A"}`)},
+		{Op: "update", Type: models.SyncTask, ID: "550e8400-e29b-41d4-a716-446655440000", Data: json.RawMessage(`[]`)},
+		{Op: "create", Type: models.SyncTask},
+	} {
+		result := runBatchOperation(context.Background(), "user-1", stores, op, false)
+		if result.Status != http.StatusBadRequest || result.Error == "" {
+			t.Errorf("Expected %+v to be rejected, got %+v", op, result)
+		}
+	}
+}
+
+func TestBatchValidation(t *testing.T) {
+	h := NewBatchHandler(nil)
+	tooMany := `{"operations":[` + strings.Repeat(`{"op":"delete"},`, maxBatchOperations) + `{"op":"delete"}]}`
+	tooManyIDs := `{"ids":["` + strings.Repeat(`550e8400-e29b-41d4-a716-446655440000","`, maxBatchOperations) + `550e8400-e29b-41d4-a716-446655440000"]}`
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		user    bool
+		status  int
+	}{
+		{name: "batch without a session", handler: h.Run, body: `{}`, status: http.StatusUnauthorized},
+		{name: "invalid mode", handler: h.Run, body: `{"mode":"some"}`, user: true, status: http.StatusBadRequest},
+		{name: "too many operations", handler: h.Run, body: tooMany, user: true, status: http.StatusBadRequest},
+		{name: "complete without a session", handler: h.CompleteTasks, body: `{"ids":[]}`, status: http.StatusUnauthorized},
+		{name: "complete nothing", handler: h.CompleteTasks, body: `{"ids":[]}`, user: true, status: http.StatusBadRequest},
+		{name: "complete too many", handler: h.CompleteTasks, body: tooManyIDs, user: true, status: http.StatusBadRequest},
+		{name: "move an invalid ID", handler: h.MoveLogEntries, body: `{"ids":["42"],"project_id":null}`, user: true, status: http.StatusBadRequest},
+		{name: "move an invalid body", handler: h.MoveLogEntries, body: `{"ids":`, user: true, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/batch", strings.NewReader(tt.body))
+			recorder := httptest.NewRecorder()
+			if tt.user {
+				signedIn(t, tt.handler).ServeHTTP(recorder, req)
+			} else {
+				tt.handler(recorder, req)
+			}
+			if recorder.Code != tt.status {
+				t.Errorf("Expected %d, got %d: %s", tt.status, recorder.Code, recorder.Body)
+			}
+		})
+	}
+}
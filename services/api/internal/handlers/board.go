@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/chrispotter/makerlog/services/api/internal/rank"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// errNeighborsOutOfOrder means after_id does not sort above before_id.
+var errNeighborsOutOfOrder = errors.New("neighbors out of order")
+
+// Board returns a project's tasks grouped into status columns, each in the
+// user's chosen order.
+func (h *TaskHandler) Board(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	projectID := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(projectID); err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	workflow, err := h.queries.GetWorkflow(r.Context(), projectID, userID)
+	if err != nil {
+		http.Error(w, "Failed to get project workflow", http.StatusInternalServerError)
+		return
+	}
+	if workflow == nil {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
+	tasks, err := h.queries.ListBoardTasks(r.Context(), projectID, userID)
+	if err != nil {
+		http.Error(w, "Failed to list tasks", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, newBoard(workflow, tasks))
+}
+
+// Move puts a task into a status column between two neighbors. Only the moved
+// task's row is written.
+func (h *TaskHandler) Move(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.MoveTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	for field, neighborID := range map[string]*string{"after_id": req.AfterID, "before_id": req.BeforeID} {
+		if neighborID == nil {
+			continue
+		}
+		if _, err := uuid.Parse(*neighborID); err != nil {
+			http.Error(w, "Invalid "+field+" format", http.StatusBadRequest)
+			return
+		}
+		if *neighborID == id {
+			http.Error(w, "A task cannot be its own neighbor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	task, err := h.queries.GetTask(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, "Failed to get task", http.StatusInternalServerError)
+		return
+	}
+	if task == nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+
+	if !checkWritable(r.Context(), w, h.queries, userID, models.RoleMember, &task.ProjectID) {
+		return
+	}
+
+	status := req.Status
+	if status == "" {
+		status = task.Status
+	}
+	workflow, err := h.queries.GetWorkflow(r.Context(), task.ProjectID, userID)
+	if err != nil {
+		http.Error(w, "Failed to get project workflow", http.StatusInternalServerError)
+		return
+	}
+	if err := validateStatusChange(workflow, task.Status, status); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.checkBlockers(r.Context(), userID, task, workflow.IsClosed(status), forced(r)); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// The ranks are read, spread out if need be, and taken in one
+	// transaction, so a concurrent move cannot take the same ones.
+	tx, err := h.queries.Begin(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to move task", http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			_ = err
+		}
+	}()
+	tasks := NewTaskHandler(h.queries.WithTx(tx))
+
+	position, err := tasks.movePosition(r.Context(), userID, task, status, req)
+	var invalid validationError
+	if errors.As(err, &invalid) {
+		http.Error(w, invalid.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, errNeighborsOutOfOrder) {
+		http.Error(w, "after_id must be above before_id in the column", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to move task", http.StatusInternalServerError)
+		return
+	}
+
+	moved, err := tasks.queries.MoveTask(r.Context(), id, userID, status, position, ifMatchVersions(r))
+	if isPreconditionFailed(err) {
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to move task", http.StatusInternalServerError)
+		return
+	}
+	if moved == nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to move task", http.StatusInternalServerError)
+		return
+	}
+	h.continueSeries(r.Context(), task.CompletedAt != nil, moved)
+
+	setETag(w, etag(moved.Version))
+	writeJSON(w, moved)
+}
+
+// movePosition picks the new rank for task in the status column, among the
+// tasks there userID can see.
+func (h *TaskHandler) movePosition(ctx context.Context, userID string, task *models.Task, status string, req models.MoveTaskRequest) (string, error) {
+	above, below, err := h.neighborPositions(ctx, userID, task, status, req)
+	if err != nil {
+		return "", err
+	}
+	if above != "" && above == below {
+		// Concurrent inserts can leave two tasks with the same rank. Spread
+		// the column out and read the neighbors again.
+		if err := h.queries.RebalanceTaskPositions(ctx, task.ProjectID, status, userID, task.ID); err != nil {
+			return "", err
+		}
+		if above, below, err = h.neighborPositions(ctx, userID, task, status, req); err != nil {
+			return "", err
+		}
+	}
+
+	position, err := rank.Between(above, below)
+	if errors.Is(err, rank.ErrOutOfOrder) {
+		return "", errNeighborsOutOfOrder
+	}
+	return position, err
+}
+
+// neighborPositions returns the ranks the task must land between. A missing
+// neighbor is looked up next to the given one, and with neither the task is
+// placed after the last task in the column.
+func (h *TaskHandler) neighborPositions(ctx context.Context, userID string, task *models.Task, status string, req models.MoveTaskRequest) (string, string, error) {
+	above, err := h.neighbor(ctx, userID, task, status, req.AfterID, "after_id")
+	if err != nil {
+		return "", "", err
+	}
+	below, err := h.neighbor(ctx, userID, task, status, req.BeforeID, "before_id")
+	if err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case above != nil && below != nil:
+		return above.Position, below.Position, nil
+	case above != nil:
+		next, err := h.queries.NextTaskPosition(ctx, task.ProjectID, status, userID, above.Position, task.ID)
+		return above.Position, next, err
+	case below != nil:
+		previous, err := h.queries.PreviousTaskPosition(ctx, task.ProjectID, status, userID, below.Position, task.ID)
+		return previous, below.Position, err
+	default:
+		last, err := h.queries.LastTaskPosition(ctx, task.ProjectID, status, userID, task.ID)
+		return last, "", err
+	}
+}
+
+func (h *TaskHandler) neighbor(ctx context.Context, userID string, task *models.Task, status string, id *string, field string) (*models.Task, error) {
+	if id == nil {
+		return nil, nil
+	}
+	neighbor, err := h.queries.GetTask(ctx, *id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if neighbor == nil {
+		return nil, validationError(field + " task not found")
+	}
+	if neighbor.ProjectID != task.ProjectID || neighbor.Status != status {
+		return nil, validationError(field + " must be in the target column")
+	}
+	return neighbor, nil
+}
+
+// newBoard groups tasks, already sorted by status and rank, into columns.
+// Every workflow status gets a column, in workflow order, even when empty;
+// any other status still in use gets a column after them.
+func newBoard(workflow *models.Workflow, tasks []models.Task) models.Board {
+	byStatus := make(map[string][]models.Task)
+	for _, task := range tasks {
+		byStatus[task.Status] = append(byStatus[task.Status], task)
+	}
+
+	columns := make([]models.BoardColumn, 0, len(workflow.Statuses))
+	for _, status := range workflow.Statuses {
+		columns = append(columns, models.BoardColumn{Status: status.Key, Name: status.Name, Category: status.Category})
+	}
+	var extra []string
+	for status := range byStatus {
+		if workflow.Status(status) == nil {
+			extra = append(extra, status)
+		}
+	}
+	sort.Strings(extra)
+	for _, status := range extra {
+		columns = append(columns, models.BoardColumn{Status: status})
+	}
+
+	board := models.Board{ProjectID: workflow.ProjectID, Columns: columns}
+	for i := range board.Columns {
+		board.Columns[i].Tasks = byStatus[board.Columns[i].Status]
+		if board.Columns[i].Tasks == nil {
+			board.Columns[i].Tasks = []models.Task{}
+		}
+	}
+	return board
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+func TestNewBoard(t *testing.T) {
+	tasks := []models.Task{
+		{ID: "1", Status: "blocked", Position: "i"},
+		{ID: "2", Status: "done", Position: "i"},
+		{ID: "3", Status: "todo", Position: "a"},
+		{ID: "4", Status: "todo", Position: "r"},
+	}
+
+	board := newBoard(&models.Workflow{ProjectID: "project-1", Statuses: []models.TaskStatus{
+		{Key: "todo", Name: "To Do", Category: models.StatusCategoryOpen},
+		{Key: "in_progress", Name: "In Progress", Category: models.StatusCategoryActive},
+		{Key: "done", Name: "Done", Category: models.StatusCategoryClosed},
+	}}, tasks)
+
+	wantStatuses := []string{"todo", "in_progress", "done", "blocked"}
+	if len(board.Columns) != len(wantStatuses) {
+		t.Fatalf("Expected %d columns, got %d", len(wantStatuses), len(board.Columns))
+	}
+	for i, status := range wantStatuses {
+		if board.Columns[i].Status != status {
+			t.Errorf("Expected column %d to be %s, got %s", i, status, board.Columns[i].Status)
+		}
+	}
+
+	todo := board.Columns[0].Tasks
+	if len(todo) != 2 || todo[0].ID != "3" || todo[1].ID != "4" {
+		t.Errorf("Expected todo column to keep rank order, got %+v", todo)
+	}
+	if board.Columns[1].Tasks == nil {
+		t.Error("Expected empty column to have an empty task list, not null")
+	}
+	if board.Columns[2].Name != "Done" || board.Columns[2].Category != models.StatusCategoryClosed {
+		t.Errorf("Expected workflow columns to carry name and category, got %+v", board.Columns[2])
+	}
+	if board.Columns[3].Category != "" {
+		t.Error("Expected status outside the workflow to have no category")
+	}
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/ical"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// calendarHistory is how far back a feed reaches, for overdue tasks and
+// log entries.
+const calendarHistory = 90 * 24 * time.Hour
+
+// calendarRefresh is how often calendar apps are asked to fetch the feed.
+const calendarRefresh = time.Hour
+
+// CalendarHandler serves each user's iCalendar feed under a secret URL,
+// since calendar apps cannot log in, and lets the user manage it.
+type CalendarHandler struct {
+	queries     *database.Queries
+	apiURL      string
+	frontendURL string
+}
+
+// NewCalendarHandler returns a handler that gives out feed URLs under
+// apiURL, this API's public address, and links feed items to the web app at
+// frontendURL.
+func NewCalendarHandler(queries *database.Queries, apiURL, frontendURL string) *CalendarHandler {
+	return &CalendarHandler{
+		queries:     queries,
+		apiURL:      strings.TrimSuffix(apiURL, "/"),
+		frontendURL: strings.TrimSuffix(frontendURL, "/"),
+	}
+}
+
+// Get returns the user's calendar feed, without its URL.
+func (h *CalendarHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	feed, err := h.queries.GetCalendarFeed(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to get calendar feed", http.StatusInternalServerError)
+		return
+	}
+	if feed == nil {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, feed)
+}
+
+// Rotate creates the user's calendar feed, or gives it a new token if it
+// exists, so the old URL stops working. The response holds the URL, which
+// cannot be retrieved again.
+func (h *CalendarHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token, hash, err := newSecretToken()
+	if err != nil {
+		http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+		return
+	}
+	feed, err := h.queries.SetCalendarFeed(r.Context(), userID, hash)
+	if err != nil {
+		http.Error(w, "Failed to create calendar feed", http.StatusInternalServerError)
+		return
+	}
+	feed.Token = token
+	feed.URL = h.apiURL + "/api/calendar/" + token + ".ics"
+
+	setNoStore(w)
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, feed)
+}
+
+// Delete turns the feed off.
+func (h *CalendarHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := h.queries.DeleteCalendarFeed(r.Context(), userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Feed publishes the open tasks the feed's owner created that have a due
+// date, as all-day events or, with ?tasks=todo, as to-dos. With
+// ?log_entries=true their log entries are added as all-day events. Tasks
+// overdue and entries older than calendarHistory are left out. The token
+// in the path is the only authentication; the feed supports If-None-Match.
+func (h *CalendarHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	taskKind := ical.Event
+	switch r.URL.Query().Get("tasks") {
+	case "", "event":
+	case "todo":
+		taskKind = ical.Todo
+	default:
+		http.Error(w, "Invalid tasks, must be event or todo", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.queries.UseCalendarFeed(r.Context(), hashSecretToken(chi.URLParam(r, "token")))
+	if err != nil {
+		http.Error(w, "Failed to get calendar feed", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "Calendar feed not found", http.StatusNotFound)
+		return
+	}
+
+	settings, err := h.queries.GetUserSettings(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get settings", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now().In(userLocation(settings))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	since := today.Add(-calendarHistory)
+
+	tasks, err := h.queries.ListTasks(r.Context(), user.ID, database.TaskFilter{CreatedBy: &user.ID, DueFrom: &since})
+	if err != nil {
+		http.Error(w, "Failed to list tasks", http.StatusInternalServerError)
+		return
+	}
+	var logEntries []models.LogEntry
+	if r.URL.Query().Get("log_entries") == "true" {
+		logEntries, err = h.queries.ListLogEntriesBetween(r.Context(), user.ID, since, today)
+		if err != nil {
+			http.Error(w, "Failed to get log entries", http.StatusInternalServerError)
+			return
+		}
+	}
+	projectNames, err := h.queries.ProjectNames(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get projects", http.StatusInternalServerError)
+		return
+	}
+
+	cal := &ical.Calendar{
+		Name:       "Makerlog",
+		Refresh:    calendarRefresh,
+		Components: h.calendarComponents(tasks, taskKind, logEntries, projectNames),
+	}
+	var buf bytes.Buffer
+	if err := ical.Write(&buf, cal); err != nil {
+		http.Error(w, "Failed to write calendar", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("Cache-Control", "private, no-cache")
+	setETag(w, tag)
+	if notModified(w, r, tag) {
+		return
+	}
+	writeText(w, "text/calendar; charset=utf-8", buf.String())
+}
+
+// calendarComponents turns tasks into items of the kind given and log
+// entries into events. Items link to their project in the web app.
+func (h *CalendarHandler) calendarComponents(tasks []models.Task, taskKind string, logEntries []models.LogEntry, projectNames map[string]string) []ical.Component {
+	components := []ical.Component{}
+	for _, task := range tasks {
+		description := task.Description
+		if name := projectNames[task.ProjectID]; name != "" {
+			description = strings.TrimSpace("Project: " + name + "\n\n" + description)
+		}
+		components = append(components, ical.Component{
+			Kind:        taskKind,
+			UID:         "task-" + task.ID + "@makerlog",
+			Stamp:       task.UpdatedAt,
+			Date:        *task.DueDate,
+			Summary:     task.Title,
+			Description: description,
+			URL:         h.frontendURL + "/projects/" + task.ProjectID,
+			Priority:    calendarPriority(task.Priority),
+		})
+	}
+	for _, logEntry := range logEntries {
+		summary, _, _ := strings.Cut(logEntry.Content, "\n")
+		component := ical.Component{
+			Kind:        ical.Event,
+			UID:         "log-entry-" + logEntry.ID + "@makerlog",
+			Stamp:       logEntry.UpdatedAt,
+			Date:        logEntry.LogDate,
+			Summary:     summary,
+			Description: logEntry.Content,
+		}
+		if logEntry.ProjectID != nil {
+			if name := projectNames[*logEntry.ProjectID]; name != "" {
+				component.Summary = name + ": " + summary
+			}
+			component.URL = h.frontendURL + "/projects/" + *logEntry.ProjectID
+		}
+		components = append(components, component)
+	}
+	return components
+}
+
+// calendarPriority maps a task priority onto iCalendar's 1 (highest) to 9.
+func calendarPriority(priority string) int {
+	switch priority {
+	case "urgent":
+		return 1
+	case "high":
+		return 3
+	case "medium":
+		return 5
+	case "low":
+		return 9
+	}
+	return 0
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/ical"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+func TestCalendarComponents(t *testing.T) {
+	h := NewCalendarHandler(nil, "http://api.test/", "http://app.test/")
+	due := time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)
+	website := "p1"
+	tasks := []models.Task{{ID: "t1", ProjectID: "p1", Title: "Launch", Priority: "high", DueDate: &due}}
+	logEntries := []models.LogEntry{
+		{ID: "l1", ProjectID: &website, LogDate: due, Content: "Fixed the footer\nand the header"},
+		{ID: "l2", LogDate: due, Content: "Read a paper"},
+	}
+
+	components := h.calendarComponents(tasks, ical.Todo, logEntries, map[string]string{"p1": "Website"})
+	if len(components) != 3 {
+		t.Fatalf("Expected 3 components, got %d", len(components))
+	}
+
+	task := components[0]
+	if task.Kind != ical.Todo || task.UID != "task-t1@makerlog" || task.Priority != 3 {
+		t.Errorf("Unexpected task component %+v", task)
+	}
+	if task.Description != "Project: Website" {
+		t.Errorf("Expected the project as description, got %q", task.Description)
+	}
+	if task.URL != "http://app.test/projects/p1" {
+		t.Errorf("Expected a link to the project, got %q", task.URL)
+	}
+
+	if got := components[1]; got.Kind != ical.Event || got.Summary != "Website: Fixed the footer" || got.Description != "Fixed the footer\nand the header" {
+		t.Errorf("Unexpected log entry component %+v", got)
+	}
+	if got := components[2]; got.Summary != "Read a paper" || got.URL != "" {
+		t.Errorf("Unexpected log entry component %+v", got)
+	}
+}
+
+func TestCalendarFeedRejectsInvalidTasks(t *testing.T) {
+	h := NewCalendarHandler(nil, "http://api.test", "http://app.test")
+	w := httptest.NewRecorder()
+	h.Feed(w, httptest.NewRequest(http.MethodGet, "/api/calendar/token.ics?tasks=journal", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", w.Code)
+	}
+}
+
+func TestCalendarPriority(t *testing.T) {
+	for priority, want := range map[string]int{"urgent": 1, "high": 3, "medium": 5, "low": 9, "": 0} {
+		if got := calendarPriority(priority); got != want {
+			t.Errorf("calendarPriority(%q) = %d, want %d", priority, got, want)
+		}
+	}
+}
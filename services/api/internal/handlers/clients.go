@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ClientHandler serves the clients of the user's workspaces. Every member
+// can see them; admins manage them.
+type ClientHandler struct {
+	queries *database.Queries
+}
+
+func NewClientHandler(queries *database.Queries) *ClientHandler {
+	return &ClientHandler{queries: queries}
+}
+
+func (h *ClientHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var workspaceID *string
+	if value := r.URL.Query().Get("workspace_id"); value != "" {
+		if _, err := uuid.Parse(value); err != nil {
+			http.Error(w, "Invalid workspace_id format", http.StatusBadRequest)
+			return
+		}
+		workspaceID = &value
+	}
+
+	clients, err := h.queries.ListClients(r.Context(), userID, workspaceID)
+	if err != nil {
+		http.Error(w, "Failed to list clients", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, clients)
+}
+
+func (h *ClientHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	name, email, ok := clientFields(w, req.Name, req.Email)
+	if !ok {
+		return
+	}
+
+	var workspace *models.Workspace
+	if req.WorkspaceID != nil {
+		if _, err := uuid.Parse(*req.WorkspaceID); err != nil {
+			http.Error(w, "Invalid workspace_id format", http.StatusBadRequest)
+			return
+		}
+		if workspace = checkWorkspaceRole(r.Context(), w, h.queries, *req.WorkspaceID, userID, models.RoleAdmin); workspace == nil {
+			return
+		}
+	} else {
+		var err error
+		if workspace, err = h.queries.GetPersonalWorkspace(r.Context(), userID); err != nil || workspace == nil {
+			http.Error(w, "Failed to get personal workspace", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	client, err := h.queries.CreateClient(r.Context(), workspace.ID, name, email)
+	if err != nil {
+		http.Error(w, "Failed to create client", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, client)
+}
+
+func (h *ClientHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid client ID format", http.StatusBadRequest)
+		return
+	}
+
+	client := h.getClient(r.Context(), w, id, userID)
+	if client == nil {
+		return
+	}
+
+	writeJSON(w, client)
+}
+
+func (h *ClientHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid client ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	name, email, ok := clientFields(w, req.Name, req.Email)
+	if !ok {
+		return
+	}
+
+	client := h.getClient(r.Context(), w, id, userID)
+	if client == nil {
+		return
+	}
+	if checkWorkspaceRole(r.Context(), w, h.queries, client.WorkspaceID, userID, models.RoleAdmin) == nil {
+		return
+	}
+
+	updated, err := h.queries.UpdateClient(r.Context(), id, userID, name, email)
+	if err != nil {
+		http.Error(w, "Failed to update client", http.StatusInternalServerError)
+		return
+	}
+	if updated == nil {
+		http.Error(w, "Client not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, updated)
+}
+
+// Delete removes the client. Its projects stay, without a client.
+func (h *ClientHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid client ID format", http.StatusBadRequest)
+		return
+	}
+
+	client := h.getClient(r.Context(), w, id, userID)
+	if client == nil {
+		return
+	}
+	if checkWorkspaceRole(r.Context(), w, h.queries, client.WorkspaceID, userID, models.RoleAdmin) == nil {
+		return
+	}
+
+	err := h.queries.DeleteClient(r.Context(), id, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Client not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete client", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getClient loads a client the user can see, writing a 404 if there is
+// none.
+func (h *ClientHandler) getClient(ctx context.Context, w http.ResponseWriter, id, userID string) *models.Client {
+	client, err := h.queries.GetClient(ctx, id, userID)
+	if err != nil {
+		http.Error(w, "Failed to get client", http.StatusInternalServerError)
+		return nil
+	}
+	if client == nil {
+		http.Error(w, "Client not found", http.StatusNotFound)
+		return nil
+	}
+	return client
+}
+
+// clientFields trims and checks a client's name and optional email,
+// writing a 400 when they are invalid. An empty email clears it.
+func clientFields(w http.ResponseWriter, name string, email *string) (string, *string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		http.Error(w, "Client name is required", http.StatusBadRequest)
+		return "", nil, false
+	}
+	if email != nil {
+		trimmed := strings.TrimSpace(*email)
+		if trimmed == "" {
+			return name, nil, true
+		}
+		if !strings.Contains(trimmed, "@") {
+			http.Error(w, "Invalid email", http.StatusBadRequest)
+			return "", nil, false
+		}
+		email = &trimmed
+	}
+	return name, email, true
+}
@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// CommentHandler serves comments and reactions on log entries. Anyone who
+// can see an entry may comment and react; a comment can be edited by its
+// author and deleted by its author or the entry's author.
+type CommentHandler struct {
+	queries *database.Queries
+}
+
+func NewCommentHandler(queries *database.Queries) *CommentHandler {
+	return &CommentHandler{queries: queries}
+}
+
+// List returns the entry's comments as threads, oldest first.
+func (h *CommentHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid log entry ID format", http.StatusBadRequest)
+		return
+	}
+
+	if h.getLogEntry(r.Context(), w, id, userID) == nil {
+		return
+	}
+
+	comments, err := h.queries.ListComments(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, "Failed to list comments", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, commentThreads(comments))
+}
+
+func (h *CommentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid log entry ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		http.Error(w, "Content is required", http.StatusBadRequest)
+		return
+	}
+	if req.ParentID != nil {
+		if _, err := uuid.Parse(*req.ParentID); err != nil {
+			http.Error(w, "Invalid parent_id format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	logEntry := h.getLogEntry(r.Context(), w, id, userID)
+	if logEntry == nil {
+		return
+	}
+	if !checkWritable(r.Context(), w, h.queries, userID, models.RoleViewer, logEntry.ProjectID) {
+		return
+	}
+
+	comment, err := h.queries.CreateComment(r.Context(), id, req.ParentID, userID, req.Content)
+	if errors.Is(err, database.ErrInvalidParent) {
+		http.Error(w, "parent_id is not a comment on this log entry", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+		return
+	}
+	sideEffect(r.Context(), h.queries, "notify about comment "+comment.ID, func(queries *database.Queries) error {
+		return queries.NotifyComment(r.Context(), comment)
+	})
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, comment)
+}
+
+// Update changes a comment's content. Only its author may.
+func (h *CommentHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid comment ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		http.Error(w, "Content is required", http.StatusBadRequest)
+		return
+	}
+
+	comment, logEntry := h.getComment(r.Context(), w, id, userID)
+	if comment == nil {
+		return
+	}
+	if comment.UserID != userID {
+		http.Error(w, "Only the author can edit a comment", http.StatusForbidden)
+		return
+	}
+	if !checkWritable(r.Context(), w, h.queries, userID, models.RoleViewer, logEntry.ProjectID) {
+		return
+	}
+
+	updated, err := h.queries.UpdateComment(r.Context(), id, userID, req.Content)
+	if err != nil {
+		http.Error(w, "Failed to update comment", http.StatusInternalServerError)
+		return
+	}
+	if updated == nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, updated)
+}
+
+// Delete removes a comment's content, keeping its replies. The comment's
+// author and the log entry's author may delete it.
+func (h *CommentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid comment ID format", http.StatusBadRequest)
+		return
+	}
+
+	comment, logEntry := h.getComment(r.Context(), w, id, userID)
+	if comment == nil {
+		return
+	}
+	if comment.UserID != userID && logEntry.UserID != userID {
+		http.Error(w, "Only the comment's or the log entry's author can delete a comment", http.StatusForbidden)
+		return
+	}
+	if !checkWritable(r.Context(), w, h.queries, userID, models.RoleViewer, logEntry.ProjectID) {
+		return
+	}
+
+	err := h.queries.DeleteComment(r.Context(), id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CommentHandler) Reactions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid log entry ID format", http.StatusBadRequest)
+		return
+	}
+
+	if h.getLogEntry(r.Context(), w, id, userID) == nil {
+		return
+	}
+
+	reactions, err := h.queries.ListReactions(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, "Failed to list reactions", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, reactions)
+}
+
+// AddReaction reacts to the entry with the emoji in the path. Reacting twice
+// with the same emoji is a no-op.
+func (h *CommentHandler) AddReaction(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, emoji, ok := reactionParams(w, r)
+	if !ok {
+		return
+	}
+
+	logEntry := h.getLogEntry(r.Context(), w, id, userID)
+	if logEntry == nil {
+		return
+	}
+	if !checkWritable(r.Context(), w, h.queries, userID, models.RoleViewer, logEntry.ProjectID) {
+		return
+	}
+
+	reaction, err := h.queries.AddReaction(r.Context(), id, userID, emoji)
+	if err != nil {
+		http.Error(w, "Failed to add reaction", http.StatusInternalServerError)
+		return
+	}
+	if reaction == nil {
+		http.Error(w, "Log entry not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, reaction)
+}
+
+func (h *CommentHandler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, emoji, ok := reactionParams(w, r)
+	if !ok {
+		return
+	}
+
+	logEntry := h.getLogEntry(r.Context(), w, id, userID)
+	if logEntry == nil {
+		return
+	}
+	if !checkWritable(r.Context(), w, h.queries, userID, models.RoleViewer, logEntry.ProjectID) {
+		return
+	}
+
+	err := h.queries.RemoveReaction(r.Context(), id, userID, emoji)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Reaction not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to remove reaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getLogEntry loads a log entry the user can see, writing a 404 if there is
+// none.
+func (h *CommentHandler) getLogEntry(ctx context.Context, w http.ResponseWriter, id, userID string) *models.LogEntry {
+	logEntry, err := h.queries.GetLogEntry(ctx, id, userID)
+	if err != nil {
+		http.Error(w, "Failed to get log entry", http.StatusInternalServerError)
+		return nil
+	}
+	if logEntry == nil {
+		http.Error(w, "Log entry not found", http.StatusNotFound)
+		return nil
+	}
+	return logEntry
+}
+
+// getComment loads a comment the user can see along with its log entry,
+// writing a 404 if there is none.
+func (h *CommentHandler) getComment(ctx context.Context, w http.ResponseWriter, id, userID string) (*models.Comment, *models.LogEntry) {
+	comment, err := h.queries.GetComment(ctx, id, userID)
+	if err != nil {
+		http.Error(w, "Failed to get comment", http.StatusInternalServerError)
+		return nil, nil
+	}
+	if comment == nil || comment.DeletedAt != nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return nil, nil
+	}
+	logEntry := h.getLogEntry(ctx, w, comment.LogEntryID, userID)
+	if logEntry == nil {
+		return nil, nil
+	}
+	return comment, logEntry
+}
+
+// reactionParams reads the log entry ID and emoji from the path.
+func reactionParams(w http.ResponseWriter, r *http.Request) (id, emoji string, ok bool) {
+	id = chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid log entry ID format", http.StatusBadRequest)
+		return "", "", false
+	}
+	emoji, err := url.PathUnescape(chi.URLParam(r, "emoji"))
+	if err != nil || !validEmoji(emoji) {
+		http.Error(w, "Invalid emoji", http.StatusBadRequest)
+		return "", "", false
+	}
+	return id, emoji, true
+}
+
+// validEmoji accepts a single emoji, including sequences joined with
+// zero-width joiners or modified by variation selectors and skin tones.
+func validEmoji(s string) bool {
+	if s == "" || len(s) > 32 || !utf8.ValidString(s) {
+		return false
+	}
+	first, _ := utf8.DecodeRuneInString(s)
+	if !unicode.Is(unicode.So, first) {
+		return false
+	}
+	for _, r := range s {
+		if r < utf8.RuneSelf || (!unicode.IsGraphic(r) && r != '\u200d') {
+			return false
+		}
+	}
+	return true
+}
+
+// commentThreads nests replies under their parents, keeping the order of
+// comments, which is oldest first. Replies whose parent is missing are kept
+// at the top level.
+func commentThreads(comments []models.Comment) []models.Comment {
+	known := make(map[string]bool, len(comments))
+	for _, comment := range comments {
+		known[comment.ID] = true
+	}
+	children := map[string][]models.Comment{}
+	var roots []models.Comment
+	for _, comment := range comments {
+		if comment.ParentID != nil && known[*comment.ParentID] {
+			children[*comment.ParentID] = append(children[*comment.ParentID], comment)
+		} else {
+			roots = append(roots, comment)
+		}
+	}
+
+	var attach func(list []models.Comment) []models.Comment
+	attach = func(list []models.Comment) []models.Comment {
+		for i := range list {
+			list[i].Replies = attach(children[list[i].ID])
+		}
+		return list
+	}
+	threads := attach(roots)
+	if threads == nil {
+		threads = []models.Comment{}
+	}
+	return threads
+}
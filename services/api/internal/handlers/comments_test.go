@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+func TestValidEmoji(t *testing.T) {
+	tests := []struct {
+		emoji string
+		want  bool
+	}{
+		{"👍", true},
+		{"🎉", true},
+		{"❤️", true},
+		{"👍🏽", true},
+		{"👩\u200d💻", true},
+		{"🇳🇱", true},
+		{"", false},
+		{"+1", false},
+		{":tada:", false},
+		{"é", false},
+		{"👍 ", false},
+		{"\xff", false},
+	}
+
+	for _, tt := range tests {
+		if got := validEmoji(tt.emoji); got != tt.want {
+			t.Errorf("validEmoji(%q) = %v, want %v", tt.emoji, got, tt.want)
+		}
+	}
+}
+
+func TestCommentThreads(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	comments := []models.Comment{
+		{ID: "a"},
+		{ID: "b"},
+		{ID: "c", ParentID: ptr("a")},
+		{ID: "d", ParentID: ptr("c")},
+		{ID: "e", ParentID: ptr("a")},
+		{ID: "f", ParentID: ptr("gone")},
+	}
+
+	threads := commentThreads(comments)
+
+	if len(threads) != 3 || threads[0].ID != "a" || threads[1].ID != "b" || threads[2].ID != "f" {
+		t.Fatalf("Expected top-level a, b, f, got %+v", threads)
+	}
+	replies := threads[0].Replies
+	if len(replies) != 2 || replies[0].ID != "c" || replies[1].ID != "e" {
+		t.Fatalf("Expected a's replies c, e, got %+v", replies)
+	}
+	if len(replies[0].Replies) != 1 || replies[0].Replies[0].ID != "d" {
+		t.Errorf("Expected c's reply d, got %+v", replies[0].Replies)
+	}
+	if threads[1].Replies != nil {
+		t.Errorf("Expected no replies for b, got %+v", threads[1].Replies)
+	}
+
+	if empty := commentThreads(nil); empty == nil || len(empty) != 0 {
+		t.Errorf("Expected an empty list, got %#v", empty)
+	}
+}
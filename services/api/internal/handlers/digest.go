@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/digest"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+// DigestHandler previews the user's email digest.
+type DigestHandler struct {
+	queries *database.Queries
+}
+
+func NewDigestHandler(queries *database.Queries) *DigestHandler {
+	return &DigestHandler{queries: queries}
+}
+
+// Preview renders the digest the user would get now, without sending it.
+// ?period= picks daily or weekly, defaulting to the user's setting or daily
+// when it is off, and ?format= picks html (the default) or text.
+func (h *DigestHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "html"
+	}
+	if format != "html" && format != "text" {
+		http.Error(w, "Invalid format, must be html or text", http.StatusBadRequest)
+		return
+	}
+	period := r.URL.Query().Get("period")
+	if period != "" && period != models.DigestDaily && period != models.DigestWeekly {
+		http.Error(w, "Invalid period, must be daily or weekly", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.queries.GetUserByID(r.Context(), userID)
+	if err != nil || user == nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+	settings, err := h.queries.GetUserSettings(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to get settings", http.StatusInternalServerError)
+		return
+	}
+	if period == "" {
+		period = settings.Digest
+		if period == models.DigestOff {
+			period = models.DigestDaily
+		}
+	}
+
+	d, err := digest.Build(r.Context(), h.queries, user, period, time.Now().In(userLocation(settings)))
+	if err != nil {
+		http.Error(w, "Failed to build digest", http.StatusInternalServerError)
+		return
+	}
+	msg, err := digest.Render(d, user.Email)
+	if err != nil {
+		http.Error(w, "Failed to render digest", http.StatusInternalServerError)
+		return
+	}
+
+	if format == "text" {
+		writeText(w, "text/plain; charset=utf-8", msg.Text)
+		return
+	}
+	writeText(w, "text/html; charset=utf-8", msg.HTML)
+}
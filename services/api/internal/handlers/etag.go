@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+)
+
+// ETags are the quoted row version, so they only change when the row is
+// written and can be compared directly in the update's WHERE clause.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// etagWithDigest is used when a representation also carries data derived
+// from other rows. The version still leads, so If-Match keeps checking the
+// row itself, while the digest makes If-None-Match notice derived changes.
+func etagWithDigest(version int64, derived interface{}) string {
+	data, err := json.Marshal(derived)
+	if err != nil {
+		return etag(version)
+	}
+	sum := sha256.Sum256(data)
+	return `"` + strconv.FormatInt(version, 10) + "-" + hex.EncodeToString(sum[:8]) + `"`
+}
+
+func setETag(w http.ResponseWriter, tag string) {
+	w.Header().Set("ETag", tag)
+}
+
+// ifMatchVersions turns the If-Match header into the versions a write may
+// apply to. It returns nil when the header is absent or "*", meaning the
+// write is unconditional. Tags that are weak or not ours can never match, so
+// they are dropped, which leaves a non-nil empty slice.
+func ifMatchVersions(r *http.Request) []int64 {
+	header := r.Header.Get("If-Match")
+	if header == "" || strings.TrimSpace(header) == "*" {
+		return nil
+	}
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		if version, ok := parseETag(strings.TrimSpace(tag)); ok {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// notModified handles If-None-Match for a GET. It writes a 304 and returns
+// true when the client's copy is current. Comparison is weak, per RFC 9110.
+func notModified(w http.ResponseWriter, r *http.Request, current string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == current {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// parseETag extracts the row version from one of our strong tags.
+func parseETag(tag string) (int64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	value := tag[1 : len(tag)-1]
+	if i := strings.IndexByte(value, '-'); i >= 0 {
+		value = value[:i]
+	}
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return version, true
+}
+
+// isPreconditionFailed reports whether a conditional write was rejected
+// because the row has changed since the client read it.
+func isPreconditionFailed(err error) bool {
+	return errors.Is(err, database.ErrVersionMismatch)
+}
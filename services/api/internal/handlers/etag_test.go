@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	if got := etag(3); got != `"3"` {
+		t.Errorf("Expected \"3\", got %s", got)
+	}
+}
+
+func TestIfMatchVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected []int64
+	}{
+		{
+			name:     "no header",
+			header:   "",
+			expected: nil,
+		},
+		{
+			name:     "wildcard",
+			header:   "*",
+			expected: nil,
+		},
+		{
+			name:     "single tag",
+			header:   `"4"`,
+			expected: []int64{4},
+		},
+		{
+			name:     "tag list",
+			header:   `"4", "5"`,
+			expected: []int64{4, 5},
+		},
+		{
+			name:     "tag with digest",
+			header:   `"4-0a1b2c3d4e5f6071"`,
+			expected: []int64{4},
+		},
+		{
+			name:     "weak tag never matches",
+			header:   `W/"4"`,
+			expected: []int64{},
+		},
+		{
+			name:     "unquoted tag never matches",
+			header:   `4`,
+			expected: []int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/test", nil)
+			if tt.header != "" {
+				req.Header.Set("If-Match", tt.header)
+			}
+
+			versions := ifMatchVersions(req)
+			if (versions == nil) != (tt.expected == nil) {
+				t.Fatalf("Expected nil=%v, got %v", tt.expected == nil, versions)
+			}
+			if len(versions) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, versions)
+			}
+			for i := range versions {
+				if versions[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, versions)
+				}
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected bool
+	}{
+		{
+			name:     "no header",
+			header:   "",
+			expected: false,
+		},
+		{
+			name:     "current version",
+			header:   `"2"`,
+			expected: true,
+		},
+		{
+			name:     "weak current version",
+			header:   `W/"2"`,
+			expected: true,
+		},
+		{
+			name:     "stale version",
+			header:   `"1"`,
+			expected: false,
+		},
+		{
+			name:     "wildcard",
+			header:   "*",
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.header != "" {
+				req.Header.Set("If-None-Match", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			got := notModified(w, req, etag(2))
+			if got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+			if got && w.Code != http.StatusNotModified {
+				t.Errorf("Expected status %d, got %d", http.StatusNotModified, w.Code)
+			}
+		})
+	}
+}
+
+func TestETagWithDigest(t *testing.T) {
+	a := etagWithDigest(3, map[string]int{"subtasks": 1})
+	b := etagWithDigest(3, map[string]int{"subtasks": 2})
+	if a == b {
+		t.Error("Expected derived data to change the tag")
+	}
+	if version, ok := parseETag(a); !ok || version != 3 {
+		t.Errorf("Expected tag %s to carry version 3", a)
+	}
+}
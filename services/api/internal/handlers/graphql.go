@@ -0,0 +1,674 @@
+package handlers
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// Limits on a GraphQL operation: how deeply fields may nest, how much it
+// may ask for, see complexity, and how long the query may be.
+const (
+	graphqlMaxDepth       = 10
+	graphqlMaxComplexity  = 5000
+	graphqlMaxQueryLength = 10000
+	// graphqlListWeight is how many items a list is taken to hold.
+	graphqlListWeight = 10
+)
+
+//go:embed schema.graphql
+var graphqlSchema string
+
+// GraphQLHandler serves the GraphQL API over users, projects, tasks and log
+// entries. Relations are loaded for all the rows at one level of the
+// operation at once, one query per relation and level, and writes are made
+// with the same checks as the REST API, so they are validated and
+// authorized alike.
+type GraphQLHandler struct {
+	queries    *database.Queries
+	projects   *ProjectHandler
+	tasks      *TaskHandler
+	logEntries *LogEntryHandler
+	schema     *graphql.Schema
+	// types is the schema as the complexity check reads it.
+	types *ast.Schema
+}
+
+func NewGraphQLHandler(queries *database.Queries) *GraphQLHandler {
+	h := &GraphQLHandler{
+		queries:    queries,
+		projects:   NewProjectHandler(queries),
+		tasks:      NewTaskHandler(queries),
+		logEntries: NewLogEntryHandler(queries),
+	}
+	h.schema = graphql.MustParseSchema(graphqlSchema, &graphqlResolver{h: h},
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(graphqlMaxDepth),
+		graphql.MaxQueryLength(graphqlMaxQueryLength),
+	)
+	h.types = gqlparser.MustLoadSchema(&ast.Source{Name: "schema.graphql", Input: graphqlSchema})
+	return h
+}
+
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Serve runs a GraphQL request POSTed as JSON. Errors in the operation are
+// reported in the response with status 200, as GraphQL clients expect.
+func (h *GraphQLHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	if _, ok := middleware.GetUserID(r.Context()); !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req graphqlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		http.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+
+	if h.complexity(req.Query) > graphqlMaxComplexity {
+		writeJSON(w, &graphql.Response{Errors: []*gqlerrors.QueryError{
+			gqlerrors.Errorf("The operation asks for too much, with a complexity over the limit of %d.", graphqlMaxComplexity),
+		}})
+		return
+	}
+	writeJSON(w, h.schema.Exec(r.Context(), req.Query, req.OperationName, req.Variables))
+}
+
+// complexity weighs the operations of a query before it runs. Each field
+// selected counts once, and the fields below a list count as many times as
+// the list is taken to hold items, so nested lists quickly add up. It
+// returns the weight of the heaviest operation, and stops adding up once
+// past graphqlMaxComplexity. A query that does not parse weighs nothing,
+// and Exec reports what is wrong with it.
+func (h *GraphQLHandler) complexity(query string) int {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return 0
+	}
+	heaviest := 0
+	for _, operation := range doc.Operations {
+		root := h.types.Query
+		if operation.Operation == ast.Mutation {
+			root = h.types.Mutation
+		}
+		walk := complexityWalk{types: h.types, fragments: doc.Fragments, spreading: map[string]bool{}}
+		heaviest = max(heaviest, walk.weigh(root, operation.SelectionSet, 1))
+	}
+	return heaviest
+}
+
+type complexityWalk struct {
+	types     *ast.Schema
+	fragments ast.FragmentDefinitionList
+	// spreading holds the fragments being weighed, so that one spreading
+	// itself, which validation refuses later, is weighed only once.
+	spreading map[string]bool
+}
+
+// weigh weighs selections of the object typed parent, each field counting
+// each times. parent is nil below a field the schema lacks.
+func (w *complexityWalk) weigh(parent *ast.Definition, selections ast.SelectionSet, each int) int {
+	total := 0
+	for _, selection := range selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			total += each
+			var field *ast.FieldDefinition
+			if parent != nil {
+				field = parent.Fields.ForName(selection.Name)
+			}
+			var child *ast.Definition
+			childEach := each
+			if field != nil {
+				child = w.types.Types[field.Type.Name()]
+				if field.Type.Elem != nil {
+					childEach = min(each*graphqlListWeight, graphqlMaxComplexity+1)
+				}
+			}
+			total += w.weigh(child, selection.SelectionSet, childEach)
+		case *ast.InlineFragment:
+			typed := parent
+			if selection.TypeCondition != "" {
+				typed = w.types.Types[selection.TypeCondition]
+			}
+			total += w.weigh(typed, selection.SelectionSet, each)
+		case *ast.FragmentSpread:
+			fragment := w.fragments.ForName(selection.Name)
+			if fragment == nil || w.spreading[fragment.Name] {
+				continue
+			}
+			w.spreading[fragment.Name] = true
+			total += w.weigh(w.types.Types[fragment.TypeCondition], fragment.SelectionSet, each)
+			delete(w.spreading, fragment.Name)
+		}
+		if total > graphqlMaxComplexity {
+			return total
+		}
+	}
+	return total
+}
+
+// Schema returns the schema in the GraphQL schema definition language.
+func (h *GraphQLHandler) Schema(w http.ResponseWriter, r *http.Request) {
+	writeText(w, "text/plain; charset=utf-8", graphqlSchema)
+}
+
+// graphqlUserID returns the signed-in user, whom Serve has checked for.
+func graphqlUserID(ctx context.Context) string {
+	userID, _ := middleware.GetUserID(ctx)
+	return userID
+}
+
+// Extensions carries the HTTP status the REST API would have answered
+// with in a GraphQL error.
+func (e statusError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"status": e.status}
+}
+
+// graphqlError gives err the status the REST API would have answered with,
+// for its extensions.
+func graphqlError(err error) error {
+	status, message := errorStatus(err)
+	return statusError{status: status, message: message}
+}
+
+// graphqlID checks an ID argument, answering like the REST API does for a
+// malformed one in the URL.
+func graphqlID(id graphql.ID, what string) (string, error) {
+	if _, err := uuid.Parse(string(id)); err != nil {
+		return "", badRequest(fmt.Sprintf("Invalid %s ID format", what))
+	}
+	return string(id), nil
+}
+
+// optionalID turns an optional ID argument into a *string, checking it
+// like the REST API checks the query parameter name.
+func optionalID(id *graphql.ID, name string) (*string, error) {
+	if id == nil {
+		return nil, nil
+	}
+	if _, err := uuid.Parse(string(*id)); err != nil {
+		return nil, badRequest(fmt.Sprintf("Invalid %s format", name))
+	}
+	s := string(*id)
+	return &s, nil
+}
+
+// versions turns a version argument into the versions a write may apply
+// to, as an If-Match header would.
+func versions(version *int32) []int64 {
+	if version == nil {
+		return nil
+	}
+	return []int64{int64(*version)}
+}
+
+// date is the Date scalar, a calendar date in YYYY-MM-DD format.
+type date string
+
+func newDate(t time.Time) date {
+	return date(t.Format("2006-01-02"))
+}
+
+func (date) ImplementsGraphQLType(name string) bool {
+	return name == "Date"
+}
+
+func (d *date) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if _, err := time.Parse("2006-01-02", s); !ok || err != nil {
+		return errors.New("Date must be in YYYY-MM-DD format")
+	}
+	*d = date(s)
+	return nil
+}
+
+// nullDate is a Date in an update, where null clears it and leaving it
+// out keeps it, like graphql.NullString.
+type nullDate struct {
+	Value *date
+	Set   bool
+}
+
+func (nullDate) ImplementsGraphQLType(name string) bool {
+	return name == "Date"
+}
+
+func (d *nullDate) UnmarshalGraphQL(input interface{}) error {
+	d.Set = true
+	if input == nil {
+		return nil
+	}
+	d.Value = new(date)
+	return d.Value.UnmarshalGraphQL(input)
+}
+
+func (d *nullDate) Nullable() {}
+
+// Update inputs become the merge patch a REST request body would make.
+
+func patchString(s graphql.NullString) models.PatchField {
+	if s.Value == nil {
+		return models.PatchField{Present: s.Set, Null: s.Set}
+	}
+	return models.PatchField{Present: true, Value: *s.Value}
+}
+
+func patchID(id graphql.NullID) models.PatchField {
+	if id.Value == nil {
+		return models.PatchField{Present: id.Set, Null: id.Set}
+	}
+	return models.PatchField{Present: true, Value: string(*id.Value)}
+}
+
+func patchDate(d nullDate) models.PatchField {
+	if d.Value == nil {
+		return models.PatchField{Present: d.Set, Null: d.Set}
+	}
+	return models.PatchField{Present: true, Value: string(*d.Value)}
+}
+
+func patchInt(n graphql.NullInt) models.PatchInt {
+	if n.Value == nil {
+		return models.PatchInt{Present: n.Set, Null: n.Set}
+	}
+	return models.PatchInt{Present: true, Value: int64(*n.Value)}
+}
+
+func patchBool(b graphql.NullBool) models.PatchBool {
+	if b.Value == nil {
+		return models.PatchBool{Present: b.Set, Null: b.Set}
+	}
+	return models.PatchBool{Present: true, Value: *b.Value}
+}
+
+func stringID(id *graphql.ID) *string {
+	if id == nil {
+		return nil
+	}
+	s := string(*id)
+	return &s
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// graphqlResolver resolves the fields of Query and Mutation.
+type graphqlResolver struct {
+	h *GraphQLHandler
+}
+
+func (r *graphqlResolver) Me(ctx context.Context) (*userResolver, error) {
+	me, err := r.h.queries.GetUserByID(ctx, graphqlUserID(ctx))
+	if err != nil || me == nil {
+		return nil, internalError("Failed to get user")
+	}
+	return &userResolver{user: *me}, nil
+}
+
+func (r *graphqlResolver) Projects(ctx context.Context, args struct {
+	WorkspaceID *graphql.ID
+	Archived    bool
+}) ([]*projectResolver, error) {
+	workspaceID, err := optionalID(args.WorkspaceID, "workspace_id")
+	if err != nil {
+		return nil, err
+	}
+	projects, err := r.h.queries.ListProjects(ctx, graphqlUserID(ctx), workspaceID, args.Archived)
+	if err != nil {
+		return nil, internalError("Failed to get projects")
+	}
+	return r.h.projectResolvers(projects), nil
+}
+
+func (r *graphqlResolver) Project(ctx context.Context, args struct{ ID graphql.ID }) (*projectResolver, error) {
+	id, err := graphqlID(args.ID, "project")
+	if err != nil {
+		return nil, err
+	}
+	project, err := r.h.queries.GetProject(ctx, id, graphqlUserID(ctx))
+	if err != nil {
+		return nil, internalError("Failed to get project")
+	}
+	return r.h.projectResolver(project), nil
+}
+
+func (r *graphqlResolver) Tasks(ctx context.Context, args struct {
+	ProjectID   *graphql.ID
+	WorkspaceID *graphql.ID
+	Priority    *string
+	Due         *string
+}) ([]*taskResolver, error) {
+	query := url.Values{}
+	for name, value := range map[string]*string{
+		"project_id":   stringID(args.ProjectID),
+		"workspace_id": stringID(args.WorkspaceID),
+		"priority":     args.Priority,
+		"due":          args.Due,
+	} {
+		if value != nil {
+			query.Set(name, *value)
+		}
+	}
+	filter, err := taskFilterFromQuery(query, time.Now())
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+	tasks, err := r.h.queries.ListTasks(ctx, graphqlUserID(ctx), filter)
+	if err != nil {
+		return nil, internalError("Failed to list tasks")
+	}
+	return r.h.taskResolvers(tasks), nil
+}
+
+func (r *graphqlResolver) Task(ctx context.Context, args struct{ ID graphql.ID }) (*taskResolver, error) {
+	id, err := graphqlID(args.ID, "task")
+	if err != nil {
+		return nil, err
+	}
+	task, err := r.h.queries.GetTask(ctx, id, graphqlUserID(ctx))
+	if err != nil {
+		return nil, internalError("Failed to get task")
+	}
+	return r.h.taskResolver(task), nil
+}
+
+func (r *graphqlResolver) LogEntries(ctx context.Context, args struct{ ProjectID *graphql.ID }) ([]*logEntryResolver, error) {
+	projectID, err := optionalID(args.ProjectID, "project_id")
+	if err != nil {
+		return nil, err
+	}
+	logEntries, err := r.h.queries.ListLogEntries(ctx, graphqlUserID(ctx), projectID)
+	if err != nil {
+		return nil, internalError("Failed to list log entries")
+	}
+	return r.h.logEntryResolvers(logEntries), nil
+}
+
+func (r *graphqlResolver) LogEntry(ctx context.Context, args struct{ ID graphql.ID }) (*logEntryResolver, error) {
+	id, err := graphqlID(args.ID, "log entry")
+	if err != nil {
+		return nil, err
+	}
+	logEntry, err := r.h.queries.GetLogEntry(ctx, id, graphqlUserID(ctx))
+	if err != nil {
+		return nil, internalError("Failed to get log entry")
+	}
+	return r.h.logEntryResolver(logEntry), nil
+}
+
+func (r *graphqlResolver) Today(ctx context.Context) ([]*logEntryResolver, error) {
+	logEntries, err := r.h.queries.GetTodayLogEntries(ctx, graphqlUserID(ctx), time.Now())
+	if err != nil {
+		return nil, internalError("Failed to get today's log entries")
+	}
+	return r.h.logEntryResolvers(logEntries), nil
+}
+
+type createProjectInput struct {
+	ID          *graphql.ID
+	WorkspaceID *graphql.ID
+	Name        string
+	Description *string
+}
+
+type updateProjectInput struct {
+	Name            graphql.NullString
+	Description     graphql.NullString
+	ClientID        graphql.NullID
+	HourlyRateCents graphql.NullInt
+	Currency        graphql.NullString
+}
+
+func (r *graphqlResolver) CreateProject(ctx context.Context, args struct{ Input createProjectInput }) (*projectResolver, error) {
+	project, err := r.h.projects.create(ctx, graphqlUserID(ctx), models.CreateProjectRequest{
+		ID:          stringID(args.Input.ID),
+		WorkspaceID: stringID(args.Input.WorkspaceID),
+		Name:        args.Input.Name,
+		Description: stringValue(args.Input.Description),
+	})
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return r.h.projectResolver(project), nil
+}
+
+func (r *graphqlResolver) UpdateProject(ctx context.Context, args struct {
+	ID      graphql.ID
+	Input   updateProjectInput
+	Version *int32
+}) (*projectResolver, error) {
+	id, err := graphqlID(args.ID, "project")
+	if err != nil {
+		return nil, err
+	}
+	patch, err := projectPatchFromRequest(models.PatchProjectRequest{
+		Name:            patchString(args.Input.Name),
+		Description:     patchString(args.Input.Description),
+		ClientID:        patchID(args.Input.ClientID),
+		HourlyRateCents: patchInt(args.Input.HourlyRateCents),
+		Currency:        patchString(args.Input.Currency),
+	})
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	project, err := r.h.projects.patch(ctx, graphqlUserID(ctx), id, patch, versions(args.Version))
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return r.h.projectResolver(project), nil
+}
+
+func (r *graphqlResolver) DeleteProject(ctx context.Context, args struct {
+	ID      graphql.ID
+	Version *int32
+}) (bool, error) {
+	id, err := graphqlID(args.ID, "project")
+	if err != nil {
+		return false, err
+	}
+	if err := r.h.projects.delete(ctx, graphqlUserID(ctx), id, versions(args.Version)); err != nil {
+		return false, graphqlError(err)
+	}
+	return true, nil
+}
+
+type createTaskInput struct {
+	ID             *graphql.ID
+	ProjectID      graphql.ID
+	ParentTaskID   *graphql.ID
+	Title          string
+	Description    *string
+	Status         *string
+	Priority       *string
+	DueDate        *date
+	RecurrenceRule *string
+}
+
+type updateTaskInput struct {
+	ProjectID      graphql.NullID
+	ParentTaskID   graphql.NullID
+	Title          graphql.NullString
+	Description    graphql.NullString
+	Status         graphql.NullString
+	Priority       graphql.NullString
+	DueDate        nullDate
+	RecurrenceRule graphql.NullString
+}
+
+func (r *graphqlResolver) CreateTask(ctx context.Context, args struct{ Input createTaskInput }) (*taskResolver, error) {
+	var dueDate *string
+	if args.Input.DueDate != nil {
+		s := string(*args.Input.DueDate)
+		dueDate = &s
+	}
+	task, err := r.h.tasks.create(ctx, graphqlUserID(ctx), models.CreateTaskRequest{
+		ID:             stringID(args.Input.ID),
+		ProjectID:      string(args.Input.ProjectID),
+		ParentTaskID:   stringID(args.Input.ParentTaskID),
+		Title:          args.Input.Title,
+		Description:    stringValue(args.Input.Description),
+		Status:         stringValue(args.Input.Status),
+		Priority:       stringValue(args.Input.Priority),
+		DueDate:        dueDate,
+		RecurrenceRule: args.Input.RecurrenceRule,
+	})
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return r.h.taskResolver(task), nil
+}
+
+func (r *graphqlResolver) UpdateTask(ctx context.Context, args struct {
+	ID      graphql.ID
+	Input   updateTaskInput
+	Version *int32
+	Force   bool
+}) (*taskResolver, error) {
+	id, err := graphqlID(args.ID, "task")
+	if err != nil {
+		return nil, err
+	}
+	patch, err := taskPatchFromRequest(models.PatchTaskRequest{
+		ProjectID:      patchID(args.Input.ProjectID),
+		ParentTaskID:   patchID(args.Input.ParentTaskID),
+		Title:          patchString(args.Input.Title),
+		Description:    patchString(args.Input.Description),
+		Status:         patchString(args.Input.Status),
+		Priority:       patchString(args.Input.Priority),
+		DueDate:        patchDate(args.Input.DueDate),
+		RecurrenceRule: patchString(args.Input.RecurrenceRule),
+	})
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	task, err := r.h.tasks.patch(ctx, graphqlUserID(ctx), id, patch, versions(args.Version), args.Force)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return r.h.taskResolver(task), nil
+}
+
+func (r *graphqlResolver) DeleteTask(ctx context.Context, args struct {
+	ID      graphql.ID
+	Version *int32
+}) (bool, error) {
+	id, err := graphqlID(args.ID, "task")
+	if err != nil {
+		return false, err
+	}
+	if err := r.h.tasks.delete(ctx, graphqlUserID(ctx), id, versions(args.Version)); err != nil {
+		return false, graphqlError(err)
+	}
+	return true, nil
+}
+
+type createLogEntryInput struct {
+	ID              *graphql.ID
+	TaskID          *graphql.ID
+	ProjectID       *graphql.ID
+	Content         string
+	LogDate         date
+	DurationMinutes *int32
+	Billable        *bool
+}
+
+type updateLogEntryInput struct {
+	TaskID          graphql.NullID
+	ProjectID       graphql.NullID
+	Content         graphql.NullString
+	LogDate         nullDate
+	DurationMinutes graphql.NullInt
+	Billable        graphql.NullBool
+}
+
+func (r *graphqlResolver) CreateLogEntry(ctx context.Context, args struct{ Input createLogEntryInput }) (*logEntryResolver, error) {
+	var durationMinutes *int
+	if args.Input.DurationMinutes != nil {
+		minutes := int(*args.Input.DurationMinutes)
+		durationMinutes = &minutes
+	}
+	logEntry, err := r.h.logEntries.create(ctx, graphqlUserID(ctx), models.CreateLogEntryRequest{
+		ID:              stringID(args.Input.ID),
+		TaskID:          stringID(args.Input.TaskID),
+		ProjectID:       stringID(args.Input.ProjectID),
+		Content:         args.Input.Content,
+		LogDate:         string(args.Input.LogDate),
+		DurationMinutes: durationMinutes,
+		Billable:        args.Input.Billable != nil && *args.Input.Billable,
+	})
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return r.h.logEntryResolver(logEntry), nil
+}
+
+func (r *graphqlResolver) UpdateLogEntry(ctx context.Context, args struct {
+	ID      graphql.ID
+	Input   updateLogEntryInput
+	Version *int32
+}) (*logEntryResolver, error) {
+	id, err := graphqlID(args.ID, "log entry")
+	if err != nil {
+		return nil, err
+	}
+	patch, err := logEntryPatchFromRequest(models.PatchLogEntryRequest{
+		TaskID:          patchID(args.Input.TaskID),
+		ProjectID:       patchID(args.Input.ProjectID),
+		Content:         patchString(args.Input.Content),
+		LogDate:         patchDate(args.Input.LogDate),
+		DurationMinutes: patchInt(args.Input.DurationMinutes),
+		Billable:        patchBool(args.Input.Billable),
+	})
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	logEntry, err := r.h.logEntries.patch(ctx, graphqlUserID(ctx), id, patch, versions(args.Version))
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return r.h.logEntryResolver(logEntry), nil
+}
+
+func (r *graphqlResolver) DeleteLogEntry(ctx context.Context, args struct {
+	ID      graphql.ID
+	Version *int32
+}) (bool, error) {
+	id, err := graphqlID(args.ID, "log entry")
+	if err != nil {
+		return false, err
+	}
+	if err := r.h.logEntries.delete(ctx, graphqlUserID(ctx), id, versions(args.Version)); err != nil {
+		return false, graphqlError(err)
+	}
+	return true, nil
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func postGraphQL(t *testing.T, handler http.Handler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/graphql", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGraphQLRequiresAuth(t *testing.T) {
+	handler := NewGraphQLHandler(nil)
+	rec := postGraphQL(t, http.HandlerFunc(handler.Serve), `{"query": "{ me { id } }"}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", rec.Code)
+	}
+}
+
+func TestGraphQLRejectsInvalidBodies(t *testing.T) {
+	handler := signedIn(t, NewGraphQLHandler(nil).Serve)
+	for _, body := range []string{`not json`, `{"query": "  "}`} {
+		if rec := postGraphQL(t, handler, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+		}
+	}
+}
+
+// aliasedProjects selects the projects with their tasks n times over.
+func aliasedProjects(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "p%d: projects { tasks { id } } ", i)
+	}
+	return b.String()
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantData   bool
+		wantError  string
+		wantStatus float64
+	}{
+		{
+			name:      "depth limit",
+			body:      `{"query": "{ tasks { parent { parent { parent { parent { parent { parent { parent { parent { parent { id } } } } } } } } } } }"}`,
+			wantError: `Field "id" has depth 11 that exceeds max depth 10`,
+		},
+		{
+			name:      "complexity limit",
+			body:      `{"query": "{ projects { tasks { subtasks { logEntries { id content } } } } }"}`,
+			wantError: "over the limit of 5000",
+		},
+		{
+			name:      "complexity of aliases",
+			body:      `{"query": "{ ` + aliasedProjects(50) + ` }"}`,
+			wantError: "over the limit of 5000",
+		},
+		{
+			name:      "query length limit",
+			body:      `{"query": "{ me { id ` + strings.Repeat("name ", 2000) + `} }"}`,
+			wantError: "exceeds the maximum allowed query length of 10000 bytes",
+		},
+		{
+			name:       "malformed ID",
+			body:       `{"query": "query($id: ID!) { project(id: $id) { name } }", "variables": {"id": "42"}}`,
+			wantData:   true,
+			wantError:  "Invalid project ID format",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "bad task filter",
+			body:       `{"query": "{ tasks(due: \"someday\") { id } }"}`,
+			wantData:   true,
+			wantError:  "Invalid due filter, must be overdue or this_week",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "REST validation",
+			body:       `{"query": "mutation { createProject(input: {name: \"\"}) { id } }"}`,
+			wantData:   true,
+			wantError:  "Project name is required",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "input types",
+			body:      `{"query": "mutation { createLogEntry(input: {content: \"Shipped\", logDate: \"yesterday\"}) { id } }"}`,
+			wantData:  true,
+			wantError: "Date must be in YYYY-MM-DD format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postGraphQL(t, signedIn(t, NewGraphQLHandler(nil).Serve), tt.body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var response struct {
+				Data   json.RawMessage `json:"data"`
+				Errors []struct {
+					Message    string                 `json:"message"`
+					Extensions map[string]interface{} `json:"extensions"`
+				} `json:"errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if got := response.Data != nil; got != tt.wantData {
+				t.Errorf("Expected data %v, got %s", tt.wantData, response.Data)
+			}
+			if len(response.Errors) != 1 || !strings.Contains(response.Errors[0].Message, tt.wantError) {
+				t.Fatalf("Expected one error containing %q, got %+v", tt.wantError, response.Errors)
+			}
+			if tt.wantStatus != 0 && response.Errors[0].Extensions["status"] != tt.wantStatus {
+				t.Errorf("Expected status extension %v, got %v", tt.wantStatus, response.Errors[0].Extensions)
+			}
+		})
+	}
+}
+
+func TestGraphQLSchema(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewGraphQLHandler(nil).Schema(rec, httptest.NewRequest(http.MethodGet, "/api/graphql/schema", nil))
+
+	schema := rec.Body.String()
+	for _, want := range []string{
+		"type Query {",
+		"type Mutation {",
+		"  tasks: [Task!]!\n",
+		"  updateTask(id: ID!, input: UpdateTaskInput!, version: Int, force: Boolean = false): Task!\n",
+		"  deleteLogEntry(id: ID!, version: Int): Boolean!\n",
+		"scalar Date\n",
+	} {
+		if !strings.Contains(schema, want) {
+			t.Errorf("Expected schema to contain %q, got:\n%s", want, schema)
+		}
+	}
+}
+
+func TestGraphQLComplexity(t *testing.T) {
+	h := NewGraphQLHandler(nil)
+	for query, want := range map[string]int{
+		"{ me { id name } }":                                                     3,
+		"{ projects { id tasks { id } } }":                                       121,
+		"{ " + aliasedProjects(2) + "}":                                          222,
+		"mutation { deleteTask(id: \"x\") }":                                     1,
+		"{ tasks { ...fields } } fragment fields on Task { id subtasks { id } }": 121,
+		"{ tasks { ...loop } } fragment loop on Task { parent { ...loop } }":     11,
+		"{ not a query": 0,
+	} {
+		if got := h.complexity(query); got != want {
+			t.Errorf("complexity(%q) = %d, want %d", query, got, want)
+		}
+	}
+	if got := h.complexity("{ " + aliasedProjects(1000) + "}"); got <= graphqlMaxComplexity || got > 2*graphqlMaxComplexity {
+		t.Errorf("Expected the weight to stop a little past the limit, got %d", got)
+	}
+}
+
+func TestGraphQLLevelLoadsRelationOnce(t *testing.T) {
+	var level graphqlLevel
+	var fetches int32
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b", "a", "c"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, err := level.load("project", key, func() (map[string]interface{}, error) {
+				atomic.AddInt32(&fetches, 1)
+				return map[string]interface{}{"a": 1, "b": 2}, nil
+			})
+			if err != nil {
+				t.Errorf("load(%q) failed: %v", key, err)
+			}
+			if want := map[string]interface{}{"a": 1, "b": 2, "c": nil}[key]; found != want {
+				t.Errorf("load(%q) = %v, want %v", key, found, want)
+			}
+		}()
+	}
+	wg.Wait()
+	if fetches != 1 {
+		t.Errorf("Expected one fetch for the level, got %d", fetches)
+	}
+}
@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// graphqlLevel is the rows of one type that an operation resolves
+// together, such as the projects of a list or the tasks of all those
+// projects. Their fields resolve concurrently; the first of them to need a
+// relation loads it for all of them in one query, and the rest wait for
+// and share what it found.
+type graphqlLevel struct {
+	h     *GraphQLHandler
+	mu    sync.Mutex
+	loads map[string]*graphqlLoad
+}
+
+type graphqlLoad struct {
+	once  sync.Once
+	found map[string]interface{}
+	err   error
+}
+
+// load returns what the relation holds for key, calling fetch for the
+// whole level the first time the relation is needed.
+func (l *graphqlLevel) load(relation, key string, fetch func() (map[string]interface{}, error)) (interface{}, error) {
+	l.mu.Lock()
+	if l.loads == nil {
+		l.loads = map[string]*graphqlLoad{}
+	}
+	load := l.loads[relation]
+	if load == nil {
+		load = &graphqlLoad{}
+		l.loads[relation] = load
+	}
+	l.mu.Unlock()
+
+	load.once.Do(func() {
+		load.found, load.err = fetch()
+	})
+	return load.found[key], load.err
+}
+
+// user resolves the user who created a row of the level, given the
+// creators of all of them. It is null once they no longer share a
+// workspace or project.
+func (l *graphqlLevel) user(ctx context.Context, userID string, creators func() []string) (*userResolver, error) {
+	found, err := l.load("user", userID, func() (map[string]interface{}, error) {
+		users, err := l.h.queries.GetUsersByIDs(ctx, creators(), graphqlUserID(ctx))
+		if err != nil {
+			return nil, internalError("Failed to get users")
+		}
+		found := map[string]interface{}{}
+		for _, user := range users {
+			found[user.ID] = &userResolver{user: user}
+		}
+		return found, nil
+	})
+	if user, ok := found.(*userResolver); ok {
+		return user, err
+	}
+	return nil, err
+}
+
+// uniqueIDs returns the IDs key gives for n rows, leaving out repeats and
+// nil ones.
+func uniqueIDs(n int, key func(i int) *string) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for i := 0; i < n; i++ {
+		if id := key(i); id != nil && !seen[*id] {
+			seen[*id] = true
+			ids = append(ids, *id)
+		}
+	}
+	return ids
+}
+
+// loadProjects finds the projects with ids, as a level of their own.
+func (h *GraphQLHandler) loadProjects(ctx context.Context, ids []string) (map[string]interface{}, error) {
+	projects, err := h.queries.GetProjectsByIDs(ctx, ids, graphqlUserID(ctx))
+	if err != nil {
+		return nil, internalError("Failed to get projects")
+	}
+	found := map[string]interface{}{}
+	for _, project := range h.projectResolvers(projects) {
+		found[project.project.ID] = project
+	}
+	return found, nil
+}
+
+// loadTasks finds the tasks with ids, as a level of their own.
+func (h *GraphQLHandler) loadTasks(ctx context.Context, ids []string) (map[string]interface{}, error) {
+	tasks, err := h.queries.GetTasksByIDs(ctx, ids, graphqlUserID(ctx))
+	if err != nil {
+		return nil, internalError("Failed to get tasks")
+	}
+	found := map[string]interface{}{}
+	for _, task := range h.taskResolvers(tasks) {
+		found[task.task.ID] = task
+	}
+	return found, nil
+}
+
+// groupTasks files tasks, as a level of their own, under the ID owner
+// gives, with an empty list for every ID that has none.
+func (h *GraphQLHandler) groupTasks(ids []string, tasks []models.Task, owner func(models.Task) *string) map[string]interface{} {
+	groups := map[string][]*taskResolver{}
+	for _, task := range h.taskResolvers(tasks) {
+		if id := owner(task.task); id != nil {
+			groups[*id] = append(groups[*id], task)
+		}
+	}
+	found := map[string]interface{}{}
+	for _, id := range ids {
+		found[id] = append([]*taskResolver{}, groups[id]...)
+	}
+	return found
+}
+
+// groupLogEntries is groupTasks for log entries.
+func (h *GraphQLHandler) groupLogEntries(ids []string, logEntries []models.LogEntry, owner func(models.LogEntry) *string) map[string]interface{} {
+	groups := map[string][]*logEntryResolver{}
+	for _, logEntry := range h.logEntryResolvers(logEntries) {
+		if id := owner(logEntry.logEntry); id != nil {
+			groups[*id] = append(groups[*id], logEntry)
+		}
+	}
+	found := map[string]interface{}{}
+	for _, id := range ids {
+		found[id] = append([]*logEntryResolver{}, groups[id]...)
+	}
+	return found
+}
+
+func nullableID(id *string) *graphql.ID {
+	if id == nil {
+		return nil
+	}
+	gid := graphql.ID(*id)
+	return &gid
+}
+
+func nullableTime(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
+
+func nullableInt(n *int) *int32 {
+	if n == nil {
+		return nil
+	}
+	i := int32(*n)
+	return &i
+}
+
+type userResolver struct {
+	user models.User
+}
+
+func (r *userResolver) ID() graphql.ID          { return graphql.ID(r.user.ID) }
+func (r *userResolver) Email() string           { return r.user.Email }
+func (r *userResolver) Name() string            { return r.user.Name }
+func (r *userResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.user.CreatedAt} }
+func (r *userResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.user.UpdatedAt} }
+
+type projectLevel struct {
+	graphqlLevel
+	projects []models.Project
+}
+
+type projectResolver struct {
+	project models.Project
+	level   *projectLevel
+}
+
+func (h *GraphQLHandler) projectResolvers(projects []models.Project) []*projectResolver {
+	level := &projectLevel{graphqlLevel: graphqlLevel{h: h}, projects: projects}
+	resolvers := make([]*projectResolver, len(projects))
+	for i, project := range projects {
+		resolvers[i] = &projectResolver{project: project, level: level}
+	}
+	return resolvers
+}
+
+// projectResolver resolves a project read or written on its own, or null
+// for none.
+func (h *GraphQLHandler) projectResolver(project *models.Project) *projectResolver {
+	if project == nil {
+		return nil
+	}
+	return h.projectResolvers([]models.Project{*project})[0]
+}
+
+func (l *projectLevel) ids(key func(models.Project) *string) []string {
+	return uniqueIDs(len(l.projects), func(i int) *string { return key(l.projects[i]) })
+}
+
+func (r *projectResolver) ID() graphql.ID            { return graphql.ID(r.project.ID) }
+func (r *projectResolver) WorkspaceID() graphql.ID   { return graphql.ID(r.project.WorkspaceID) }
+func (r *projectResolver) Name() string              { return r.project.Name }
+func (r *projectResolver) Description() string       { return r.project.Description }
+func (r *projectResolver) ClientID() *graphql.ID     { return nullableID(r.project.ClientID) }
+func (r *projectResolver) Currency() string          { return r.project.Currency }
+func (r *projectResolver) ArchivedAt() *graphql.Time { return nullableTime(r.project.ArchivedAt) }
+func (r *projectResolver) Version() int32            { return int32(r.project.Version) }
+func (r *projectResolver) CreatedAt() graphql.Time   { return graphql.Time{Time: r.project.CreatedAt} }
+func (r *projectResolver) UpdatedAt() graphql.Time   { return graphql.Time{Time: r.project.UpdatedAt} }
+
+func (r *projectResolver) HourlyRateCents() *int32 {
+	if r.project.HourlyRateCents == nil {
+		return nil
+	}
+	cents := int32(*r.project.HourlyRateCents)
+	return &cents
+}
+
+func (r *projectResolver) User(ctx context.Context) (*userResolver, error) {
+	return r.level.user(ctx, r.project.UserID, func() []string {
+		return r.level.ids(func(project models.Project) *string { return &project.UserID })
+	})
+}
+
+func (r *projectResolver) Tasks(ctx context.Context) ([]*taskResolver, error) {
+	found, err := r.level.load("tasks", r.project.ID, func() (map[string]interface{}, error) {
+		ids := r.level.ids(func(project models.Project) *string { return &project.ID })
+		tasks, err := r.level.h.queries.ListTasksByProjectIDs(ctx, ids, graphqlUserID(ctx))
+		if err != nil {
+			return nil, internalError("Failed to get tasks")
+		}
+		return r.level.h.groupTasks(ids, tasks, func(task models.Task) *string { return &task.ProjectID }), nil
+	})
+	if tasks, ok := found.([]*taskResolver); ok {
+		return tasks, err
+	}
+	return nil, err
+}
+
+func (r *projectResolver) LogEntries(ctx context.Context) ([]*logEntryResolver, error) {
+	found, err := r.level.load("logEntries", r.project.ID, func() (map[string]interface{}, error) {
+		ids := r.level.ids(func(project models.Project) *string { return &project.ID })
+		logEntries, err := r.level.h.queries.ListLogEntriesByProjectIDs(ctx, ids, graphqlUserID(ctx))
+		if err != nil {
+			return nil, internalError("Failed to get log entries")
+		}
+		return r.level.h.groupLogEntries(ids, logEntries, func(logEntry models.LogEntry) *string { return logEntry.ProjectID }), nil
+	})
+	if logEntries, ok := found.([]*logEntryResolver); ok {
+		return logEntries, err
+	}
+	return nil, err
+}
+
+type taskLevel struct {
+	graphqlLevel
+	tasks []models.Task
+}
+
+type taskResolver struct {
+	task  models.Task
+	level *taskLevel
+}
+
+func (h *GraphQLHandler) taskResolvers(tasks []models.Task) []*taskResolver {
+	level := &taskLevel{graphqlLevel: graphqlLevel{h: h}, tasks: tasks}
+	resolvers := make([]*taskResolver, len(tasks))
+	for i, task := range tasks {
+		resolvers[i] = &taskResolver{task: task, level: level}
+	}
+	return resolvers
+}
+
+// taskResolver resolves a task read or written on its own, or null for
+// none.
+func (h *GraphQLHandler) taskResolver(task *models.Task) *taskResolver {
+	if task == nil {
+		return nil
+	}
+	return h.taskResolvers([]models.Task{*task})[0]
+}
+
+func (l *taskLevel) ids(key func(models.Task) *string) []string {
+	return uniqueIDs(len(l.tasks), func(i int) *string { return key(l.tasks[i]) })
+}
+
+func (r *taskResolver) ID() graphql.ID             { return graphql.ID(r.task.ID) }
+func (r *taskResolver) ProjectID() graphql.ID      { return graphql.ID(r.task.ProjectID) }
+func (r *taskResolver) ParentTaskID() *graphql.ID  { return nullableID(r.task.ParentTaskID) }
+func (r *taskResolver) Title() string              { return r.task.Title }
+func (r *taskResolver) Description() string        { return r.task.Description }
+func (r *taskResolver) Status() string             { return r.task.Status }
+func (r *taskResolver) Priority() string           { return r.task.Priority }
+func (r *taskResolver) Position() string           { return r.task.Position }
+func (r *taskResolver) CompletedAt() *graphql.Time { return nullableTime(r.task.CompletedAt) }
+func (r *taskResolver) RecurrenceRule() *string    { return r.task.RecurrenceRule }
+func (r *taskResolver) Version() int32             { return int32(r.task.Version) }
+func (r *taskResolver) CreatedAt() graphql.Time    { return graphql.Time{Time: r.task.CreatedAt} }
+func (r *taskResolver) UpdatedAt() graphql.Time    { return graphql.Time{Time: r.task.UpdatedAt} }
+
+func (r *taskResolver) DueDate() *date {
+	if r.task.DueDate == nil {
+		return nil
+	}
+	dueDate := newDate(*r.task.DueDate)
+	return &dueDate
+}
+
+func (r *taskResolver) User(ctx context.Context) (*userResolver, error) {
+	return r.level.user(ctx, r.task.UserID, func() []string {
+		return r.level.ids(func(task models.Task) *string { return &task.UserID })
+	})
+}
+
+func (r *taskResolver) Project(ctx context.Context) (*projectResolver, error) {
+	found, err := r.level.load("project", r.task.ProjectID, func() (map[string]interface{}, error) {
+		return r.level.h.loadProjects(ctx, r.level.ids(func(task models.Task) *string { return &task.ProjectID }))
+	})
+	if project, ok := found.(*projectResolver); ok {
+		return project, err
+	}
+	return nil, err
+}
+
+func (r *taskResolver) Parent(ctx context.Context) (*taskResolver, error) {
+	if r.task.ParentTaskID == nil {
+		return nil, nil
+	}
+	found, err := r.level.load("parent", *r.task.ParentTaskID, func() (map[string]interface{}, error) {
+		return r.level.h.loadTasks(ctx, r.level.ids(func(task models.Task) *string { return task.ParentTaskID }))
+	})
+	if parent, ok := found.(*taskResolver); ok {
+		return parent, err
+	}
+	return nil, err
+}
+
+func (r *taskResolver) Subtasks(ctx context.Context) ([]*taskResolver, error) {
+	found, err := r.level.load("subtasks", r.task.ID, func() (map[string]interface{}, error) {
+		ids := r.level.ids(func(task models.Task) *string { return &task.ID })
+		tasks, err := r.level.h.queries.ListTasksByParentIDs(ctx, ids, graphqlUserID(ctx))
+		if err != nil {
+			return nil, internalError("Failed to get subtasks")
+		}
+		return r.level.h.groupTasks(ids, tasks, func(task models.Task) *string { return task.ParentTaskID }), nil
+	})
+	if subtasks, ok := found.([]*taskResolver); ok {
+		return subtasks, err
+	}
+	return nil, err
+}
+
+func (r *taskResolver) LogEntries(ctx context.Context) ([]*logEntryResolver, error) {
+	found, err := r.level.load("logEntries", r.task.ID, func() (map[string]interface{}, error) {
+		ids := r.level.ids(func(task models.Task) *string { return &task.ID })
+		logEntries, err := r.level.h.queries.ListLogEntriesByTaskIDs(ctx, ids, graphqlUserID(ctx))
+		if err != nil {
+			return nil, internalError("Failed to get log entries")
+		}
+		return r.level.h.groupLogEntries(ids, logEntries, func(logEntry models.LogEntry) *string { return logEntry.TaskID }), nil
+	})
+	if logEntries, ok := found.([]*logEntryResolver); ok {
+		return logEntries, err
+	}
+	return nil, err
+}
+
+type logEntryLevel struct {
+	graphqlLevel
+	logEntries []models.LogEntry
+}
+
+type logEntryResolver struct {
+	logEntry models.LogEntry
+	level    *logEntryLevel
+}
+
+func (h *GraphQLHandler) logEntryResolvers(logEntries []models.LogEntry) []*logEntryResolver {
+	level := &logEntryLevel{graphqlLevel: graphqlLevel{h: h}, logEntries: logEntries}
+	resolvers := make([]*logEntryResolver, len(logEntries))
+	for i, logEntry := range logEntries {
+		resolvers[i] = &logEntryResolver{logEntry: logEntry, level: level}
+	}
+	return resolvers
+}
+
+// logEntryResolver resolves a log entry read or written on its own, or
+// null for none.
+func (h *GraphQLHandler) logEntryResolver(logEntry *models.LogEntry) *logEntryResolver {
+	if logEntry == nil {
+		return nil
+	}
+	return h.logEntryResolvers([]models.LogEntry{*logEntry})[0]
+}
+
+func (l *logEntryLevel) ids(key func(models.LogEntry) *string) []string {
+	return uniqueIDs(len(l.logEntries), func(i int) *string { return key(l.logEntries[i]) })
+}
+
+func (r *logEntryResolver) ID() graphql.ID          { return graphql.ID(r.logEntry.ID) }
+func (r *logEntryResolver) TaskID() *graphql.ID     { return nullableID(r.logEntry.TaskID) }
+func (r *logEntryResolver) ProjectID() *graphql.ID  { return nullableID(r.logEntry.ProjectID) }
+func (r *logEntryResolver) Content() string         { return r.logEntry.Content }
+func (r *logEntryResolver) LogDate() date           { return newDate(r.logEntry.LogDate) }
+func (r *logEntryResolver) DurationMinutes() *int32 { return nullableInt(r.logEntry.DurationMinutes) }
+func (r *logEntryResolver) Billable() bool          { return r.logEntry.Billable }
+func (r *logEntryResolver) CommentCount() *int32    { return nullableInt(r.logEntry.CommentCount) }
+func (r *logEntryResolver) Version() int32          { return int32(r.logEntry.Version) }
+func (r *logEntryResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.logEntry.CreatedAt} }
+func (r *logEntryResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.logEntry.UpdatedAt} }
+
+func (r *logEntryResolver) User(ctx context.Context) (*userResolver, error) {
+	return r.level.user(ctx, r.logEntry.UserID, func() []string {
+		return r.level.ids(func(logEntry models.LogEntry) *string { return &logEntry.UserID })
+	})
+}
+
+func (r *logEntryResolver) Project(ctx context.Context) (*projectResolver, error) {
+	if r.logEntry.ProjectID == nil {
+		return nil, nil
+	}
+	found, err := r.level.load("project", *r.logEntry.ProjectID, func() (map[string]interface{}, error) {
+		return r.level.h.loadProjects(ctx, r.level.ids(func(logEntry models.LogEntry) *string { return logEntry.ProjectID }))
+	})
+	if project, ok := found.(*projectResolver); ok {
+		return project, err
+	}
+	return nil, err
+}
+
+func (r *logEntryResolver) Task(ctx context.Context) (*taskResolver, error) {
+	if r.logEntry.TaskID == nil {
+		return nil, nil
+	}
+	found, err := r.level.load("task", *r.logEntry.TaskID, func() (map[string]interface{}, error) {
+		return r.level.h.loadTasks(ctx, r.level.ids(func(logEntry models.LogEntry) *string { return logEntry.TaskID }))
+	})
+	if task, ok := found.(*taskResolver); ok {
+		return task, err
+	}
+	return nil, err
+}
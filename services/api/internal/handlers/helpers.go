@@ -1,9 +1,21 @@
 package handlers
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/google/uuid"
 )
 
 // writeJSON encodes the given data as JSON and writes it to the response writer
@@ -14,3 +26,262 @@ func writeJSON(w http.ResponseWriter, data interface{}) {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
+
+// setNoStore marks a response that holds a secret, such as a new token, so
+// that neither HTTP caches nor the Idempotency middleware keep it.
+func setNoStore(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// writeText writes a non-JSON body with its content type.
+func writeText(w http.ResponseWriter, contentType, body string) {
+	w.Header().Set("Content-Type", contentType)
+	if _, err := io.WriteString(w, body); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
+
+// checkWritable refuses a change unless the user holds at least role in the
+// workspace of each given project and none of them is archived. Nil project
+// IDs, and projects the user cannot see, are let through for the caller to
+// report as not found. It writes the error response and returns false when
+// the change must not go ahead.
+func checkWritable(ctx context.Context, w http.ResponseWriter, queries *database.Queries, userID, role string, projectIDs ...*string) bool {
+	return checkProjectRole(ctx, w, queries, userID, role, false, projectIDs...)
+}
+
+// checkProjectRole is checkWritable for changes that are allowed on archived
+// projects when allowArchived is set, such as unarchiving one.
+func checkProjectRole(ctx context.Context, w http.ResponseWriter, queries *database.Queries, userID, role string, allowArchived bool, projectIDs ...*string) bool {
+	if err := requireProjectRole(ctx, queries, userID, role, allowArchived, projectIDs...); err != nil {
+		writeError(w, err)
+		return false
+	}
+	return true
+}
+
+// requireWritable is checkWritable returning the error instead of writing
+// it.
+func requireWritable(ctx context.Context, queries *database.Queries, userID, role string, projectIDs ...*string) error {
+	return requireProjectRole(ctx, queries, userID, role, false, projectIDs...)
+}
+
+// requireProjectRole is checkProjectRole returning the error instead of
+// writing it.
+func requireProjectRole(ctx context.Context, queries *database.Queries, userID, role string, allowArchived bool, projectIDs ...*string) error {
+	for _, projectID := range projectIDs {
+		if projectID == nil {
+			continue
+		}
+		access, err := queries.GetProjectAccess(ctx, *projectID, userID)
+		if err != nil {
+			return internalError("Failed to get project")
+		}
+		if access == nil {
+			continue
+		}
+		if !models.RoleAtLeast(access.Role, role) {
+			return statusError{status: http.StatusForbidden, message: "Your workspace role does not allow this"}
+		}
+		if access.ArchivedAt != nil && !allowArchived {
+			return conflict("Project is archived; unarchive it to make changes")
+		}
+	}
+	return nil
+}
+
+// checkWorkspaceRole loads the workspace and refuses the request unless the
+// user holds at least role in it. It writes the error response and returns
+// nil when the request must not go ahead.
+func checkWorkspaceRole(ctx context.Context, w http.ResponseWriter, queries *database.Queries, workspaceID, userID, role string) *models.Workspace {
+	workspace, err := requireWorkspaceRole(ctx, queries, workspaceID, userID, role)
+	if err != nil {
+		writeError(w, err)
+		return nil
+	}
+	return workspace
+}
+
+// requireWorkspaceRole is checkWorkspaceRole returning the error instead of
+// writing it.
+func requireWorkspaceRole(ctx context.Context, queries *database.Queries, workspaceID, userID, role string) (*models.Workspace, error) {
+	workspace, err := queries.GetWorkspace(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, internalError("Failed to get workspace")
+	}
+	if workspace == nil {
+		return nil, notFound("Workspace not found")
+	}
+	if !models.RoleAtLeast(workspace.Role, role) {
+		return nil, statusError{status: http.StatusForbidden, message: "Your workspace role does not allow this"}
+	}
+	return workspace, nil
+}
+
+// checkProjectMember refuses the request unless the user holds at least role
+// in the project, through its workspace or the project itself. Projects in
+// the trash count as not found. It writes the error response and returns nil
+// when the request must not go ahead.
+func checkProjectMember(ctx context.Context, w http.ResponseWriter, queries *database.Queries, projectID, userID, role string) *database.ProjectAccess {
+	access, err := queries.GetProjectAccess(ctx, projectID, userID)
+	if err != nil {
+		http.Error(w, "Failed to get project", http.StatusInternalServerError)
+		return nil
+	}
+	if access == nil || access.DeletedAt != nil {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return nil
+	}
+	if !models.RoleAtLeast(access.Role, role) {
+		http.Error(w, "Your workspace role does not allow this", http.StatusForbidden)
+		return nil
+	}
+	return access
+}
+
+// statusError is an error with the HTTP status to answer it with. The
+// functions that create, change and delete items return it, so the REST
+// handlers, batches, sync and GraphQL all report failures alike.
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e statusError) Error() string {
+	return e.message
+}
+
+func badRequest(message string) error {
+	return statusError{status: http.StatusBadRequest, message: message}
+}
+
+func notFound(message string) error {
+	return statusError{status: http.StatusNotFound, message: message}
+}
+
+func conflict(message string) error {
+	return statusError{status: http.StatusConflict, message: message}
+}
+
+func internalError(message string) error {
+	return statusError{status: http.StatusInternalServerError, message: message}
+}
+
+// errPreconditionFailed answers a conditional write to a row that has
+// changed since the client read it.
+var errPreconditionFailed error = statusError{status: http.StatusPreconditionFailed, message: "Precondition failed"}
+
+// errorStatus returns the status and message to answer err with. Besides
+// statusError it knows validationError and transitionError; anything else
+// is an internal error, logged rather than shown.
+func errorStatus(err error) (int, string) {
+	var status statusError
+	var transition transitionError
+	var invalid validationError
+	switch {
+	case errors.As(err, &status):
+		return status.status, status.message
+	case errors.As(err, &transition):
+		return http.StatusConflict, transition.Error()
+	case errors.As(err, &invalid):
+		return http.StatusBadRequest, invalid.Error()
+	}
+	log.Printf("Unexpected error: %v", err)
+	return http.StatusInternalServerError, "Internal server error"
+}
+
+// writeError responds with the status and message errorStatus gives err.
+func writeError(w http.ResponseWriter, err error) {
+	status, message := errorStatus(err)
+	http.Error(w, message, status)
+}
+
+// sideEffect runs apply, a write that follows a change but must not undo it
+// when it fails, such as a notification. It runs in a transaction of its
+// own, or in a savepoint when queries already run in one, so a failed
+// statement is rolled back and only logged instead of aborting the
+// transaction of a batch. what describes the write for the log.
+func sideEffect(ctx context.Context, queries *database.Queries, what string, apply func(queries *database.Queries) error) {
+	tx, err := queries.Begin(ctx)
+	if err != nil {
+		log.Printf("Failed to %s: %v", what, err)
+		return
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			_ = err
+		}
+	}()
+	if err := apply(queries.WithTx(tx)); err != nil {
+		log.Printf("Failed to %s: %v", what, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Failed to %s: %v", what, err)
+	}
+}
+
+// forced reports whether the request passes ?force=true, which lets a task
+// with open blockers be completed.
+func forced(r *http.Request) bool {
+	return r.URL.Query().Get("force") == "true"
+}
+
+// validationError carries a client-facing message for a rejected request.
+type validationError string
+
+func (e validationError) Error() string {
+	return string(e)
+}
+
+// patchRequired reads a merge patch member for a column that can be changed
+// but not cleared. It returns nil when the member was omitted.
+func patchRequired(f models.PatchField, field string) (*string, error) {
+	if !f.Present {
+		return nil, nil
+	}
+	if f.Null || f.Value == "" {
+		return nil, validationError(field + " cannot be empty")
+	}
+	return &f.Value, nil
+}
+
+// patchText reads a merge patch member for a free-text column, where null
+// clears the text. It returns nil when the member was omitted.
+func patchText(f models.PatchField) *string {
+	if !f.Present {
+		return nil
+	}
+	return &f.Value
+}
+
+// patchUUID reads a merge patch member for a nullable reference. The first
+// result reports whether the member was present; a nil ID clears the link.
+func patchUUID(f models.PatchField, field string) (bool, *string, error) {
+	if !f.Present {
+		return false, nil, nil
+	}
+	if f.Null {
+		return true, nil, nil
+	}
+	if _, err := uuid.Parse(f.Value); err != nil {
+		return false, nil, validationError("Invalid " + field + " format")
+	}
+	return true, &f.Value, nil
+}
+
+// newSecretToken returns a random token for a secret link, such as an
+// invitation or a calendar feed, and the hash stored in its place.
+func newSecretToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashSecretToken(token), nil
+}
+
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/mail"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// InvitationHandler invites people to workspaces and projects by link or
+// email.
+type InvitationHandler struct {
+	queries *database.Queries
+	mailer  mail.Mailer
+	baseURL string
+	ttl     time.Duration
+}
+
+// NewInvitationHandler returns a handler that emails invitation links under
+// baseURL, the web app's address, valid for ttl.
+func NewInvitationHandler(queries *database.Queries, mailer mail.Mailer, baseURL string, ttl time.Duration) *InvitationHandler {
+	return &InvitationHandler{queries: queries, mailer: mailer, baseURL: strings.TrimSuffix(baseURL, "/"), ttl: ttl}
+}
+
+// CreateForWorkspace invites someone to the workspace. Admins may invite with
+// any role up to their own.
+func (h *InvitationHandler) CreateForWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid workspace ID format", http.StatusBadRequest)
+		return
+	}
+
+	req, ok := decodeInvitationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	workspace := checkWorkspaceRole(r.Context(), w, h.queries, id, userID, models.RoleAdmin)
+	if workspace == nil {
+		return
+	}
+	if workspace.Personal {
+		http.Error(w, "Personal workspaces cannot be shared", http.StatusConflict)
+		return
+	}
+	if !canManageMember(workspace.Role, models.RoleViewer, req.Role) {
+		http.Error(w, "Your workspace role does not allow this", http.StatusForbidden)
+		return
+	}
+
+	h.create(r.Context(), w, userID, &id, nil, workspace.Name, req)
+}
+
+// CreateForProject invites someone to the project alone. Owner is not a
+// project role.
+func (h *InvitationHandler) CreateForProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	req, ok := decodeInvitationRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Role == models.RoleOwner {
+		http.Error(w, "Invalid role, must be admin, member or viewer", http.StatusBadRequest)
+		return
+	}
+
+	access := checkProjectMember(r.Context(), w, h.queries, id, userID, models.RoleAdmin)
+	if access == nil {
+		return
+	}
+	if !canManageMember(access.Role, models.RoleViewer, req.Role) {
+		http.Error(w, "Your workspace role does not allow this", http.StatusForbidden)
+		return
+	}
+
+	project, err := h.queries.GetProject(r.Context(), id, userID)
+	if err != nil || project == nil {
+		http.Error(w, "Failed to get project", http.StatusInternalServerError)
+		return
+	}
+
+	h.create(r.Context(), w, userID, nil, &id, project.Name, req)
+}
+
+// decodeInvitationRequest reads and validates an invitation, defaulting the
+// role to member.
+func decodeInvitationRequest(w http.ResponseWriter, r *http.Request) (models.CreateInvitationRequest, bool) {
+	var req models.CreateInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+	if req.Email != nil {
+		address, err := netmail.ParseAddress(strings.TrimSpace(*req.Email))
+		if err != nil {
+			http.Error(w, "Invalid email", http.StatusBadRequest)
+			return req, false
+		}
+		req.Email = &address.Address
+	}
+	if req.Role == "" {
+		req.Role = models.RoleMember
+	}
+	if !models.ValidRole(req.Role) {
+		http.Error(w, "Invalid role, must be owner, admin, member or viewer", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// create stores the invitation and emails its link if it names an email
+// address. The link is returned either way, so a failed email is only
+// logged.
+func (h *InvitationHandler) create(ctx context.Context, w http.ResponseWriter, userID string, workspaceID, projectID *string, targetName string, req models.CreateInvitationRequest) {
+	token, hash, err := newSecretToken()
+	if err != nil {
+		http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+
+	invitation, err := h.queries.CreateInvitation(ctx, workspaceID, projectID, req.Email, req.Role, hash, userID, time.Now().Add(h.ttl))
+	if err != nil {
+		http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+	invitation.Token = token
+	invitation.URL = h.baseURL + "/invite?token=" + url.QueryEscape(token)
+
+	if invitation.Email != nil {
+		inviter, err := h.queries.GetUserByID(ctx, userID)
+		if err != nil || inviter == nil {
+			log.Printf("Failed to get inviter %s: %v", userID, err)
+		} else if err := h.mailer.Send(invitationMessage(*invitation.Email, inviter.Name, targetName, invitation)); err != nil {
+			log.Printf("Failed to email invitation %s: %v", invitation.ID, err)
+		}
+	}
+
+	setNoStore(w)
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, invitation)
+}
+
+func invitationMessage(to, inviterName, targetName string, invitation *models.Invitation) mail.Message {
+	return mail.Message{
+		To:      to,
+		Subject: fmt.Sprintf("%s invited you to %s on Maker Log", inviterName, targetName),
+		Text: fmt.Sprintf("%s invited you to join %s on Maker Log with the %s role.\n\nAccept the invitation:\n%s\n\nThe link expires on %s.\n",
+			inviterName, targetName, invitation.Role, invitation.URL, invitation.ExpiresAt.UTC().Format("January 2, 2006")),
+	}
+}
+
+// ListForWorkspace returns the workspace's pending invitations.
+func (h *InvitationHandler) ListForWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid workspace ID format", http.StatusBadRequest)
+		return
+	}
+
+	if checkWorkspaceRole(r.Context(), w, h.queries, id, userID, models.RoleAdmin) == nil {
+		return
+	}
+
+	h.list(r.Context(), w, "workspace_id", id)
+}
+
+// ListForProject returns the project's pending invitations.
+func (h *InvitationHandler) ListForProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	if checkProjectMember(r.Context(), w, h.queries, id, userID, models.RoleAdmin) == nil {
+		return
+	}
+
+	h.list(r.Context(), w, "project_id", id)
+}
+
+func (h *InvitationHandler) list(ctx context.Context, w http.ResponseWriter, column, id string) {
+	invitations, err := h.queries.ListPendingInvitations(ctx, column, id)
+	if err != nil {
+		http.Error(w, "Failed to list invitations", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, invitations)
+}
+
+// Revoke deletes a pending invitation so its link stops working.
+func (h *InvitationHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid invitation ID format", http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := h.queries.GetInvitation(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to get invitation", http.StatusInternalServerError)
+		return
+	}
+	if invitation == nil {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+	if invitation.WorkspaceID != nil {
+		if checkWorkspaceRole(r.Context(), w, h.queries, *invitation.WorkspaceID, userID, models.RoleAdmin) == nil {
+			return
+		}
+	} else if checkProjectMember(r.Context(), w, h.queries, *invitation.ProjectID, userID, models.RoleAdmin) == nil {
+		return
+	}
+
+	err = h.queries.RevokeInvitation(r.Context(), id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Invitation was already accepted", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to revoke invitation", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Accept gives the logged-in user the role the invitation offers. New users
+// accept by registering with the token instead.
+func (h *InvitationHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := h.queries.AcceptInvitation(r.Context(), hashSecretToken(req.Token), userID)
+	if err != nil {
+		http.Error(w, "Failed to accept invitation", http.StatusInternalServerError)
+		return
+	}
+	if invitation == nil {
+		http.Error(w, "Invitation is invalid, used or expired", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, invitation)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+func TestNewSecretToken(t *testing.T) {
+	token, hash, err := newSecretToken()
+	if err != nil {
+		t.Fatalf("newSecretToken: %v", err)
+	}
+	if len(token) != 43 {
+		t.Errorf("Expected a 43 character token, got %q", token)
+	}
+	if hash != hashSecretToken(token) {
+		t.Error("Expected the hash of the token")
+	}
+	if strings.Contains(hash, token) || len(hash) != 64 {
+		t.Errorf("Expected a hex SHA-256 hash, got %q", hash)
+	}
+
+	other, _, err := newSecretToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == token {
+		t.Error("Expected tokens to differ")
+	}
+}
+
+func TestDecodeInvitationRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantRole string
+		wantCode int
+	}{
+		{name: "link only", body: `{}`, wantRole: models.RoleMember},
+		{name: "email and role", body: `{"email":" ada@example.com ","role":"viewer"}`, wantRole: models.RoleViewer},
+		{name: "display name", body: `{"email":"Ada <ada@example.com>"}`, wantRole: models.RoleMember},
+		{name: "bad email", body: `{"email":"ada"}`, wantCode: http.StatusBadRequest},
+		{name: "injected header", body: `{"email":"ada@example.com\r\nBcc: eve@example.com"}`, wantCode: http.StatusBadRequest},
+		{name: "bad role", body: `{"role":"superuser"}`, wantCode: http.StatusBadRequest},
+		{name: "bad body", body: `{`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/api/workspaces/x/invitations", strings.NewReader(tt.body))
+
+			req, ok := decodeInvitationRequest(w, r)
+			if tt.wantCode != 0 {
+				if ok || w.Code != tt.wantCode {
+					t.Errorf("Expected %d, got ok=%v code=%d", tt.wantCode, ok, w.Code)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("Expected the request to be accepted, got %d: %s", w.Code, w.Body.String())
+			}
+			if req.Role != tt.wantRole {
+				t.Errorf("Expected role %s, got %s", tt.wantRole, req.Role)
+			}
+			if req.Email != nil && *req.Email != "ada@example.com" {
+				t.Errorf("Expected the bare address, got %q", *req.Email)
+			}
+		})
+	}
+}
+
+func TestInvitationMessage(t *testing.T) {
+	invitation := &models.Invitation{
+		Role:      models.RoleAdmin,
+		URL:       "http://localhost:3000/invite?token=abc",
+		ExpiresAt: time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC),
+	}
+
+	msg := invitationMessage("ada@example.com", "Grace", "Compilers", invitation)
+
+	if msg.To != "ada@example.com" {
+		t.Errorf("Expected the invitee as recipient, got %s", msg.To)
+	}
+	if msg.Subject != "Grace invited you to Compilers on Maker Log" {
+		t.Errorf("Unexpected subject %q", msg.Subject)
+	}
+	for _, want := range []string{"admin role", invitation.URL, "March 8, 2024"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("Expected the body to mention %q, got %q", want, msg.Text)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+// item is a project, task or log entry as batches and sync handle
+// them: the record itself, and what conflict checks need to know of it.
+type item struct {
+	record    interface{}
+	version   int64
+	updatedAt time.Time
+}
+
+// itemStore reads and writes one type of item with the same checks as the
+// REST API. Fields come as the JSON of a REST request body; versions and
+// force stand in for a request's If-Match header and ?force=true.
+type itemStore interface {
+	getItem(ctx context.Context, userID, id string) (*item, error)
+	createItem(ctx context.Context, userID string, data []byte) (*item, error)
+	patchItem(ctx context.Context, userID, id string, data []byte, versions []int64, force bool) (*item, error)
+	deleteItem(ctx context.Context, userID, id string, versions []int64) error
+}
+
+// itemStores returns the store for each type of item, running on queries.
+func itemStores(queries *database.Queries) map[string]itemStore {
+	return map[string]itemStore{
+		models.SyncProject:  NewProjectHandler(queries),
+		models.SyncTask:     NewTaskHandler(queries),
+		models.SyncLogEntry: NewLogEntryHandler(queries),
+	}
+}
+
+// decodeData decodes the fields of a create or patch into req.
+func decodeData(data []byte, req interface{}) error {
+	if err := json.Unmarshal(data, req); err != nil {
+		return validationError("Invalid request body")
+	}
+	return nil
+}
+
+// recordJSON encodes an item's record for a batch or sync result.
+func recordJSON(written *item) json.RawMessage {
+	data, err := json.Marshal(written.record)
+	if err != nil {
+		log.Printf("Error encoding record: %v", err)
+		return nil
+	}
+	return data
+}
+
+// hasStatus reports whether err is a statusError with status.
+func hasStatus(err error, status int) bool {
+	var e statusError
+	return errors.As(err, &e) && e.status == status
+}
+
+func projectItem(project *models.Project, err error) (*item, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &item{record: project, version: project.Version, updatedAt: project.UpdatedAt}, nil
+}
+
+func (h *ProjectHandler) getItem(ctx context.Context, userID, id string) (*item, error) {
+	return projectItem(h.get(ctx, userID, id))
+}
+
+func (h *ProjectHandler) createItem(ctx context.Context, userID string, data []byte) (*item, error) {
+	var req models.CreateProjectRequest
+	if err := decodeData(data, &req); err != nil {
+		return nil, err
+	}
+	return projectItem(h.create(ctx, userID, req))
+}
+
+func (h *ProjectHandler) patchItem(ctx context.Context, userID, id string, data []byte, versions []int64, force bool) (*item, error) {
+	var req models.PatchProjectRequest
+	if err := decodeData(data, &req); err != nil {
+		return nil, err
+	}
+	patch, err := projectPatchFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return projectItem(h.patch(ctx, userID, id, patch, versions))
+}
+
+func (h *ProjectHandler) deleteItem(ctx context.Context, userID, id string, versions []int64) error {
+	return h.delete(ctx, userID, id, versions)
+}
+
+func taskItem(task *models.Task, err error) (*item, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &item{record: task, version: task.Version, updatedAt: task.UpdatedAt}, nil
+}
+
+func (h *TaskHandler) getItem(ctx context.Context, userID, id string) (*item, error) {
+	return taskItem(h.get(ctx, userID, id))
+}
+
+func (h *TaskHandler) createItem(ctx context.Context, userID string, data []byte) (*item, error) {
+	var req models.CreateTaskRequest
+	if err := decodeData(data, &req); err != nil {
+		return nil, err
+	}
+	return taskItem(h.create(ctx, userID, req))
+}
+
+func (h *TaskHandler) patchItem(ctx context.Context, userID, id string, data []byte, versions []int64, force bool) (*item, error) {
+	var req models.PatchTaskRequest
+	if err := decodeData(data, &req); err != nil {
+		return nil, err
+	}
+	patch, err := taskPatchFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return taskItem(h.patch(ctx, userID, id, patch, versions, force))
+}
+
+func (h *TaskHandler) deleteItem(ctx context.Context, userID, id string, versions []int64) error {
+	return h.delete(ctx, userID, id, versions)
+}
+
+func logEntryItem(logEntry *models.LogEntry, err error) (*item, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &item{record: logEntry, version: logEntry.Version, updatedAt: logEntry.UpdatedAt}, nil
+}
+
+func (h *LogEntryHandler) getItem(ctx context.Context, userID, id string) (*item, error) {
+	return logEntryItem(h.get(ctx, userID, id))
+}
+
+func (h *LogEntryHandler) createItem(ctx context.Context, userID string, data []byte) (*item, error) {
+	var req models.CreateLogEntryRequest
+	if err := decodeData(data, &req); err != nil {
+		return nil, err
+	}
+	return logEntryItem(h.create(ctx, userID, req))
+}
+
+func (h *LogEntryHandler) patchItem(ctx context.Context, userID, id string, data []byte, versions []int64, force bool) (*item, error) {
+	var req models.PatchLogEntryRequest
+	if err := decodeData(data, &req); err != nil {
+		return nil, err
+	}
+	patch, err := logEntryPatchFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return logEntryItem(h.patch(ctx, userID, id, patch, versions))
+}
+
+func (h *LogEntryHandler) deleteItem(ctx context.Context, userID, id string, versions []int64) error {
+	return h.delete(ctx, userID, id, versions)
+}
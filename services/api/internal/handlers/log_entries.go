@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -37,7 +40,7 @@ func (h *LogEntryHandler) List(w http.ResponseWriter, r *http.Request) {
 		projectID = &projectIDStr
 	}
 
-	logEntries, err := h.queries.ListLogEntries(userID, projectID)
+	logEntries, err := h.queries.ListLogEntries(r.Context(), userID, projectID)
 	if err != nil {
 		http.Error(w, "Failed to list log entries", http.StatusInternalServerError)
 		return
@@ -59,22 +62,38 @@ func (h *LogEntryHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Content == "" {
-		http.Error(w, "Content is required", http.StatusBadRequest)
+	logEntry, err := h.create(r.Context(), userID, req)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
+	setETag(w, etag(logEntry.Version))
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, logEntry)
+}
+
+// create checks and creates a log entry, dated today unless the request
+// gives a date.
+func (h *LogEntryHandler) create(ctx context.Context, userID string, req models.CreateLogEntryRequest) (*models.LogEntry, error) {
+	if req.Content == "" {
+		return nil, validationError("Content is required")
+	}
+
 	// Validate optional UUID fields
+	if req.ID != nil {
+		if _, err := uuid.Parse(*req.ID); err != nil {
+			return nil, validationError("Invalid id format")
+		}
+	}
 	if req.TaskID != nil {
 		if _, err := uuid.Parse(*req.TaskID); err != nil {
-			http.Error(w, "Invalid task_id format", http.StatusBadRequest)
-			return
+			return nil, validationError("Invalid task_id format")
 		}
 	}
 	if req.ProjectID != nil {
 		if _, err := uuid.Parse(*req.ProjectID); err != nil {
-			http.Error(w, "Invalid project_id format", http.StatusBadRequest)
-			return
+			return nil, validationError("Invalid project_id format")
 		}
 	}
 
@@ -84,21 +103,30 @@ func (h *LogEntryHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if req.LogDate != "" {
 		logDate, err = time.Parse("2006-01-02", req.LogDate)
 		if err != nil {
-			http.Error(w, "Invalid log date format. Use YYYY-MM-DD", http.StatusBadRequest)
-			return
+			return nil, validationError("Invalid log date format. Use YYYY-MM-DD")
 		}
 	} else {
 		logDate = time.Now()
 	}
 
-	logEntry, err := h.queries.CreateLogEntry(userID, req.TaskID, req.ProjectID, req.Content, logDate)
+	if req.DurationMinutes != nil && !validDuration(int64(*req.DurationMinutes)) {
+		return nil, validationError(invalidDurationMessage)
+	}
+
+	if err := requireWritable(ctx, h.queries, userID, models.RoleMember, req.ProjectID); err != nil {
+		return nil, err
+	}
+
+	logEntry, err := h.queries.CreateLogEntry(ctx, req.ID, userID, req.TaskID, req.ProjectID, req.Content, logDate, req.DurationMinutes, req.Billable)
+	if errors.Is(err, database.ErrIDTaken) {
+		return nil, conflict("ID already in use")
+	}
 	if err != nil {
-		http.Error(w, "Failed to create log entry", http.StatusInternalServerError)
-		return
+		return nil, internalError("Failed to create log entry")
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	writeJSON(w, logEntry)
+	notifyMentions(ctx, h.queries, logEntry, userID)
+	return logEntry, nil
 }
 
 func (h *LogEntryHandler) Get(w http.ResponseWriter, r *http.Request) {
@@ -114,19 +142,31 @@ func (h *LogEntryHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logEntry, err := h.queries.GetLogEntry(id, userID)
+	logEntry, err := h.get(r.Context(), userID, id)
 	if err != nil {
-		http.Error(w, "Failed to get log entry", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
-	if logEntry == nil {
-		http.Error(w, "Log entry not found", http.StatusNotFound)
+
+	setETag(w, etag(logEntry.Version))
+	if notModified(w, r, etag(logEntry.Version)) {
 		return
 	}
-
 	writeJSON(w, logEntry)
 }
 
+// get returns the log entry, or an error when the user cannot see it.
+func (h *LogEntryHandler) get(ctx context.Context, userID, id string) (*models.LogEntry, error) {
+	logEntry, err := h.queries.GetLogEntry(ctx, id, userID)
+	if err != nil {
+		return nil, internalError("Failed to get log entry")
+	}
+	if logEntry == nil {
+		return nil, notFound("Log entry not found")
+	}
+	return logEntry, nil
+}
+
 func (h *LogEntryHandler) Update(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -158,7 +198,16 @@ func (h *LogEntryHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logEntry, err := h.queries.UpdateLogEntry(id, userID, req.Content, logDate)
+	if err := h.checkEntryWritable(r.Context(), id, userID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	logEntry, err := h.queries.UpdateLogEntry(r.Context(), id, userID, req.Content, logDate, ifMatchVersions(r))
+	if isPreconditionFailed(err) {
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to update log entry", http.StatusInternalServerError)
 		return
@@ -168,9 +217,153 @@ func (h *LogEntryHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	notifyMentions(r.Context(), h.queries, logEntry, userID)
+
+	setETag(w, etag(logEntry.Version))
+	writeJSON(w, logEntry)
+}
+
+// Patch applies a JSON Merge Patch, changing only the fields supplied.
+// task_id, project_id and duration_minutes may be reassigned or cleared
+// with null.
+func (h *LogEntryHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid log entry ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.PatchLogEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	patch, err := logEntryPatchFromRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.servePatch(w, r, id, userID, patch)
+}
+
+// servePatch applies a log entry patch for a request, writing the response.
+func (h *LogEntryHandler) servePatch(w http.ResponseWriter, r *http.Request, id, userID string, patch database.LogEntryPatch) {
+	logEntry, err := h.patch(r.Context(), userID, id, patch, ifMatchVersions(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	setETag(w, etag(logEntry.Version))
 	writeJSON(w, logEntry)
 }
 
+// patch checks and applies a log entry patch over the given versions, or
+// any version when versions is nil.
+func (h *LogEntryHandler) patch(ctx context.Context, userID, id string, patch database.LogEntryPatch, versions []int64) (*models.LogEntry, error) {
+	if patch.SetTaskID && patch.TaskID != nil {
+		task, err := h.queries.GetTask(ctx, *patch.TaskID, userID)
+		if err != nil {
+			return nil, internalError("Failed to get task")
+		}
+		if task == nil {
+			return nil, validationError("Task not found")
+		}
+	}
+	if patch.SetProjectID && patch.ProjectID != nil {
+		project, err := h.queries.GetProject(ctx, *patch.ProjectID, userID)
+		if err != nil {
+			return nil, internalError("Failed to get project")
+		}
+		if project == nil {
+			return nil, validationError("Project not found")
+		}
+	}
+
+	if err := h.checkEntryWritable(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	if patch.SetProjectID {
+		if err := requireWritable(ctx, h.queries, userID, models.RoleMember, patch.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	logEntry, err := h.queries.PatchLogEntry(ctx, id, userID, patch, versions)
+	if isPreconditionFailed(err) {
+		return nil, errPreconditionFailed
+	}
+	if err != nil {
+		return nil, internalError("Failed to update log entry")
+	}
+	if logEntry == nil {
+		return nil, notFound("Log entry not found")
+	}
+
+	// Moving an entry into a project can newly show it to those mentioned.
+	if patch.Content != nil || patch.SetProjectID {
+		notifyMentions(ctx, h.queries, logEntry, userID)
+	}
+	return logEntry, nil
+}
+
+func logEntryPatchFromRequest(req models.PatchLogEntryRequest) (database.LogEntryPatch, error) {
+	var patch database.LogEntryPatch
+	var err error
+	if patch.Content, err = patchRequired(req.Content, "Content"); err != nil {
+		return patch, err
+	}
+	logDate, err := patchRequired(req.LogDate, "Log date")
+	if err != nil {
+		return patch, err
+	}
+	if logDate != nil {
+		parsed, err := time.Parse("2006-01-02", *logDate)
+		if err != nil {
+			return patch, validationError("Invalid log date format. Use YYYY-MM-DD")
+		}
+		patch.LogDate = &parsed
+	}
+	if patch.SetTaskID, patch.TaskID, err = patchUUID(req.TaskID, "task_id"); err != nil {
+		return patch, err
+	}
+	if patch.SetProjectID, patch.ProjectID, err = patchUUID(req.ProjectID, "project_id"); err != nil {
+		return patch, err
+	}
+	if req.DurationMinutes.Present {
+		patch.SetDuration = true
+		if !req.DurationMinutes.Null {
+			if !validDuration(req.DurationMinutes.Value) {
+				return patch, validationError(invalidDurationMessage)
+			}
+			minutes := int(req.DurationMinutes.Value)
+			patch.DurationMinutes = &minutes
+		}
+	}
+	if req.Billable.Present {
+		if req.Billable.Null {
+			return patch, validationError("Billable cannot be null")
+		}
+		patch.Billable = &req.Billable.Value
+	}
+	return patch, nil
+}
+
+const invalidDurationMessage = "Invalid duration_minutes, must be between 1 and 1440"
+
+// validDuration accepts up to a day's worth of minutes.
+func validDuration(minutes int64) bool {
+	return minutes >= 1 && minutes <= 24*60
+}
+
 func (h *LogEntryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -184,14 +377,47 @@ func (h *LogEntryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.queries.DeleteLogEntry(id, userID); err != nil {
-		http.Error(w, "Failed to delete log entry", http.StatusInternalServerError)
+	if err := h.delete(r.Context(), userID, id, ifMatchVersions(r)); err != nil {
+		writeError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// delete moves the log entry to the trash, if it is still at one of
+// versions or versions is nil.
+func (h *LogEntryHandler) delete(ctx context.Context, userID, id string, versions []int64) error {
+	if err := h.checkEntryWritable(ctx, id, userID); err != nil {
+		return err
+	}
+
+	err := h.queries.DeleteLogEntry(ctx, id, userID, versions)
+	if err == sql.ErrNoRows {
+		return notFound("Log entry not found")
+	}
+	if isPreconditionFailed(err) {
+		return errPreconditionFailed
+	}
+	if err != nil {
+		return internalError("Failed to delete log entry")
+	}
+	return nil
+}
+
+// checkEntryWritable refuses changes to an entry in an archived project. A
+// missing entry is let through for the write to report.
+func (h *LogEntryHandler) checkEntryWritable(ctx context.Context, id, userID string) error {
+	logEntry, err := h.queries.GetLogEntry(ctx, id, userID)
+	if err != nil {
+		return internalError("Failed to get log entry")
+	}
+	if logEntry == nil {
+		return nil
+	}
+	return requireWritable(ctx, h.queries, userID, models.RoleMember, logEntry.ProjectID)
+}
+
 func (h *LogEntryHandler) Today(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -201,7 +427,7 @@ func (h *LogEntryHandler) Today(w http.ResponseWriter, r *http.Request) {
 
 	// Get today's log entries
 	today := time.Now()
-	logEntries, err := h.queries.GetTodayLogEntries(userID, today)
+	logEntries, err := h.queries.GetTodayLogEntries(r.Context(), userID, today)
 	if err != nil {
 		http.Error(w, "Failed to get today's log entries", http.StatusInternalServerError)
 		return
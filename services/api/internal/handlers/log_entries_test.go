@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -165,3 +166,141 @@ func TestUpdateLogEntryRequestValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestLogEntryPatchFromRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		json         string
+		wantErr      bool
+		setTaskID    bool
+		clearTaskID  bool
+		setProjectID bool
+		wantLogDate  bool
+	}{
+		{
+			name: "content only",
+			json: `{"content":"Updated"}`,
+		},
+		{
+			name:        "date only",
+			json:        `{"log_date":"2024-01-05"}`,
+			wantLogDate: true,
+		},
+		{
+			name:        "clear task",
+			json:        `{"task_id":null}`,
+			setTaskID:   true,
+			clearTaskID: true,
+		},
+		{
+			name:         "reassign project",
+			json:         `{"project_id":"550e8400-e29b-41d4-a716-446655440000"}`,
+			setProjectID: true,
+		},
+		{
+			name:    "invalid task ID",
+			json:    `{"task_id":"invalid-uuid"}`,
+			wantErr: true,
+		},
+		{
+			name:    "null content",
+			json:    `{"content":null}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid date",
+			json:    `{"log_date":"01/05/2024"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.PatchLogEntryRequest
+			if err := json.Unmarshal([]byte(tt.json), &req); err != nil {
+				t.Fatalf("Failed to unmarshal patch: %v", err)
+			}
+
+			patch, err := logEntryPatchFromRequest(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if patch.SetTaskID != tt.setTaskID {
+				t.Errorf("Expected SetTaskID=%v", tt.setTaskID)
+			}
+			if tt.clearTaskID && patch.TaskID != nil {
+				t.Error("Expected task_id to be cleared")
+			}
+			if patch.SetProjectID != tt.setProjectID {
+				t.Errorf("Expected SetProjectID=%v", tt.setProjectID)
+			}
+			if (patch.LogDate != nil) != tt.wantLogDate {
+				t.Errorf("Expected log date set=%v", tt.wantLogDate)
+			}
+		})
+	}
+}
+
+func TestLogEntryTimePatchFromRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		json         string
+		wantErr      bool
+		setDuration  bool
+		wantDuration int
+		wantBillable *bool
+	}{
+		{
+			name:         "duration",
+			json:         `{"duration_minutes":90}`,
+			setDuration:  true,
+			wantDuration: 90,
+		},
+		{
+			name:        "clear duration",
+			json:        `{"duration_minutes":null}`,
+			setDuration: true,
+		},
+		{
+			name:         "billable",
+			json:         `{"billable":true}`,
+			wantBillable: func() *bool { b := true; return &b }(),
+		},
+		{name: "zero duration", json: `{"duration_minutes":0}`, wantErr: true},
+		{name: "more than a day", json: `{"duration_minutes":1441}`, wantErr: true},
+		{name: "null billable", json: `{"billable":null}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.PatchLogEntryRequest
+			if err := json.Unmarshal([]byte(tt.json), &req); err != nil {
+				t.Fatalf("Failed to unmarshal patch: %v", err)
+			}
+
+			patch, err := logEntryPatchFromRequest(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if patch.SetDuration != tt.setDuration {
+				t.Errorf("Expected SetDuration=%v", tt.setDuration)
+			}
+			if tt.wantDuration != 0 && (patch.DurationMinutes == nil || *patch.DurationMinutes != tt.wantDuration) {
+				t.Errorf("Expected duration %d, got %v", tt.wantDuration, patch.DurationMinutes)
+			}
+			if tt.wantDuration == 0 && patch.DurationMinutes != nil {
+				t.Errorf("Expected no duration, got %d", *patch.DurationMinutes)
+			}
+			if (patch.Billable == nil) != (tt.wantBillable == nil) ||
+				(tt.wantBillable != nil && *patch.Billable != *tt.wantBillable) {
+				t.Errorf("Expected billable %v, got %v", tt.wantBillable, patch.Billable)
+			}
+		})
+	}
+}
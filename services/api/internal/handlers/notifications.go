@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 200
+)
+
+// NotificationHandler serves the user's notification inbox and preferences.
+type NotificationHandler struct {
+	queries *database.Queries
+}
+
+func NewNotificationHandler(queries *database.Queries) *NotificationHandler {
+	return &NotificationHandler{queries: queries}
+}
+
+// List returns the user's notifications, newest first. ?unread=true leaves
+// out those already read and ?limit= caps how many are returned.
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := defaultNotificationLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxNotificationLimit {
+			http.Error(w, "Invalid limit, must be between 1 and 200", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	notifications, err := h.queries.ListNotifications(r.Context(), userID, r.URL.Query().Get("unread") == "true", limit)
+	if err != nil {
+		http.Error(w, "Failed to list notifications", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, notifications)
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid notification ID format", http.StatusBadRequest)
+		return
+	}
+
+	notification, err := h.queries.MarkNotificationRead(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, "Failed to mark notification read", http.StatusInternalServerError)
+		return
+	}
+	if notification == nil {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, notification)
+}
+
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	marked, err := h.queries.MarkAllNotificationsRead(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to mark notifications read", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]int64{"marked": marked})
+}
+
+// Preferences returns how the user receives each type of notification.
+func (h *NotificationHandler) Preferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	preferences, err := h.queries.GetNotificationPreferences(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to get notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, preferences)
+}
+
+// UpdatePreferences changes the preferences for the types listed, leaving
+// the others alone.
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.UpdateNotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for _, preference := range req.Preferences {
+		if !validNotificationType(preference.Type) {
+			http.Error(w, "Invalid type, must be one of "+strings.Join(models.NotificationTypes, ", "), http.StatusBadRequest)
+			return
+		}
+	}
+
+	preferences, err := h.queries.SetNotificationPreferences(r.Context(), userID, req.Preferences)
+	if err != nil {
+		http.Error(w, "Failed to update notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, preferences)
+}
+
+func validNotificationType(notificationType string) bool {
+	for _, t := range models.NotificationTypes {
+		if t == notificationType {
+			return true
+		}
+	}
+	return false
+}
+
+// mentionPattern matches @handle where the @ does not follow a word
+// character, so email addresses in the text are not mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9][A-Za-z0-9._+-]*)`)
+
+// parseMentions returns the distinct handles mentioned in content, lower
+// cased, in order of first mention. A handle is a user's username.
+func parseMentions(content string) []string {
+	var handles []string
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		handle := strings.ToLower(strings.TrimRight(match[1], "._-"))
+		if handle != "" && !seen[handle] {
+			seen[handle] = true
+			handles = append(handles, handle)
+		}
+	}
+	return handles
+}
+
+// notifyMentions notifies the users mentioned in a log entry. A failure is
+// only logged, as the entry itself has been saved.
+func notifyMentions(ctx context.Context, queries *database.Queries, logEntry *models.LogEntry, actorID string) {
+	sideEffect(ctx, queries, "notify mentions in log entry "+logEntry.ID, func(queries *database.Queries) error {
+		return queries.NotifyMentions(ctx, logEntry.ID, actorID, parseMentions(logEntry.Content))
+	})
+}
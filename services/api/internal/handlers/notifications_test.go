@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"", nil},
+		{"no mentions here", nil},
+		{"@alice can you review?", []string{"alice"}},
+		{"Thanks @Bob.", []string{"bob"}},
+		{"cc @alice, @bob and @alice again", []string{"alice", "bob"}},
+		{"(@jane.doe) paired on this", []string{"jane.doe"}},
+		{"mail alice@example.com", nil},
+		{"@@alice", nil},
+		{"@ alone", nil},
+	}
+
+	for _, tt := range tests {
+		if got := parseMentions(tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseMentions(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestValidNotificationType(t *testing.T) {
+	for _, notificationType := range []string{"task_due", "comment", "mention"} {
+		if !validNotificationType(notificationType) {
+			t.Errorf("Expected %q to be valid", notificationType)
+		}
+	}
+	for _, notificationType := range []string{"", "digest", "Comment"} {
+		if validNotificationType(notificationType) {
+			t.Errorf("Expected %q to be invalid", notificationType)
+		}
+	}
+}
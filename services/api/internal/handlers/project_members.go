@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// Members lists the users who joined the project itself through an
+// invitation. Members of its workspace are listed with the workspace.
+func (h *ProjectHandler) Members(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	if checkProjectMember(r.Context(), w, h.queries, id, userID, models.RoleViewer) == nil {
+		return
+	}
+
+	members, err := h.queries.ListProjectMembers(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to list members", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, members)
+}
+
+// RemoveMember takes a project member out of the project. Admins may remove
+// anyone, and members may always remove themselves.
+func (h *ProjectHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	memberID := chi.URLParam(r, "userID")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(memberID); err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	role := models.RoleAdmin
+	if memberID == userID {
+		role = models.RoleViewer
+	}
+	if checkProjectMember(r.Context(), w, h.queries, id, userID, role) == nil {
+		return
+	}
+
+	err := h.queries.RemoveProjectMember(r.Context(), id, memberID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to remove member", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
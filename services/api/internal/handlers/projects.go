@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/chrispotter/makerlog/services/api/internal/database"
 	"github.com/chrispotter/makerlog/services/api/internal/middleware"
@@ -26,7 +30,16 @@ func (h *ProjectHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	projects, err := h.queries.ListProjects(userID)
+	var workspaceID *string
+	if value := r.URL.Query().Get("workspace_id"); value != "" {
+		if _, err := uuid.Parse(value); err != nil {
+			http.Error(w, "Invalid workspace_id format", http.StatusBadRequest)
+			return
+		}
+		workspaceID = &value
+	}
+
+	projects, err := h.queries.ListProjects(r.Context(), userID, workspaceID, r.URL.Query().Get("archived") == "true")
 	if err != nil {
 		http.Error(w, "Failed to list projects", http.StatusInternalServerError)
 		return
@@ -48,22 +61,92 @@ func (h *ProjectHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	project, err := h.create(r.Context(), userID, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	setETag(w, etag(project.Version))
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, project)
+}
+
+// create checks and creates a project, in the user's personal workspace
+// unless the request names another.
+func (h *ProjectHandler) create(ctx context.Context, userID string, req models.CreateProjectRequest) (*models.Project, error) {
 	if req.Name == "" {
-		http.Error(w, "Project name is required", http.StatusBadRequest)
+		return nil, validationError("Project name is required")
+	}
+
+	if req.ID != nil {
+		if _, err := uuid.Parse(*req.ID); err != nil {
+			return nil, validationError("Invalid id format")
+		}
+	}
+
+	var workspace *models.Workspace
+	var err error
+	if req.WorkspaceID != nil {
+		if _, err := uuid.Parse(*req.WorkspaceID); err != nil {
+			return nil, validationError("Invalid workspace_id format")
+		}
+		if workspace, err = requireWorkspaceRole(ctx, h.queries, *req.WorkspaceID, userID, models.RoleAdmin); err != nil {
+			return nil, err
+		}
+	} else if workspace, err = h.queries.GetPersonalWorkspace(ctx, userID); err != nil || workspace == nil {
+		return nil, internalError("Failed to get personal workspace")
+	}
+
+	project, err := h.queries.CreateProject(ctx, req.ID, userID, workspace.ID, req.Name, req.Description)
+	if errors.Is(err, database.ErrIDTaken) {
+		return nil, conflict("ID already in use")
+	}
+	if err != nil {
+		return nil, internalError("Failed to create project")
+	}
+	return project, nil
+}
+
+func (h *ProjectHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
 		return
 	}
 
-	project, err := h.queries.CreateProject(userID, req.Name, req.Description)
+	project, err := h.get(r.Context(), userID, id)
 	if err != nil {
-		http.Error(w, "Failed to create project", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	setETag(w, etag(project.Version))
+	if notModified(w, r, etag(project.Version)) {
+		return
+	}
 	writeJSON(w, project)
 }
 
-func (h *ProjectHandler) Get(w http.ResponseWriter, r *http.Request) {
+// get returns the project, or an error when the user cannot see it.
+func (h *ProjectHandler) get(ctx context.Context, userID, id string) (*models.Project, error) {
+	project, err := h.queries.GetProject(ctx, id, userID)
+	if err != nil {
+		return nil, internalError("Failed to get project")
+	}
+	if project == nil {
+		return nil, notFound("Project not found")
+	}
+	return project, nil
+}
+
+func (h *ProjectHandler) Update(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -76,9 +159,28 @@ func (h *ProjectHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project, err := h.queries.GetProject(id, userID)
+	var req models.UpdateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, "Project name is required", http.StatusBadRequest)
+		return
+	}
+
+	if !checkWritable(r.Context(), w, h.queries, userID, models.RoleAdmin, &id) {
+		return
+	}
+
+	project, err := h.queries.UpdateProject(r.Context(), id, userID, req.Name, req.Description, ifMatchVersions(r))
+	if isPreconditionFailed(err) {
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Failed to get project", http.StatusInternalServerError)
+		http.Error(w, "Failed to update project", http.StatusInternalServerError)
 		return
 	}
 	if project == nil {
@@ -86,10 +188,12 @@ func (h *ProjectHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, etag(project.Version))
 	writeJSON(w, project)
 }
 
-func (h *ProjectHandler) Update(w http.ResponseWriter, r *http.Request) {
+// Patch applies a JSON Merge Patch, changing only the fields supplied.
+func (h *ProjectHandler) Patch(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -102,18 +206,148 @@ func (h *ProjectHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req models.UpdateProjectRequest
+	var req models.PatchProjectRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if req.Name == "" {
-		http.Error(w, "Project name is required", http.StatusBadRequest)
+	patch, err := projectPatchFromRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	project, err := h.queries.UpdateProject(id, userID, req.Name, req.Description)
+	project, err := h.patch(r.Context(), userID, id, patch, ifMatchVersions(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	setETag(w, etag(project.Version))
+	writeJSON(w, project)
+}
+
+// patch checks and applies a project patch over the given versions, or any
+// version when versions is nil.
+func (h *ProjectHandler) patch(ctx context.Context, userID, id string, patch database.ProjectPatch, versions []int64) (*models.Project, error) {
+	if err := requireWritable(ctx, h.queries, userID, models.RoleAdmin, &id); err != nil {
+		return nil, err
+	}
+	if patch.SetClientID && patch.ClientID != nil {
+		if err := h.checkClient(ctx, id, *patch.ClientID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	project, err := h.queries.PatchProject(ctx, id, userID, patch, versions)
+	if isPreconditionFailed(err) {
+		return nil, errPreconditionFailed
+	}
+	if err != nil {
+		return nil, internalError("Failed to update project")
+	}
+	if project == nil {
+		return nil, notFound("Project not found")
+	}
+	return project, nil
+}
+
+func projectPatchFromRequest(req models.PatchProjectRequest) (database.ProjectPatch, error) {
+	var patch database.ProjectPatch
+	var err error
+	if patch.Name, err = patchRequired(req.Name, "Project name"); err != nil {
+		return patch, err
+	}
+	patch.Description = patchText(req.Description)
+	if patch.SetClientID, patch.ClientID, err = patchUUID(req.ClientID, "client_id"); err != nil {
+		return patch, err
+	}
+	if req.HourlyRateCents.Present {
+		patch.SetHourlyRate = true
+		if !req.HourlyRateCents.Null {
+			if req.HourlyRateCents.Value < 0 {
+				return patch, validationError("Hourly rate cannot be negative")
+			}
+			patch.HourlyRateCents = &req.HourlyRateCents.Value
+		}
+	}
+	currency, err := patchRequired(req.Currency, "Currency")
+	if err != nil {
+		return patch, err
+	}
+	if currency != nil {
+		code := strings.ToUpper(*currency)
+		if !validCurrency(code) {
+			return patch, validationError("Invalid currency, must be a three-letter ISO 4217 code")
+		}
+		patch.Currency = &code
+	}
+	return patch, nil
+}
+
+// validCurrency accepts three-letter codes in the shape of ISO 4217.
+func validCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkClient refuses to link a project to a client outside the project's
+// workspace.
+func (h *ProjectHandler) checkClient(ctx context.Context, projectID, clientID, userID string) error {
+	project, err := h.get(ctx, userID, projectID)
+	if err != nil {
+		return err
+	}
+	client, err := h.queries.GetClient(ctx, clientID, userID)
+	if err != nil {
+		return internalError("Failed to get client")
+	}
+	if client == nil || client.WorkspaceID != project.WorkspaceID {
+		return validationError("Client not found in the project's workspace")
+	}
+	return nil
+}
+
+// Archive makes the project read-only and hides it from the project list.
+func (h *ProjectHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true)
+}
+
+// Unarchive makes an archived project writable and listed again.
+func (h *ProjectHandler) Unarchive(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false)
+}
+
+func (h *ProjectHandler) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !checkProjectRole(r.Context(), w, h.queries, userID, models.RoleAdmin, true, &id) {
+		return
+	}
+
+	project, err := h.queries.SetProjectArchived(r.Context(), id, userID, archived, ifMatchVersions(r))
+	if isPreconditionFailed(err) {
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to update project", http.StatusInternalServerError)
 		return
@@ -123,6 +357,7 @@ func (h *ProjectHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, etag(project.Version))
 	writeJSON(w, project)
 }
 
@@ -139,10 +374,30 @@ func (h *ProjectHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.queries.DeleteProject(id, userID); err != nil {
-		http.Error(w, "Failed to delete project", http.StatusInternalServerError)
+	if err := h.delete(r.Context(), userID, id, ifMatchVersions(r)); err != nil {
+		writeError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// delete moves the project to the trash, if it is still at one of versions
+// or versions is nil.
+func (h *ProjectHandler) delete(ctx context.Context, userID, id string, versions []int64) error {
+	if err := requireProjectRole(ctx, h.queries, userID, models.RoleAdmin, true, &id); err != nil {
+		return err
+	}
+
+	err := h.queries.DeleteProject(ctx, id, userID, versions)
+	if err == sql.ErrNoRows {
+		return notFound("Project not found")
+	}
+	if isPreconditionFailed(err) {
+		return errPreconditionFailed
+	}
+	if err != nil {
+		return internalError("Failed to delete project")
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/chrispotter/makerlog/services/api/internal/models"
@@ -118,3 +119,121 @@ func TestUpdateProjectRequestValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestProjectPatchFromRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		json            string
+		wantErr         bool
+		wantName        bool
+		wantDescription bool
+	}{
+		{
+			name:     "name only",
+			json:     `{"name":"Renamed"}`,
+			wantName: true,
+		},
+		{
+			name:            "null description clears it",
+			json:            `{"description":null}`,
+			wantDescription: true,
+		},
+		{
+			name:    "null name",
+			json:    `{"name":null}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.PatchProjectRequest
+			if err := json.Unmarshal([]byte(tt.json), &req); err != nil {
+				t.Fatalf("Failed to unmarshal patch: %v", err)
+			}
+
+			patch, err := projectPatchFromRequest(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (patch.Name != nil) != tt.wantName {
+				t.Errorf("Expected name set=%v", tt.wantName)
+			}
+			if (patch.Description != nil) != tt.wantDescription {
+				t.Errorf("Expected description set=%v", tt.wantDescription)
+			}
+		})
+	}
+}
+
+func TestProjectBillingPatchFromRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		json         string
+		wantErr      bool
+		setClientID  bool
+		setRate      bool
+		wantRate     *int64
+		wantCurrency string
+	}{
+		{
+			name:        "link client",
+			json:        `{"client_id":"550e8400-e29b-41d4-a716-446655440000"}`,
+			setClientID: true,
+		},
+		{
+			name:        "unlink client",
+			json:        `{"client_id":null}`,
+			setClientID: true,
+		},
+		{
+			name:         "rate and lower case currency",
+			json:         `{"hourly_rate_cents":12500,"currency":"eur"}`,
+			setRate:      true,
+			wantRate:     func() *int64 { n := int64(12500); return &n }(),
+			wantCurrency: "EUR",
+		},
+		{
+			name:    "clear rate",
+			json:    `{"hourly_rate_cents":null}`,
+			setRate: true,
+		},
+		{name: "negative rate", json: `{"hourly_rate_cents":-1}`, wantErr: true},
+		{name: "invalid currency", json: `{"currency":"EURO"}`, wantErr: true},
+		{name: "null currency", json: `{"currency":null}`, wantErr: true},
+		{name: "invalid client ID", json: `{"client_id":"acme"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.PatchProjectRequest
+			if err := json.Unmarshal([]byte(tt.json), &req); err != nil {
+				t.Fatalf("Failed to unmarshal patch: %v", err)
+			}
+
+			patch, err := projectPatchFromRequest(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if patch.SetClientID != tt.setClientID {
+				t.Errorf("Expected SetClientID=%v", tt.setClientID)
+			}
+			if patch.SetHourlyRate != tt.setRate {
+				t.Errorf("Expected SetHourlyRate=%v", tt.setRate)
+			}
+			if (patch.HourlyRateCents == nil) != (tt.wantRate == nil) ||
+				(tt.wantRate != nil && *patch.HourlyRateCents != *tt.wantRate) {
+				t.Errorf("Expected rate %v, got %v", tt.wantRate, patch.HourlyRateCents)
+			}
+			if tt.wantCurrency != "" && (patch.Currency == nil || *patch.Currency != tt.wantCurrency) {
+				t.Errorf("Expected currency %s, got %v", tt.wantCurrency, patch.Currency)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/chrispotter/makerlog/services/api/internal/reports"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ReportHandler serves reports on the user's work.
+type ReportHandler struct {
+	queries *database.Queries
+}
+
+func NewReportHandler(queries *database.Queries) *ReportHandler {
+	return &ReportHandler{queries: queries}
+}
+
+// Standup reports the log entries the user wrote on the previous working
+// day, the active tasks they created and those of their tasks that are
+// blocked. ?date= picks the day of the standup, today in the user's
+// timezone by default, and ?format= picks json, markdown or slack.
+func (h *ReportHandler) Standup(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "markdown" && format != "slack" {
+		http.Error(w, "Invalid format, must be json, markdown or slack", http.StatusBadRequest)
+		return
+	}
+
+	var date time.Time
+	if value := r.URL.Query().Get("date"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			http.Error(w, "Invalid date format. Use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		date = parsed
+	} else {
+		settings, err := h.queries.GetUserSettings(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "Failed to get settings", http.StatusInternalServerError)
+			return
+		}
+		now := time.Now().In(userLocation(settings))
+		date = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	yesterday := reports.PreviousWorkingDay(date)
+
+	logEntries, err := h.queries.GetTodayLogEntries(r.Context(), userID, yesterday)
+	if err != nil {
+		http.Error(w, "Failed to get log entries", http.StatusInternalServerError)
+		return
+	}
+	active := models.StatusCategoryActive
+	today, err := h.queries.ListTasks(r.Context(), userID, database.TaskFilter{CreatedBy: &userID, StatusCategory: &active})
+	if err != nil {
+		http.Error(w, "Failed to list tasks", http.StatusInternalServerError)
+		return
+	}
+	blockers, err := h.queries.ListTasks(r.Context(), userID, database.TaskFilter{CreatedBy: &userID, Blocked: true})
+	if err != nil {
+		http.Error(w, "Failed to list tasks", http.StatusInternalServerError)
+		return
+	}
+
+	report := &models.StandupReport{
+		Date:          date.Format("2006-01-02"),
+		YesterdayDate: yesterday.Format("2006-01-02"),
+		Yesterday:     logEntries,
+		Today:         today,
+		Blockers:      blockers,
+	}
+	if report.Yesterday == nil {
+		report.Yesterday = []models.LogEntry{}
+	}
+	if report.Today == nil {
+		report.Today = []models.Task{}
+	}
+	if report.Blockers == nil {
+		report.Blockers = []models.Task{}
+	}
+
+	if format == "json" {
+		writeJSON(w, report)
+		return
+	}
+	projectNames, err := h.queries.ProjectNames(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to get projects", http.StatusInternalServerError)
+		return
+	}
+	if format == "markdown" {
+		writeText(w, "text/markdown; charset=utf-8", reports.StandupMarkdown(report, projectNames))
+		return
+	}
+	writeText(w, "text/plain; charset=utf-8", reports.StandupSlack(report, projectNames))
+}
+
+// maxReportDays bounds the date range of a report.
+const maxReportDays = 366
+
+// reportRange reads the required ?from= and ?to= dates of a report,
+// writing a 400 if they are missing or span too long.
+func reportRange(w http.ResponseWriter, r *http.Request, name string) (from, to time.Time, ok bool) {
+	query := r.URL.Query()
+	from, err := time.Parse("2006-01-02", query.Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid from date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+	to, err = time.Parse("2006-01-02", query.Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to date format. Use YYYY-MM-DD", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+	if to.Before(from) {
+		http.Error(w, "to must not be before from", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+	if to.Sub(from) >= maxReportDays*24*time.Hour {
+		http.Error(w, fmt.Sprintf("A %s covers at most %d days", name, maxReportDays), http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// Timesheet totals the time logged on the projects the user can see between
+// ?from= and ?to=, inclusive, per client, project and week, pricing the
+// billable time at each project's hourly rate. ?workspace_id= and
+// ?client_id= narrow it down and ?format= picks json or csv.
+func (h *ReportHandler) Timesheet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		http.Error(w, "Invalid format, must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	from, to, ok := reportRange(w, r, "timesheet")
+	if !ok {
+		return
+	}
+
+	var filter database.TimesheetFilter
+	if value := query.Get("workspace_id"); value != "" {
+		if _, err := uuid.Parse(value); err != nil {
+			http.Error(w, "Invalid workspace_id format", http.StatusBadRequest)
+			return
+		}
+		filter.WorkspaceID = &value
+	}
+	if value := query.Get("client_id"); value != "" {
+		if _, err := uuid.Parse(value); err != nil {
+			http.Error(w, "Invalid client_id format", http.StatusBadRequest)
+			return
+		}
+		filter.ClientID = &value
+	}
+
+	rows, err := h.queries.ListTimesheetRows(r.Context(), userID, from, to, filter)
+	if err != nil {
+		http.Error(w, "Failed to build timesheet", http.StatusInternalServerError)
+		return
+	}
+	timesheet := reports.Timesheet(from.Format("2006-01-02"), to.Format("2006-01-02"), rows)
+
+	if format == "json" {
+		writeJSON(w, timesheet)
+		return
+	}
+	var buf bytes.Buffer
+	if err := reports.WriteTimesheetCSV(&buf, timesheet); err != nil {
+		http.Error(w, "Failed to write timesheet", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="timesheet-%s-%s.csv"`, timesheet.From, timesheet.To))
+	writeText(w, "text/csv; charset=utf-8", buf.String())
+}
+
+// ProjectPDF renders a PDF of the project for the dates ?from= through
+// ?to=: its header, the tasks completed and the log entries by day.
+// Completion times count by the user's timezone.
+func (h *ReportHandler) ProjectPDF(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	from, to, ok := reportRange(w, r, "report")
+	if !ok {
+		return
+	}
+
+	project, err := h.queries.GetProject(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, "Failed to get project", http.StatusInternalServerError)
+		return
+	}
+	if project == nil {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
+	report := &reports.ProjectReport{Project: project, From: from, To: to}
+	if project.ClientID != nil {
+		client, err := h.queries.GetClient(r.Context(), *project.ClientID, userID)
+		if err != nil {
+			http.Error(w, "Failed to get client", http.StatusInternalServerError)
+			return
+		}
+		if client != nil {
+			report.Client = client.Name
+		}
+	}
+
+	settings, err := h.queries.GetUserSettings(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to get settings", http.StatusInternalServerError)
+		return
+	}
+	report.Location = userLocation(settings)
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, report.Location)
+	end := time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, report.Location)
+	completed := true
+	report.Completed, err = h.queries.ListTasks(r.Context(), userID, database.TaskFilter{
+		ProjectID: &id, Completed: &completed, CompletedFrom: &start, CompletedBefore: &end,
+	})
+	if err != nil {
+		http.Error(w, "Failed to list tasks", http.StatusInternalServerError)
+		return
+	}
+
+	report.LogEntries, err = h.queries.ListProjectLogEntriesBetween(r.Context(), userID, id, from, to)
+	if err != nil {
+		http.Error(w, "Failed to get log entries", http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := reports.WriteProjectPDF(&buf, report); err != nil {
+		http.Error(w, "Failed to write report", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="project-report-%s-%s.pdf"`, from.Format("2006-01-02"), to.Format("2006-01-02")))
+	writeText(w, "application/pdf", buf.String())
+}
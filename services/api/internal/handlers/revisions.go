@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/chrispotter/makerlog/services/api/internal/textdiff"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// currentRevision names the entity as it is now in a revision diff.
+const currentRevision = "current"
+
+// textFields are the tracked fields that get a line diff.
+var textFields = map[string]bool{"content": true, "description": true}
+
+// Revisions lists the log entry's revisions, newest first.
+func (h *LogEntryHandler) Revisions(w http.ResponseWriter, r *http.Request) {
+	listRevisions(w, r, h.queries, models.RevisionEntityLogEntry, "log entry")
+}
+
+// RevisionDiff compares two revisions of the log entry.
+func (h *LogEntryHandler) RevisionDiff(w http.ResponseWriter, r *http.Request) {
+	diffRevisions(w, r, h.queries, models.RevisionEntityLogEntry, "log entry")
+}
+
+// RestoreRevision puts the log entry's tracked fields back to how they were
+// in a revision. The restore is itself an edit, so it adds a revision.
+func (h *LogEntryHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	userID, id, revision, ok := getRevision(w, r, h.queries, models.RevisionEntityLogEntry, "log entry")
+	if !ok {
+		return
+	}
+
+	var data struct {
+		Content         string  `json:"content"`
+		LogDate         string  `json:"log_date"`
+		TaskID          *string `json:"task_id"`
+		ProjectID       *string `json:"project_id"`
+		DurationMinutes *int    `json:"duration_minutes"`
+		Billable        *bool   `json:"billable"`
+	}
+	if err := json.Unmarshal(revision.Data, &data); err != nil {
+		http.Error(w, "Failed to read revision", http.StatusInternalServerError)
+		return
+	}
+	logDate, err := parseRevisionDate(data.LogDate)
+	if err != nil || logDate == nil {
+		http.Error(w, "Failed to read revision", http.StatusInternalServerError)
+		return
+	}
+
+	patch := database.LogEntryPatch{
+		Content:      &data.Content,
+		LogDate:      logDate,
+		SetTaskID:    true,
+		TaskID:       data.TaskID,
+		SetProjectID: true,
+		ProjectID:    data.ProjectID,
+	}
+	// Revisions from before time tracking have no billable field; they
+	// leave the entry's time as it is.
+	if data.Billable != nil {
+		patch.SetDuration = true
+		patch.DurationMinutes = data.DurationMinutes
+		patch.Billable = data.Billable
+	}
+	h.servePatch(w, r, id, userID, patch)
+}
+
+// Revisions lists the task's revisions, newest first.
+func (h *TaskHandler) Revisions(w http.ResponseWriter, r *http.Request) {
+	listRevisions(w, r, h.queries, models.RevisionEntityTask, "task")
+}
+
+// RevisionDiff compares two revisions of the task.
+func (h *TaskHandler) RevisionDiff(w http.ResponseWriter, r *http.Request) {
+	diffRevisions(w, r, h.queries, models.RevisionEntityTask, "task")
+}
+
+// RestoreRevision puts the task's tracked fields back to how they were in a
+// revision, with the same checks as a patch. The restore is itself an edit,
+// so it adds a revision.
+func (h *TaskHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	userID, id, revision, ok := getRevision(w, r, h.queries, models.RevisionEntityTask, "task")
+	if !ok {
+		return
+	}
+
+	var data struct {
+		Title          string  `json:"title"`
+		Description    string  `json:"description"`
+		Status         string  `json:"status"`
+		Priority       string  `json:"priority"`
+		DueDate        *string `json:"due_date"`
+		ProjectID      string  `json:"project_id"`
+		ParentTaskID   *string `json:"parent_task_id"`
+		RecurrenceRule *string `json:"recurrence_rule"`
+	}
+	if err := json.Unmarshal(revision.Data, &data); err != nil {
+		http.Error(w, "Failed to read revision", http.StatusInternalServerError)
+		return
+	}
+	var dueDate *time.Time
+	if data.DueDate != nil {
+		var err error
+		if dueDate, err = parseRevisionDate(*data.DueDate); err != nil {
+			http.Error(w, "Failed to read revision", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.servePatch(w, r, id, userID, database.TaskPatch{
+		ProjectID:         &data.ProjectID,
+		SetParentTaskID:   true,
+		ParentTaskID:      data.ParentTaskID,
+		Title:             &data.Title,
+		Description:       &data.Description,
+		Status:            &data.Status,
+		Priority:          &data.Priority,
+		SetDueDate:        true,
+		DueDate:           dueDate,
+		SetRecurrenceRule: true,
+		RecurrenceRule:    data.RecurrenceRule,
+	})
+}
+
+func listRevisions(w http.ResponseWriter, r *http.Request, queries *database.Queries, entityType, name string) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid "+name+" ID format", http.StatusBadRequest)
+		return
+	}
+
+	current, err := queries.CurrentRevisionData(r.Context(), entityType, id, userID)
+	if err != nil {
+		http.Error(w, "Failed to get "+name, http.StatusInternalServerError)
+		return
+	}
+	if current == nil {
+		http.Error(w, capitalize(name)+" not found", http.StatusNotFound)
+		return
+	}
+
+	revisions, err := queries.ListRevisions(r.Context(), entityType, id, userID)
+	if err != nil {
+		http.Error(w, "Failed to list revisions", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, revisions)
+}
+
+// diffRevisions compares the revisions named by ?from= and ?to=, each a
+// revision ID or "current"; to defaults to the current state.
+func diffRevisions(w http.ResponseWriter, r *http.Request, queries *database.Queries, entityType, name string) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid "+name+" ID format", http.StatusBadRequest)
+		return
+	}
+
+	current, err := queries.CurrentRevisionData(r.Context(), entityType, id, userID)
+	if err != nil {
+		http.Error(w, "Failed to get "+name, http.StatusInternalServerError)
+		return
+	}
+	if current == nil {
+		http.Error(w, capitalize(name)+" not found", http.StatusNotFound)
+		return
+	}
+
+	diff := models.RevisionDiff{From: r.URL.Query().Get("from"), To: r.URL.Query().Get("to")}
+	if diff.From == "" {
+		http.Error(w, "from is required", http.StatusBadRequest)
+		return
+	}
+	if diff.To == "" {
+		diff.To = currentRevision
+	}
+
+	data := make(map[string]json.RawMessage, 2)
+	for field, revisionID := range map[string]string{"from": diff.From, "to": diff.To} {
+		if revisionID == currentRevision {
+			data[field] = current
+			continue
+		}
+		if _, err := uuid.Parse(revisionID); err != nil {
+			http.Error(w, "Invalid "+field+" format", http.StatusBadRequest)
+			return
+		}
+		revision, err := queries.GetRevision(r.Context(), entityType, id, revisionID, userID)
+		if err != nil {
+			http.Error(w, "Failed to get revision", http.StatusInternalServerError)
+			return
+		}
+		if revision == nil {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+			return
+		}
+		data[field] = revision.Data
+	}
+
+	if diff.Changes, err = diffRevisionData(data["from"], data["to"]); err != nil {
+		http.Error(w, "Failed to read revision", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, diff)
+}
+
+// getRevision reads the entity and revision IDs from the URL and loads the
+// revision. It writes the error response and returns false when there is
+// nothing to restore.
+func getRevision(w http.ResponseWriter, r *http.Request, queries *database.Queries, entityType, name string) (string, string, *models.Revision, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", "", nil, false
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid "+name+" ID format", http.StatusBadRequest)
+		return "", "", nil, false
+	}
+
+	revisionID := chi.URLParam(r, "revisionID")
+	if _, err := uuid.Parse(revisionID); err != nil {
+		http.Error(w, "Invalid revision ID format", http.StatusBadRequest)
+		return "", "", nil, false
+	}
+
+	revision, err := queries.GetRevision(r.Context(), entityType, id, revisionID, userID)
+	if err != nil {
+		http.Error(w, "Failed to get revision", http.StatusInternalServerError)
+		return "", "", nil, false
+	}
+	if revision == nil {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return "", "", nil, false
+	}
+	return userID, id, revision, true
+}
+
+// diffRevisionData lists the fields whose values differ between two sets of
+// tracked fields, by field name. Text fields also get a line diff.
+func diffRevisionData(from, to json.RawMessage) ([]models.FieldChange, error) {
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(from, &before); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(to, &after); err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(before)+len(after))
+	for field := range before {
+		fields = append(fields, field)
+	}
+	for field := range after {
+		if _, ok := before[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := []models.FieldChange{}
+	for _, field := range fields {
+		change := models.FieldChange{Field: field, From: jsonValue(before[field]), To: jsonValue(after[field])}
+		if bytes.Equal(change.From, change.To) {
+			continue
+		}
+		if textFields[field] {
+			change.Lines = textdiff.Lines(diffText(change.From), diffText(change.To))
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// diffText reads a value of a text field for diffing. Null reads as empty
+// text, and a value that is not a string is shown as it was stored.
+func diffText(value json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(value, &text); err != nil {
+		return string(value)
+	}
+	return text
+}
+
+// jsonValue treats a missing value as null.
+func jsonValue(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
+
+// parseRevisionDate reads a date column as jsonb renders it.
+func parseRevisionDate(value string) (*time.Time, error) {
+	if len(value) > len("2006-01-02") {
+		value = value[:len("2006-01-02")]
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+func capitalize(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
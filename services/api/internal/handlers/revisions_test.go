@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/chrispotter/makerlog/services/api/internal/textdiff"
+)
+
+func TestDiffRevisionData(t *testing.T) {
+	from := json.RawMessage(`{"content": "one\ntwo", "log_date": "2026-10-01", "task_id": null, "project_id": "p1"}`)
+	to := json.RawMessage(`{"content": "one\n2", "log_date": "2026-10-01", "task_id": "t1", "project_id": "p1"}`)
+
+	changes, err := diffRevisionData(from, to)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %+v", changes)
+	}
+
+	content := changes[0]
+	if content.Field != "content" {
+		t.Errorf("Expected changes sorted by field, got %s first", content.Field)
+	}
+	wantLines := []textdiff.Line{{Op: textdiff.Equal, Text: "one"}, {Op: textdiff.Delete, Text: "two"}, {Op: textdiff.Insert, Text: "2"}}
+	if len(content.Lines) != len(wantLines) {
+		t.Fatalf("Expected content line diff %v, got %v", wantLines, content.Lines)
+	}
+	for i := range wantLines {
+		if content.Lines[i] != wantLines[i] {
+			t.Errorf("Expected line %d to be %v, got %v", i, wantLines[i], content.Lines[i])
+		}
+	}
+
+	taskID := changes[1]
+	if taskID.Field != "task_id" || string(taskID.From) != "null" || string(taskID.To) != `"t1"` {
+		t.Errorf("Unexpected task_id change %+v", taskID)
+	}
+	if taskID.Lines != nil {
+		t.Error("Expected no line diff for a non-text field")
+	}
+
+	// A text field stored as something other than a string is diffed as is.
+	changes, err = diffRevisionData(json.RawMessage(`{"description": 5}`), json.RawMessage(`{"description": "five"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	wantLines = []textdiff.Line{{Op: textdiff.Delete, Text: "5"}, {Op: textdiff.Insert, Text: "five"}}
+	if len(changes) != 1 || len(changes[0].Lines) != len(wantLines) {
+		t.Fatalf("Expected description line diff %v, got %+v", wantLines, changes)
+	}
+	for i := range wantLines {
+		if changes[0].Lines[i] != wantLines[i] {
+			t.Errorf("Expected line %d to be %v, got %v", i, wantLines[i], changes[0].Lines[i])
+		}
+	}
+
+	if _, err := diffRevisionData(json.RawMessage(`[]`), to); err == nil {
+		t.Error("Expected error for data that is not an object")
+	}
+}
+
+func TestParseRevisionDate(t *testing.T) {
+	for _, value := range []string{"2026-10-18", "2026-10-18T00:00:00"} {
+		date, err := parseRevisionDate(value)
+		if err != nil {
+			t.Errorf("parseRevisionDate(%q) returned error %v", value, err)
+			continue
+		}
+		if date.Format("2006-01-02") != "2026-10-18" {
+			t.Errorf("parseRevisionDate(%q) = %s", value, date)
+		}
+	}
+	if _, err := parseRevisionDate("yesterday"); err == nil {
+		t.Error("Expected error for an invalid date")
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+// SettingsHandler serves the user's own settings.
+type SettingsHandler struct {
+	queries *database.Queries
+}
+
+func NewSettingsHandler(queries *database.Queries) *SettingsHandler {
+	return &SettingsHandler{queries: queries}
+}
+
+func (h *SettingsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.queries.GetUserSettings(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to get settings", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, settings)
+}
+
+// Update changes the settings given in the body, leaving the others alone.
+func (h *SettingsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.UpdateUserSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Timezone != nil && !validTimezone(*req.Timezone) {
+		http.Error(w, "Invalid timezone, must be an IANA name such as Europe/Amsterdam", http.StatusBadRequest)
+		return
+	}
+	if req.Digest != nil && !validDigest(*req.Digest) {
+		http.Error(w, "Invalid digest, must be off, daily or weekly", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.queries.UpdateUserSettings(r.Context(), userID, req.Timezone, req.Digest)
+	if err != nil {
+		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, settings)
+}
+
+// validTimezone accepts IANA names. time.LoadLocation also takes "" and
+// "Local", which would depend on the server's clock.
+func validTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+func validDigest(digest string) bool {
+	return digest == models.DigestOff || digest == models.DigestDaily || digest == models.DigestWeekly
+}
+
+// userLocation returns the location of the user's timezone setting.
+func userLocation(settings *models.UserSettings) *time.Location {
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
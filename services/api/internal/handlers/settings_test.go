@@ -0,0 +1,29 @@
+package handlers
+
+import "testing"
+
+func TestValidTimezone(t *testing.T) {
+	for _, name := range []string{"UTC", "Europe/Amsterdam", "America/New_York"} {
+		if !validTimezone(name) {
+			t.Errorf("Expected %q to be valid", name)
+		}
+	}
+	for _, name := range []string{"", "Local", "Mars/Olympus", "+02:00"} {
+		if validTimezone(name) {
+			t.Errorf("Expected %q to be invalid", name)
+		}
+	}
+}
+
+func TestValidDigest(t *testing.T) {
+	for _, digest := range []string{"off", "daily", "weekly"} {
+		if !validDigest(digest) {
+			t.Errorf("Expected %q to be valid", digest)
+		}
+	}
+	for _, digest := range []string{"", "monthly", "Daily"} {
+		if validDigest(digest) {
+			t.Errorf("Expected %q to be invalid", digest)
+		}
+	}
+}
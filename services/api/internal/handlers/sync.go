@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSyncLimit = 500
+	maxSyncLimit     = 1000
+	// maxSyncChanges caps the changes in one push.
+	maxSyncChanges = 500
+)
+
+// SyncHandler lets clients that work offline catch up on what changed and
+// push what they changed meanwhile. Pushed changes are made with the same
+// checks as the REST API, so they are validated and authorized alike.
+type SyncHandler struct {
+	queries *database.Queries
+	stores  map[string]itemStore
+}
+
+func NewSyncHandler(queries *database.Queries, projects *ProjectHandler, tasks *TaskHandler, logEntries *LogEntryHandler) *SyncHandler {
+	return &SyncHandler{
+		queries: queries,
+		stores: map[string]itemStore{
+			models.SyncProject:  projects,
+			models.SyncTask:     tasks,
+			models.SyncLogEntry: logEntries,
+		},
+	}
+}
+
+// Changes returns what changed after ?since, the cursor of a previous sync,
+// or everything without it. Pages hold at most ?limit items; the client
+// keeps asking from the returned cursor while has_more is true.
+func (h *SyncHandler) Changes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var since int64
+	if value := r.URL.Query().Get("since"); value != "" {
+		var err error
+		if since, err = strconv.ParseInt(value, 10, 64); err != nil || since < 0 {
+			http.Error(w, "Invalid since, must be a cursor from a previous sync", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := defaultSyncLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxSyncLimit {
+			http.Error(w, "Invalid limit, must be 1 to "+strconv.Itoa(maxSyncLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	changes, err := h.queries.ListChangesSince(r.Context(), userID, since, limit)
+	if err != nil {
+		http.Error(w, "Failed to list changes", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, changes)
+}
+
+// Push applies the client's changes in order and reports on each. A change
+// made from an outdated copy is a conflict, which the later change wins.
+// ?force=true lets changes complete tasks with open blockers.
+func (h *SyncHandler) Push(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.SyncPushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Changes) > maxSyncChanges {
+		http.Error(w, "Too many changes, at most "+strconv.Itoa(maxSyncChanges)+" per push", http.StatusBadRequest)
+		return
+	}
+
+	response := models.SyncPushResponse{Results: []models.SyncResult{}}
+	for _, change := range req.Changes {
+		response.Results = append(response.Results, h.apply(r.Context(), userID, change, forced(r)))
+	}
+
+	writeJSON(w, response)
+}
+
+// apply makes one change. Items the user cannot see are created under the
+// client's ID, which deleting then has nothing to do. Otherwise the change
+// is written only over the version it was made from; if the item has moved
+// on, the change still wins when it was made after the item's last update.
+func (h *SyncHandler) apply(ctx context.Context, userID string, change models.SyncChange, force bool) models.SyncResult {
+	result := models.SyncResult{Type: change.Type, ID: change.ID}
+	reject := func(message string) models.SyncResult {
+		result.Status = models.SyncRejected
+		result.Error = message
+		return result
+	}
+
+	store, ok := h.stores[change.Type]
+	if !ok {
+		return reject("Invalid type, must be project, task or log_entry")
+	}
+	if _, err := uuid.Parse(change.ID); err != nil {
+		return reject("Invalid id format")
+	}
+	var data map[string]json.RawMessage
+	switch change.Op {
+	case "upsert":
+		if err := json.Unmarshal(change.Data, &data); err != nil || data == nil {
+			return reject("Invalid data, must be a JSON object")
+		}
+	case "delete":
+	default:
+		return reject("Invalid op, must be upsert or delete")
+	}
+
+	current, err := store.getItem(ctx, userID, change.ID)
+	if hasStatus(err, http.StatusNotFound) {
+		result.Status = models.SyncApplied
+		if change.Op == "delete" {
+			return result
+		}
+		id, err := json.Marshal(change.ID)
+		if err != nil {
+			return reject("Invalid id format")
+		}
+		data["id"] = id
+		body, err := json.Marshal(data)
+		if err != nil {
+			return reject("Invalid data, must be a JSON object")
+		}
+		written, err := store.createItem(ctx, userID, body)
+		return syncOutcome(result, written, err)
+	}
+	if err != nil {
+		_, message := errorStatus(err)
+		return reject(message)
+	}
+
+	result.Status = models.SyncApplied
+	if change.BaseVersion == nil || *change.BaseVersion != current.version {
+		result.Status = models.SyncConflict
+		if !change.ModifiedAt.After(current.updatedAt) {
+			result.Resolution = models.SyncServerWins
+			result.Record = recordJSON(current)
+			return result
+		}
+		result.Resolution = models.SyncClientWins
+	}
+
+	versions := []int64{current.version}
+	var written *item
+	if change.Op == "upsert" {
+		body, marshalErr := json.Marshal(data)
+		if marshalErr != nil {
+			return reject("Invalid data, must be a JSON object")
+		}
+		written, err = store.patchItem(ctx, userID, change.ID, body, versions, force)
+	} else {
+		err = store.deleteItem(ctx, userID, change.ID, versions)
+	}
+	if hasStatus(err, http.StatusPreconditionFailed) {
+		// Someone else wrote in between; theirs is the later change.
+		result.Status = models.SyncConflict
+		result.Resolution = models.SyncServerWins
+		if latest, err := store.getItem(ctx, userID, change.ID); err == nil {
+			result.Record = recordJSON(latest)
+		}
+		return result
+	}
+	return syncOutcome(result, written, err)
+}
+
+// syncOutcome completes result from the outcome of the write: the item
+// written, if any, or the error it failed with.
+func syncOutcome(result models.SyncResult, written *item, err error) models.SyncResult {
+	if err != nil {
+		result.Status = models.SyncRejected
+		result.Resolution = ""
+		_, result.Error = errorStatus(err)
+		return result
+	}
+	if written != nil {
+		result.Record = recordJSON(written)
+	}
+	return result
+}
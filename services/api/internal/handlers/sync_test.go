@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+// fakeItems stands in for the store of one item type, keeping items in
+// memory and checking versions like the real ones.
+type fakeItems struct {
+	items map[string]*fakeItem
+	// writeBetween, if set, is called before a write so a test can slip
+	// in a competing one.
+	writeBetween func()
+}
+
+type fakeItem struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// snapshot returns a copy of the item, as a store returns it.
+func (f *fakeItem) snapshot() *item {
+	record := *f
+	return &item{record: record, version: f.Version, updatedAt: f.UpdatedAt}
+}
+
+func (f *fakeItems) getItem(ctx context.Context, userID, id string) (*item, error) {
+	found := f.items[id]
+	if found == nil {
+		return nil, notFound("Item not found")
+	}
+	return found.snapshot(), nil
+}
+
+func (f *fakeItems) createItem(ctx context.Context, userID string, data []byte) (*item, error) {
+	var created fakeItem
+	if err := json.Unmarshal(data, &created); err != nil || created.Title == "" {
+		return nil, validationError("Title is required")
+	}
+	created.Version, created.UpdatedAt = 1, time.Now()
+	f.items[created.ID] = &created
+	return created.snapshot(), nil
+}
+
+// written finds the item for a write, checking versions.
+func (f *fakeItems) written(id string, versions []int64) (*fakeItem, error) {
+	if f.writeBetween != nil {
+		f.writeBetween()
+	}
+	found := f.items[id]
+	if found == nil {
+		return nil, notFound("Item not found")
+	}
+	if versions != nil && (len(versions) != 1 || versions[0] != found.Version) {
+		return nil, errPreconditionFailed
+	}
+	return found, nil
+}
+
+func (f *fakeItems) patchItem(ctx context.Context, userID, id string, data []byte, versions []int64, force bool) (*item, error) {
+	found, err := f.written(id, versions)
+	if err != nil {
+		return nil, err
+	}
+	var patch fakeItem
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, validationError("Invalid request body")
+	}
+	found.Title = patch.Title
+	found.Version++
+	found.UpdatedAt = time.Now()
+	return found.snapshot(), nil
+}
+
+func (f *fakeItems) deleteItem(ctx context.Context, userID, id string, versions []int64) error {
+	found, err := f.written(id, versions)
+	if err != nil {
+		return err
+	}
+	delete(f.items, found.ID)
+	return nil
+}
+
+func TestSyncApply(t *testing.T) {
+	const id = "550e8400-e29b-41d4-a716-446655440000"
+	lastUpdate := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	version := func(v int64) *int64 { return &v }
+	fresh := func() *fakeItems {
+		return &fakeItems{items: map[string]*fakeItem{id: {ID: id, Title: "Server", Version: 3, UpdatedAt: lastUpdate}}}
+	}
+
+	tests := []struct {
+		name       string
+		items      *fakeItems
+		change     models.SyncChange
+		status     string
+		resolution string
+		title      string // the item's title afterwards, empty if deleted
+	}{
+		{
+			name:   "create under the client's ID",
+			items:  &fakeItems{items: map[string]*fakeItem{}},
+			change: models.SyncChange{Op: "upsert", Data: json.RawMessage(`{"title":"Client"}`)},
+			status: models.SyncApplied,
+			title:  "Client",
+		},
+		{
+			name:   "update from the current version",
+			items:  fresh(),
+			change: models.SyncChange{Op: "upsert", BaseVersion: version(3), Data: json.RawMessage(`{"title":"Client"}`)},
+			status: models.SyncApplied,
+			title:  "Client",
+		},
+		{
+			name:       "later change from an old version wins",
+			items:      fresh(),
+			change:     models.SyncChange{Op: "upsert", BaseVersion: version(2), ModifiedAt: lastUpdate.Add(time.Minute), Data: json.RawMessage(`{"title":"Client"}`)},
+			status:     models.SyncConflict,
+			resolution: models.SyncClientWins,
+			title:      "Client",
+		},
+		{
+			name:       "earlier change from an old version loses",
+			items:      fresh(),
+			change:     models.SyncChange{Op: "upsert", BaseVersion: version(2), ModifiedAt: lastUpdate.Add(-time.Minute), Data: json.RawMessage(`{"title":"Client"}`)},
+			status:     models.SyncConflict,
+			resolution: models.SyncServerWins,
+			title:      "Server",
+		},
+		{
+			name:   "delete from the current version",
+			items:  fresh(),
+			change: models.SyncChange{Op: "delete", BaseVersion: version(3)},
+			status: models.SyncApplied,
+		},
+		{
+			name:       "earlier delete from an old version loses",
+			items:      fresh(),
+			change:     models.SyncChange{Op: "delete", BaseVersion: version(1), ModifiedAt: lastUpdate},
+			status:     models.SyncConflict,
+			resolution: models.SyncServerWins,
+			title:      "Server",
+		},
+		{
+			name:   "deleting what is gone",
+			items:  &fakeItems{items: map[string]*fakeItem{}},
+			change: models.SyncChange{Op: "delete", BaseVersion: version(3)},
+			status: models.SyncApplied,
+		},
+		{
+			name:   "invalid create is rejected",
+			items:  &fakeItems{items: map[string]*fakeItem{}},
+			change: models.SyncChange{Op: "upsert", Data: json.RawMessage(`{}`)},
+			status: models.SyncRejected,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &SyncHandler{stores: map[string]itemStore{models.SyncTask: tt.items}}
+			tt.change.Type, tt.change.ID = models.SyncTask, id
+			result := h.apply(context.Background(), "user-1", tt.change, false)
+
+			if result.Status != tt.status || result.Resolution != tt.resolution {
+				t.Errorf("Expected %s/%s, got %s/%s (%s)", tt.status, tt.resolution, result.Status, result.Resolution, result.Error)
+			}
+			title := ""
+			if item := tt.items.items[id]; item != nil {
+				title = item.Title
+			}
+			if title != tt.title {
+				t.Errorf("Expected title %q afterwards, got %q", tt.title, title)
+			}
+			if tt.status == models.SyncConflict && len(result.Record) == 0 {
+				t.Error("Expected a conflict to return the record")
+			}
+		})
+	}
+}
+
+func TestSyncApplyLosesRace(t *testing.T) {
+	const id = "550e8400-e29b-41d4-a716-446655440000"
+	items := &fakeItems{items: map[string]*fakeItem{id: {ID: id, Title: "Server", Version: 3}}}
+	items.writeBetween = func() {
+		items.items[id].Title = "Other"
+		items.items[id].Version = 4
+		items.writeBetween = nil
+	}
+	h := &SyncHandler{stores: map[string]itemStore{models.SyncTask: items}}
+	base := int64(3)
+
+	result := h.apply(context.Background(), "user-1", models.SyncChange{
+		Type: models.SyncTask, ID: id, Op: "upsert", BaseVersion: &base, Data: json.RawMessage(`{"title":"Client"}`),
+	}, false)
+	if result.Status != models.SyncConflict || result.Resolution != models.SyncServerWins {
+		t.Errorf("Expected the competing write to win, got %s/%s", result.Status, result.Resolution)
+	}
+	var record fakeItem
+	if err := json.Unmarshal(result.Record, &record); err != nil || record.Title != "Other" {
+		t.Errorf("Expected the competing record, got %s", result.Record)
+	}
+}
+
+func TestSyncApplyRejectsInvalidChanges(t *testing.T) {
+	h := &SyncHandler{stores: map[string]itemStore{models.SyncTask: &fakeItems{}}}
+	const id = "550e8400-e29b-41d4-a716-446655440000"
+	for _, change := range []models.SyncChange{
+		{Type: "comment", ID: id, Op: "delete"},
+		{Type: models.SyncTask, ID: "42", Op: "delete"},
+		{Type: models.SyncTask, ID: id, Op: "merge"},
+		{Type: models.SyncTask, ID: id, Op: "upsert", Data: json.RawMessage(`["title"]`)},
+		{Type: models.SyncTask, ID: id, Op: "upsert"},
+	} {
+		result := h.apply(context.Background(), "user-1", change, false)
+		if result.Status != models.SyncRejected || result.Error == "" {
+			t.Errorf("Expected %+v to be rejected, got %+v", change, result)
+		}
+	}
+}
+
+func TestSyncRequiresSession(t *testing.T) {
+	h := NewSyncHandler(nil, NewProjectHandler(nil), NewTaskHandler(nil), NewLogEntryHandler(nil))
+	for _, handler := range []http.HandlerFunc{h.Changes, h.Push} {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodGet, "/api/sync", nil))
+		if recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 without a session, got %d", recorder.Code)
+		}
+	}
+}
@@ -1,12 +1,20 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/chrispotter/makerlog/services/api/internal/database"
 	"github.com/chrispotter/makerlog/services/api/internal/middleware"
 	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/chrispotter/makerlog/services/api/internal/rank"
+	"github.com/chrispotter/makerlog/services/api/internal/recurrence"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
@@ -19,6 +27,8 @@ func NewTaskHandler(queries *database.Queries) *TaskHandler {
 	return &TaskHandler{queries: queries}
 }
 
+// List returns the user's tasks. Besides project_id it accepts a priority
+// filter and due=overdue or due=this_week, which only match open tasks.
 func (h *TaskHandler) List(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -26,25 +36,122 @@ func (h *TaskHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Optional project_id filter
-	var projectID *string
-	if projectIDStr := r.URL.Query().Get("project_id"); projectIDStr != "" {
-		if _, err := uuid.Parse(projectIDStr); err != nil {
-			http.Error(w, "Invalid project_id format", http.StatusBadRequest)
+	filter, err := taskFilterFromQuery(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tasks, err := h.queries.ListTasks(r.Context(), userID, filter)
+	if err != nil {
+		http.Error(w, "Failed to list tasks", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, tasks)
+}
+
+// Upcoming returns open tasks due within the next ?days= days (default 7),
+// soonest and most pressing first.
+func (h *TaskHandler) Upcoming(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	days := defaultUpcomingDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed < 1 || parsed > maxUpcomingDays {
+			http.Error(w, "Invalid days, must be between 1 and 90", http.StatusBadRequest)
 			return
 		}
-		projectID = &projectIDStr
+		days = parsed
 	}
 
-	tasks, err := h.queries.ListTasks(userID, projectID)
+	today := startOfDay(time.Now())
+	until := today.AddDate(0, 0, days)
+	tasks, err := h.queries.ListTasks(r.Context(), userID, database.TaskFilter{DueFrom: &today, DueBefore: &until})
 	if err != nil {
-		http.Error(w, "Failed to list tasks", http.StatusInternalServerError)
+		http.Error(w, "Failed to list upcoming tasks", http.StatusInternalServerError)
 		return
 	}
 
 	writeJSON(w, tasks)
 }
 
+const (
+	defaultUpcomingDays = 7
+	maxUpcomingDays     = 90
+)
+
+var validPriorities = map[string]bool{
+	"low":    true,
+	"medium": true,
+	"high":   true,
+	"urgent": true,
+}
+
+func taskFilterFromQuery(query url.Values, now time.Time) (database.TaskFilter, error) {
+	var filter database.TaskFilter
+
+	if projectID := query.Get("project_id"); projectID != "" {
+		if _, err := uuid.Parse(projectID); err != nil {
+			return filter, validationError("Invalid project_id format")
+		}
+		filter.ProjectID = &projectID
+	}
+
+	if workspaceID := query.Get("workspace_id"); workspaceID != "" {
+		if _, err := uuid.Parse(workspaceID); err != nil {
+			return filter, validationError("Invalid workspace_id format")
+		}
+		filter.WorkspaceID = &workspaceID
+	}
+
+	if priority := query.Get("priority"); priority != "" {
+		if !validPriorities[priority] {
+			return filter, validationError("Invalid priority, must be low, medium, high or urgent")
+		}
+		filter.Priority = &priority
+	}
+
+	today := startOfDay(now)
+	switch query.Get("due") {
+	case "":
+	case "overdue":
+		filter.DueBefore = &today
+	case "this_week":
+		// Weeks run Monday to Sunday.
+		monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		nextMonday := monday.AddDate(0, 0, 7)
+		filter.DueFrom = &monday
+		filter.DueBefore = &nextMonday
+	default:
+		return filter, validationError("Invalid due filter, must be overdue or this_week")
+	}
+
+	return filter, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// parseDueDate parses an optional YYYY-MM-DD due date.
+func parseDueDate(value *string) (*time.Time, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	dueDate, err := time.Parse("2006-01-02", *value)
+	if err != nil {
+		return nil, validationError("Invalid due date format. Use YYYY-MM-DD")
+	}
+	return &dueDate, nil
+}
+
 func (h *TaskHandler) Create(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -58,28 +165,107 @@ func (h *TaskHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Title == "" {
-		http.Error(w, "Task title is required", http.StatusBadRequest)
+	task, err := h.create(r.Context(), userID, req)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
+	setETag(w, etag(task.Version))
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, task)
+}
+
+// create checks and creates a task at the bottom of its board column.
+func (h *TaskHandler) create(ctx context.Context, userID string, req models.CreateTaskRequest) (*models.Task, error) {
+	if req.Title == "" {
+		return nil, validationError("Task title is required")
+	}
+
 	if _, err := uuid.Parse(req.ProjectID); err != nil {
-		http.Error(w, "Invalid project_id format", http.StatusBadRequest)
-		return
+		return nil, validationError("Invalid project_id format")
+	}
+
+	if req.ID != nil {
+		if _, err := uuid.Parse(*req.ID); err != nil {
+			return nil, validationError("Invalid id format")
+		}
 	}
 
+	if req.ParentTaskID != nil {
+		if _, err := uuid.Parse(*req.ParentTaskID); err != nil {
+			return nil, validationError("Invalid parent_task_id format")
+		}
+		if err := h.checkParent(ctx, userID, *req.ParentTaskID, req.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	workflow, err := h.queries.GetWorkflow(ctx, req.ProjectID, userID)
+	if err != nil {
+		return nil, internalError("Failed to get project workflow")
+	}
+	if workflow == nil {
+		return nil, validationError("Project not found")
+	}
+	if err := requireWritable(ctx, h.queries, userID, models.RoleMember, &req.ProjectID); err != nil {
+		return nil, err
+	}
 	if req.Status == "" {
-		req.Status = "todo"
+		req.Status = workflow.DefaultStatus()
+	}
+	if workflow.Status(req.Status) == nil {
+		return nil, validateStatusChange(workflow, "", req.Status)
+	}
+
+	if req.Priority != "" && !validPriorities[req.Priority] {
+		return nil, validationError("Invalid priority, must be low, medium, high or urgent")
 	}
 
-	task, err := h.queries.CreateTask(userID, req.ProjectID, req.Title, req.Description, req.Status)
+	dueDate, err := parseDueDate(req.DueDate)
 	if err != nil {
-		http.Error(w, "Failed to create task", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	writeJSON(w, task)
+	rule, err := parseRecurrenceRule(req.RecurrenceRule)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil && dueDate == nil {
+		return nil, validationError("Recurring tasks need a due_date")
+	}
+
+	// New tasks go to the bottom of their board column.
+	last, err := h.queries.LastTaskPosition(ctx, req.ProjectID, req.Status, userID, "")
+	if err != nil {
+		return nil, internalError("Failed to create task")
+	}
+	position, err := rank.Between(last, "")
+	if err != nil {
+		return nil, internalError("Failed to create task")
+	}
+
+	task, err := h.queries.CreateTask(ctx, database.CreateTaskParams{
+		ID:             req.ID,
+		UserID:         userID,
+		ProjectID:      req.ProjectID,
+		ParentTaskID:   req.ParentTaskID,
+		Title:          req.Title,
+		Description:    req.Description,
+		Status:         req.Status,
+		Priority:       req.Priority,
+		Position:       position,
+		DueDate:        dueDate,
+		RecurrenceRule: rule,
+	})
+	if errors.Is(err, database.ErrIDTaken) {
+		return nil, conflict("ID already in use")
+	}
+	if err != nil {
+		return nil, internalError("Failed to create task")
+	}
+	h.continueSeries(ctx, false, task)
+	return task, nil
 }
 
 func (h *TaskHandler) Get(w http.ResponseWriter, r *http.Request) {
@@ -95,17 +281,59 @@ func (h *TaskHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := h.queries.GetTask(id, userID)
+	task, err := h.get(r.Context(), userID, id)
 	if err != nil {
-		http.Error(w, "Failed to get task", http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
-	if task == nil {
-		http.Error(w, "Task not found", http.StatusNotFound)
+
+	subtasks, err := h.queries.ListSubtasks(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, "Failed to get subtasks", http.StatusInternalServerError)
 		return
 	}
 
-	writeJSON(w, task)
+	graph, err := h.queries.GetTaskDependencyGraph(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, "Failed to get task dependencies", http.StatusInternalServerError)
+		return
+	}
+
+	detail := newTaskDetail(*task, subtasks, *graph)
+	tag := etagWithDigest(task.Version, detail)
+	setETag(w, tag)
+	if notModified(w, r, tag) {
+		return
+	}
+	writeJSON(w, detail)
+}
+
+// get returns the task, or an error when the user cannot see it.
+func (h *TaskHandler) get(ctx context.Context, userID, id string) (*models.Task, error) {
+	task, err := h.queries.GetTask(ctx, id, userID)
+	if err != nil {
+		return nil, internalError("Failed to get task")
+	}
+	if task == nil {
+		return nil, notFound("Task not found")
+	}
+	return task, nil
+}
+
+// checkParent refuses a parent task the user cannot see or that is in
+// another project than projectID.
+func (h *TaskHandler) checkParent(ctx context.Context, userID, parentID, projectID string) error {
+	parent, err := h.queries.GetTask(ctx, parentID, userID)
+	if err != nil {
+		return internalError("Failed to get parent task")
+	}
+	if parent == nil {
+		return validationError("Parent task not found")
+	}
+	if parent.ProjectID != projectID {
+		return validationError("Subtasks must belong to their parent's project")
+	}
+	return nil
 }
 
 func (h *TaskHandler) Update(w http.ResponseWriter, r *http.Request) {
@@ -132,7 +360,37 @@ func (h *TaskHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := h.queries.UpdateTask(id, userID, req.Title, req.Description, req.Status)
+	current, err := h.queries.GetTask(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, "Failed to get task", http.StatusInternalServerError)
+		return
+	}
+	if current == nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if !checkWritable(r.Context(), w, h.queries, userID, models.RoleMember, &current.ProjectID) {
+		return
+	}
+	workflow, err := h.queries.GetWorkflow(r.Context(), current.ProjectID, userID)
+	if err != nil {
+		http.Error(w, "Failed to get project workflow", http.StatusInternalServerError)
+		return
+	}
+	if err := validateStatusChange(workflow, current.Status, req.Status); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.checkBlockers(r.Context(), userID, current, workflow.IsClosed(req.Status), forced(r)); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	task, err := h.queries.UpdateTask(r.Context(), id, userID, req.Title, req.Description, req.Status, ifMatchVersions(r))
+	if isPreconditionFailed(err) {
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to update task", http.StatusInternalServerError)
 		return
@@ -141,10 +399,211 @@ func (h *TaskHandler) Update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Task not found", http.StatusNotFound)
 		return
 	}
+	h.continueSeries(r.Context(), current.CompletedAt != nil, task)
 
+	setETag(w, etag(task.Version))
 	writeJSON(w, task)
 }
 
+// Patch applies a JSON Merge Patch, changing only the fields supplied.
+// Setting project_id moves the task, with its subtasks, to another of the
+// user's projects; parent_task_id re-parents it or, with null, detaches it.
+func (h *TaskHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.PatchTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	patch, err := taskPatchFromRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.servePatch(w, r, id, userID, patch)
+}
+
+// servePatch applies a task patch for a request, writing the response.
+func (h *TaskHandler) servePatch(w http.ResponseWriter, r *http.Request, id, userID string, patch database.TaskPatch) {
+	task, err := h.patch(r.Context(), userID, id, patch, ifMatchVersions(r), forced(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	setETag(w, etag(task.Version))
+	writeJSON(w, task)
+}
+
+// patch checks and applies a task patch over the given versions, or any
+// version when versions is nil. force lets it complete a task with open
+// blockers.
+func (h *TaskHandler) patch(ctx context.Context, userID, id string, patch database.TaskPatch, versions []int64, force bool) (*models.Task, error) {
+	current, err := h.get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.ProjectID != nil && *patch.ProjectID == current.ProjectID {
+		patch.ProjectID = nil
+	}
+	if err := requireWritable(ctx, h.queries, userID, models.RoleMember, &current.ProjectID, patch.ProjectID); err != nil {
+		return nil, err
+	}
+
+	projectID := current.ProjectID
+	workflow, err := h.queries.GetWorkflow(ctx, current.ProjectID, userID)
+	if err != nil {
+		return nil, internalError("Failed to get project workflow")
+	}
+	if patch.ProjectID != nil && *patch.ProjectID != current.ProjectID {
+		target, err := h.queries.GetWorkflow(ctx, *patch.ProjectID, userID)
+		if err != nil {
+			return nil, internalError("Failed to get project workflow")
+		}
+		if target == nil {
+			return nil, validationError("Project not found")
+		}
+		// The task keeps the closest status the target workflow has
+		// unless the patch picks one.
+		if patch.Status == nil {
+			status := mapStatus(workflow, target, current.Status)
+			patch.Status = &status
+		}
+		projectID, workflow = *patch.ProjectID, target
+	}
+
+	parentID := current.ParentTaskID
+	if patch.SetParentTaskID {
+		parentID = patch.ParentTaskID
+	}
+	if parentID != nil {
+		if err := h.checkParent(ctx, userID, *parentID, projectID); err != nil {
+			return nil, err
+		}
+	}
+
+	dueDate, rule := current.DueDate, current.RecurrenceRule
+	if patch.SetDueDate {
+		dueDate = patch.DueDate
+	}
+	if patch.SetRecurrenceRule {
+		rule = patch.RecurrenceRule
+	}
+	if rule != nil && dueDate == nil {
+		return nil, validationError("Recurring tasks need a due_date")
+	}
+
+	if patch.Status != nil {
+		if err := validateStatusChange(workflow, current.Status, *patch.Status); err != nil {
+			return nil, err
+		}
+		if err := h.checkBlockers(ctx, userID, current, workflow.IsClosed(*patch.Status), force); err != nil {
+			return nil, err
+		}
+	}
+
+	task, err := h.queries.PatchTask(ctx, id, userID, patch, versions)
+	if errors.Is(err, database.ErrParentCycle) {
+		return nil, validationError("A task cannot be a subtask of itself or its subtasks")
+	}
+	if isPreconditionFailed(err) {
+		return nil, errPreconditionFailed
+	}
+	if err != nil {
+		return nil, internalError("Failed to update task")
+	}
+	if task == nil {
+		return nil, notFound("Task not found")
+	}
+	h.continueSeries(ctx, current.CompletedAt != nil, task)
+	return task, nil
+}
+
+func taskPatchFromRequest(req models.PatchTaskRequest) (database.TaskPatch, error) {
+	var patch database.TaskPatch
+	var err error
+	if patch.ProjectID, err = patchRequired(req.ProjectID, "project_id"); err != nil {
+		return patch, err
+	}
+	if patch.ProjectID != nil {
+		if _, err := uuid.Parse(*patch.ProjectID); err != nil {
+			return patch, validationError("Invalid project_id format")
+		}
+	}
+	if patch.SetParentTaskID, patch.ParentTaskID, err = patchUUID(req.ParentTaskID, "parent_task_id"); err != nil {
+		return patch, err
+	}
+	if patch.Title, err = patchRequired(req.Title, "Task title"); err != nil {
+		return patch, err
+	}
+	patch.Description = patchText(req.Description)
+	if patch.Status, err = patchRequired(req.Status, "Task status"); err != nil {
+		return patch, err
+	}
+	if patch.Priority, err = patchRequired(req.Priority, "Task priority"); err != nil {
+		return patch, err
+	}
+	if patch.Priority != nil && !validPriorities[*patch.Priority] {
+		return patch, validationError("Invalid priority, must be low, medium, high or urgent")
+	}
+	if req.DueDate.Present {
+		patch.SetDueDate = true
+		if patch.DueDate, err = parseDueDate(patchText(req.DueDate)); err != nil {
+			return patch, err
+		}
+	}
+	if req.RecurrenceRule.Present {
+		patch.SetRecurrenceRule = true
+		if !req.RecurrenceRule.Null && req.RecurrenceRule.Value != "" {
+			if patch.RecurrenceRule, err = parseRecurrenceRule(&req.RecurrenceRule.Value); err != nil {
+				return patch, err
+			}
+		}
+	}
+	return patch, nil
+}
+
+// parseRecurrenceRule validates an optional recurrence rule and returns it
+// in canonical form.
+func parseRecurrenceRule(value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	rule, err := recurrence.Parse(*value)
+	if err != nil {
+		return nil, validationError("Invalid recurrence_rule: " + err.Error())
+	}
+	canonical := rule.String()
+	return &canonical, nil
+}
+
+// continueSeries creates the next occurrence of a recurring task that has
+// just been completed. A failure is only logged: the task itself was saved,
+// and the scheduler fills in missing occurrences on its next run.
+func (h *TaskHandler) continueSeries(ctx context.Context, wasCompleted bool, task *models.Task) {
+	if wasCompleted || task.CompletedAt == nil || task.RecurrenceRule == nil {
+		return
+	}
+	sideEffect(ctx, h.queries, "create next occurrence of task "+task.ID, func(queries *database.Queries) error {
+		_, err := queries.CreateNextOccurrence(ctx, task, nil)
+		return err
+	})
+}
+
 func (h *TaskHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -158,10 +617,218 @@ func (h *TaskHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.queries.DeleteTask(id, userID); err != nil {
-		http.Error(w, "Failed to delete task", http.StatusInternalServerError)
+	if err := h.delete(r.Context(), userID, id, ifMatchVersions(r)); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// delete moves the task to the trash, if it is still at one of versions or
+// versions is nil.
+func (h *TaskHandler) delete(ctx context.Context, userID, id string, versions []int64) error {
+	task, err := h.get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := requireWritable(ctx, h.queries, userID, models.RoleMember, &task.ProjectID); err != nil {
+		return err
+	}
+
+	err = h.queries.DeleteTask(ctx, id, userID, versions)
+	if err == sql.ErrNoRows {
+		return notFound("Task not found")
+	}
+	if isPreconditionFailed(err) {
+		return errPreconditionFailed
+	}
+	if err != nil {
+		return internalError("Failed to delete task")
+	}
+	return nil
+}
+
+// AddDependency marks the task as blocked by another of the user's tasks.
+func (h *TaskHandler) AddDependency(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AddTaskDependencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := uuid.Parse(req.BlockedByID); err != nil {
+		http.Error(w, "Invalid blocked_by_id format", http.StatusBadRequest)
+		return
+	}
+
+	task, err := h.queries.GetTask(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, "Failed to get task", http.StatusInternalServerError)
+		return
+	}
+	if task == nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+
+	blocker, err := h.queries.GetTask(r.Context(), req.BlockedByID, userID)
+	if err != nil {
+		http.Error(w, "Failed to get task", http.StatusInternalServerError)
+		return
+	}
+	if blocker == nil {
+		http.Error(w, "Blocking task not found", http.StatusBadRequest)
+		return
+	}
+	if !checkWritable(r.Context(), w, h.queries, userID, models.RoleMember, &task.ProjectID) {
+		return
+	}
+
+	dependency, err := h.queries.AddTaskDependency(r.Context(), task.ID, blocker.ID)
+	if errors.Is(err, database.ErrDependencyCycle) {
+		http.Error(w, "Dependency would create a cycle", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to add dependency", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, dependency)
+}
+
+// RemoveDependency removes a blocked-by relation.
+func (h *TaskHandler) RemoveDependency(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	blockedByID := chi.URLParam(r, "blockedByID")
+	if _, err := uuid.Parse(blockedByID); err != nil {
+		http.Error(w, "Invalid blocked_by_id format", http.StatusBadRequest)
+		return
+	}
+
+	task, err := h.queries.GetTask(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, "Failed to get task", http.StatusInternalServerError)
+		return
+	}
+	if task == nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	if !checkWritable(r.Context(), w, h.queries, userID, models.RoleMember, &task.ProjectID) {
+		return
+	}
+
+	err = h.queries.RemoveTaskDependency(r.Context(), id, blockedByID, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Dependency not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to remove dependency", http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// checkBlockers refuses to let userID move an unfinished task to a closed
+// status while any task it is blocked by is unfinished, unless force is
+// set, as ?force=true does for a request.
+func (h *TaskHandler) checkBlockers(ctx context.Context, userID string, current *models.Task, closing, force bool) error {
+	if !closing || current.CompletedAt != nil || force {
+		return nil
+	}
+
+	openBlockers, err := h.queries.CountOpenBlockers(ctx, current.ID, userID)
+	if err != nil {
+		return internalError("Failed to check task dependencies")
+	}
+	if openBlockers > 0 {
+		return conflict("Task has open blockers; pass force=true to complete it anyway")
+	}
+	return nil
+}
+
+// newTaskDetail assembles the single-task representation from the task, its
+// descendants (parents before children) and its dependency graph.
+func newTaskDetail(task models.Task, subtasks []models.Task, graph models.TaskGraph) models.TaskDetail {
+	detail := models.TaskDetail{
+		TaskTree:     newTaskTree(task, subtasks),
+		BlockedBy:    []string{},
+		Dependencies: graph,
+	}
+
+	finished := make(map[string]bool, len(graph.Tasks))
+	for _, t := range graph.Tasks {
+		finished[t.ID] = t.CompletedAt != nil
+	}
+	for _, edge := range graph.Edges {
+		if edge.TaskID != task.ID {
+			continue
+		}
+		detail.BlockedBy = append(detail.BlockedBy, edge.BlockedByID)
+		if !finished[edge.BlockedByID] {
+			detail.OpenBlockers++
+		}
+	}
+	return detail
+}
+
+// newTaskTree nests descendants under root and rolls progress up from the
+// leaves: a leaf is 0 or 1 depending on whether it is finished, and a parent
+// is the mean of its children.
+func newTaskTree(root models.Task, descendants []models.Task) models.TaskTree {
+	children := make(map[string][]models.Task)
+	for _, t := range descendants {
+		if t.ParentTaskID != nil {
+			children[*t.ParentTaskID] = append(children[*t.ParentTaskID], t)
+		}
+	}
+
+	var build func(task models.Task) models.TaskTree
+	build = func(task models.Task) models.TaskTree {
+		node := models.TaskTree{Task: task, Subtasks: []models.TaskTree{}}
+		for _, child := range children[task.ID] {
+			node.Subtasks = append(node.Subtasks, build(child))
+		}
+		if len(node.Subtasks) == 0 {
+			if task.CompletedAt != nil {
+				node.Progress = 1
+			}
+			return node
+		}
+		var total float64
+		for _, sub := range node.Subtasks {
+			total += sub.Progress
+		}
+		node.Progress = total / float64(len(node.Subtasks))
+		return node
+	}
+	return build(root)
+}
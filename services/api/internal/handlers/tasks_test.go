@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"encoding/json"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/chrispotter/makerlog/services/api/internal/models"
 	"github.com/google/uuid"
@@ -116,3 +119,332 @@ func TestTaskDefaultStatus(t *testing.T) {
 		t.Errorf("Expected default status to be 'todo', got %s", status)
 	}
 }
+
+func TestTaskPatchFromRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		json        string
+		wantErr     bool
+		wantProject bool
+		wantStatus  bool
+	}{
+		{
+			name: "empty patch",
+			json: `{}`,
+		},
+		{
+			name:       "status only",
+			json:       `{"status":"done"}`,
+			wantStatus: true,
+		},
+		{
+			name:        "move to another project",
+			json:        `{"project_id":"550e8400-e29b-41d4-a716-446655440000"}`,
+			wantProject: true,
+		},
+		{
+			name:    "null project",
+			json:    `{"project_id":null}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid project ID",
+			json:    `{"project_id":"invalid-uuid"}`,
+			wantErr: true,
+		},
+		{
+			name:    "empty title",
+			json:    `{"title":""}`,
+			wantErr: true,
+		},
+		{
+			name:    "null status",
+			json:    `{"status":null}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.PatchTaskRequest
+			if err := json.Unmarshal([]byte(tt.json), &req); err != nil {
+				t.Fatalf("Failed to unmarshal patch: %v", err)
+			}
+
+			patch, err := taskPatchFromRequest(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (patch.ProjectID != nil) != tt.wantProject {
+				t.Errorf("Expected project_id set=%v", tt.wantProject)
+			}
+			if (patch.Status != nil) != tt.wantStatus {
+				t.Errorf("Expected status set=%v", tt.wantStatus)
+			}
+			if patch.Title != nil {
+				t.Error("Expected omitted title to be left unchanged")
+			}
+		})
+	}
+}
+
+func TestTaskFilterFromQuery(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, 1, 10, 15, 30, 0, 0, time.UTC)
+	monday := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	nextMonday := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	today := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantErr   bool
+		priority  string
+		dueFrom   *time.Time
+		dueBefore *time.Time
+	}{
+		{
+			name:  "no filters",
+			query: "",
+		},
+		{
+			name:     "priority",
+			query:    "priority=high",
+			priority: "high",
+		},
+		{
+			name:    "invalid priority",
+			query:   "priority=critical",
+			wantErr: true,
+		},
+		{
+			name:      "overdue",
+			query:     "due=overdue",
+			dueBefore: &today,
+		},
+		{
+			name:      "this week",
+			query:     "due=this_week",
+			dueFrom:   &monday,
+			dueBefore: &nextMonday,
+		},
+		{
+			name:    "invalid due filter",
+			query:   "due=tomorrow",
+			wantErr: true,
+		},
+		{
+			name:    "invalid project ID",
+			query:   "project_id=invalid-uuid",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("Failed to parse query: %v", err)
+			}
+
+			filter, err := taskFilterFromQuery(query, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.priority != "" && (filter.Priority == nil || *filter.Priority != tt.priority) {
+				t.Errorf("Expected priority %s, got %v", tt.priority, filter.Priority)
+			}
+			if !sameTime(filter.DueFrom, tt.dueFrom) {
+				t.Errorf("Expected DueFrom %v, got %v", tt.dueFrom, filter.DueFrom)
+			}
+			if !sameTime(filter.DueBefore, tt.dueBefore) {
+				t.Errorf("Expected DueBefore %v, got %v", tt.dueBefore, filter.DueBefore)
+			}
+		})
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestTaskPatchDueDateAndPriority(t *testing.T) {
+	var req models.PatchTaskRequest
+	if err := json.Unmarshal([]byte(`{"due_date":null,"priority":"urgent"}`), &req); err != nil {
+		t.Fatalf("Failed to unmarshal patch: %v", err)
+	}
+
+	patch, err := taskPatchFromRequest(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !patch.SetDueDate || patch.DueDate != nil {
+		t.Error("Expected null due_date to clear the due date")
+	}
+	if patch.Priority == nil || *patch.Priority != "urgent" {
+		t.Errorf("Expected priority urgent, got %v", patch.Priority)
+	}
+
+	if err := json.Unmarshal([]byte(`{"priority":"whenever"}`), &req); err != nil {
+		t.Fatalf("Failed to unmarshal patch: %v", err)
+	}
+	if _, err := taskPatchFromRequest(req); err == nil {
+		t.Error("Expected error for invalid priority")
+	}
+}
+
+func TestNewTaskTreeProgress(t *testing.T) {
+	root := "root"
+	a := "a"
+	completed := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	tasks := []models.Task{
+		{ID: "a", ParentTaskID: &root, Status: "in_progress"},
+		{ID: "b", ParentTaskID: &root, Status: "done", CompletedAt: &completed},
+		{ID: "a1", ParentTaskID: &a, Status: "done", CompletedAt: &completed},
+		{ID: "a2", ParentTaskID: &a, Status: "todo"},
+	}
+
+	tree := newTaskTree(models.Task{ID: "root", Status: "todo"}, tasks)
+
+	if len(tree.Subtasks) != 2 {
+		t.Fatalf("Expected 2 subtasks, got %d", len(tree.Subtasks))
+	}
+	if got := tree.Subtasks[0].Progress; got != 0.5 {
+		t.Errorf("Expected subtask a progress 0.5, got %v", got)
+	}
+	if got := tree.Subtasks[1].Progress; got != 1 {
+		t.Errorf("Expected subtask b progress 1, got %v", got)
+	}
+	if got := tree.Progress; got != 0.75 {
+		t.Errorf("Expected root progress 0.75, got %v", got)
+	}
+}
+
+func TestNewTaskDetailBlockers(t *testing.T) {
+	completed := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	graph := models.TaskGraph{
+		Tasks: []models.Task{
+			{ID: "b1", Status: "shipped", CompletedAt: &completed},
+			{ID: "b2", Status: "todo"},
+			{ID: "d1", Status: "todo"},
+		},
+		Edges: []models.TaskDependency{
+			{TaskID: "task", BlockedByID: "b1"},
+			{TaskID: "task", BlockedByID: "b2"},
+			{TaskID: "d1", BlockedByID: "task"},
+		},
+	}
+
+	detail := newTaskDetail(models.Task{ID: "task", Status: "todo"}, nil, graph)
+
+	if len(detail.BlockedBy) != 2 {
+		t.Errorf("Expected 2 direct blockers, got %v", detail.BlockedBy)
+	}
+	if detail.OpenBlockers != 1 {
+		t.Errorf("Expected 1 open blocker, got %d", detail.OpenBlockers)
+	}
+	if detail.Progress != 0 {
+		t.Errorf("Expected progress 0 for open leaf task, got %v", detail.Progress)
+	}
+}
+
+func TestTaskPatchParent(t *testing.T) {
+	tests := []struct {
+		name      string
+		json      string
+		wantErr   bool
+		setParent bool
+		clear     bool
+	}{
+		{
+			name:      "set parent",
+			json:      `{"parent_task_id":"550e8400-e29b-41d4-a716-446655440000"}`,
+			setParent: true,
+		},
+		{
+			name:      "detach from parent",
+			json:      `{"parent_task_id":null}`,
+			setParent: true,
+			clear:     true,
+		},
+		{
+			name:    "invalid parent ID",
+			json:    `{"parent_task_id":"invalid-uuid"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.PatchTaskRequest
+			if err := json.Unmarshal([]byte(tt.json), &req); err != nil {
+				t.Fatalf("Failed to unmarshal patch: %v", err)
+			}
+
+			patch, err := taskPatchFromRequest(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if patch.SetParentTaskID != tt.setParent {
+				t.Errorf("Expected SetParentTaskID=%v", tt.setParent)
+			}
+			if tt.clear != (patch.ParentTaskID == nil) {
+				t.Errorf("Expected parent cleared=%v", tt.clear)
+			}
+		})
+	}
+}
+
+func TestTaskPatchRecurrenceRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr bool
+		want    *string
+		set     bool
+	}{
+		{name: "omitted", json: `{}`},
+		{name: "stop recurring", json: `{"recurrence_rule":null}`, set: true},
+		{name: "canonicalized", json: `{"recurrence_rule":"freq=weekly;byday=th,mo"}`, set: true, want: strPtr("FREQ=WEEKLY;BYDAY=MO,TH")},
+		{name: "unsupported", json: `{"recurrence_rule":"FREQ=YEARLY"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.PatchTaskRequest
+			if err := json.Unmarshal([]byte(tt.json), &req); err != nil {
+				t.Fatalf("Failed to unmarshal patch: %v", err)
+			}
+
+			patch, err := taskPatchFromRequest(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if patch.SetRecurrenceRule != tt.set {
+				t.Errorf("Expected SetRecurrenceRule=%v", tt.set)
+			}
+			if (tt.want == nil) != (patch.RecurrenceRule == nil) || (tt.want != nil && *tt.want != *patch.RecurrenceRule) {
+				t.Errorf("Expected rule %v, got %v", tt.want, patch.RecurrenceRule)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// TrashHandler lists deleted items and brings them back until the purge job
+// removes them for good.
+type TrashHandler struct {
+	queries   *database.Queries
+	retention time.Duration
+}
+
+func NewTrashHandler(queries *database.Queries, retention time.Duration) *TrashHandler {
+	return &TrashHandler{queries: queries, retention: retention}
+}
+
+func (h *TrashHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	trash, err := h.queries.ListTrash(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to list trash", http.StatusInternalServerError)
+		return
+	}
+	trash.RetentionDays = retentionDays(h.retention)
+
+	writeJSON(w, trash)
+}
+
+// RestoreProject brings back a deleted project with the tasks and log entries
+// deleted along with it.
+func (h *TrashHandler) RestoreProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !checkProjectRole(r.Context(), w, h.queries, userID, models.RoleAdmin, true, &id) {
+		return
+	}
+
+	project, err := h.queries.RestoreProject(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, "Failed to restore project", http.StatusInternalServerError)
+		return
+	}
+	if project == nil {
+		http.Error(w, "Project not found in trash", http.StatusNotFound)
+		return
+	}
+
+	setETag(w, etag(project.Version))
+	writeJSON(w, project)
+}
+
+// RestoreTask brings back a deleted task with the subtasks deleted along with
+// it.
+func (h *TrashHandler) RestoreTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid task ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !h.checkRestorable(r.Context(), w, "tasks", id, userID) {
+		return
+	}
+
+	task, err := h.queries.RestoreTask(r.Context(), id, userID)
+	if errors.Is(err, database.ErrParentDeleted) {
+		http.Error(w, "Restore the task's project or parent task first", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to restore task", http.StatusInternalServerError)
+		return
+	}
+	if task == nil {
+		http.Error(w, "Task not found in trash", http.StatusNotFound)
+		return
+	}
+
+	setETag(w, etag(task.Version))
+	writeJSON(w, task)
+}
+
+func (h *TrashHandler) RestoreLogEntry(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid log entry ID format", http.StatusBadRequest)
+		return
+	}
+
+	if !h.checkRestorable(r.Context(), w, "log_entries", id, userID) {
+		return
+	}
+
+	logEntry, err := h.queries.RestoreLogEntry(r.Context(), id, userID)
+	if errors.Is(err, database.ErrParentDeleted) {
+		http.Error(w, "Restore the log entry's project first", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to restore log entry", http.StatusInternalServerError)
+		return
+	}
+	if logEntry == nil {
+		http.Error(w, "Log entry not found in trash", http.StatusNotFound)
+		return
+	}
+
+	setETag(w, etag(logEntry.Version))
+	writeJSON(w, logEntry)
+}
+
+// checkRestorable checks the user may edit the project of the task or log
+// entry in the trash. Items the user cannot see are left for the restore to
+// report as not found.
+func (h *TrashHandler) checkRestorable(ctx context.Context, w http.ResponseWriter, table, id, userID string) bool {
+	projectID, found, err := h.queries.TrashedItemProject(ctx, table, id, userID)
+	if err != nil {
+		http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+		return false
+	}
+	if !found {
+		return true
+	}
+	return checkWritable(ctx, w, h.queries, userID, models.RoleMember, projectID)
+}
+
+// retentionDays rounds the retention period up to whole days.
+func retentionDays(retention time.Duration) int {
+	day := 24 * time.Hour
+	return int((retention + day - 1) / day)
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetentionDays(t *testing.T) {
+	tests := []struct {
+		retention time.Duration
+		want      int
+	}{
+		{30 * 24 * time.Hour, 30},
+		{36 * time.Hour, 2},
+		{time.Hour, 1},
+		{0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := retentionDays(tt.retention); got != tt.want {
+			t.Errorf("retentionDays(%s) = %d, want %d", tt.retention, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var statusKeyPattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+var validStatusCategories = map[string]bool{
+	models.StatusCategoryOpen:   true,
+	models.StatusCategoryActive: true,
+	models.StatusCategoryClosed: true,
+}
+
+// transitionError means the workflow does not allow a task to move between
+// two of its statuses.
+type transitionError struct {
+	from, to string
+}
+
+func (e transitionError) Error() string {
+	return fmt.Sprintf("Cannot move a task from %s to %s in this project's workflow", e.from, e.to)
+}
+
+// Workflow returns the project's task statuses in board order.
+func (h *ProjectHandler) Workflow(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	workflow, err := h.queries.GetWorkflow(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, "Failed to get workflow", http.StatusInternalServerError)
+		return
+	}
+	if workflow == nil {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, workflow)
+}
+
+// UpdateWorkflow replaces the project's statuses. Statuses that tasks are
+// still in cannot be removed.
+func (h *ProjectHandler) UpdateWorkflow(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid project ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateWorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	statuses, err := workflowFromRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !checkWritable(r.Context(), w, h.queries, userID, models.RoleAdmin, &id) {
+		return
+	}
+
+	workflow, err := h.queries.ReplaceWorkflow(r.Context(), id, userID, statuses)
+	var inUse *database.StatusInUseError
+	if errors.As(err, &inUse) {
+		http.Error(w, fmt.Sprintf("Status %s is still used by %d tasks; move them first", inUse.Status, inUse.Tasks), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update workflow", http.StatusInternalServerError)
+		return
+	}
+	if workflow == nil {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, workflow)
+}
+
+func workflowFromRequest(req models.UpdateWorkflowRequest) ([]models.TaskStatus, error) {
+	if len(req.Statuses) == 0 {
+		return nil, validationError("A workflow needs at least one status")
+	}
+
+	keys := make(map[string]bool, len(req.Statuses))
+	for _, status := range req.Statuses {
+		if !statusKeyPattern.MatchString(status.Key) {
+			return nil, validationError("Status keys must be 1-50 lowercase letters, digits or underscores")
+		}
+		if keys[status.Key] {
+			return nil, validationError("Duplicate status " + status.Key)
+		}
+		keys[status.Key] = true
+	}
+
+	statuses := make([]models.TaskStatus, 0, len(req.Statuses))
+	for _, status := range req.Statuses {
+		name := strings.TrimSpace(status.Name)
+		if name == "" {
+			return nil, validationError("Status name is required")
+		}
+		if !validStatusCategories[status.Category] {
+			return nil, validationError("Invalid category for status " + status.Key + ", must be open, active or closed")
+		}
+		for _, to := range status.AllowedTransitions {
+			if !keys[to] {
+				return nil, validationError("Status " + status.Key + " allows a transition to unknown status " + to)
+			}
+		}
+		statuses = append(statuses, models.TaskStatus{
+			Key:                status.Key,
+			Name:               name,
+			Category:           status.Category,
+			AllowedTransitions: status.AllowedTransitions,
+		})
+	}
+	return statuses, nil
+}
+
+// validateStatusChange checks that to is a status of the workflow and that
+// the workflow lets a task in from move there. Tasks in a status the workflow
+// no longer has, or from another project, may move anywhere.
+func validateStatusChange(workflow *models.Workflow, from, to string) error {
+	if workflow.Status(to) == nil {
+		keys := make([]string, 0, len(workflow.Statuses))
+		for _, status := range workflow.Statuses {
+			keys = append(keys, status.Key)
+		}
+		return validationError("Invalid status " + to + ", must be one of " + strings.Join(keys, ", "))
+	}
+	if from == to {
+		return nil
+	}
+	current := workflow.Status(from)
+	if current == nil || current.AllowedTransitions == nil || contains(current.AllowedTransitions, to) {
+		return nil
+	}
+	return transitionError{from: from, to: to}
+}
+
+// mapStatus picks the status a task keeps when it moves between projects,
+// matching the SQL used for its subtasks: the same key if the target has it,
+// else the target's first status in the same category, else its default.
+func mapStatus(from, to *models.Workflow, key string) string {
+	if to.Status(key) != nil {
+		return key
+	}
+	if current := from.Status(key); current != nil {
+		for _, status := range to.Statuses {
+			if status.Category == current.Category {
+				return status.Key
+			}
+		}
+	}
+	if len(to.Statuses) > 0 {
+		return to.Statuses[0].Key
+	}
+	return key
+}
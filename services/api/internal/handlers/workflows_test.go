@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+func TestWorkflowFromRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []models.WorkflowStatusRequest
+		wantErr  bool
+	}{
+		{
+			name: "valid workflow",
+			statuses: []models.WorkflowStatusRequest{
+				{Key: "backlog", Name: "Backlog", Category: "open", AllowedTransitions: []string{"review"}},
+				{Key: "review", Name: "Review", Category: "active"},
+				{Key: "shipped", Name: "Shipped", Category: "closed", AllowedTransitions: []string{}},
+			},
+		},
+		{
+			name:    "no statuses",
+			wantErr: true,
+		},
+		{
+			name:     "invalid key",
+			statuses: []models.WorkflowStatusRequest{{Key: "In Review", Name: "In Review", Category: "active"}},
+			wantErr:  true,
+		},
+		{
+			name: "duplicate key",
+			statuses: []models.WorkflowStatusRequest{
+				{Key: "todo", Name: "To Do", Category: "open"},
+				{Key: "todo", Name: "Again", Category: "open"},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "missing name",
+			statuses: []models.WorkflowStatusRequest{{Key: "todo", Name: " ", Category: "open"}},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid category",
+			statuses: []models.WorkflowStatusRequest{{Key: "todo", Name: "To Do", Category: "pending"}},
+			wantErr:  true,
+		},
+		{
+			name:     "transition to unknown status",
+			statuses: []models.WorkflowStatusRequest{{Key: "todo", Name: "To Do", Category: "open", AllowedTransitions: []string{"done"}}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses, err := workflowFromRequest(models.UpdateWorkflowRequest{Statuses: tt.statuses})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(statuses) != len(tt.statuses) {
+				t.Fatalf("Expected %d statuses, got %d", len(tt.statuses), len(statuses))
+			}
+			if statuses[1].AllowedTransitions != nil {
+				t.Error("Expected omitted transitions to stay nil so any move is allowed")
+			}
+			if statuses[2].AllowedTransitions == nil {
+				t.Error("Expected empty transitions to stay empty so the status is final")
+			}
+		})
+	}
+}
+
+func TestValidateStatusChange(t *testing.T) {
+	workflow := &models.Workflow{Statuses: []models.TaskStatus{
+		{Key: "backlog", Category: "open", AllowedTransitions: []string{"review"}},
+		{Key: "review", Category: "active"},
+		{Key: "shipped", Category: "closed", AllowedTransitions: []string{}},
+	}}
+
+	tests := []struct {
+		name           string
+		from, to       string
+		wantInvalid    bool
+		wantTransition bool
+	}{
+		{name: "allowed transition", from: "backlog", to: "review"},
+		{name: "unrestricted status", from: "review", to: "backlog"},
+		{name: "same status", from: "shipped", to: "shipped"},
+		{name: "not allowed", from: "backlog", to: "shipped", wantTransition: true},
+		{name: "final status", from: "shipped", to: "review", wantTransition: true},
+		{name: "unknown target", from: "backlog", to: "done", wantInvalid: true},
+		{name: "from a status outside the workflow", from: "todo", to: "shipped"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStatusChange(workflow, tt.from, tt.to)
+			var invalid validationError
+			var transition transitionError
+			if errors.As(err, &invalid) != tt.wantInvalid {
+				t.Errorf("Expected invalid status=%v, got %v", tt.wantInvalid, err)
+			}
+			if errors.As(err, &transition) != tt.wantTransition {
+				t.Errorf("Expected disallowed transition=%v, got %v", tt.wantTransition, err)
+			}
+		})
+	}
+}
+
+func TestMapStatus(t *testing.T) {
+	from := &models.Workflow{Statuses: []models.TaskStatus{
+		{Key: "todo", Category: "open"},
+		{Key: "in_progress", Category: "active"},
+		{Key: "done", Category: "closed"},
+		{Key: "parked", Category: "active"},
+	}}
+	to := &models.Workflow{Statuses: []models.TaskStatus{
+		{Key: "backlog", Category: "open"},
+		{Key: "review", Category: "active"},
+		{Key: "done", Category: "closed"},
+	}}
+
+	tests := map[string]string{
+		"done":        "done",
+		"in_progress": "review",
+		"todo":        "backlog",
+		"unknown":     "backlog",
+	}
+	for status, want := range tests {
+		if got := mapStatus(from, to, status); got != want {
+			t.Errorf("Expected %s to map to %s, got %s", status, want, got)
+		}
+	}
+}
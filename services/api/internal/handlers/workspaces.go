@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// WorkspaceHandler manages shared workspaces and their members.
+type WorkspaceHandler struct {
+	queries *database.Queries
+}
+
+func NewWorkspaceHandler(queries *database.Queries) *WorkspaceHandler {
+	return &WorkspaceHandler{queries: queries}
+}
+
+// canManageMember reports whether a member with actorRole may give a member
+// with memberRole the role newRole, or remove them when newRole is empty.
+// Admins and owners manage members, but never above their own role.
+func canManageMember(actorRole, memberRole, newRole string) bool {
+	if !models.RoleAtLeast(actorRole, models.RoleAdmin) || !models.RoleAtLeast(actorRole, memberRole) {
+		return false
+	}
+	return newRole == "" || models.RoleAtLeast(actorRole, newRole)
+}
+
+func (h *WorkspaceHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workspaces, err := h.queries.ListWorkspaces(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to list workspaces", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, workspaces)
+}
+
+func (h *WorkspaceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Workspace name is required", http.StatusBadRequest)
+		return
+	}
+
+	workspace, err := h.queries.CreateWorkspace(r.Context(), userID, req.Name)
+	if err != nil {
+		http.Error(w, "Failed to create workspace", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, workspace)
+}
+
+func (h *WorkspaceHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid workspace ID format", http.StatusBadRequest)
+		return
+	}
+
+	workspace := checkWorkspaceRole(r.Context(), w, h.queries, id, userID, models.RoleViewer)
+	if workspace == nil {
+		return
+	}
+
+	writeJSON(w, workspace)
+}
+
+// Update renames the workspace. Admins and owners may do so.
+func (h *WorkspaceHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid workspace ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Workspace name is required", http.StatusBadRequest)
+		return
+	}
+
+	if checkWorkspaceRole(r.Context(), w, h.queries, id, userID, models.RoleAdmin) == nil {
+		return
+	}
+
+	workspace, err := h.queries.RenameWorkspace(r.Context(), id, userID, req.Name)
+	if err != nil {
+		http.Error(w, "Failed to update workspace", http.StatusInternalServerError)
+		return
+	}
+	if workspace == nil {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, workspace)
+}
+
+// Delete removes a shared workspace. Only owners may, and only once its
+// projects have been deleted or moved; personal workspaces stay.
+func (h *WorkspaceHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid workspace ID format", http.StatusBadRequest)
+		return
+	}
+
+	workspace := checkWorkspaceRole(r.Context(), w, h.queries, id, userID, models.RoleOwner)
+	if workspace == nil {
+		return
+	}
+	if workspace.Personal {
+		http.Error(w, "Personal workspaces cannot be deleted", http.StatusConflict)
+		return
+	}
+
+	err := h.queries.DeleteWorkspace(r.Context(), id)
+	if errors.Is(err, database.ErrWorkspaceNotEmpty) {
+		http.Error(w, "Delete the workspace's projects first", http.StatusConflict)
+		return
+	}
+	if err == sql.ErrNoRows {
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete workspace", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Members lists the workspace's members. Any member may see them.
+func (h *WorkspaceHandler) Members(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid workspace ID format", http.StatusBadRequest)
+		return
+	}
+
+	if checkWorkspaceRole(r.Context(), w, h.queries, id, userID, models.RoleViewer) == nil {
+		return
+	}
+
+	members, err := h.queries.ListWorkspaceMembers(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to list members", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, members)
+}
+
+// AddMember adds a registered user to the workspace by email.
+func (h *WorkspaceHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid workspace ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.AddWorkspaceMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.RoleMember
+	}
+	if !models.ValidRole(req.Role) {
+		http.Error(w, "Invalid role, must be owner, admin, member or viewer", http.StatusBadRequest)
+		return
+	}
+
+	workspace := checkWorkspaceRole(r.Context(), w, h.queries, id, userID, models.RoleAdmin)
+	if workspace == nil {
+		return
+	}
+	if workspace.Personal {
+		http.Error(w, "Personal workspaces cannot be shared", http.StatusConflict)
+		return
+	}
+	if !canManageMember(workspace.Role, models.RoleViewer, req.Role) {
+		http.Error(w, "Your workspace role does not allow this", http.StatusForbidden)
+		return
+	}
+
+	user, err := h.queries.GetUserByEmail(r.Context(), req.Email)
+	if err != nil {
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Error(w, "No user with that email", http.StatusNotFound)
+		return
+	}
+
+	member, err := h.queries.AddWorkspaceMember(r.Context(), id, user.ID, req.Role)
+	if errors.Is(err, database.ErrAlreadyMember) {
+		http.Error(w, "User is already a member", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to add member", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, member)
+}
+
+// UpdateMember changes a member's role.
+func (h *WorkspaceHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	memberID := chi.URLParam(r, "userID")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid workspace ID format", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(memberID); err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateWorkspaceMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !models.ValidRole(req.Role) {
+		http.Error(w, "Invalid role, must be owner, admin, member or viewer", http.StatusBadRequest)
+		return
+	}
+
+	workspace := checkWorkspaceRole(r.Context(), w, h.queries, id, userID, models.RoleAdmin)
+	if workspace == nil {
+		return
+	}
+	memberRole, ok := h.memberRole(r.Context(), w, id, memberID)
+	if !ok {
+		return
+	}
+	if !canManageMember(workspace.Role, memberRole, req.Role) {
+		http.Error(w, "Your workspace role does not allow this", http.StatusForbidden)
+		return
+	}
+
+	member, err := h.queries.UpdateWorkspaceMember(r.Context(), id, memberID, req.Role)
+	if errors.Is(err, database.ErrLastOwner) {
+		http.Error(w, "A workspace must keep at least one owner", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update member", http.StatusInternalServerError)
+		return
+	}
+	if member == nil {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, member)
+}
+
+// RemoveMember takes a member out of the workspace. Members may always
+// remove themselves, which is how they leave.
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	memberID := chi.URLParam(r, "userID")
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid workspace ID format", http.StatusBadRequest)
+		return
+	}
+	if _, err := uuid.Parse(memberID); err != nil {
+		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		return
+	}
+
+	workspace := checkWorkspaceRole(r.Context(), w, h.queries, id, userID, models.RoleViewer)
+	if workspace == nil {
+		return
+	}
+	if workspace.Personal {
+		http.Error(w, "Personal workspaces cannot be left", http.StatusConflict)
+		return
+	}
+	if memberID != userID {
+		memberRole, ok := h.memberRole(r.Context(), w, id, memberID)
+		if !ok {
+			return
+		}
+		if !canManageMember(workspace.Role, memberRole, "") {
+			http.Error(w, "Your workspace role does not allow this", http.StatusForbidden)
+			return
+		}
+	}
+
+	err := h.queries.RemoveWorkspaceMember(r.Context(), id, memberID)
+	if errors.Is(err, database.ErrLastOwner) {
+		http.Error(w, "A workspace must keep at least one owner", http.StatusConflict)
+		return
+	}
+	if err == sql.ErrNoRows {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to remove member", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// memberRole returns the role of a member of the workspace, writing a 404 if
+// the user is not one.
+func (h *WorkspaceHandler) memberRole(ctx context.Context, w http.ResponseWriter, workspaceID, userID string) (string, bool) {
+	role, err := h.queries.WorkspaceRole(ctx, workspaceID, userID)
+	if err != nil {
+		http.Error(w, "Failed to get member", http.StatusInternalServerError)
+		return "", false
+	}
+	if role == "" {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return "", false
+	}
+	return role, true
+}
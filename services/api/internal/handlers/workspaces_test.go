@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+func TestCanManageMember(t *testing.T) {
+	tests := []struct {
+		name                           string
+		actorRole, memberRole, newRole string
+		want                           bool
+	}{
+		{"owner promotes member to owner", models.RoleOwner, models.RoleMember, models.RoleOwner, true},
+		{"owner demotes owner", models.RoleOwner, models.RoleOwner, models.RoleAdmin, true},
+		{"admin promotes viewer to admin", models.RoleAdmin, models.RoleViewer, models.RoleAdmin, true},
+		{"admin cannot promote to owner", models.RoleAdmin, models.RoleMember, models.RoleOwner, false},
+		{"admin cannot demote owner", models.RoleAdmin, models.RoleOwner, models.RoleMember, false},
+		{"admin removes member", models.RoleAdmin, models.RoleMember, "", true},
+		{"admin cannot remove owner", models.RoleAdmin, models.RoleOwner, "", false},
+		{"member cannot manage", models.RoleMember, models.RoleViewer, models.RoleViewer, false},
+		{"viewer cannot remove", models.RoleViewer, models.RoleViewer, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canManageMember(tt.actorRole, tt.memberRole, tt.newRole); got != tt.want {
+				t.Errorf("canManageMember(%q, %q, %q) = %v, want %v", tt.actorRole, tt.memberRole, tt.newRole, got, tt.want)
+			}
+		})
+	}
+}
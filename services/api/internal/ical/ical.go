@@ -0,0 +1,122 @@
+// Package ical writes iCalendar (RFC 5545) feeds of all-day events and
+// to-dos.
+package ical
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Calendar is a feed of components, published under Name.
+type Calendar struct {
+	Name string
+	// Refresh is how often clients should fetch the feed again, if set.
+	Refresh    time.Duration
+	Components []Component
+}
+
+// Kinds of Component.
+const (
+	Event = "VEVENT"
+	Todo  = "VTODO"
+)
+
+// Component is an all-day event or a to-do. An event takes place on Date;
+// a to-do is due on it.
+type Component struct {
+	Kind        string
+	UID         string
+	Stamp       time.Time // when the item last changed
+	Date        time.Time
+	Summary     string
+	Description string
+	URL         string
+	// Priority runs from 1, the highest, to 9, with 0 for undefined.
+	Priority int
+}
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+	maxLineOctets  = 75
+)
+
+// Write writes the calendar with CRLF line endings, escaping text values
+// and folding long lines.
+func Write(w io.Writer, cal *Calendar) error {
+	var b strings.Builder
+	line := func(name, value string) {
+		fold(&b, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Makerlog//Makerlog//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if cal.Name != "" {
+		line("X-WR-CALNAME", escape(cal.Name))
+	}
+	if cal.Refresh > 0 {
+		duration := "PT" + strconv.Itoa(int(cal.Refresh.Minutes())) + "M"
+		line("REFRESH-INTERVAL;VALUE=DURATION", duration)
+		line("X-PUBLISHED-TTL", duration)
+	}
+	for _, c := range cal.Components {
+		line("BEGIN", c.Kind)
+		line("UID", escape(c.UID))
+		line("DTSTAMP", c.Stamp.UTC().Format(dateTimeFormat))
+		if c.Kind == Todo {
+			line("DUE;VALUE=DATE", c.Date.Format(dateFormat))
+			line("STATUS", "NEEDS-ACTION")
+		} else {
+			line("DTSTART;VALUE=DATE", c.Date.Format(dateFormat))
+			line("DTEND;VALUE=DATE", c.Date.AddDate(0, 0, 1).Format(dateFormat))
+			line("TRANSP", "TRANSPARENT")
+		}
+		line("SUMMARY", escape(c.Summary))
+		if c.Description != "" {
+			line("DESCRIPTION", escape(c.Description))
+		}
+		if c.URL != "" {
+			line("URL", c.URL)
+		}
+		if c.Priority > 0 {
+			line("PRIORITY", strconv.Itoa(c.Priority))
+		}
+		line("END", c.Kind)
+	}
+	line("END", "VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escape escapes a TEXT value.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// fold writes a content line, breaking it into lines of at most 75 octets
+// continued by a leading space, without splitting a UTF-8 sequence.
+func fold(b *strings.Builder, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the continuation's length.
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,93 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	stamp := time.Date(2024, 3, 4, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+	cal := &Calendar{
+		Name:    "Makerlog",
+		Refresh: time.Hour,
+		Components: []Component{
+			{
+				Kind: Todo, UID: "task-1@makerlog", Stamp: stamp,
+				Date:    time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC),
+				Summary: "Ship v2; then rest", Description: "Line one\nLine two, with comma",
+				URL: "http://localhost:3000/tasks/1", Priority: 1,
+			},
+			{
+				Kind: Event, UID: "log-entry-2@makerlog", Stamp: stamp,
+				Date:    time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+				Summary: `Fixed C:\temp`,
+			},
+		},
+	}
+
+	var b strings.Builder
+	if err := Write(&b, cal); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Makerlog//Makerlog//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:Makerlog",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT60M",
+		"X-PUBLISHED-TTL:PT60M",
+		"BEGIN:VTODO",
+		"UID:task-1@makerlog",
+		"DTSTAMP:20240304T083000Z",
+		"DUE;VALUE=DATE:20240308",
+		"STATUS:NEEDS-ACTION",
+		`SUMMARY:Ship v2\; then rest`,
+		`DESCRIPTION:Line one\nLine two\, with comma`,
+		"URL:http://localhost:3000/tasks/1",
+		"PRIORITY:1",
+		"END:VTODO",
+		"BEGIN:VEVENT",
+		"UID:log-entry-2@makerlog",
+		"DTSTAMP:20240304T083000Z",
+		"DTSTART;VALUE=DATE:20240229",
+		"DTEND;VALUE=DATE:20240301",
+		"TRANSP:TRANSPARENT",
+		`SUMMARY:Fixed C:\\temp`,
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	if got := b.String(); got != want {
+		t.Errorf("Write =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFold(t *testing.T) {
+	var b strings.Builder
+	line := "SUMMARY:" + strings.Repeat("é", 80)
+	fold(&b, line)
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %q", lines)
+	}
+	var joined string
+	for i, l := range lines {
+		if len(l) > 75 {
+			t.Errorf("Line %d is %d octets long", i, len(l))
+		}
+		if i > 0 {
+			if !strings.HasPrefix(l, " ") {
+				t.Errorf("Continuation line %d does not start with a space", i)
+			}
+			l = l[1:]
+		}
+		joined += l
+	}
+	if joined != line {
+		t.Errorf("Unfolding gave %q, want %q", joined, line)
+	}
+}
@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/digest"
+	"github.com/chrispotter/makerlog/services/api/internal/mail"
+)
+
+// EmailDigests sends opted-in users their daily or weekly digest once the
+// local time in their timezone reaches hour on a day it is due. Each
+// digest is claimed for its local date before it is built, so it goes out
+// at most once, and digests with nothing logged or completed are skipped.
+// A claim is given back when building or sending the digest fails, so the
+// next run tries again.
+func EmailDigests(queries *database.Queries, mailer mail.Mailer, interval time.Duration, hour int) Job {
+	return Job{
+		Name:     "email-digests",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			subscribers, err := queries.ListDigestSubscribers(ctx)
+			if err != nil {
+				return err
+			}
+			for i := range subscribers {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				s := &subscribers[i]
+				loc, err := time.LoadLocation(s.Settings.Timezone)
+				if err != nil {
+					log.Printf("email-digests: user %s has invalid timezone %q", s.User.ID, s.Settings.Timezone)
+					continue
+				}
+				now := time.Now().In(loc)
+				if !digest.Due(s.Settings.Digest, now, hour) {
+					continue
+				}
+				today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+				if s.LastDigestOn != nil && !s.LastDigestOn.Before(today) {
+					continue
+				}
+				claimed, err := queries.ClaimDigest(ctx, s.User.ID, today)
+				if err != nil {
+					return err
+				}
+				if !claimed {
+					continue
+				}
+
+				if err := sendDigest(ctx, queries, mailer, s, now); err != nil {
+					if err := queries.ReleaseDigest(context.WithoutCancel(ctx), s.User.ID, today, s.LastDigestOn); err != nil {
+						log.Printf("email-digests: failed to release the digest of user %s: %v", s.User.ID, err)
+					}
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					log.Printf("email-digests: failed to send the digest of user %s: %v", s.User.ID, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// sendDigest builds the subscriber's digest and emails it, unless it is
+// empty.
+func sendDigest(ctx context.Context, queries *database.Queries, mailer mail.Mailer, s *database.DigestSubscriber, now time.Time) error {
+	d, err := digest.Build(ctx, queries, &s.User, s.Settings.Digest, now)
+	if err != nil {
+		return err
+	}
+	if d.Empty() {
+		return nil
+	}
+	msg, err := digest.Render(d, s.User.Email)
+	if err != nil {
+		return err
+	}
+	return mailer.Send(msg)
+}
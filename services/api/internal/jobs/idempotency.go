@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+)
+
+// PurgeIdempotencyKeys deletes Idempotency-Keys whose responses have expired.
+func PurgeIdempotencyKeys(queries *database.Queries, interval time.Duration) Job {
+	return Job{
+		Name:     "purge-idempotency-keys",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			purged, err := queries.PurgeIdempotencyKeys(ctx)
+			if err != nil {
+				return err
+			}
+			if purged > 0 {
+				log.Printf("purge-idempotency-keys: removed %d keys", purged)
+			}
+			return nil
+		},
+	}
+}
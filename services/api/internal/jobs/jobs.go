@@ -0,0 +1,41 @@
+// Package jobs runs periodic background work alongside the API server. Every
+// job must be idempotent: it runs once at startup and then on its interval,
+// and several API instances may run the same job at once.
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Job is a named unit of periodic work.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Start runs each job in its own goroutine until ctx is cancelled. Errors
+// are logged and the job is retried on its next tick.
+func Start(ctx context.Context, jobs ...Job) {
+	for _, job := range jobs {
+		go run(ctx, job)
+	}
+}
+
+func run(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Job %s failed: %v", job.Name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStartRunsUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := make(chan struct{}, 10)
+
+	Start(ctx, Job{
+		Name:     "test",
+		Interval: time.Millisecond,
+		Run: func(ctx context.Context) error {
+			runs <- struct{}{}
+			return errors.New("keeps going after errors")
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected run %d to happen", i+1)
+		}
+	}
+	cancel()
+
+	// Drain a run that may have been in flight when ctx was cancelled.
+	time.Sleep(10 * time.Millisecond)
+	for len(runs) > 0 {
+		<-runs
+	}
+	time.Sleep(10 * time.Millisecond)
+	if len(runs) != 0 {
+		t.Error("Expected no runs after cancellation")
+	}
+}
+
+func TestStartRunsImmediately(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ran := make(chan struct{}, 1)
+
+	Start(ctx, Job{
+		Name:     "test",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			ran <- struct{}{}
+			return nil
+		},
+	})
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("Expected job to run at startup")
+	}
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/mail"
+)
+
+// DueReminders notifies task creators of open tasks due within lookahead of
+// the start of today. Reminders are unique per task and due date, so
+// overlapping runs remind no one twice.
+func DueReminders(queries *database.Queries, interval, lookahead time.Duration) Job {
+	return Job{
+		Name:     "due-reminders",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			today := time.Now().UTC().Truncate(24 * time.Hour)
+			created, err := queries.CreateDueReminders(ctx, today.Add(lookahead))
+			if err != nil {
+				return err
+			}
+			if created > 0 {
+				log.Printf("due-reminders: created %d reminders", created)
+			}
+			return nil
+		},
+	}
+}
+
+// NotificationDigest emails each user the notifications they asked to get by
+// email since the last run, in a single message.
+func NotificationDigest(queries *database.Queries, mailer mail.Mailer, interval time.Duration) Job {
+	return Job{
+		Name:     "notification-digest",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			emails, err := queries.ClaimEmailNotifications(ctx)
+			if err != nil {
+				return err
+			}
+			for _, email := range emails {
+				if err := mailer.Send(notificationDigestMessage(email)); err != nil {
+					log.Printf("notification-digest: failed to email %s: %v", email.Email, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func notificationDigestMessage(email database.NotificationEmail) mail.Message {
+	var text strings.Builder
+	fmt.Fprintf(&text, "Hi %s,\n\nHere is what happened on Makerlog:\n\n", email.Name)
+	for _, notification := range email.Notifications {
+		fmt.Fprintf(&text, "- %s\n", notification.Message)
+	}
+
+	subject := "1 new notification"
+	if n := len(email.Notifications); n != 1 {
+		subject = fmt.Sprintf("%d new notifications", n)
+	}
+	return mail.Message{
+		To:      email.Email,
+		Subject: "Makerlog: " + subject,
+		Text:    text.String(),
+	}
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+)
+
+// RecurringTasks materializes the occurrences of recurring tasks that fall
+// due within lookahead of today, so they show up on boards and in upcoming
+// views before they are due. Occurrences are unique per series and index,
+// so runs that overlap, or repeat after a restart, create nothing twice.
+func RecurringTasks(queries *database.Queries, interval, lookahead time.Duration) Job {
+	return Job{
+		Name:     "recurring-tasks",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			horizon := time.Now().UTC().Add(lookahead)
+
+			heads, err := queries.ListRecurrenceHeads(ctx)
+			if err != nil {
+				return err
+			}
+			for i := range heads {
+				task := &heads[i]
+				for task != nil {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+					if task, err = queries.CreateNextOccurrence(ctx, task, &horizon); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
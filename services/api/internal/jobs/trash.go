@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+)
+
+// PurgeTrash permanently deletes projects, tasks and log entries that have
+// been in the trash for longer than retention.
+func PurgeTrash(queries *database.Queries, interval, retention time.Duration) Job {
+	return Job{
+		Name:     "purge-trash",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			purged, err := queries.PurgeTrash(ctx, time.Now().Add(-retention))
+			if err != nil {
+				return err
+			}
+			if purged > 0 {
+				log.Printf("purge-trash: removed %d items", purged)
+			}
+			return nil
+		},
+	}
+}
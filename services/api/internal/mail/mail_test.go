@@ -0,0 +1,112 @@
+package mail
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileMailerWritesMessage(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	mailer := NewFileMailer(dir, "Maker Log <noreply@example.com>")
+
+	if err := mailer.Send(Message{To: "ada@example.com", Subject: "Hello", Text: "Line one\nLine two"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected one .eml file, got %v (%v)", files, err)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			t.Errorf("Failed to close file: %v", err)
+		}
+	}()
+
+	msg, err := mail.ReadMessage(f)
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	if got := msg.Header.Get("To"); got != "ada@example.com" {
+		t.Errorf("To = %q", got)
+	}
+	if got := msg.Header.Get("Subject"); got != "Hello" {
+		t.Errorf("Subject = %q", got)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(body); got != "Line one\r\nLine two" {
+		t.Errorf("Body = %q", got)
+	}
+}
+
+func TestComposeAlternative(t *testing.T) {
+	data, err := Compose("noreply@example.com", Message{
+		To:      "ada@example.com",
+		Subject: "Café digest",
+		Text:    "plain",
+		HTML:    "<p>html</p>",
+	}, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Compose: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Café digest" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q (%v)", mediaType, err)
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies = append(bodies, part.Header.Get("Content-Type")+" "+string(body))
+	}
+	want := []string{`text/plain; charset="utf-8" plain`, `text/html; charset="utf-8" <p>html</p>`}
+	if strings.Join(bodies, "|") != strings.Join(want, "|") {
+		t.Errorf("Parts = %q, want %q", bodies, want)
+	}
+}
+
+func TestComposeRejectsLineBreaks(t *testing.T) {
+	for _, msg := range []struct{ from, to string }{
+		{"noreply@example.com", "ada@example.com\r\nBcc: eve@example.com"},
+		{"noreply@example.com\nBcc: eve@example.com", "ada@example.com"},
+	} {
+		if _, err := Compose(msg.from, Message{To: msg.to, Subject: "Hi", Text: "x"}, time.Now()); err == nil {
+			t.Errorf("Expected Compose to refuse From %q and To %q", msg.from, msg.to)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends messages through an SMTP server. It authenticates with
+// PLAIN auth when Username is set, which net/smtp only allows over TLS or
+// to localhost; the connection is upgraded with STARTTLS when the server
+// offers it.
+type SMTPMailer struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPMailer(addr, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{Addr: addr, Username: username, Password: password, From: from}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	data, err := Compose(m.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, envelopeAddress(m.From), []string{envelopeAddress(msg.To)}, data)
+}
+
+// envelopeAddress strips the display name from an address such as
+// "Makerlog <noreply@example.com>".
+func envelopeAddress(address string) string {
+	if parsed, err := netmail.ParseAddress(address); err == nil {
+		return parsed.Address
+	}
+	return address
+}
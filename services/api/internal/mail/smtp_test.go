@@ -0,0 +1,106 @@
+package mail
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer accepts one session and records the envelope and data.
+func fakeSMTPServer(t *testing.T) (addr string, session chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := ln.Close(); err != nil {
+			t.Logf("closing listener: %v", err)
+		}
+	})
+
+	session = make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() {
+			if err := conn.Close(); err != nil {
+				_ = err
+			}
+		}()
+		text := textproto.NewConn(conn)
+		// A failed reply ends the session at the next read.
+		reply := func(line string) {
+			if err := text.PrintfLine("%s", line); err != nil {
+				_ = err
+			}
+		}
+
+		var got []string
+		reply("220 localhost ready")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.Fields(line + " ")[0]); verb {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "MAIL", "RCPT":
+				got = append(got, line)
+				reply("250 OK")
+			case "DATA":
+				reply("354 Go ahead")
+				data, err := text.ReadDotLines()
+				if err != nil {
+					return
+				}
+				got = append(got, strings.Join(data, "\n"))
+				reply("250 OK")
+			case "QUIT":
+				reply("221 Bye")
+				session <- got
+				return
+			default:
+				reply(fmt.Sprintf("502 %s not implemented", verb))
+			}
+		}
+	}()
+	return ln.Addr().String(), session
+}
+
+func TestSMTPMailerSendsMessage(t *testing.T) {
+	addr, session := fakeSMTPServer(t)
+	mailer := NewSMTPMailer(addr, "", "", "Maker Log <noreply@example.com>")
+
+	if err := mailer.Send(Message{To: "ada@example.com", Subject: "Hello", Text: "Hi"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := <-session
+	if len(got) != 3 {
+		t.Fatalf("Expected MAIL, RCPT and DATA, got %q", got)
+	}
+	if got[0] != "MAIL FROM:<noreply@example.com>" || got[1] != "RCPT TO:<ada@example.com>" {
+		t.Errorf("Unexpected envelope %q", got[:2])
+	}
+	if !strings.Contains(got[2], "Subject: Hello") || !strings.Contains(got[2], "From: Maker Log <noreply@example.com>") {
+		t.Errorf("Unexpected data %q", got[2])
+	}
+}
+
+func TestEnvelopeAddress(t *testing.T) {
+	for in, want := range map[string]string{
+		"noreply@example.com":             "noreply@example.com",
+		"Maker Log <noreply@example.com>": "noreply@example.com",
+		"not an address":                  "not an address",
+	} {
+		if got := envelopeAddress(in); got != want {
+			t.Errorf("envelopeAddress(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
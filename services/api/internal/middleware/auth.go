@@ -2,8 +2,13 @@ package middleware
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
 	"net/http"
+	"strings"
 
+	"github.com/chrispotter/makerlog/services/api/internal/models"
 	"github.com/gorilla/sessions"
 )
 
@@ -11,9 +16,43 @@ type contextKey string
 
 const userIDKey contextKey = "userID"
 
-func Auth(store *sessions.CookieStore) func(http.Handler) http.Handler {
+// AccessTokens looks up personal access tokens by the SHA-256 of the token;
+// *database.Queries is one.
+type AccessTokens interface {
+	UseAccessToken(ctx context.Context, tokenHash string) (userID, scope string, err error)
+}
+
+// Auth lets through requests that carry a personal access token as
+// "Authorization: Bearer <token>", or else a session cookie from logging
+// in, with the user's ID in the context. Read-only tokens may only make GET
+// and HEAD requests.
+func Auth(store *sessions.CookieStore, tokens AccessTokens) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token, ok := bearerToken(r); ok {
+				if tokens == nil || token == "" {
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				sum := sha256.Sum256([]byte(token))
+				userID, scope, err := tokens.UseAccessToken(r.Context(), hex.EncodeToString(sum[:]))
+				if err != nil {
+					log.Printf("checking access token: %v", err)
+					http.Error(w, "Failed to check access token", http.StatusInternalServerError)
+					return
+				}
+				if userID == "" {
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				if scope != models.ScopeWrite && r.Method != http.MethodGet && r.Method != http.MethodHead {
+					http.Error(w, "Access token is read-only", http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userIDKey, userID)))
+				return
+			}
+
 			session, err := store.Get(r, "makerlog-session")
 			if err != nil {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -33,6 +72,16 @@ func Auth(store *sessions.CookieStore) func(http.Handler) http.Handler {
 	}
 }
 
+// bearerToken returns the token of an "Authorization: Bearer" header, and
+// whether the request has one.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
 func GetUserID(ctx context.Context) (string, bool) {
 	userID, ok := ctx.Value(userIDKey).(string)
 	return userID, ok
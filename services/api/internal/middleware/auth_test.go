@@ -2,10 +2,13 @@ package middleware
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/chrispotter/makerlog/services/api/internal/models"
 	"github.com/gorilla/sessions"
 )
 
@@ -126,7 +129,7 @@ func TestAuthMiddleware(t *testing.T) {
 			})
 
 			// Create auth middleware
-			authMiddleware := Auth(sessionStore)
+			authMiddleware := Auth(sessionStore, nil)
 			handler := authMiddleware(nextHandler)
 
 			// Create request and response recorder
@@ -156,3 +159,53 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+// fakeAccessTokens holds tokens by hash.
+type fakeAccessTokens map[string][2]string
+
+func (f fakeAccessTokens) UseAccessToken(ctx context.Context, tokenHash string) (string, string, error) {
+	token := f[tokenHash]
+	return token[0], token[1], nil
+}
+
+func TestAuthMiddlewareAccessTokens(t *testing.T) {
+	hash := func(token string) string {
+		sum := sha256.Sum256([]byte(token))
+		return hex.EncodeToString(sum[:])
+	}
+	tokens := fakeAccessTokens{
+		hash("write-token"): {"user-123", models.ScopeWrite},
+		hash("read-token"):  {"user-123", models.ScopeRead},
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		header         string
+		expectedStatus int
+	}{
+		{name: "write token", method: http.MethodPost, header: "Bearer write-token", expectedStatus: http.StatusOK},
+		{name: "read token reading", method: http.MethodGet, header: "bearer read-token", expectedStatus: http.StatusOK},
+		{name: "read token writing", method: http.MethodPatch, header: "Bearer read-token", expectedStatus: http.StatusForbidden},
+		{name: "unknown token", method: http.MethodGet, header: "Bearer nope", expectedStatus: http.StatusUnauthorized},
+		{name: "empty token", method: http.MethodGet, header: "Bearer ", expectedStatus: http.StatusUnauthorized},
+		{name: "other scheme", method: http.MethodGet, header: "Basic d3JpdGUtdG9rZW4=", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Auth(sessions.NewCookieStore([]byte("test-secret-key")), tokens)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if userID, _ := GetUserID(r.Context()); userID != "user-123" {
+					t.Errorf("Expected userID user-123, got %s", userID)
+				}
+			}))
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			req.Header.Set("Authorization", tt.header)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// idempotencyLease is how long a request holds its key before a retry may
+// take it over, longer than any request is allowed to run.
+const idempotencyLease = 2 * time.Minute
+
+// replayedHeaders are the response headers stored for replay.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// IdempotencyStore keeps the requests made with an Idempotency-Key;
+// *database.Queries is one, shared by every instance of the API.
+type IdempotencyStore interface {
+	ClaimIdempotencyKey(ctx context.Context, userID, key, fingerprint string, ttl, lease time.Duration) (*database.IdempotentRequest, bool, error)
+	SaveIdempotentResponse(ctx context.Context, userID, key string, statusCode int, header http.Header, body []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, userID, key string) error
+}
+
+// Idempotency makes POST requests that carry an Idempotency-Key safe to
+// retry: the first is served and its response kept for ttl, and retries get
+// that response back. Reusing a key for a different request is refused, as
+// is a retry while the first is still running, unless it has run past
+// idempotencyLease and so is taken to have died. Server errors are not kept,
+// so they can be retried, and neither are responses marked
+// "Cache-Control: no-store", which hold secrets such as new tokens that must
+// not be stored. Keys belong to the user, so it must follow Auth.
+func Idempotency(store IdempotencyStore, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			userID, ok := GetUserID(r.Context())
+			if r.Method != http.MethodPost || key == "" || !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				http.Error(w, "Invalid Idempotency-Key, must be at most 255 characters", http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fingerprint := requestFingerprint(r, body)
+
+			earlier, claimed, err := store.ClaimIdempotencyKey(r.Context(), userID, key, fingerprint, ttl, idempotencyLease)
+			if err != nil {
+				http.Error(w, "Failed to check Idempotency-Key", http.StatusInternalServerError)
+				return
+			}
+			if !claimed {
+				switch {
+				case earlier != nil && earlier.Fingerprint != fingerprint:
+					http.Error(w, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+				case earlier == nil || earlier.StatusCode == 0:
+					http.Error(w, "A request with this Idempotency-Key is in progress", http.StatusConflict)
+				default:
+					for name, values := range earlier.Header {
+						w.Header()[name] = values
+					}
+					w.Header().Set("Idempotent-Replayed", "true")
+					w.WriteHeader(earlier.StatusCode)
+					if _, err := w.Write(earlier.Body); err != nil {
+						log.Printf("Error replaying idempotent response: %v", err)
+					}
+				}
+				return
+			}
+
+			// The outcome is stored even if the client has gone, so that
+			// its retry gets it rather than waiting out the key.
+			ctx := context.WithoutCancel(r.Context())
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			saved := false
+			defer func() {
+				// Runs on panics too, which must not leave the key stuck.
+				if !saved {
+					if err := store.ReleaseIdempotencyKey(ctx, userID, key); err != nil {
+						log.Printf("idempotency: failed to release key: %v", err)
+					}
+				}
+			}()
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status >= http.StatusInternalServerError || noStore(w.Header()) {
+				return
+			}
+			header := http.Header{}
+			for _, name := range replayedHeaders {
+				if values := w.Header().Values(name); len(values) > 0 {
+					header[name] = values
+				}
+			}
+			if err := store.SaveIdempotentResponse(ctx, userID, key, recorder.status, header, recorder.body.Bytes()); err != nil {
+				log.Printf("idempotency: failed to save response: %v", err)
+				return
+			}
+			saved = true
+		})
+	}
+}
+
+// noStore reports whether the response may not be stored.
+func noStore(header http.Header) bool {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return true
+		}
+	}
+	return false
+}
+
+// requestFingerprint identifies a request by its method, target and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.RequestURI()+"\n"), body...))
+	return hex.EncodeToString(sum[:])
+}
+
+// responseRecorder passes a response through while keeping a copy.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+)
+
+// fakeIdempotencyStore keeps keys in memory, like the idempotency_keys table.
+// Keys in stale are held by requests that have run past their lease.
+type fakeIdempotencyStore struct {
+	requests map[string]*database.IdempotentRequest
+	stale    map[string]bool
+}
+
+func (s *fakeIdempotencyStore) ClaimIdempotencyKey(ctx context.Context, userID, key, fingerprint string, ttl, lease time.Duration) (*database.IdempotentRequest, bool, error) {
+	earlier, ok := s.requests[userID+"/"+key]
+	if ok && !(s.stale[userID+"/"+key] && earlier.StatusCode == 0 && earlier.Fingerprint == fingerprint) {
+		return earlier, false, nil
+	}
+	delete(s.stale, userID+"/"+key)
+	s.requests[userID+"/"+key] = &database.IdempotentRequest{Fingerprint: fingerprint}
+	return nil, true, nil
+}
+
+func (s *fakeIdempotencyStore) SaveIdempotentResponse(ctx context.Context, userID, key string, statusCode int, header http.Header, body []byte) error {
+	request := s.requests[userID+"/"+key]
+	request.StatusCode, request.Header, request.Body = statusCode, header, body
+	return nil
+}
+
+func (s *fakeIdempotencyStore) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	delete(s.requests, userID+"/"+key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	store := &fakeIdempotencyStore{requests: map[string]*database.IdempotentRequest{}}
+	created := 0
+	status := http.StatusCreated
+	handler := Idempotency(store, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		created++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if _, err := w.Write([]byte(`{"n":` + strconv.Itoa(created) + `}`)); err != nil {
+			t.Errorf("writing response: %v", err)
+		}
+	}))
+	send := func(userID, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/tasks", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", key)
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, userID))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	first := send("user-1", "key-1", `{"title":"A"}`)
+	if first.Code != http.StatusCreated || first.Body.String() != `{"n":1}` {
+		t.Fatalf("Expected the request to run, got %d %s", first.Code, first.Body)
+	}
+
+	retry := send("user-1", "key-1", `{"title":"A"}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != `{"n":1}` || created != 1 {
+		t.Errorf("Expected the first response replayed, got %d %s after %d runs", retry.Code, retry.Body, created)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" || retry.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected replayed headers, got %v", retry.Header())
+	}
+
+	if recorder := send("user-1", "key-1", `{"title":"B"}`); recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a different body, got %d", recorder.Code)
+	}
+	if recorder := send("user-2", "key-1", `{"title":"B"}`); recorder.Code != http.StatusCreated || created != 2 {
+		t.Errorf("Expected another user's key to be separate, got %d", recorder.Code)
+	}
+
+	store.requests["user-1/key-2"] = &database.IdempotentRequest{Fingerprint: "running"}
+	if recorder := send("user-1", "key-2", `{"title":"A"}`); recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a key held by a different request, got %d", recorder.Code)
+	}
+
+	status = http.StatusInternalServerError
+	send("user-1", "key-3", `{"title":"A"}`)
+	if _, ok := store.requests["user-1/key-3"]; ok {
+		t.Error("Expected a server error to release the key")
+	}
+
+	if recorder := send("user-1", strings.Repeat("k", 256), `{}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an overlong key, got %d", recorder.Code)
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	store := &fakeIdempotencyStore{requests: map[string]*database.IdempotentRequest{}}
+	var handler http.Handler
+	inner := false
+	handler = Idempotency(store, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !inner {
+			// Retry while this request is still running.
+			inner = true
+			retry := httptest.NewRecorder()
+			handler.ServeHTTP(retry, r.Clone(r.Context()))
+			if retry.Code != http.StatusConflict {
+				t.Errorf("Expected 409 while in progress, got %d", retry.Code)
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/projects", strings.NewReader(`{}`))
+	req.Header.Set("Idempotency-Key", "key")
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, "user-1"))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestIdempotencyPassesThrough(t *testing.T) {
+	store := &fakeIdempotencyStore{requests: map[string]*database.IdempotentRequest{}}
+	runs := 0
+	handler := Idempotency(store, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+	}))
+
+	withUser := context.WithValue(context.Background(), userIDKey, "user-1")
+	tests := []struct {
+		method string
+		key    string
+		ctx    context.Context
+	}{
+		{method: http.MethodPost, ctx: withUser},
+		{method: http.MethodPut, key: "key", ctx: withUser},
+		{method: http.MethodPost, key: "key", ctx: context.Background()},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/tasks", nil).WithContext(tt.ctx)
+		if tt.key != "" {
+			req.Header.Set("Idempotency-Key", tt.key)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if runs != 6 || len(store.requests) != 0 {
+		t.Errorf("Expected every request to run without a key stored, got %d runs and %d keys", runs, len(store.requests))
+	}
+}
+
+func TestIdempotencyTakesOverStaleClaims(t *testing.T) {
+	store := &fakeIdempotencyStore{requests: map[string]*database.IdempotentRequest{}, stale: map[string]bool{}}
+	runs := 0
+	handler := Idempotency(store, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/api/projects", strings.NewReader(`{}`))
+	req.Header.Set("Idempotency-Key", "key")
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, "user-1"))
+
+	// A request that died without releasing its key.
+	store.requests["user-1/key"] = &database.IdempotentRequest{Fingerprint: requestFingerprint(req, []byte(`{}`))}
+	store.stale["user-1/key"] = true
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusCreated || runs != 1 {
+		t.Errorf("Expected the retry to take over the key, got %d after %d runs", recorder.Code, runs)
+	}
+	if store.requests["user-1/key"].StatusCode != http.StatusCreated {
+		t.Errorf("Expected the retry's response kept, got %+v", store.requests["user-1/key"])
+	}
+}
+
+func TestIdempotencyDoesNotKeepSecrets(t *testing.T) {
+	store := &fakeIdempotencyStore{requests: map[string]*database.IdempotentRequest{}}
+	runs := 0
+	handler := Idempotency(store, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.WriteHeader(http.StatusCreated)
+		if _, err := w.Write([]byte(`{"token":"secret"}`)); err != nil {
+			t.Errorf("writing response: %v", err)
+		}
+	}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/calendar-feed", nil)
+		req.Header.Set("Idempotency-Key", "key")
+		req = req.WithContext(context.WithValue(req.Context(), userIDKey, "user-1"))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if _, ok := store.requests["user-1/key"]; ok || runs != 2 {
+		t.Errorf("Expected a no-store response to be released and not replayed, got %d runs", runs)
+	}
+}
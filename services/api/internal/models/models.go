@@ -1,12 +1,16 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/textdiff"
 )
 
 type User struct {
 	ID           string    `json:"id" db:"id"`
 	Email        string    `json:"email" db:"email"`
+	Username     string    `json:"username" db:"username"` // the handle others @mention
 	PasswordHash string    `json:"-" db:"password_hash"`
 	Name         string    `json:"name" db:"name"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
@@ -14,23 +18,77 @@ type User struct {
 }
 
 type Project struct {
-	ID          string    `json:"id" db:"id"`
-	UserID      string    `json:"user_id" db:"user_id"`
-	Name        string    `json:"name" db:"name"`
-	Description string    `json:"description" db:"description"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          string `json:"id" db:"id"`
+	UserID      string `json:"user_id" db:"user_id"`
+	WorkspaceID string `json:"workspace_id" db:"workspace_id"`
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+	// Billable time is charged to ClientID at HourlyRateCents, in the
+	// minor unit of Currency, an ISO 4217 code.
+	ClientID        *string `json:"client_id,omitempty" db:"client_id"`
+	HourlyRateCents *int64  `json:"hourly_rate_cents,omitempty" db:"hourly_rate_cents"`
+	Currency        string  `json:"currency" db:"currency"`
+	// An archived project is read-only and hidden from the project list.
+	ArchivedAt *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	Version    int64      `json:"version" db:"version"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 type Task struct {
-	ID          string    `json:"id" db:"id"`
-	ProjectID   string    `json:"project_id" db:"project_id"`
-	UserID      string    `json:"user_id" db:"user_id"`
-	Title       string    `json:"title" db:"title"`
-	Description string    `json:"description" db:"description"`
-	Status      string    `json:"status" db:"status"` // todo, in_progress, done
+	ID           string     `json:"id" db:"id"`
+	ProjectID    string     `json:"project_id" db:"project_id"`
+	ParentTaskID *string    `json:"parent_task_id,omitempty" db:"parent_task_id"`
+	UserID       string     `json:"user_id" db:"user_id"`
+	Title        string     `json:"title" db:"title"`
+	Description  string     `json:"description" db:"description"`
+	Status       string     `json:"status" db:"status"`     // a key of the project's workflow
+	Priority     string     `json:"priority" db:"priority"` // low, medium, high, urgent
+	Position     string     `json:"position" db:"position"` // rank within the status column
+	DueDate      *time.Time `json:"due_date,omitempty" db:"due_date"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	// RecurrenceRule is an RRULE subset (see internal/recurrence). Every
+	// occurrence of a recurring task shares RecurrenceSeriesID and is
+	// numbered from 1 by RecurrenceIndex.
+	RecurrenceRule     *string    `json:"recurrence_rule,omitempty" db:"recurrence_rule"`
+	RecurrenceSeriesID *string    `json:"recurrence_series_id,omitempty" db:"recurrence_series_id"`
+	RecurrenceIndex    *int       `json:"recurrence_index,omitempty" db:"recurrence_index"`
+	DeletedAt          *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	Version            int64      `json:"version" db:"version"`
+	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// TaskDependency records that TaskID cannot be finished before BlockedByID.
+type TaskDependency struct {
+	TaskID      string    `json:"task_id" db:"task_id"`
+	BlockedByID string    `json:"blocked_by_id" db:"blocked_by_id"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TaskTree is a task with its subtasks nested below it. Progress is the
+// share of the task that is done, averaged over its children when it has any.
+type TaskTree struct {
+	Task
+	Progress float64    `json:"progress"`
+	Subtasks []TaskTree `json:"subtasks"`
+}
+
+// TaskGraph is the part of the dependency graph connected to a task: every
+// task it transitively waits on or holds up, and the edges between them.
+type TaskGraph struct {
+	Tasks []Task           `json:"tasks"`
+	Edges []TaskDependency `json:"edges"`
+}
+
+// TaskDetail is the single-task representation, with its subtask tree and
+// dependency graph.
+type TaskDetail struct {
+	TaskTree
+	BlockedBy    []string  `json:"blocked_by"`
+	OpenBlockers int       `json:"open_blockers"`
+	Dependencies TaskGraph `json:"dependencies"`
 }
 
 type LogEntry struct {
@@ -40,15 +98,70 @@ type LogEntry struct {
 	ProjectID *string   `json:"project_id,omitempty" db:"project_id"`
 	Content   string    `json:"content" db:"content"`
 	LogDate   time.Time `json:"log_date" db:"log_date"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	// DurationMinutes is the time spent, which Billable marks as chargeable
+	// to the project's client.
+	DurationMinutes *int       `json:"duration_minutes,omitempty" db:"duration_minutes"`
+	Billable        bool       `json:"billable" db:"billable"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	Version         int64      `json:"version" db:"version"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+	// CommentCount and Reactions, counted by emoji, are only filled in
+	// when reading entries, not in responses to writes.
+	CommentCount *int           `json:"comment_count,omitempty" db:"comment_count"`
+	Reactions    map[string]int `json:"reactions,omitempty" db:"reactions"`
+}
+
+// Comment is a remark on a log entry, or a reply to another comment on it
+// when ParentID is set. Deleted comments keep their place in the thread
+// with their content cleared.
+type Comment struct {
+	ID         string     `json:"id" db:"id"`
+	LogEntryID string     `json:"log_entry_id" db:"log_entry_id"`
+	ParentID   *string    `json:"parent_id,omitempty" db:"parent_id"`
+	UserID     string     `json:"user_id" db:"user_id"`
+	UserName   string     `json:"user_name" db:"user_name"`
+	Content    string     `json:"content" db:"content"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	Replies    []Comment  `json:"replies,omitempty" db:"-"`
+}
+
+type CreateCommentRequest struct {
+	Content  string  `json:"content"`
+	ParentID *string `json:"parent_id,omitempty"`
+}
+
+type UpdateCommentRequest struct {
+	Content string `json:"content"`
+}
+
+// Reaction is one user's emoji on a log entry.
+type Reaction struct {
+	LogEntryID string    `json:"log_entry_id" db:"log_entry_id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	UserName   string    `json:"user_name" db:"user_name"`
+	Emoji      string    `json:"emoji" db:"emoji"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 }
 
 // Request/Response structs
+// RegisterRequest creates an account, accepting the invitation with
+// InviteToken if one is given. Without a Username, the account gets one
+// made from the email address.
 type RegisterRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Name     string `json:"name"`
+	Email       string `json:"email"`
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password"`
+	Name        string `json:"name"`
+	InviteToken string `json:"invite_token,omitempty"`
+}
+
+// UpdateUserRequest changes the fields of the user's account that are
+// given.
+type UpdateUserRequest struct {
+	Username *string `json:"username,omitempty"`
 }
 
 type LoginRequest struct {
@@ -56,9 +169,14 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// CreateProjectRequest puts the project in the user's personal workspace
+// unless WorkspaceID names another. Clients working offline may pick the ID
+// themselves, as may the other create requests.
 type CreateProjectRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	ID          *string `json:"id,omitempty"`
+	WorkspaceID *string `json:"workspace_id,omitempty"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
 }
 
 type UpdateProjectRequest struct {
@@ -67,10 +185,15 @@ type UpdateProjectRequest struct {
 }
 
 type CreateTaskRequest struct {
-	ProjectID   string `json:"project_id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Status      string `json:"status"`
+	ID             *string `json:"id,omitempty"`
+	ProjectID      string  `json:"project_id"`
+	ParentTaskID   *string `json:"parent_task_id,omitempty"`
+	Title          string  `json:"title"`
+	Description    string  `json:"description"`
+	Status         string  `json:"status"`
+	Priority       string  `json:"priority"`
+	DueDate        *string `json:"due_date,omitempty"`        // Format: YYYY-MM-DD
+	RecurrenceRule *string `json:"recurrence_rule,omitempty"` // e.g. FREQ=WEEKLY;BYDAY=MO,TH
 }
 
 type UpdateTaskRequest struct {
@@ -80,13 +203,636 @@ type UpdateTaskRequest struct {
 }
 
 type CreateLogEntryRequest struct {
-	TaskID    *string `json:"task_id,omitempty"`
-	ProjectID *string `json:"project_id,omitempty"`
-	Content   string  `json:"content"`
-	LogDate   string  `json:"log_date"` // Format: YYYY-MM-DD
+	ID              *string `json:"id,omitempty"`
+	TaskID          *string `json:"task_id,omitempty"`
+	ProjectID       *string `json:"project_id,omitempty"`
+	Content         string  `json:"content"`
+	LogDate         string  `json:"log_date"` // Format: YYYY-MM-DD
+	DurationMinutes *int    `json:"duration_minutes,omitempty"`
+	Billable        bool    `json:"billable"`
 }
 
 type UpdateLogEntryRequest struct {
 	Content string `json:"content"`
 	LogDate string `json:"log_date"`
 }
+
+// PatchField is a single member of a JSON Merge Patch (RFC 7396) document.
+// Present reports whether the key appeared at all, and Null whether it was
+// explicitly set to null, so omitted fields can be left untouched.
+type PatchField struct {
+	Present bool
+	Null    bool
+	Value   string
+}
+
+func (f *PatchField) UnmarshalJSON(data []byte) error {
+	f.Present = true
+	if string(data) == "null" {
+		f.Null = true
+		f.Value = ""
+		return nil
+	}
+	f.Null = false
+	return json.Unmarshal(data, &f.Value)
+}
+
+// PatchInt is a PatchField for a member holding an integer.
+type PatchInt struct {
+	Present bool
+	Null    bool
+	Value   int64
+}
+
+func (f *PatchInt) UnmarshalJSON(data []byte) error {
+	f.Present = true
+	if string(data) == "null" {
+		f.Null = true
+		f.Value = 0
+		return nil
+	}
+	f.Null = false
+	return json.Unmarshal(data, &f.Value)
+}
+
+// PatchBool is a PatchField for a member holding a boolean.
+type PatchBool struct {
+	Present bool
+	Null    bool
+	Value   bool
+}
+
+func (f *PatchBool) UnmarshalJSON(data []byte) error {
+	f.Present = true
+	if string(data) == "null" {
+		f.Null = true
+		f.Value = false
+		return nil
+	}
+	f.Null = false
+	return json.Unmarshal(data, &f.Value)
+}
+
+type PatchProjectRequest struct {
+	Name            PatchField `json:"name"`
+	Description     PatchField `json:"description"`
+	ClientID        PatchField `json:"client_id"`
+	HourlyRateCents PatchInt   `json:"hourly_rate_cents"`
+	Currency        PatchField `json:"currency"`
+}
+
+type PatchTaskRequest struct {
+	ProjectID      PatchField `json:"project_id"`
+	ParentTaskID   PatchField `json:"parent_task_id"`
+	Title          PatchField `json:"title"`
+	Description    PatchField `json:"description"`
+	Status         PatchField `json:"status"`
+	Priority       PatchField `json:"priority"`
+	DueDate        PatchField `json:"due_date"`
+	RecurrenceRule PatchField `json:"recurrence_rule"`
+}
+
+type PatchLogEntryRequest struct {
+	TaskID          PatchField `json:"task_id"`
+	ProjectID       PatchField `json:"project_id"`
+	Content         PatchField `json:"content"`
+	LogDate         PatchField `json:"log_date"`
+	DurationMinutes PatchInt   `json:"duration_minutes"`
+	Billable        PatchBool  `json:"billable"`
+}
+
+// MoveTaskRequest places a task in a board column. AfterID is the task that
+// should end up directly above it and BeforeID the one directly below; with
+// neither the task goes to the bottom of the column.
+type MoveTaskRequest struct {
+	Status   string  `json:"status"`
+	AfterID  *string `json:"after_id,omitempty"`
+	BeforeID *string `json:"before_id,omitempty"`
+}
+
+// BoardColumn is one status column of a project board, in rank order. Name
+// and Category are empty for statuses no longer in the workflow.
+type BoardColumn struct {
+	Status   string `json:"status"`
+	Name     string `json:"name,omitempty"`
+	Category string `json:"category,omitempty"`
+	Tasks    []Task `json:"tasks"`
+}
+
+type Board struct {
+	ProjectID string        `json:"project_id"`
+	Columns   []BoardColumn `json:"columns"`
+}
+
+// Status categories tell the API what a workflow status means: open
+// statuses are not started, active ones are in progress and closed ones are
+// finished.
+const (
+	StatusCategoryOpen   = "open"
+	StatusCategoryActive = "active"
+	StatusCategoryClosed = "closed"
+)
+
+// TaskStatus is one status of a project's workflow. Tasks store its Key. A
+// nil AllowedTransitions lets tasks move from it to any status; an empty one
+// makes it final.
+type TaskStatus struct {
+	ID                 string    `json:"id" db:"id"`
+	ProjectID          string    `json:"project_id" db:"project_id"`
+	Key                string    `json:"key" db:"key"`
+	Name               string    `json:"name" db:"name"`
+	Category           string    `json:"category" db:"category"`
+	Position           int       `json:"position" db:"position"`
+	AllowedTransitions []string  `json:"allowed_transitions" db:"allowed_transitions"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Workflow lists a project's statuses in board order.
+type Workflow struct {
+	ProjectID string       `json:"project_id"`
+	Statuses  []TaskStatus `json:"statuses"`
+}
+
+// Status returns the status with the given key, or nil.
+func (w *Workflow) Status(key string) *TaskStatus {
+	for i := range w.Statuses {
+		if w.Statuses[i].Key == key {
+			return &w.Statuses[i]
+		}
+	}
+	return nil
+}
+
+// DefaultStatus is the status new tasks start in: the first open status, or
+// the first status if none is open.
+func (w *Workflow) DefaultStatus() string {
+	for _, status := range w.Statuses {
+		if status.Category == StatusCategoryOpen {
+			return status.Key
+		}
+	}
+	if len(w.Statuses) > 0 {
+		return w.Statuses[0].Key
+	}
+	return ""
+}
+
+// IsClosed reports whether key is a closed status of the workflow.
+func (w *Workflow) IsClosed(key string) bool {
+	status := w.Status(key)
+	return status != nil && status.Category == StatusCategoryClosed
+}
+
+type WorkflowStatusRequest struct {
+	Key                string   `json:"key"`
+	Name               string   `json:"name"`
+	Category           string   `json:"category"`
+	AllowedTransitions []string `json:"allowed_transitions"`
+}
+
+// UpdateWorkflowRequest replaces a project's workflow; statuses are listed
+// in board order.
+type UpdateWorkflowRequest struct {
+	Statuses []WorkflowStatusRequest `json:"statuses"`
+}
+
+// Workspace roles, from most to least privileged. Owners and admins manage
+// the workspace, its members and its projects; members work on tasks and log
+// entries; viewers can only read.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+var roleRanks = map[string]int{RoleViewer: 1, RoleMember: 2, RoleAdmin: 3, RoleOwner: 4}
+
+// ValidRole reports whether role is one of the workspace roles.
+func ValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// RoleAtLeast reports whether role grants everything min does.
+func RoleAtLeast(role, min string) bool {
+	return ValidRole(role) && roleRanks[role] >= roleRanks[min]
+}
+
+// Workspace groups projects shared by its members. A personal workspace
+// belongs to a single user and cannot have other members. Role is the
+// requesting user's role in it.
+type Workspace struct {
+	ID        string    `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Personal  bool      `json:"personal" db:"personal"`
+	Role      string    `json:"role" db:"role"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type WorkspaceMember struct {
+	WorkspaceID string    `json:"workspace_id" db:"workspace_id"`
+	UserID      string    `json:"user_id" db:"user_id"`
+	Email       string    `json:"email" db:"email"`
+	Username    string    `json:"username" db:"username"`
+	Name        string    `json:"name" db:"name"`
+	Role        string    `json:"role" db:"role"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+type UpdateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// AddWorkspaceMemberRequest adds an existing user, found by email.
+type AddWorkspaceMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type UpdateWorkspaceMemberRequest struct {
+	Role string `json:"role"`
+}
+
+// ProjectMember has a role in a single project without belonging to the
+// project's workspace. Owner is a workspace role only.
+type ProjectMember struct {
+	ProjectID string    `json:"project_id" db:"project_id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	Email     string    `json:"email" db:"email"`
+	Username  string    `json:"username" db:"username"`
+	Name      string    `json:"name" db:"name"`
+	Role      string    `json:"role" db:"role"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Invitation offers a role in a workspace or a single project to whoever
+// holds its token. Only a hash of the token is stored, so Token and URL are
+// set only in the response that creates the invitation.
+type Invitation struct {
+	ID          string     `json:"id" db:"id"`
+	WorkspaceID *string    `json:"workspace_id,omitempty" db:"workspace_id"`
+	ProjectID   *string    `json:"project_id,omitempty" db:"project_id"`
+	Email       *string    `json:"email,omitempty" db:"email"`
+	Role        string     `json:"role" db:"role"`
+	InvitedBy   string     `json:"invited_by" db:"invited_by"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	AcceptedBy  *string    `json:"accepted_by,omitempty" db:"accepted_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	Token       string     `json:"token,omitempty" db:"-"`
+	URL         string     `json:"url,omitempty" db:"-"`
+}
+
+// CreateInvitationRequest invites by link, or also emails the link when
+// Email is set.
+type CreateInvitationRequest struct {
+	Email *string `json:"email,omitempty"`
+	Role  string  `json:"role"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// Notification types.
+const (
+	NotificationTaskDue = "task_due"
+	NotificationComment = "comment"
+	NotificationMention = "mention"
+)
+
+// NotificationTypes lists every notification type.
+var NotificationTypes = []string{NotificationTaskDue, NotificationComment, NotificationMention}
+
+// Notification tells a user about something that happened. The IDs point at
+// what it is about.
+type Notification struct {
+	ID         string     `json:"id" db:"id"`
+	UserID     string     `json:"user_id" db:"user_id"`
+	Type       string     `json:"type" db:"type"`
+	ActorID    *string    `json:"actor_id,omitempty" db:"actor_id"`
+	TaskID     *string    `json:"task_id,omitempty" db:"task_id"`
+	LogEntryID *string    `json:"log_entry_id,omitempty" db:"log_entry_id"`
+	CommentID  *string    `json:"comment_id,omitempty" db:"comment_id"`
+	Message    string     `json:"message" db:"message"`
+	ReadAt     *time.Time `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// NotificationPreference chooses how a user hears about one type of
+// notification: in the in-app inbox, in the email digest, both or neither.
+type NotificationPreference struct {
+	Type  string `json:"type" db:"type"`
+	InApp bool   `json:"in_app" db:"in_app"`
+	Email bool   `json:"email" db:"email"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreference `json:"preferences"`
+}
+
+// Digest frequencies.
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// UserSettings holds a user's preferences. Timezone is an IANA name and
+// decides the days that reports and digests cover.
+type UserSettings struct {
+	Timezone  string    `json:"timezone" db:"timezone"`
+	Digest    string    `json:"digest" db:"digest"` // off, daily, weekly
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type UpdateUserSettingsRequest struct {
+	Timezone *string `json:"timezone,omitempty"`
+	Digest   *string `json:"digest,omitempty"`
+}
+
+// CalendarFeed is a user's secret iCalendar feed. The token and URL are only
+// returned when the token is created or rotated.
+type CalendarFeed struct {
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	Token      string     `json:"token,omitempty" db:"-"`
+	URL        string     `json:"url,omitempty" db:"-"`
+}
+
+// Access token scopes. Read tokens may only make GET and HEAD requests;
+// write tokens may do anything the user can.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// AccessToken is a personal access token, which scripts and the CLI send as
+// "Authorization: Bearer <token>" instead of signing in. Only a hash of the
+// token is stored, so Token is set only in the response that creates it.
+type AccessToken struct {
+	ID         string     `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	Scope      string     `json:"scope" db:"scope"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	Token      string     `json:"token,omitempty" db:"-"`
+}
+
+// CreateAccessTokenRequest names a new token. Scope defaults to write, and
+// the token never expires unless ExpiresInDays is set.
+type CreateAccessTokenRequest struct {
+	Name          string `json:"name"`
+	Scope         string `json:"scope,omitempty"`
+	ExpiresInDays *int   `json:"expires_in_days,omitempty"`
+}
+
+// StandupReport is what a user did on the previous working day, what they
+// are working on and what is holding them up. Dates are YYYY-MM-DD in the
+// user's timezone.
+type StandupReport struct {
+	Date          string     `json:"date"`
+	YesterdayDate string     `json:"yesterday_date"`
+	Yesterday     []LogEntry `json:"yesterday"`
+	Today         []Task     `json:"today"`
+	Blockers      []Task     `json:"blockers"`
+}
+
+// Client is someone a workspace bills for the time logged on its projects.
+type Client struct {
+	ID          string    `json:"id" db:"id"`
+	WorkspaceID string    `json:"workspace_id" db:"workspace_id"`
+	Name        string    `json:"name" db:"name"`
+	Email       *string   `json:"email,omitempty" db:"email"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CreateClientRequest puts the client in the user's personal workspace
+// unless WorkspaceID names another.
+type CreateClientRequest struct {
+	WorkspaceID *string `json:"workspace_id,omitempty"`
+	Name        string  `json:"name"`
+	Email       *string `json:"email,omitempty"`
+}
+
+type UpdateClientRequest struct {
+	Name  string  `json:"name"`
+	Email *string `json:"email,omitempty"`
+}
+
+// TimesheetRow totals the time logged on one project in one week, which
+// starts on Monday. Amounts are in the minor unit of Currency and only
+// count billable time.
+type TimesheetRow struct {
+	ClientID        *string `json:"client_id,omitempty"`
+	ClientName      *string `json:"client_name,omitempty"`
+	ProjectID       string  `json:"project_id"`
+	ProjectName     string  `json:"project_name"`
+	Week            string  `json:"week"`
+	Minutes         int64   `json:"minutes"`
+	BillableMinutes int64   `json:"billable_minutes"`
+	HourlyRateCents *int64  `json:"hourly_rate_cents,omitempty"`
+	Currency        string  `json:"currency"`
+	AmountCents     int64   `json:"amount_cents"`
+}
+
+// TimesheetTotal sums a timesheet's rows in one currency.
+type TimesheetTotal struct {
+	Currency        string `json:"currency"`
+	Minutes         int64  `json:"minutes"`
+	BillableMinutes int64  `json:"billable_minutes"`
+	AmountCents     int64  `json:"amount_cents"`
+}
+
+// Timesheet is the time logged between two dates, YYYY-MM-DD inclusive.
+type Timesheet struct {
+	From   string           `json:"from"`
+	To     string           `json:"to"`
+	Rows   []TimesheetRow   `json:"rows"`
+	Totals []TimesheetTotal `json:"totals"`
+}
+
+// Entity types that keep a revision history.
+const (
+	RevisionEntityTask     = "task"
+	RevisionEntityLogEntry = "log_entry"
+)
+
+// Revision records one change to a task or log entry: who made it, when, which
+// fields it changed, and the tracked fields as they were before, at Version.
+type Revision struct {
+	ID            string          `json:"id" db:"id"`
+	EntityType    string          `json:"entity_type" db:"entity_type"`
+	EntityID      string          `json:"entity_id" db:"entity_id"`
+	UserID        string          `json:"user_id" db:"user_id"`
+	Version       int64           `json:"version" db:"version"`
+	Data          json.RawMessage `json:"data" db:"data"`
+	ChangedFields []string        `json:"changed_fields" db:"changed_fields"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
+
+// RevisionDiff lists the tracked fields that differ between two revisions.
+// From and To are revision IDs, or "current" for the entity as it is now.
+type RevisionDiff struct {
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange is one field of a RevisionDiff. Text fields also carry a line
+// diff.
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+	Lines []textdiff.Line `json:"lines,omitempty"`
+}
+
+// Trash lists the user's deleted items. Tasks and log entries deleted along
+// with their project or parent task are only listed under it. Items are
+// purged for good RetentionDays after they were deleted.
+type Trash struct {
+	Projects      []Project  `json:"projects"`
+	Tasks         []Task     `json:"tasks"`
+	LogEntries    []LogEntry `json:"log_entries"`
+	RetentionDays int        `json:"retention_days"`
+}
+
+type AddTaskDependencyRequest struct {
+	BlockedByID string `json:"blocked_by_id"`
+}
+
+// Sync entity types.
+const (
+	SyncProject  = "project"
+	SyncTask     = "task"
+	SyncLogEntry = "log_entry"
+)
+
+// SyncChanges is what changed after a sync cursor, in the order it changed.
+// Items in the trash or gone for good are listed under Deleted. Cursor is
+// the cursor to ask from next time; HasMore means the page was full.
+type SyncChanges struct {
+	Cursor     int64          `json:"cursor"`
+	HasMore    bool           `json:"has_more"`
+	Projects   []Project      `json:"projects"`
+	Tasks      []Task         `json:"tasks"`
+	LogEntries []LogEntry     `json:"log_entries"`
+	Deleted    []SyncDeletion `json:"deleted"`
+}
+
+type SyncDeletion struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// SyncPushRequest carries changes a client made offline, applied in order.
+type SyncPushRequest struct {
+	Changes []SyncChange `json:"changes"`
+}
+
+// SyncChange upserts or deletes one item under an ID the client chose.
+// BaseVersion is the version the client last saw, unset for items it
+// created, and ModifiedAt is when the change was made, which decides
+// conflicts. Data is the create body for new items and a JSON Merge Patch
+// for existing ones.
+type SyncChange struct {
+	Type        string          `json:"type"`
+	Op          string          `json:"op"` // upsert or delete
+	ID          string          `json:"id"`
+	BaseVersion *int64          `json:"base_version,omitempty"`
+	ModifiedAt  time.Time       `json:"modified_at"`
+	Data        json.RawMessage `json:"data,omitempty"`
+}
+
+// Sync change statuses and conflict resolutions.
+const (
+	SyncApplied  = "applied"
+	SyncConflict = "conflict"
+	SyncRejected = "rejected"
+
+	SyncClientWins = "client"
+	SyncServerWins = "server"
+)
+
+// SyncResult reports what became of one change. A conflict means the item
+// changed on the server since BaseVersion; the later change wins, as
+// Resolution says. Record is the item as the server now has it, absent
+// once deleted; Error says why a change was rejected.
+type SyncResult struct {
+	Type       string          `json:"type"`
+	ID         string          `json:"id"`
+	Status     string          `json:"status"`
+	Resolution string          `json:"resolution,omitempty"`
+	Record     json.RawMessage `json:"record,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+type SyncPushResponse struct {
+	Results []SyncResult `json:"results"`
+}
+
+// Batch modes: an atomic batch is undone entirely when an operation fails,
+// a best-effort one keeps the operations that succeeded.
+const (
+	BatchAtomic     = "atomic"
+	BatchBestEffort = "best_effort"
+)
+
+// BatchRequest carries operations run in order in one transaction. Mode
+// defaults to atomic.
+type BatchRequest struct {
+	Mode       string           `json:"mode"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation creates, updates or deletes one item; Type is one of the
+// sync types. Data is the create body or a JSON Merge Patch. ID is the item
+// to update or delete, and optionally the ID to create under. Version, if
+// set, must be the item's current version, like If-Match.
+type BatchOperation struct {
+	Op      string          `json:"op"` // create, update or delete
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Version *int64          `json:"version,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// BatchResult is the outcome of one operation: the HTTP status the same
+// request would have got on its own, and the item written or the error.
+type BatchResult struct {
+	Status int             `json:"status"`
+	Record json.RawMessage `json:"record,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// BatchResponse reports on the operations run. An atomic batch stops at
+// the first failure, which is the last result, commits nothing and sets
+// FailedIndex to the failed operation's index in the request.
+type BatchResponse struct {
+	Committed   bool          `json:"committed"`
+	FailedIndex *int          `json:"failed_index,omitempty"`
+	Results     []BatchResult `json:"results"`
+}
+
+// BulkTaskRequest names the tasks to act on.
+type BulkTaskRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// MoveLogEntriesRequest moves log entries to a project, or out of any
+// project when ProjectID is null.
+type MoveLogEntriesRequest struct {
+	IDs       []string `json:"ids"`
+	ProjectID *string  `json:"project_id"`
+}
@@ -229,3 +229,111 @@ func TestCreateLogEntryRequestDeserialization(t *testing.T) {
 		t.Errorf("Expected log_date 2024-01-05, got %s", req.LogDate)
 	}
 }
+
+func TestPatchFieldUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		present bool
+		null    bool
+		value   string
+	}{
+		{
+			name:    "omitted",
+			json:    `{}`,
+			present: false,
+		},
+		{
+			name:    "null",
+			json:    `{"task_id":null}`,
+			present: true,
+			null:    true,
+		},
+		{
+			name:    "value",
+			json:    `{"task_id":"task-123"}`,
+			present: true,
+			value:   "task-123",
+		},
+		{
+			name:    "empty string",
+			json:    `{"task_id":""}`,
+			present: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req PatchLogEntryRequest
+			if err := json.Unmarshal([]byte(tt.json), &req); err != nil {
+				t.Fatalf("Failed to unmarshal patch: %v", err)
+			}
+			if req.TaskID.Present != tt.present {
+				t.Errorf("Expected present=%v, got %v", tt.present, req.TaskID.Present)
+			}
+			if req.TaskID.Null != tt.null {
+				t.Errorf("Expected null=%v, got %v", tt.null, req.TaskID.Null)
+			}
+			if req.TaskID.Value != tt.value {
+				t.Errorf("Expected value %q, got %q", tt.value, req.TaskID.Value)
+			}
+			if req.Content.Present {
+				t.Error("Expected omitted content to be absent")
+			}
+		})
+	}
+}
+
+func TestPatchFieldRejectsNonString(t *testing.T) {
+	var req PatchTaskRequest
+	if err := json.Unmarshal([]byte(`{"title":42}`), &req); err == nil {
+		t.Error("Expected error for non-string patch value")
+	}
+}
+
+func TestWorkflow(t *testing.T) {
+	workflow := Workflow{Statuses: []TaskStatus{
+		{Key: "backlog", Category: StatusCategoryActive},
+		{Key: "ready", Category: StatusCategoryOpen},
+		{Key: "shipped", Category: StatusCategoryClosed},
+	}}
+
+	if got := workflow.DefaultStatus(); got != "ready" {
+		t.Errorf("Expected first open status as default, got %s", got)
+	}
+	if !workflow.IsClosed("shipped") || workflow.IsClosed("ready") || workflow.IsClosed("missing") {
+		t.Error("Expected only shipped to be closed")
+	}
+	if workflow.Status("missing") != nil {
+		t.Error("Expected nil for unknown status")
+	}
+
+	noOpen := Workflow{Statuses: []TaskStatus{{Key: "doing", Category: StatusCategoryActive}}}
+	if got := noOpen.DefaultStatus(); got != "doing" {
+		t.Errorf("Expected first status as default without open statuses, got %s", got)
+	}
+	if got := (&Workflow{}).DefaultStatus(); got != "" {
+		t.Errorf("Expected empty default for empty workflow, got %s", got)
+	}
+}
+
+func TestRoleAtLeast(t *testing.T) {
+	tests := []struct {
+		role, min string
+		want      bool
+	}{
+		{RoleOwner, RoleAdmin, true},
+		{RoleAdmin, RoleAdmin, true},
+		{RoleMember, RoleAdmin, false},
+		{RoleMember, RoleViewer, true},
+		{RoleViewer, RoleMember, false},
+		{"", RoleViewer, false},
+		{"superuser", RoleViewer, false},
+	}
+
+	for _, tt := range tests {
+		if got := RoleAtLeast(tt.role, tt.min); got != tt.want {
+			t.Errorf("RoleAtLeast(%q, %q) = %v, want %v", tt.role, tt.min, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Package openapi serves the API's OpenAPI 3.1 description and a page
+// rendering it as documentation. The document is kept by hand next to the
+// routes; the server package's tests check the two agree.
+package openapi
+
+import (
+	_ "embed"
+	"log"
+	"net/http"
+)
+
+// Spec is the OpenAPI document describing every route.
+//
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed docs.html
+var docsPage []byte
+
+// ServeSpec writes the OpenAPI document.
+func ServeSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(Spec); err != nil {
+		log.Printf("Error writing OpenAPI document: %v", err)
+	}
+}
+
+// ServeDocs writes a page that renders the OpenAPI document for people.
+func ServeDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write(docsPage); err != nil {
+		log.Printf("Error writing API docs: %v", err)
+	}
+}
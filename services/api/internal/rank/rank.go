@@ -0,0 +1,99 @@
+// Package rank generates lexicographic sort keys for manually ordered lists.
+//
+// Keys are base-36 fractions written with the digits 0-9a-z. A key can always
+// be generated between any two distinct keys, so moving an item only rewrites
+// that item's key. Keys never end in '0', which keeps room below every key,
+// and they must be compared bytewise (COLLATE "C" in PostgreSQL).
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// ErrOutOfOrder is returned when no key can be placed between the bounds
+// because they are equal or reversed.
+var ErrOutOfOrder = errors.New("rank: bounds are not in ascending order")
+
+// ErrInvalidKey is returned for keys that contain characters outside the
+// alphabet or end in '0'.
+var ErrInvalidKey = errors.New("rank: invalid key")
+
+// Between returns a key that sorts after a and before b. An empty a means the
+// start of the list and an empty b means the end, so Between("", "") gives
+// the first key of an empty list and Between(last, "") appends.
+func Between(a, b string) (string, error) {
+	if !valid(a) || !valid(b) {
+		return "", ErrInvalidKey
+	}
+	if b != "" && a >= b {
+		return "", ErrOutOfOrder
+	}
+	return midpoint(a, b), nil
+}
+
+// midpoint assumes a < b (an empty b being +infinity) and neither key ends in
+// the zero digit.
+func midpoint(a, b string) string {
+	if b != "" {
+		// Skip the shared prefix, reading a as zero-padded.
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:])
+		}
+	}
+
+	lo := 0
+	if a != "" {
+		lo = strings.IndexByte(digits, a[0])
+	}
+	hi := len(digits)
+	if b != "" {
+		hi = strings.IndexByte(digits, b[0])
+	}
+	if hi-lo > 1 {
+		return string(digits[(lo+hi)/2])
+	}
+
+	// The leading digits are adjacent. A longer b can be cut to its first
+	// digit; otherwise keep a's digit and find room after the rest of a.
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(digits[lo]) + midpoint(rest, "")
+}
+
+func digitAt(key string, i int) byte {
+	if i < len(key) {
+		return key[i]
+	}
+	return digits[0]
+}
+
+func valid(key string) bool {
+	if key == "" {
+		return true
+	}
+	if key[len(key)-1] == digits[0] {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package rank
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       string
+		b       string
+		wantErr error
+	}{
+		{name: "empty list", a: "", b: ""},
+		{name: "append", a: "i", b: ""},
+		{name: "prepend", a: "", b: "i"},
+		{name: "wide gap", a: "a", b: "z"},
+		{name: "adjacent digits", a: "a", b: "b"},
+		{name: "shared prefix", a: "ai", b: "aj"},
+		{name: "prefix of other", a: "a", b: "a1"},
+		{name: "backfilled keys", a: "00000001i", b: "00000002i"},
+		{name: "equal bounds", a: "i", b: "i", wantErr: ErrOutOfOrder},
+		{name: "reversed bounds", a: "r", b: "i", wantErr: ErrOutOfOrder},
+		{name: "invalid character", a: "A", b: "", wantErr: ErrInvalidKey},
+		{name: "trailing zero", a: "a0", b: "", wantErr: ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Between(tt.a, tt.b)
+			if err != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if !(tt.a < got) {
+				t.Errorf("Expected %q > %q", got, tt.a)
+			}
+			if tt.b != "" && !(got < tt.b) {
+				t.Errorf("Expected %q < %q", got, tt.b)
+			}
+			if !valid(got) {
+				t.Errorf("Generated invalid key %q", got)
+			}
+		})
+	}
+}
+
+func TestBetweenRepeatedInserts(t *testing.T) {
+	// Keep inserting at the front, the back and just after the first key,
+	// which exercises both growing and shrinking gaps.
+	keys := []string{}
+	first, err := Between("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys = append(keys, first)
+
+	for i := 0; i < 200; i++ {
+		sort.Strings(keys)
+		var a, b string
+		switch i % 3 {
+		case 0:
+			b = keys[0]
+		case 1:
+			a = keys[len(keys)-1]
+		case 2:
+			a = keys[0]
+			b = keys[1]
+		}
+		key, err := Between(a, b)
+		if err != nil {
+			t.Fatalf("Between(%q, %q): %v", a, b, err)
+		}
+		keys = append(keys, key)
+	}
+
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if seen[key] {
+			t.Fatalf("Duplicate key %q", key)
+		}
+		seen[key] = true
+	}
+}
@@ -0,0 +1,271 @@
+// Package recurrence parses and evaluates the subset of RFC 5545 recurrence
+// rules that makerlog supports for recurring tasks:
+//
+//	FREQ=DAILY[;INTERVAL=n]
+//	FREQ=WEEKLY[;INTERVAL=n][;BYDAY=MO,WE,...]
+//	FREQ=MONTHLY[;INTERVAL=n][;BYMONTHDAY=1,15,-1]
+//
+// each optionally bounded by UNTIL=YYYYMMDD or COUNT=n. Occurrences are
+// calendar dates; weeks start on Monday.
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int // 1 to 31, or -1 to -31 counting back from the month's end
+	Until      *time.Time
+	Count      int // 0 means unbounded
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=10". An optional
+// "RRULE:" prefix is accepted.
+func Parse(text string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	text = strings.TrimPrefix(strings.TrimSpace(text), "RRULE:")
+	if text == "" {
+		return rule, fmt.Errorf("empty recurrence rule")
+	}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(text, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return rule, fmt.Errorf("malformed recurrence rule part %q", part)
+		}
+		key = strings.ToUpper(key)
+		if seen[key] {
+			return rule, fmt.Errorf("duplicate recurrence rule part %s", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(value))
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return rule, fmt.Errorf("unsupported FREQ %s, must be DAILY, WEEKLY or MONTHLY", value)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return rule, fmt.Errorf("INTERVAL must be a positive integer")
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			for _, code := range strings.Split(strings.ToUpper(value), ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return rule, fmt.Errorf("unsupported BYDAY value %s", code)
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, dayText := range strings.Split(value, ",") {
+				day, err := strconv.Atoi(dayText)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return rule, fmt.Errorf("BYMONTHDAY values must be between 1 and 31 or -31 and -1")
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, day)
+			}
+		case "UNTIL":
+			until, err := time.Parse("20060102", value)
+			if err != nil {
+				return rule, fmt.Errorf("UNTIL must be a date in YYYYMMDD form")
+			}
+			rule.Until = &until
+		case "COUNT":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return rule, fmt.Errorf("COUNT must be a positive integer")
+			}
+			rule.Count = count
+		default:
+			return rule, fmt.Errorf("unsupported recurrence rule part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, fmt.Errorf("recurrence rule needs a FREQ")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return rule, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != Monthly {
+		return rule, fmt.Errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	if rule.Until != nil && rule.Count > 0 {
+		return rule, fmt.Errorf("UNTIL and COUNT cannot both be set")
+	}
+	return rule, nil
+}
+
+// String returns the rule in canonical form.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := append([]time.Weekday{}, r.ByDay...)
+		sort.Slice(days, func(i, j int) bool { return mondayIndex(days[i]) < mondayIndex(days[j]) })
+		codes := make([]string, 0, len(days))
+		for _, day := range days {
+			for code, d := range weekdayCodes {
+				if d == day {
+					codes = append(codes, code)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence after prev, which must itself be an
+// occurrence of the series: intervals are counted from it. index is prev's
+// 1-based position in the series and is checked against COUNT. The second
+// result is false once the series has ended.
+func (r Rule) Next(prev time.Time, index int) (time.Time, bool) {
+	if r.Count > 0 && index >= r.Count {
+		return time.Time{}, false
+	}
+	prev = dateOf(prev)
+
+	var next time.Time
+	switch r.Freq {
+	case Daily:
+		next = prev.AddDate(0, 0, r.Interval)
+	case Weekly:
+		next = r.nextWeekly(prev)
+	case Monthly:
+		next = r.nextMonthly(prev)
+	default:
+		return time.Time{}, false
+	}
+
+	if next.IsZero() || (r.Until != nil && next.After(dateOf(*r.Until))) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+func (r Rule) nextWeekly(prev time.Time) time.Time {
+	days := r.ByDay
+	if len(days) == 0 {
+		days = []time.Weekday{prev.Weekday()}
+	}
+	weekStart := prev.AddDate(0, 0, -mondayIndex(prev.Weekday()))
+
+	// Later days in prev's week come first, then the first matching day of
+	// the week Interval weeks on.
+	for offset := mondayIndex(prev.Weekday()) + 1; offset < 7; offset++ {
+		day := weekStart.AddDate(0, 0, offset)
+		if hasWeekday(days, day.Weekday()) {
+			return day
+		}
+	}
+	nextWeek := weekStart.AddDate(0, 0, 7*r.Interval)
+	for offset := 0; offset < 7; offset++ {
+		day := nextWeek.AddDate(0, 0, offset)
+		if hasWeekday(days, day.Weekday()) {
+			return day
+		}
+	}
+	return time.Time{}
+}
+
+func (r Rule) nextMonthly(prev time.Time) time.Time {
+	monthDays := r.ByMonthDay
+	if len(monthDays) == 0 {
+		monthDays = []int{prev.Day()}
+	}
+
+	// Months that lack a requested day are skipped, as RFC 5545 requires, so
+	// look a few years ahead before giving up (BYMONTHDAY=31 with
+	// INTERVAL=2 can skip several months in a row).
+	for step := 0; step <= 48; step++ {
+		month := time.Date(prev.Year(), prev.Month()+time.Month(step*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		for _, day := range daysInMonth(month, monthDays) {
+			if day.After(prev) {
+				return day
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// daysInMonth resolves BYMONTHDAY values against a month, in date order,
+// dropping days the month does not have.
+func daysInMonth(month time.Time, monthDays []int) []time.Time {
+	last := month.AddDate(0, 1, -1).Day()
+	var days []time.Time
+	for _, d := range monthDays {
+		if d < 0 {
+			d = last + d + 1
+		}
+		if d < 1 || d > last {
+			continue
+		}
+		days = append(days, time.Date(month.Year(), month.Month(), d, 0, 0, 0, 0, time.UTC))
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+func dateOf(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func mondayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+func hasWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
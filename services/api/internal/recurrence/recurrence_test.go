@@ -0,0 +1,148 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		wantErr bool
+		want    string
+	}{
+		{name: "daily", rule: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{name: "rrule prefix", rule: "RRULE:FREQ=DAILY;INTERVAL=2", want: "FREQ=DAILY;INTERVAL=2"},
+		{name: "weekly days normalized", rule: "freq=weekly;byday=fr,mo", want: "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{name: "monthly with count", rule: "FREQ=MONTHLY;BYMONTHDAY=1,-1;COUNT=6", want: "FREQ=MONTHLY;BYMONTHDAY=1,-1;COUNT=6"},
+		{name: "until", rule: "FREQ=DAILY;UNTIL=20240131", want: "FREQ=DAILY;UNTIL=20240131"},
+		{name: "empty", rule: "", wantErr: true},
+		{name: "missing freq", rule: "INTERVAL=2", wantErr: true},
+		{name: "yearly unsupported", rule: "FREQ=YEARLY", wantErr: true},
+		{name: "zero interval", rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{name: "bad weekday", rule: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{name: "byday with monthly", rule: "FREQ=MONTHLY;BYDAY=MO", wantErr: true},
+		{name: "bymonthday with daily", rule: "FREQ=DAILY;BYMONTHDAY=3", wantErr: true},
+		{name: "bymonthday out of range", rule: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{name: "until and count", rule: "FREQ=DAILY;UNTIL=20240131;COUNT=3", wantErr: true},
+		{name: "unsupported part", rule: "FREQ=DAILY;BYHOUR=9", wantErr: true},
+		{name: "duplicate part", rule: "FREQ=DAILY;FREQ=WEEKLY", wantErr: true},
+		{name: "malformed part", rule: "FREQ=DAILY;COUNT", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   string
+		prev   time.Time
+		index  int
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name: "daily", rule: "FREQ=DAILY",
+			prev: date(2024, 1, 31), index: 1, want: date(2024, 2, 1), wantOK: true,
+		},
+		{
+			name: "every third day", rule: "FREQ=DAILY;INTERVAL=3",
+			prev: date(2024, 1, 1), index: 1, want: date(2024, 1, 4), wantOK: true,
+		},
+		{
+			name: "weekly defaults to same weekday", rule: "FREQ=WEEKLY",
+			prev: date(2024, 1, 10), index: 1, want: date(2024, 1, 17), wantOK: true,
+		},
+		{
+			name: "weekly later day in same week", rule: "FREQ=WEEKLY;BYDAY=MO,WE,FR",
+			prev: date(2024, 1, 10), index: 1, want: date(2024, 1, 12), wantOK: true,
+		},
+		{
+			name: "weekly wraps to next week", rule: "FREQ=WEEKLY;BYDAY=MO,WE,FR",
+			prev: date(2024, 1, 12), index: 1, want: date(2024, 1, 15), wantOK: true,
+		},
+		{
+			name: "fortnightly skips a week", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH",
+			prev: date(2024, 1, 11), index: 1, want: date(2024, 1, 23), wantOK: true,
+		},
+		{
+			name: "sunday ends the week", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU",
+			prev: date(2024, 1, 8), index: 1, want: date(2024, 1, 14), wantOK: true,
+		},
+		{
+			name: "monthly same day", rule: "FREQ=MONTHLY",
+			prev: date(2024, 1, 15), index: 1, want: date(2024, 2, 15), wantOK: true,
+		},
+		{
+			name: "monthly skips months without the day", rule: "FREQ=MONTHLY;BYMONTHDAY=31",
+			prev: date(2024, 1, 31), index: 1, want: date(2024, 3, 31), wantOK: true,
+		},
+		{
+			name: "monthly last day", rule: "FREQ=MONTHLY;BYMONTHDAY=-1",
+			prev: date(2024, 1, 31), index: 1, want: date(2024, 2, 29), wantOK: true,
+		},
+		{
+			name: "monthly several days", rule: "FREQ=MONTHLY;BYMONTHDAY=1,15",
+			prev: date(2024, 1, 1), index: 1, want: date(2024, 1, 15), wantOK: true,
+		},
+		{
+			name: "quarterly", rule: "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1",
+			prev: date(2024, 11, 1), index: 1, want: date(2025, 2, 1), wantOK: true,
+		},
+		{
+			name: "count reached", rule: "FREQ=DAILY;COUNT=3",
+			prev: date(2024, 1, 3), index: 3,
+		},
+		{
+			name: "count not yet reached", rule: "FREQ=DAILY;COUNT=3",
+			prev: date(2024, 1, 2), index: 2, want: date(2024, 1, 3), wantOK: true,
+		},
+		{
+			name: "until is inclusive", rule: "FREQ=DAILY;UNTIL=20240105",
+			prev: date(2024, 1, 4), index: 4, want: date(2024, 1, 5), wantOK: true,
+		},
+		{
+			name: "past until", rule: "FREQ=DAILY;UNTIL=20240105",
+			prev: date(2024, 1, 5), index: 5,
+		},
+		{
+			name: "time of day is ignored", rule: "FREQ=DAILY",
+			prev: time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC), index: 1, want: date(2024, 1, 2), wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Failed to parse rule: %v", err)
+			}
+			got, ok := rule.Next(tt.prev, tt.index)
+			if ok != tt.wantOK {
+				t.Fatalf("Expected ok=%v, got %v (%v)", tt.wantOK, ok, got)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want.Format("2006-01-02"), got.Format("2006-01-02"))
+			}
+		})
+	}
+}
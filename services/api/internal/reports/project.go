@@ -0,0 +1,187 @@
+package reports
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// ProjectReport is what was done on a project over the dates From through
+// To, for sharing with a client.
+type ProjectReport struct {
+	Project *models.Project
+	// Client is the name of the project's client, if it has one.
+	Client     string
+	From       time.Time
+	To         time.Time
+	Completed  []models.Task
+	LogEntries []models.LogEntry
+	// Location is where completion times are shown, UTC if nil.
+	Location *time.Location
+}
+
+// Page layout, in millimetres on A4.
+const (
+	pageMargin   = 20.0
+	lineHeight   = 5.5
+	dateColumn   = 28.0
+	bulletIndent = 5.0
+)
+
+// WriteProjectPDF renders the report as a PDF: the project header, the
+// tasks completed in order of completion and the log entries grouped by
+// day. It uses the standard Helvetica font, so text is limited to the
+// Windows-1252 character set and anything outside it prints as "?".
+func WriteProjectPDF(w io.Writer, report *ProjectReport) error {
+	location := report.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, pageMargin)
+	pdf.SetTitle(report.Project.Name, true)
+	pdf.SetCreator("Makerlog", true)
+	// A fixed date keeps the output the same for the same report.
+	pdf.SetCreationDate(report.To)
+	pdf.SetModificationDate(report.To)
+	pdf.AliasNbPages("")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pageMargin + 5)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.CellFormat(0, lineHeight, tr(report.Project.Name), "", 0, "L", false, 0, "")
+		pdf.SetX(pageMargin)
+		pdf.CellFormat(0, lineHeight, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.MultiCell(0, 9, tr(report.Project.Name), "", "L", false)
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(96, 96, 96)
+	if report.Client != "" {
+		pdf.MultiCell(0, lineHeight, tr("Prepared for "+report.Client), "", "L", false)
+	}
+	pdf.MultiCell(0, lineHeight, reportPeriod(report.From, report.To), "", "L", false)
+	pdf.SetTextColor(0, 0, 0)
+	if report.Project.Description != "" {
+		pdf.Ln(3)
+		pdf.MultiCell(0, lineHeight, tr(report.Project.Description), "", "L", false)
+	}
+	pdf.Ln(3)
+	pdf.MultiCell(0, lineHeight, projectSummary(report), "", "L", false)
+
+	heading := func(text string) {
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "B", 13)
+		pdf.CellFormat(0, 8, text, "B", 1, "L", false, 0, "")
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "", 10)
+	}
+
+	heading("Completed tasks")
+	completed := append([]models.Task(nil), report.Completed...)
+	sort.SliceStable(completed, func(i, j int) bool {
+		return completed[i].CompletedAt.Before(*completed[j].CompletedAt)
+	})
+	if len(completed) == 0 {
+		pdf.MultiCell(0, lineHeight, "No tasks were completed in this period.", "", "L", false)
+	}
+	for _, task := range completed {
+		pdf.SetTextColor(96, 96, 96)
+		pdf.CellFormat(dateColumn, lineHeight, task.CompletedAt.In(location).Format("Jan 2"), "", 0, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+		pdf.MultiCell(0, lineHeight, tr(task.Title), "", "L", false)
+	}
+
+	heading("Log")
+	if len(report.LogEntries) == 0 {
+		pdf.MultiCell(0, lineHeight, "Nothing was logged in this period.", "", "L", false)
+	}
+	for i, day := range logDays(report.LogEntries) {
+		if i > 0 {
+			pdf.Ln(2)
+		}
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.MultiCell(0, lineHeight+1, day[0].LogDate.Format("Monday, January 2, 2006"), "", "L", false)
+		pdf.SetFont("Helvetica", "", 10)
+		for _, logEntry := range day {
+			content := logEntry.Content
+			if logEntry.DurationMinutes != nil {
+				content += " (" + formatDuration(*logEntry.DurationMinutes) + ")"
+			}
+			pdf.CellFormat(bulletIndent, lineHeight, "-", "", 0, "L", false, 0, "")
+			pdf.MultiCell(0, lineHeight, tr(content), "", "L", false)
+		}
+	}
+
+	return pdf.Output(w)
+}
+
+// logDays groups log entries by their date, earliest first, keeping the
+// order of entries within a day.
+func logDays(logEntries []models.LogEntry) [][]models.LogEntry {
+	sorted := append([]models.LogEntry(nil), logEntries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LogDate.Before(sorted[j].LogDate)
+	})
+	var days [][]models.LogEntry
+	for _, logEntry := range sorted {
+		n := len(days)
+		if n > 0 && days[n-1][0].LogDate.Equal(logEntry.LogDate) {
+			days[n-1] = append(days[n-1], logEntry)
+			continue
+		}
+		days = append(days, []models.LogEntry{logEntry})
+	}
+	return days
+}
+
+func reportPeriod(from, to time.Time) string {
+	if from.Equal(to) {
+		return from.Format("January 2, 2006")
+	}
+	return from.Format("January 2, 2006") + " to " + to.Format("January 2, 2006")
+}
+
+// projectSummary counts the tasks and entries of the report and the time
+// logged, if any was.
+func projectSummary(report *ProjectReport) string {
+	minutes := 0
+	for _, logEntry := range report.LogEntries {
+		if logEntry.DurationMinutes != nil {
+			minutes += *logEntry.DurationMinutes
+		}
+	}
+	summary := fmt.Sprintf("%s completed, %s", plural(len(report.Completed), "task", "tasks"), plural(len(report.LogEntries), "log entry", "log entries"))
+	if minutes > 0 {
+		summary += ", " + formatDuration(minutes) + " logged"
+	}
+	return summary + "."
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// formatDuration writes minutes as hours and minutes, e.g. 1h 30m.
+func formatDuration(minutes int) string {
+	switch {
+	case minutes < 60:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%dh", minutes/60)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
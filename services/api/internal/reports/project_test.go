@@ -0,0 +1,155 @@
+package reports
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+	"github.com/ledongthuc/pdf"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// pdfText extracts the text of each page line by line, separating text
+// drawn at different places on a line with a space.
+func pdfText(t *testing.T, data []byte) string {
+	t.Helper()
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("reading PDF: %v", err)
+	}
+	var text strings.Builder
+	for i := 1; i <= reader.NumPage(); i++ {
+		var x, y float64
+		for j, glyph := range reader.Page(i).Content().Text {
+			switch {
+			case j == 0:
+			case glyph.Y != y:
+				text.WriteString("\n")
+			case glyph.X != x:
+				text.WriteString(" ")
+			}
+			x, y = glyph.X, glyph.Y
+			text.WriteString(glyph.S)
+		}
+		text.WriteString("\n---\n")
+	}
+	return text.String()
+}
+
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("text of %s differs:\n%s\nwant\n%s", name, got, want)
+	}
+}
+
+func date(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestWriteProjectPDF(t *testing.T) {
+	completedAt := func(s string) *time.Time {
+		t.Helper()
+		at, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &at
+	}
+	minutes := func(n int) *int { return &n }
+
+	report := &ProjectReport{
+		Project: &models.Project{
+			Name:        "Website redesign",
+			Description: "New marketing site for the spring launch.",
+		},
+		Client: "Acme Café",
+		From:   date(t, "2024-03-04"),
+		To:     date(t, "2024-03-08"),
+		Completed: []models.Task{
+			{Title: "Footer links", CompletedAt: completedAt("2024-03-07T16:00:00Z")},
+			// Late on the 5th in UTC is the 6th in Berlin.
+			{Title: "Hero section", CompletedAt: completedAt("2024-03-05T23:30:00Z")},
+		},
+		LogEntries: []models.LogEntry{
+			{LogDate: date(t, "2024-03-06"), Content: "Reviewed copy with the client", DurationMinutes: minutes(45)},
+			{LogDate: date(t, "2024-03-04"), Content: "Sketched the hero section", DurationMinutes: minutes(90)},
+			{LogDate: date(t, "2024-03-06"), Content: "Built the footer"},
+		},
+		Location: time.FixedZone("CET", 3600),
+	}
+
+	var buf bytes.Buffer
+	if err := WriteProjectPDF(&buf, report); err != nil {
+		t.Fatalf("WriteProjectPDF: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Fatalf("output is not a PDF")
+	}
+	checkGolden(t, "project_report.golden", pdfText(t, buf.Bytes()))
+}
+
+func TestWriteProjectPDFEmpty(t *testing.T) {
+	report := &ProjectReport{
+		Project: &models.Project{Name: "Internal tools"},
+		From:    date(t, "2024-03-01"),
+		To:      date(t, "2024-03-01"),
+	}
+
+	var buf bytes.Buffer
+	if err := WriteProjectPDF(&buf, report); err != nil {
+		t.Fatalf("WriteProjectPDF: %v", err)
+	}
+	checkGolden(t, "project_report_empty.golden", pdfText(t, buf.Bytes()))
+}
+
+func TestWriteProjectPDFPages(t *testing.T) {
+	report := &ProjectReport{
+		Project: &models.Project{Name: "Long project"},
+		From:    date(t, "2024-01-01"),
+		To:      date(t, "2024-01-31"),
+	}
+	for day := 1; day <= 31; day++ {
+		for i := 0; i < 3; i++ {
+			report.LogEntries = append(report.LogEntries, models.LogEntry{
+				LogDate: time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+				Content: "Worked on it",
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteProjectPDF(&buf, report); err != nil {
+		t.Fatalf("WriteProjectPDF: %v", err)
+	}
+	text := pdfText(t, buf.Bytes())
+	pages := strings.Count(text, "---\n")
+	if pages < 2 {
+		t.Fatalf("got %d pages, want the log to run over several", pages)
+	}
+	if footer := fmt.Sprintf("Long project Page %d of %d", pages, pages); !strings.Contains(text, footer) {
+		t.Errorf("last page has no footer %q:\n%s", footer, text)
+	}
+}
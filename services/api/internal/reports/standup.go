@@ -0,0 +1,106 @@
+// Package reports renders reports on a user's tasks and log entries in the
+// formats people paste into other tools.
+package reports
+
+import (
+	"strings"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+// PreviousWorkingDay returns the last weekday before the date, so the
+// standup on a Monday looks back at Friday.
+func PreviousWorkingDay(date time.Time) time.Time {
+	day := date.AddDate(0, 0, -1)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// standupStyle holds the markup of one output format.
+type standupStyle struct {
+	bold   func(s string) string
+	bullet string
+	escape func(s string) string
+}
+
+var markdownStyle = standupStyle{
+	bold:   func(s string) string { return "**" + s + "**" },
+	bullet: "- ",
+	escape: func(s string) string { return s },
+}
+
+// Slack mrkdwn has single-asterisk bold and needs &, < and > escaped.
+var slackStyle = standupStyle{
+	bold:   func(s string) string { return "*" + s + "*" },
+	bullet: "• ",
+	escape: strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace,
+}
+
+// StandupMarkdown renders the report as Markdown, labelling entries and
+// tasks with the names of their projects.
+func StandupMarkdown(report *models.StandupReport, projectNames map[string]string) string {
+	return renderStandup(markdownStyle, "## Standup for "+displayDate(report.Date), report, projectNames)
+}
+
+// StandupSlack renders the report as Slack mrkdwn.
+func StandupSlack(report *models.StandupReport, projectNames map[string]string) string {
+	return renderStandup(slackStyle, slackStyle.bold("Standup for "+displayDate(report.Date)), report, projectNames)
+}
+
+func renderStandup(style standupStyle, title string, report *models.StandupReport, projectNames map[string]string) string {
+	var b strings.Builder
+	b.WriteString(title + "\n")
+
+	section := func(heading string, items []string) {
+		b.WriteString("\n" + heading + "\n")
+		if len(items) == 0 {
+			items = []string{"None"}
+		}
+		for _, item := range items {
+			// Continuation lines are indented to stay within the bullet.
+			b.WriteString(style.bullet + strings.ReplaceAll(item, "\n", "\n  ") + "\n")
+		}
+	}
+	task := func(task models.Task) string {
+		if name := projectNames[task.ProjectID]; name != "" {
+			return style.escape(task.Title + " (" + name + ")")
+		}
+		return style.escape(task.Title)
+	}
+
+	var yesterday []string
+	for _, logEntry := range report.Yesterday {
+		item := style.escape(strings.TrimSpace(logEntry.Content))
+		if logEntry.ProjectID != nil && projectNames[*logEntry.ProjectID] != "" {
+			item = style.bold(style.escape(projectNames[*logEntry.ProjectID])+":") + " " + item
+		}
+		yesterday = append(yesterday, item)
+	}
+	section(style.bold("Yesterday")+" ("+displayDate(report.YesterdayDate)+")", yesterday)
+
+	var today []string
+	for _, t := range report.Today {
+		today = append(today, task(t))
+	}
+	section(style.bold("Today"), today)
+
+	var blockers []string
+	for _, t := range report.Blockers {
+		blockers = append(blockers, task(t))
+	}
+	section(style.bold("Blockers"), blockers)
+
+	return b.String()
+}
+
+// displayDate turns a YYYY-MM-DD date into "Mon, Jan 2".
+func displayDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format("Mon, Jan 2")
+}
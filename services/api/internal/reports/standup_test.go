@@ -0,0 +1,84 @@
+package reports
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+func TestPreviousWorkingDay(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2024-03-12", "2024-03-11"}, // Tuesday to Monday
+		{"2024-03-11", "2024-03-08"}, // Monday to Friday
+		{"2024-03-10", "2024-03-08"}, // Sunday to Friday
+		{"2024-03-09", "2024-03-08"}, // Saturday to Friday
+		{"2024-03-01", "2024-02-29"}, // Friday across a leap day
+	}
+
+	for _, tt := range tests {
+		date, err := time.Parse("2006-01-02", tt.date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := PreviousWorkingDay(date).Format("2006-01-02"); got != tt.want {
+			t.Errorf("PreviousWorkingDay(%s) = %s, want %s", tt.date, got, tt.want)
+		}
+	}
+}
+
+func standupReport() *models.StandupReport {
+	website := "p1"
+	return &models.StandupReport{
+		Date:          "2024-03-11",
+		YesterdayDate: "2024-03-08",
+		Yesterday: []models.LogEntry{
+			{ProjectID: &website, Content: "Fixed the <nav> & footer"},
+			{Content: "Read a paper\nand took notes"},
+		},
+		Today: []models.Task{{Title: "Pagination", ProjectID: "p2"}},
+	}
+}
+
+func TestStandupMarkdown(t *testing.T) {
+	got := StandupMarkdown(standupReport(), map[string]string{"p1": "Website", "p2": "API"})
+	want := `## Standup for Mon, Mar 11
+
+**Yesterday** (Fri, Mar 8)
+- **Website:** Fixed the <nav> & footer
+- Read a paper
+  and took notes
+
+**Today**
+- Pagination (API)
+
+**Blockers**
+- None
+`
+	if got != want {
+		t.Errorf("StandupMarkdown =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStandupSlack(t *testing.T) {
+	got := StandupSlack(standupReport(), map[string]string{"p1": "Website", "p2": "API"})
+	want := `*Standup for Mon, Mar 11*
+
+*Yesterday* (Fri, Mar 8)
+• *Website:* Fixed the &lt;nav&gt; &amp; footer
+• Read a paper
+  and took notes
+
+*Today*
+• Pagination (API)
+
+*Blockers*
+• None
+`
+	if got != want {
+		t.Errorf("StandupSlack =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,76 @@
+package reports
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+// Timesheet prices the billable time of each row at its project's rate,
+// rounding to the nearest minor unit, and totals the rows per currency in
+// order of first appearance.
+func Timesheet(from, to string, rows []models.TimesheetRow) *models.Timesheet {
+	timesheet := &models.Timesheet{From: from, To: to, Rows: rows, Totals: []models.TimesheetTotal{}}
+	index := map[string]int{}
+	for i := range rows {
+		row := &rows[i]
+		row.AmountCents = 0
+		if rate := row.HourlyRateCents; rate != nil {
+			row.AmountCents = (row.BillableMinutes*(*rate) + 30) / 60
+		}
+
+		n, ok := index[row.Currency]
+		if !ok {
+			n = len(timesheet.Totals)
+			index[row.Currency] = n
+			timesheet.Totals = append(timesheet.Totals, models.TimesheetTotal{Currency: row.Currency})
+		}
+		total := &timesheet.Totals[n]
+		total.Minutes += row.Minutes
+		total.BillableMinutes += row.BillableMinutes
+		total.AmountCents += row.AmountCents
+	}
+	return timesheet
+}
+
+// WriteTimesheetCSV writes one line per row, with hours and money as
+// decimals for spreadsheets and invoicing tools.
+func WriteTimesheetCSV(w io.Writer, timesheet *models.Timesheet) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"client", "project", "week", "hours", "billable_hours", "hourly_rate", "currency", "amount"}); err != nil {
+		return err
+	}
+	for _, row := range timesheet.Rows {
+		client, rate := "", ""
+		if row.ClientName != nil {
+			client = *row.ClientName
+		}
+		if row.HourlyRateCents != nil {
+			rate = formatCents(*row.HourlyRateCents)
+		}
+		err := out.Write([]string{
+			client, row.ProjectName, row.Week,
+			formatHours(row.Minutes), formatHours(row.BillableMinutes),
+			rate, row.Currency, formatCents(row.AmountCents),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func formatHours(minutes int64) string {
+	return fmt.Sprintf("%.2f", float64(minutes)/60)
+}
+
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
@@ -0,0 +1,58 @@
+package reports
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/chrispotter/makerlog/services/api/internal/models"
+)
+
+func TestTimesheet(t *testing.T) {
+	acme := "Acme"
+	rate := int64(10000)
+	odd := int64(9999)
+	timesheet := Timesheet("2024-03-01", "2024-03-31", []models.TimesheetRow{
+		{ClientName: &acme, ProjectName: "Website", Week: "2024-03-04", Minutes: 150, BillableMinutes: 90, HourlyRateCents: &rate, Currency: "EUR"},
+		{ClientName: &acme, ProjectName: "Website", Week: "2024-03-11", Minutes: 20, BillableMinutes: 20, HourlyRateCents: &odd, Currency: "EUR"},
+		{ProjectName: "Side project", Week: "2024-03-04", Minutes: 60, BillableMinutes: 60, Currency: "USD"},
+	})
+
+	wantAmounts := []int64{15000, 3333, 0}
+	for i, want := range wantAmounts {
+		if got := timesheet.Rows[i].AmountCents; got != want {
+			t.Errorf("Row %d amount = %d, want %d", i, got, want)
+		}
+	}
+
+	if len(timesheet.Totals) != 2 {
+		t.Fatalf("Expected totals for EUR and USD, got %+v", timesheet.Totals)
+	}
+	eur := timesheet.Totals[0]
+	if eur.Currency != "EUR" || eur.Minutes != 170 || eur.BillableMinutes != 110 || eur.AmountCents != 18333 {
+		t.Errorf("Unexpected EUR total %+v", eur)
+	}
+	if usd := timesheet.Totals[1]; usd.Currency != "USD" || usd.AmountCents != 0 {
+		t.Errorf("Unexpected USD total %+v", usd)
+	}
+}
+
+func TestWriteTimesheetCSV(t *testing.T) {
+	acme := "Acme, Inc."
+	rate := int64(12550)
+	timesheet := Timesheet("2024-03-01", "2024-03-31", []models.TimesheetRow{
+		{ClientName: &acme, ProjectName: "Website", Week: "2024-03-04", Minutes: 150, BillableMinutes: 90, HourlyRateCents: &rate, Currency: "EUR"},
+		{ProjectName: "Side project", Week: "2024-03-04", Minutes: 45, Currency: "USD"},
+	})
+
+	var buf bytes.Buffer
+	if err := WriteTimesheetCSV(&buf, timesheet); err != nil {
+		t.Fatalf("WriteTimesheetCSV failed: %v", err)
+	}
+	want := `client,project,week,hours,billable_hours,hourly_rate,currency,amount
+"Acme, Inc.",Website,2024-03-04,2.50,1.50,125.50,EUR,188.25
+,Side project,2024-03-04,0.75,0.00,,USD,0.00
+`
+	if got := buf.String(); got != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", got, want)
+	}
+}
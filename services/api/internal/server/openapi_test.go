@@ -0,0 +1,274 @@
+package server
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/chrispotter/makerlog/services/api/internal/openapi"
+	"github.com/go-chi/chi/v5"
+)
+
+// specDocument is the part of the OpenAPI document the tests read.
+type specDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]*specSchema `json:"schemas"`
+	} `json:"components"`
+}
+
+type specSchema struct {
+	Ref        string                     `json:"$ref"`
+	AllOf      []*specSchema              `json:"allOf"`
+	Properties map[string]json.RawMessage `json:"properties"`
+}
+
+// modelPackages hold the types the API reads and writes as JSON.
+var modelPackages = []string{"../models", "../textdiff"}
+
+func loadSpec(t *testing.T) specDocument {
+	t.Helper()
+	var spec specDocument
+	if err := json.Unmarshal(openapi.Spec, &spec); err != nil {
+		t.Fatalf("Failed to parse OpenAPI document: %v", err)
+	}
+	return spec
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	spec := loadSpec(t)
+
+	routes := map[string]bool{}
+	err := chi.Walk(NewRouter(Config{}), func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		routes[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk routes: %v", err)
+	}
+
+	documented := map[string]bool{}
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	for _, route := range sortedKeys(routes) {
+		if !documented[route] {
+			t.Errorf("Route %s is not in the OpenAPI document", route)
+		}
+	}
+	for _, operation := range sortedKeys(documented) {
+		if !routes[operation] {
+			t.Errorf("OpenAPI document has %s, which is not a route", operation)
+		}
+	}
+}
+
+func TestSpecCoversModels(t *testing.T) {
+	spec := loadSpec(t)
+
+	for _, dir := range modelPackages {
+		for name, fields := range jsonFields(t, dir) {
+			schema, ok := spec.Components.Schemas[name]
+			if !ok {
+				t.Errorf("Model %s has no schema in the OpenAPI document", name)
+				continue
+			}
+			properties := schemaProperties(t, spec, schema)
+			for _, field := range fields {
+				if !properties[field] {
+					t.Errorf("Schema %s is missing field %s", name, field)
+				}
+				delete(properties, field)
+			}
+			for _, field := range sortedKeys(properties) {
+				t.Errorf("Schema %s has field %s, which %s does not", name, field, name)
+			}
+		}
+	}
+}
+
+func TestSpecRefsResolve(t *testing.T) {
+	var document interface{}
+	if err := json.Unmarshal(openapi.Spec, &document); err != nil {
+		t.Fatalf("Failed to parse OpenAPI document: %v", err)
+	}
+
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if ref, ok := value["$ref"].(string); ok && resolve(document, ref) == nil {
+				t.Errorf("Reference %s does not resolve", ref)
+			}
+			for _, child := range value {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range value {
+				walk(child)
+			}
+		}
+	}
+	walk(document)
+}
+
+// resolve follows a local JSON pointer reference, returning nil if it
+// leads nowhere.
+func resolve(document interface{}, ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	value := document
+	for _, part := range strings.Split(ref[2:], "/") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		if value, ok = object[part]; !ok {
+			return nil
+		}
+	}
+	return value
+}
+
+// schemaProperties collects a schema's properties, following references
+// and the schemas it extends with allOf.
+func schemaProperties(t *testing.T, spec specDocument, schema *specSchema) map[string]bool {
+	t.Helper()
+	properties := map[string]bool{}
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		referenced, ok := spec.Components.Schemas[name]
+		if !ok {
+			t.Errorf("Reference %s does not resolve", schema.Ref)
+			return properties
+		}
+		return schemaProperties(t, spec, referenced)
+	}
+	for _, part := range schema.AllOf {
+		for property := range schemaProperties(t, spec, part) {
+			properties[property] = true
+		}
+	}
+	for property := range schema.Properties {
+		properties[property] = true
+	}
+	return properties
+}
+
+// jsonFields lists the JSON fields of each exported struct type in the
+// package in dir, with embedded structs' fields promoted. Types that
+// encode themselves are left out, since their fields are not what goes
+// over the wire.
+func jsonFields(t *testing.T, dir string) map[string][]string {
+	t.Helper()
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info fs.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", dir, err)
+	}
+
+	structs := map[string]*ast.StructType{}
+	custom := map[string]bool{}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				switch decl := decl.(type) {
+				case *ast.GenDecl:
+					for _, spec := range decl.Specs {
+						typeSpec, ok := spec.(*ast.TypeSpec)
+						if !ok || !typeSpec.Name.IsExported() {
+							continue
+						}
+						if structType, ok := typeSpec.Type.(*ast.StructType); ok {
+							structs[typeSpec.Name.Name] = structType
+						}
+					}
+				case *ast.FuncDecl:
+					if decl.Recv != nil && (decl.Name.Name == "MarshalJSON" || decl.Name.Name == "UnmarshalJSON") {
+						custom[receiverName(decl.Recv.List[0].Type)] = true
+					}
+				}
+			}
+		}
+	}
+
+	var fieldsOf func(structType *ast.StructType) []string
+	fieldsOf = func(structType *ast.StructType) []string {
+		var fields []string
+		for _, field := range structType.Fields.List {
+			name := ""
+			if field.Tag != nil {
+				tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`")).Get("json")
+				name = strings.Split(tag, ",")[0]
+			}
+			if name == "-" {
+				continue
+			}
+			if len(field.Names) == 0 {
+				if embedded, ok := structs[receiverName(field.Type)]; ok && name == "" {
+					fields = append(fields, fieldsOf(embedded)...)
+					continue
+				}
+				if name == "" {
+					name = receiverName(field.Type)
+				}
+				fields = append(fields, name)
+				continue
+			}
+			for _, ident := range field.Names {
+				if !ident.IsExported() {
+					continue
+				}
+				if name != "" {
+					fields = append(fields, name)
+				} else {
+					fields = append(fields, ident.Name)
+				}
+			}
+		}
+		return fields
+	}
+
+	fields := map[string][]string{}
+	for name, structType := range structs {
+		if !custom[name] {
+			fields[name] = fieldsOf(structType)
+		}
+	}
+	return fields
+}
+
+func receiverName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	}
+	return ""
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,218 @@
+// Package server wires the API's handlers into its router.
+package server
+
+import (
+	"time"
+
+	"github.com/chrispotter/makerlog/services/api/internal/database"
+	"github.com/chrispotter/makerlog/services/api/internal/handlers"
+	"github.com/chrispotter/makerlog/services/api/internal/mail"
+	"github.com/chrispotter/makerlog/services/api/internal/middleware"
+	"github.com/chrispotter/makerlog/services/api/internal/openapi"
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+	"github.com/gorilla/sessions"
+)
+
+// Config is what the handlers depend on.
+type Config struct {
+	Queries      *database.Queries
+	SessionStore *sessions.CookieStore
+	Mailer       mail.Mailer
+	// FrontendURL is the web app's address, for CORS and links; APIURL is
+	// this API's public address.
+	FrontendURL    string
+	APIURL         string
+	TrashRetention time.Duration
+	InvitationTTL  time.Duration
+	// IdempotencyTTL is how long responses are kept for replay to requests
+	// retried with the same Idempotency-Key.
+	IdempotencyTTL time.Duration
+}
+
+// NewRouter returns the router serving every API route.
+func NewRouter(cfg Config) *chi.Mux {
+	// Setup handlers
+	authHandler := handlers.NewAuthHandler(cfg.Queries, cfg.SessionStore)
+	projectHandler := handlers.NewProjectHandler(cfg.Queries)
+	taskHandler := handlers.NewTaskHandler(cfg.Queries)
+	logEntryHandler := handlers.NewLogEntryHandler(cfg.Queries)
+	trashHandler := handlers.NewTrashHandler(cfg.Queries, cfg.TrashRetention)
+	workspaceHandler := handlers.NewWorkspaceHandler(cfg.Queries)
+	commentHandler := handlers.NewCommentHandler(cfg.Queries)
+	invitationHandler := handlers.NewInvitationHandler(cfg.Queries, cfg.Mailer, cfg.FrontendURL, cfg.InvitationTTL)
+	notificationHandler := handlers.NewNotificationHandler(cfg.Queries)
+	settingsHandler := handlers.NewSettingsHandler(cfg.Queries)
+	digestHandler := handlers.NewDigestHandler(cfg.Queries)
+	reportHandler := handlers.NewReportHandler(cfg.Queries)
+	clientHandler := handlers.NewClientHandler(cfg.Queries)
+	calendarHandler := handlers.NewCalendarHandler(cfg.Queries, cfg.APIURL, cfg.FrontendURL)
+	accessTokenHandler := handlers.NewAccessTokenHandler(cfg.Queries)
+	syncHandler := handlers.NewSyncHandler(cfg.Queries, projectHandler, taskHandler, logEntryHandler)
+	batchHandler := handlers.NewBatchHandler(cfg.Queries)
+	graphqlHandler := handlers.NewGraphQLHandler(cfg.Queries)
+
+	// Setup router
+	r := chi.NewRouter()
+
+	// Middleware
+	r.Use(chimiddleware.Logger)
+	r.Use(chimiddleware.Recoverer)
+	r.Use(chimiddleware.Timeout(60 * time.Second))
+
+	// CORS configuration
+	r.Use(cors.Handler(cors.Options{
+		AllowedOrigins:   []string{cfg.FrontendURL},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Idempotency-Key", "If-Match", "If-None-Match"},
+		ExposedHeaders:   []string{"Link", "ETag", "Idempotent-Replayed"},
+		AllowCredentials: true,
+		MaxAge:           300,
+	}))
+
+	// Public routes
+	r.Post("/api/auth/register", authHandler.Register)
+	r.Post("/api/auth/login", authHandler.Login)
+	r.Get("/api/calendar/{token}.ics", calendarHandler.Feed)
+	r.Get("/api/openapi.json", openapi.ServeSpec)
+	r.Get("/api/docs", openapi.ServeDocs)
+
+	// Protected routes
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Auth(cfg.SessionStore, cfg.Queries))
+		r.Use(middleware.Idempotency(cfg.Queries, cfg.IdempotencyTTL))
+
+		// Auth routes
+		r.Post("/api/auth/logout", authHandler.Logout)
+		r.Get("/api/auth/me", authHandler.Me)
+		r.Patch("/api/auth/me", authHandler.Update)
+
+		// Workspaces routes
+		r.Get("/api/workspaces", workspaceHandler.List)
+		r.Post("/api/workspaces", workspaceHandler.Create)
+		r.Get("/api/workspaces/{id}", workspaceHandler.Get)
+		r.Put("/api/workspaces/{id}", workspaceHandler.Update)
+		r.Delete("/api/workspaces/{id}", workspaceHandler.Delete)
+		r.Get("/api/workspaces/{id}/members", workspaceHandler.Members)
+		r.Post("/api/workspaces/{id}/members", workspaceHandler.AddMember)
+		r.Put("/api/workspaces/{id}/members/{userID}", workspaceHandler.UpdateMember)
+		r.Delete("/api/workspaces/{id}/members/{userID}", workspaceHandler.RemoveMember)
+		r.Get("/api/workspaces/{id}/invitations", invitationHandler.ListForWorkspace)
+		r.Post("/api/workspaces/{id}/invitations", invitationHandler.CreateForWorkspace)
+
+		// Invitations routes
+		r.Post("/api/invitations/accept", invitationHandler.Accept)
+		r.Delete("/api/invitations/{id}", invitationHandler.Revoke)
+
+		// Clients routes
+		r.Get("/api/clients", clientHandler.List)
+		r.Post("/api/clients", clientHandler.Create)
+		r.Get("/api/clients/{id}", clientHandler.Get)
+		r.Put("/api/clients/{id}", clientHandler.Update)
+		r.Delete("/api/clients/{id}", clientHandler.Delete)
+
+		// Projects routes
+		r.Get("/api/projects", projectHandler.List)
+		r.Post("/api/projects", projectHandler.Create)
+		r.Get("/api/projects/{id}", projectHandler.Get)
+		r.Put("/api/projects/{id}", projectHandler.Update)
+		r.Patch("/api/projects/{id}", projectHandler.Patch)
+		r.Delete("/api/projects/{id}", projectHandler.Delete)
+		r.Post("/api/projects/{id}/archive", projectHandler.Archive)
+		r.Post("/api/projects/{id}/unarchive", projectHandler.Unarchive)
+		r.Post("/api/projects/{id}/restore", trashHandler.RestoreProject)
+		r.Get("/api/projects/{id}/board", taskHandler.Board)
+		r.Get("/api/projects/{id}/report.pdf", reportHandler.ProjectPDF)
+		r.Get("/api/projects/{id}/workflow", projectHandler.Workflow)
+		r.Put("/api/projects/{id}/workflow", projectHandler.UpdateWorkflow)
+		r.Get("/api/projects/{id}/members", projectHandler.Members)
+		r.Delete("/api/projects/{id}/members/{userID}", projectHandler.RemoveMember)
+		r.Get("/api/projects/{id}/invitations", invitationHandler.ListForProject)
+		r.Post("/api/projects/{id}/invitations", invitationHandler.CreateForProject)
+
+		// Tasks routes
+		r.Get("/api/tasks", taskHandler.List)
+		r.Post("/api/tasks", taskHandler.Create)
+		r.Post("/api/tasks/complete", batchHandler.CompleteTasks)
+		r.Get("/api/tasks/upcoming", taskHandler.Upcoming)
+		r.Get("/api/tasks/{id}", taskHandler.Get)
+		r.Put("/api/tasks/{id}", taskHandler.Update)
+		r.Patch("/api/tasks/{id}", taskHandler.Patch)
+		r.Delete("/api/tasks/{id}", taskHandler.Delete)
+		r.Post("/api/tasks/{id}/move", taskHandler.Move)
+		r.Post("/api/tasks/{id}/restore", trashHandler.RestoreTask)
+		r.Get("/api/tasks/{id}/revisions", taskHandler.Revisions)
+		r.Get("/api/tasks/{id}/revisions/diff", taskHandler.RevisionDiff)
+		r.Post("/api/tasks/{id}/revisions/{revisionID}/restore", taskHandler.RestoreRevision)
+		r.Post("/api/tasks/{id}/dependencies", taskHandler.AddDependency)
+		r.Delete("/api/tasks/{id}/dependencies/{blockedByID}", taskHandler.RemoveDependency)
+
+		// Log entries routes
+		r.Get("/api/log-entries", logEntryHandler.List)
+		r.Post("/api/log-entries", logEntryHandler.Create)
+		r.Post("/api/log-entries/move", batchHandler.MoveLogEntries)
+		r.Get("/api/log-entries/{id}", logEntryHandler.Get)
+		r.Put("/api/log-entries/{id}", logEntryHandler.Update)
+		r.Patch("/api/log-entries/{id}", logEntryHandler.Patch)
+		r.Delete("/api/log-entries/{id}", logEntryHandler.Delete)
+		r.Post("/api/log-entries/{id}/restore", trashHandler.RestoreLogEntry)
+		r.Get("/api/log-entries/{id}/revisions", logEntryHandler.Revisions)
+		r.Get("/api/log-entries/{id}/revisions/diff", logEntryHandler.RevisionDiff)
+		r.Post("/api/log-entries/{id}/revisions/{revisionID}/restore", logEntryHandler.RestoreRevision)
+		r.Get("/api/log-entries/{id}/comments", commentHandler.List)
+		r.Post("/api/log-entries/{id}/comments", commentHandler.Create)
+		r.Get("/api/log-entries/{id}/reactions", commentHandler.Reactions)
+		r.Put("/api/log-entries/{id}/reactions/{emoji}", commentHandler.AddReaction)
+		r.Delete("/api/log-entries/{id}/reactions/{emoji}", commentHandler.RemoveReaction)
+
+		// Comments routes
+		r.Put("/api/comments/{id}", commentHandler.Update)
+		r.Delete("/api/comments/{id}", commentHandler.Delete)
+
+		// Notifications routes
+		r.Get("/api/notifications", notificationHandler.List)
+		r.Post("/api/notifications/read-all", notificationHandler.MarkAllRead)
+		r.Post("/api/notifications/{id}/read", notificationHandler.MarkRead)
+		r.Get("/api/notifications/preferences", notificationHandler.Preferences)
+		r.Put("/api/notifications/preferences", notificationHandler.UpdatePreferences)
+
+		// Settings routes
+		r.Get("/api/settings", settingsHandler.Get)
+		r.Put("/api/settings", settingsHandler.Update)
+		r.Get("/api/digest/preview", digestHandler.Preview)
+
+		// Calendar feed routes
+		r.Get("/api/calendar-feed", calendarHandler.Get)
+		r.Post("/api/calendar-feed", calendarHandler.Rotate)
+		r.Delete("/api/calendar-feed", calendarHandler.Delete)
+
+		// Access token routes
+		r.Get("/api/access-tokens", accessTokenHandler.List)
+		r.Post("/api/access-tokens", accessTokenHandler.Create)
+		r.Delete("/api/access-tokens/{id}", accessTokenHandler.Delete)
+
+		// Reports routes
+		r.Get("/api/reports/standup", reportHandler.Standup)
+		r.Get("/api/reports/timesheet", reportHandler.Timesheet)
+
+		// Trash route - deleted items awaiting purge
+		r.Get("/api/trash", trashHandler.List)
+
+		// Today route - get today's log entries
+		r.Get("/api/today", logEntryHandler.Today)
+
+		// Sync routes
+		r.Get("/api/sync", syncHandler.Changes)
+		r.Post("/api/sync", syncHandler.Push)
+
+		// Batch route - many writes in one transaction
+		r.Post("/api/batch", batchHandler.Run)
+
+		// GraphQL routes
+		r.Post("/api/graphql", graphqlHandler.Serve)
+		r.Get("/api/graphql/schema", graphqlHandler.Schema)
+	})
+
+	return r
+}
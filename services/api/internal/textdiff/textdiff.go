@@ -0,0 +1,102 @@
+// Package textdiff computes line-based differences between two texts.
+package textdiff
+
+import "strings"
+
+// Op says what happened to a line going from the old text to the new one.
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Delete Op = "delete"
+	Insert Op = "insert"
+)
+
+// Line is one line of a diff.
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxCells bounds the size of the table used to find common lines. Longer
+// texts that differ in the middle are shown as a single replacement.
+const maxCells = 1 << 22
+
+// Lines returns the edits that turn a into b, line by line, keeping as many
+// lines unchanged as possible. Deletions come before insertions where both
+// are needed.
+func Lines(a, b string) []Line {
+	return diff(split(a), split(b))
+}
+
+func split(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+func diff(a, b []string) []Line {
+	var prefix, suffix []Line
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, Line{Equal, a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append([]Line{{Equal, a[len(a)-1]}}, suffix...)
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	lines := prefix
+	if (len(a)+1)*(len(b)+1) > maxCells {
+		lines = appendOps(lines, Delete, a)
+		lines = appendOps(lines, Insert, b)
+	} else {
+		lines = append(lines, common(a, b)...)
+	}
+	return append(lines, suffix...)
+}
+
+// common diffs a and b through their longest common subsequence.
+func common(a, b []string) []Line {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []Line
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Equal, a[i]})
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Delete, a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Insert, b[j]})
+			j++
+		}
+	}
+	lines = appendOps(lines, Delete, a[i:])
+	return appendOps(lines, Insert, b[j:])
+}
+
+func appendOps(lines []Line, op Op, texts []string) []Line {
+	for _, text := range texts {
+		lines = append(lines, Line{op, text})
+	}
+	return lines
+}
@@ -0,0 +1,43 @@
+package textdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Line
+	}{
+		{"identical", "one\ntwo", "one\ntwo", []Line{{Equal, "one"}, {Equal, "two"}}},
+		{"both empty", "", "", nil},
+		{"from empty", "", "one", []Line{{Insert, "one"}}},
+		{"to empty", "one", "", []Line{{Delete, "one"}}},
+		{"trailing newline ignored", "one\n", "one", []Line{{Equal, "one"}}},
+		{
+			"changed line",
+			"one\ntwo\nthree", "one\n2\nthree",
+			[]Line{{Equal, "one"}, {Delete, "two"}, {Insert, "2"}, {Equal, "three"}},
+		},
+		{
+			"insert and delete",
+			"a\nb\nc\nd", "a\nc\nd\ne",
+			[]Line{{Equal, "a"}, {Delete, "b"}, {Equal, "c"}, {Equal, "d"}, {Insert, "e"}},
+		},
+		{
+			"reordered",
+			"a\nb\nc", "c\na\nb",
+			[]Line{{Insert, "c"}, {Equal, "a"}, {Equal, "b"}, {Delete, "c"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Row versions back the ETag/If-Match optimistic concurrency checks. Every
+-- update increments the version, so a write conditioned on the version a
+-- client last read fails instead of silently overwriting someone else's edit.
+ALTER TABLE projects ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE tasks ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE log_entries ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE log_entries DROP COLUMN IF EXISTS version;
+ALTER TABLE tasks DROP COLUMN IF EXISTS version;
+ALTER TABLE projects DROP COLUMN IF EXISTS version;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN priority VARCHAR(20) NOT NULL DEFAULT 'medium'
+    CHECK (priority IN ('low', 'medium', 'high', 'urgent'));
+ALTER TABLE tasks ADD COLUMN due_date DATE;
+ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMP;
+
+UPDATE tasks SET completed_at = updated_at WHERE status = 'done';
+
+-- Overdue, due-this-week and upcoming views only ever look at open tasks
+-- with a due date, so a partial index keeps them cheap for large backlogs.
+CREATE INDEX idx_tasks_user_open_due_date ON tasks(user_id, due_date)
+    WHERE due_date IS NOT NULL AND status <> 'done';
+CREATE INDEX idx_tasks_user_priority ON tasks(user_id, priority);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_tasks_user_priority;
+DROP INDEX IF EXISTS idx_tasks_user_open_due_date;
+ALTER TABLE tasks DROP COLUMN IF EXISTS completed_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS due_date;
+ALTER TABLE tasks DROP COLUMN IF EXISTS priority;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN parent_task_id UUID REFERENCES tasks(id) ON DELETE CASCADE;
+ALTER TABLE tasks ADD CONSTRAINT chk_tasks_not_own_parent CHECK (parent_task_id <> id);
+CREATE INDEX idx_tasks_parent_task_id ON tasks(parent_task_id);
+
+-- task_id is blocked by blocked_by_id. Cycles are rejected by the API before
+-- an edge is inserted.
+CREATE TABLE task_dependencies (
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    blocked_by_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (task_id, blocked_by_id),
+    CHECK (task_id <> blocked_by_id)
+);
+
+CREATE INDEX idx_task_dependencies_blocked_by_id ON task_dependencies(blocked_by_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS task_dependencies;
+DROP INDEX IF EXISTS idx_tasks_parent_task_id;
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS chk_tasks_not_own_parent;
+ALTER TABLE tasks DROP COLUMN IF EXISTS parent_task_id;
+-- +goose StatementEnd
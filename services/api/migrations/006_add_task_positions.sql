@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- position is a lexicographic rank (see internal/rank) ordering tasks within
+-- a project's status column. It must compare bytewise, hence COLLATE "C".
+ALTER TABLE tasks ADD COLUMN position VARCHAR(255) COLLATE "C" NOT NULL DEFAULT '';
+
+-- Keep the existing created_at order. The trailing 'i' keeps every key valid
+-- and leaves room on both sides of it.
+UPDATE tasks t
+SET position = lpad(r.rn::text, 8, '0') || 'i'
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY project_id, status ORDER BY created_at) AS rn
+    FROM tasks
+) r
+WHERE t.id = r.id;
+
+CREATE INDEX idx_tasks_project_status_position ON tasks(project_id, status, position);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_tasks_project_status_position;
+ALTER TABLE tasks DROP COLUMN IF EXISTS position;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tasks ADD COLUMN recurrence_rule TEXT;
+ALTER TABLE tasks ADD COLUMN recurrence_series_id UUID;
+ALTER TABLE tasks ADD COLUMN recurrence_index INTEGER;
+
+-- One row per occurrence: completing a task and the scheduler can both try
+-- to create the same next occurrence, and only the first insert wins.
+CREATE UNIQUE INDEX idx_tasks_recurrence_occurrence ON tasks(recurrence_series_id, recurrence_index)
+    WHERE recurrence_series_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_tasks_recurrence_occurrence;
+ALTER TABLE tasks DROP COLUMN IF EXISTS recurrence_index;
+ALTER TABLE tasks DROP COLUMN IF EXISTS recurrence_series_id;
+ALTER TABLE tasks DROP COLUMN IF EXISTS recurrence_rule;
+-- +goose StatementEnd
@@ -0,0 +1,56 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Each project defines the statuses its tasks can be in. key is the value
+-- stored in tasks.status; category tells the API whether a status counts as
+-- open, in progress or finished. A NULL allowed_transitions lets tasks move
+-- from the status to any other.
+CREATE TABLE project_statuses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    category VARCHAR(20) NOT NULL CHECK (category IN ('open', 'active', 'closed')),
+    position INTEGER NOT NULL,
+    allowed_transitions TEXT[],
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (project_id, key)
+);
+
+CREATE INDEX idx_project_statuses_project_position ON project_statuses(project_id, position);
+
+-- Every existing project gets the default workflow.
+INSERT INTO project_statuses (project_id, key, name, category, position)
+SELECT p.id, s.key, s.name, s.category, s.position
+FROM projects p
+CROSS JOIN (VALUES
+    ('todo', 'To Do', 'open', 1),
+    ('in_progress', 'In Progress', 'active', 2),
+    ('done', 'Done', 'closed', 3)
+) AS s(key, name, category, position);
+
+-- Statuses were free-form, so keep any others already in use as in-progress
+-- columns after the defaults.
+INSERT INTO project_statuses (project_id, key, name, category, position)
+SELECT project_id, status, initcap(replace(status, '_', ' ')), 'active',
+    3 + row_number() OVER (PARTITION BY project_id ORDER BY status)
+FROM (
+    SELECT DISTINCT project_id, status FROM tasks
+    WHERE status NOT IN ('todo', 'in_progress', 'done')
+) extra;
+
+-- completed_at is now the one marker of a finished task, whatever the
+-- workflow calls its closed statuses, so open-task queries and this index
+-- test it instead of status.
+DROP INDEX IF EXISTS idx_tasks_user_open_due_date;
+CREATE INDEX idx_tasks_user_open_due_date ON tasks(user_id, due_date)
+    WHERE due_date IS NOT NULL AND completed_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_tasks_user_open_due_date;
+CREATE INDEX idx_tasks_user_open_due_date ON tasks(user_id, due_date)
+    WHERE due_date IS NOT NULL AND status <> 'done';
+DROP TABLE IF EXISTS project_statuses;
+-- +goose StatementEnd
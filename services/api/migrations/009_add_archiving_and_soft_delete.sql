@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE projects ADD COLUMN archived_at TIMESTAMP;
+ALTER TABLE projects ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE log_entries ADD COLUMN deleted_at TIMESTAMP;
+
+-- The trash listing and the purge job only look at deleted rows.
+CREATE INDEX idx_projects_deleted_at ON projects(user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_tasks_deleted_at ON tasks(user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_log_entries_deleted_at ON log_entries(user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Soft-deleted rows would reappear without the column, so drop them first.
+DELETE FROM log_entries WHERE deleted_at IS NOT NULL;
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+DELETE FROM projects WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_log_entries_deleted_at;
+DROP INDEX IF EXISTS idx_tasks_deleted_at;
+DROP INDEX IF EXISTS idx_projects_deleted_at;
+ALTER TABLE log_entries DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE projects DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE projects DROP COLUMN IF EXISTS archived_at;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Append-only history of task and log entry edits. data holds the tracked
+-- fields as they were before the change, that is at the given version of the
+-- entity; user_id is the user who made the change.
+CREATE TABLE revisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    entity_type VARCHAR(20) NOT NULL CHECK (entity_type IN ('task', 'log_entry')),
+    entity_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    version BIGINT NOT NULL,
+    data JSONB NOT NULL,
+    changed_fields TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_revisions_entity ON revisions(entity_type, entity_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS revisions;
+-- +goose StatementEnd
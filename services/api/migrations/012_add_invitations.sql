@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Project members see a single project, and its tasks and log entries,
+-- without joining its workspace.
+CREATE TABLE project_members (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('admin', 'member', 'viewer')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (project_id, user_id)
+);
+
+CREATE INDEX idx_project_members_user_id ON project_members(user_id);
+
+-- An invitation targets either a workspace or a project. token_hash is the
+-- SHA-256 of the token sent to the invitee; the token itself is not kept.
+CREATE TABLE invitations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    email VARCHAR(255),
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'admin', 'member', 'viewer')),
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    invited_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    accepted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((workspace_id IS NULL) <> (project_id IS NULL)),
+    CHECK (project_id IS NULL OR role <> 'owner')
+);
+
+CREATE INDEX idx_invitations_workspace_id ON invitations(workspace_id);
+CREATE INDEX idx_invitations_project_id ON invitations(project_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS invitations;
+DROP TABLE IF EXISTS project_members;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Comments on a log entry form threads through parent_id. A deleted comment
+-- keeps its row, with the content cleared, so replies to it stay in place.
+CREATE TABLE comments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    log_entry_id UUID NOT NULL REFERENCES log_entries(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    deleted_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_comments_log_entry_id ON comments(log_entry_id, created_at);
+
+CREATE TABLE reactions (
+    log_entry_id UUID NOT NULL REFERENCES log_entries(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (log_entry_id, user_id, emoji)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS reactions;
+DROP TABLE IF EXISTS comments;
+-- +goose StatementEnd
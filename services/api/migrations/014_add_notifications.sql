@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- in_app and email record where the notification goes, from the user's
+-- preferences when it was created. dedupe_key stops a source from notifying
+-- a user twice about the same thing.
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('task_due', 'comment', 'mention')),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    task_id UUID REFERENCES tasks(id) ON DELETE CASCADE,
+    log_entry_id UUID REFERENCES log_entries(id) ON DELETE CASCADE,
+    comment_id UUID REFERENCES comments(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    dedupe_key VARCHAR(255),
+    in_app BOOLEAN NOT NULL DEFAULT TRUE,
+    email BOOLEAN NOT NULL DEFAULT FALSE,
+    read_at TIMESTAMP,
+    emailed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, dedupe_key)
+);
+
+CREATE INDEX idx_notifications_user_id ON notifications(user_id, created_at DESC) WHERE in_app;
+CREATE INDEX idx_notifications_email ON notifications(user_id) WHERE email AND emailed_at IS NULL;
+
+-- Missing rows mean the defaults: in-app on, email off.
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('task_due', 'comment', 'mention')),
+    in_app BOOLEAN NOT NULL,
+    email BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, type)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Missing rows mean the defaults: UTC and no digest. last_digest_on is the
+-- local date of the last digest sent, so each one goes out once.
+CREATE TABLE user_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    digest VARCHAR(10) NOT NULL DEFAULT 'off' CHECK (digest IN ('off', 'daily', 'weekly')),
+    last_digest_on DATE,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_settings_digest ON user_settings(user_id) WHERE digest <> 'off';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_settings;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE clients (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_clients_workspace_id ON clients(workspace_id);
+
+-- Rates are in the currency's minor unit, e.g. cents.
+ALTER TABLE projects
+    ADD COLUMN client_id UUID REFERENCES clients(id) ON DELETE SET NULL,
+    ADD COLUMN hourly_rate_cents BIGINT CHECK (hourly_rate_cents >= 0),
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+CREATE INDEX idx_projects_client_id ON projects(client_id);
+
+ALTER TABLE log_entries
+    ADD COLUMN duration_minutes INTEGER CHECK (duration_minutes > 0),
+    ADD COLUMN billable BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE log_entries
+    DROP COLUMN IF EXISTS billable,
+    DROP COLUMN IF EXISTS duration_minutes;
+
+ALTER TABLE projects
+    DROP COLUMN IF EXISTS currency,
+    DROP COLUMN IF EXISTS hourly_rate_cents,
+    DROP COLUMN IF EXISTS client_id;
+
+DROP TABLE IF EXISTS clients;
+-- +goose StatementEnd
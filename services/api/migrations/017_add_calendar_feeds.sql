@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Each user has at most one calendar feed. token_hash is the SHA-256 of the
+-- secret in the feed's URL; rotating the token replaces the row's hash.
+CREATE TABLE calendar_feeds (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS calendar_feeds;
+-- +goose StatementEnd
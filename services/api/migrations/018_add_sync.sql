@@ -0,0 +1,99 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Every write to a project, task or log entry numbers the row from
+-- sync_seq, so offline clients can ask for everything after the last number
+-- they saw. Rows removed for good leave a tombstone numbered the same way.
+CREATE SEQUENCE sync_seq;
+
+-- Writers hold the sync lock shared until they commit, and sync reads take
+-- it exclusively, so a read never returns a number while a smaller one is
+-- still uncommitted and a cursor cannot skip over it.
+CREATE FUNCTION sync_next_seq() RETURNS BIGINT AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock_shared(hashtext('sync_seq'));
+    RETURN nextval('sync_seq');
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION sync_touch() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.sync_seq := sync_next_seq();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE projects ADD COLUMN sync_seq BIGINT NOT NULL DEFAULT nextval('sync_seq');
+ALTER TABLE tasks ADD COLUMN sync_seq BIGINT NOT NULL DEFAULT nextval('sync_seq');
+ALTER TABLE log_entries ADD COLUMN sync_seq BIGINT NOT NULL DEFAULT nextval('sync_seq');
+
+CREATE INDEX idx_projects_sync_seq ON projects(sync_seq);
+CREATE INDEX idx_tasks_sync_seq ON tasks(sync_seq);
+CREATE INDEX idx_log_entries_sync_seq ON log_entries(sync_seq);
+
+CREATE TRIGGER projects_sync_touch BEFORE INSERT OR UPDATE ON projects
+    FOR EACH ROW EXECUTE FUNCTION sync_touch();
+CREATE TRIGGER tasks_sync_touch BEFORE INSERT OR UPDATE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION sync_touch();
+CREATE TRIGGER log_entries_sync_touch BEFORE INSERT OR UPDATE ON log_entries
+    FOR EACH ROW EXECUTE FUNCTION sync_touch();
+
+-- A tombstone is shown to the members of the workspace the row was in and
+-- to its author. Rows are purged children first, so the project is still
+-- there to look the workspace up from.
+CREATE TABLE sync_tombstones (
+    sync_seq BIGINT PRIMARY KEY,
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id UUID NOT NULL,
+    workspace_id UUID,
+    user_id UUID,
+    deleted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_sync_tombstones_workspace_id ON sync_tombstones(workspace_id, sync_seq);
+CREATE INDEX idx_sync_tombstones_user_id ON sync_tombstones(user_id, sync_seq);
+
+CREATE FUNCTION sync_tombstone() RETURNS TRIGGER AS $$
+DECLARE
+    workspace UUID;
+BEGIN
+    IF TG_TABLE_NAME = 'projects' THEN
+        workspace := OLD.workspace_id;
+    ELSE
+        SELECT p.workspace_id INTO workspace FROM projects p WHERE p.id = OLD.project_id;
+    END IF;
+    INSERT INTO sync_tombstones (sync_seq, entity_type, entity_id, workspace_id, user_id)
+    VALUES (sync_next_seq(), TG_ARGV[0], OLD.id, workspace, OLD.user_id);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER projects_sync_tombstone AFTER DELETE ON projects
+    FOR EACH ROW EXECUTE FUNCTION sync_tombstone('project');
+CREATE TRIGGER tasks_sync_tombstone AFTER DELETE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION sync_tombstone('task');
+CREATE TRIGGER log_entries_sync_tombstone AFTER DELETE ON log_entries
+    FOR EACH ROW EXECUTE FUNCTION sync_tombstone('log_entry');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS log_entries_sync_tombstone ON log_entries;
+DROP TRIGGER IF EXISTS tasks_sync_tombstone ON tasks;
+DROP TRIGGER IF EXISTS projects_sync_tombstone ON projects;
+DROP FUNCTION IF EXISTS sync_tombstone();
+DROP TABLE IF EXISTS sync_tombstones;
+
+DROP TRIGGER IF EXISTS log_entries_sync_touch ON log_entries;
+DROP TRIGGER IF EXISTS tasks_sync_touch ON tasks;
+DROP TRIGGER IF EXISTS projects_sync_touch ON projects;
+DROP INDEX IF EXISTS idx_log_entries_sync_seq;
+DROP INDEX IF EXISTS idx_tasks_sync_seq;
+DROP INDEX IF EXISTS idx_projects_sync_seq;
+ALTER TABLE log_entries DROP COLUMN IF EXISTS sync_seq;
+ALTER TABLE tasks DROP COLUMN IF EXISTS sync_seq;
+ALTER TABLE projects DROP COLUMN IF EXISTS sync_seq;
+
+DROP FUNCTION IF EXISTS sync_touch();
+DROP FUNCTION IF EXISTS sync_next_seq();
+DROP SEQUENCE IF EXISTS sync_seq;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Since workspaces, task lists are scoped by the projects a user can see
+-- rather than by user_id, so the due-date and priority views need indexes
+-- that lead with project_id. The user_id ones no longer serve any query.
+DROP INDEX IF EXISTS idx_tasks_user_open_due_date;
+DROP INDEX IF EXISTS idx_tasks_user_priority;
+
+CREATE INDEX idx_tasks_project_open_due_date ON tasks(project_id, due_date)
+    WHERE completed_at IS NULL AND deleted_at IS NULL AND due_date IS NOT NULL;
+CREATE INDEX idx_tasks_project_priority ON tasks(project_id, priority)
+    WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_tasks_project_priority;
+DROP INDEX IF EXISTS idx_tasks_project_open_due_date;
+
+CREATE INDEX idx_tasks_user_open_due_date ON tasks(user_id, due_date)
+    WHERE due_date IS NOT NULL AND completed_at IS NULL;
+CREATE INDEX idx_tasks_user_priority ON tasks(user_id, priority);
+-- +goose StatementEnd
@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A username is the handle others @mention. Handles are unique regardless
+-- of case, and mentions look them up through the index on lower(username).
+ALTER TABLE users ADD COLUMN username VARCHAR(50);
+
+-- Existing users get the part of their email before the @, cut down to the
+-- characters a handle may use. Later users with the same handle get a
+-- number after it.
+WITH base AS (
+    SELECT id, created_at, COALESCE(NULLIF(left(regexp_replace(regexp_replace(
+        regexp_replace(split_part(email, '@', 1), '[^A-Za-z0-9._+-]', '', 'g'),
+        '^[^A-Za-z0-9]+', ''), '[._-]+$', ''), 40), ''), 'user') AS handle
+    FROM users
+), ranked AS (
+    SELECT id, handle, row_number() OVER (PARTITION BY lower(handle) ORDER BY created_at, id) AS rn
+    FROM base
+)
+UPDATE users SET username = ranked.handle || CASE WHEN ranked.rn > 1 THEN ranked.rn::text ELSE '' END
+FROM ranked WHERE users.id = ranked.id;
+
+-- A numbered handle can still clash with someone's own, such as alex2;
+-- number the later of the two again until none do.
+DO $$
+BEGIN
+    LOOP
+        UPDATE users u SET username = u.username || '2'
+        WHERE EXISTS (
+            SELECT 1 FROM users other
+            WHERE lower(other.username) = lower(u.username)
+                AND (other.created_at, other.id) < (u.created_at, u.id)
+        );
+        EXIT WHEN NOT FOUND;
+    END LOOP;
+END $$;
+
+ALTER TABLE users ALTER COLUMN username SET NOT NULL;
+
+CREATE UNIQUE INDEX idx_users_username ON users(lower(username));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_username;
+ALTER TABLE users DROP COLUMN IF EXISTS username;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Personal access tokens let scripts and the CLI call the API without a
+-- browser session. Each can be revoked on its own, may expire, and is
+-- either read-only or allowed to write. token_hash is the SHA-256 of the
+-- token; the token itself is not kept.
+CREATE TABLE access_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    scope VARCHAR(10) NOT NULL CHECK (scope IN ('read', 'write')),
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_access_tokens_user_id ON access_tokens(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS access_tokens;
+-- +goose StatementEnd
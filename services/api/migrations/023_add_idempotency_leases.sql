@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A request holds its key only until locked_until. If it has not finished
+-- by then, as when its instance died, a retry with the same request takes
+-- the key over instead of waiting for it to expire. Claims made before this
+-- column existed can be taken over at once.
+ALTER TABLE idempotency_keys ADD COLUMN locked_until TIMESTAMP NOT NULL DEFAULT NOW();
+ALTER TABLE idempotency_keys ALTER COLUMN locked_until DROP DEFAULT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS locked_until;
+-- +goose StatementEnd
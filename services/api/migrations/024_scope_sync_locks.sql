@@ -0,0 +1,174 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Writers used to hold one server-wide sync lock, which every sync read
+-- took exclusively, so a read in one workspace stalled writes in all the
+-- others. Writes now lock the scope they are in: the workspace of the row's
+-- project, or for a log entry outside any project, its author. A read waits
+-- only for the writes in flight in its own scopes, and returns nothing
+-- numbered after the point it started waiting; see ListChangesSince.
+CREATE FUNCTION sync_next_seq(scope TEXT) RETURNS BIGINT AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock_shared(hashtext('sync:' || scope));
+    RETURN nextval('sync_seq');
+END;
+$$ LANGUAGE plpgsql;
+
+-- sync_wait returns once no write holds the scope's lock.
+CREATE FUNCTION sync_wait(scope TEXT) RETURNS VOID AS $$
+BEGIN
+    PERFORM pg_advisory_lock(hashtext('sync:' || scope));
+    PERFORM pg_advisory_unlock(hashtext('sync:' || scope));
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION sync_scope(project UUID, author UUID) RETURNS TEXT AS $$
+    SELECT COALESCE((SELECT p.workspace_id::text FROM projects p WHERE p.id = project), 'user:' || author::text);
+$$ LANGUAGE sql STABLE;
+
+-- Tombstones also go to the members of the row's project, who need not be
+-- in its workspace.
+ALTER TABLE sync_tombstones ADD COLUMN project_id UUID;
+CREATE INDEX idx_sync_tombstones_project_id ON sync_tombstones(project_id, sync_seq);
+
+-- A task or log entry moved out of a project leaves a tombstone there for
+-- those who cannot see where it went. Those who can get the tombstone and
+-- then the row itself, which is numbered after it.
+CREATE OR REPLACE FUNCTION sync_touch() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'projects' THEN
+        NEW.sync_seq := sync_next_seq(NEW.workspace_id::text);
+        RETURN NEW;
+    END IF;
+    IF TG_OP = 'UPDATE' THEN
+        IF OLD.project_id IS NOT NULL AND OLD.project_id IS DISTINCT FROM NEW.project_id THEN
+            INSERT INTO sync_tombstones (sync_seq, entity_type, entity_id, workspace_id, project_id)
+            SELECT sync_next_seq(p.workspace_id::text), TG_ARGV[0], OLD.id, p.workspace_id, p.id
+            FROM projects p WHERE p.id = OLD.project_id;
+        END IF;
+    END IF;
+    NEW.sync_seq := sync_next_seq(sync_scope(NEW.project_id, NEW.user_id));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER tasks_sync_touch ON tasks;
+DROP TRIGGER log_entries_sync_touch ON log_entries;
+CREATE TRIGGER tasks_sync_touch BEFORE INSERT OR UPDATE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION sync_touch('task');
+CREATE TRIGGER log_entries_sync_touch BEFORE INSERT OR UPDATE ON log_entries
+    FOR EACH ROW EXECUTE FUNCTION sync_touch('log_entry');
+
+CREATE OR REPLACE FUNCTION sync_tombstone() RETURNS TRIGGER AS $$
+DECLARE
+    workspace UUID;
+    project UUID;
+BEGIN
+    IF TG_TABLE_NAME = 'projects' THEN
+        workspace := OLD.workspace_id;
+        project := OLD.id;
+    ELSE
+        project := OLD.project_id;
+        SELECT p.workspace_id INTO workspace FROM projects p WHERE p.id = OLD.project_id;
+    END IF;
+    INSERT INTO sync_tombstones (sync_seq, entity_type, entity_id, workspace_id, project_id, user_id)
+    VALUES (sync_next_seq(COALESCE(workspace::text, 'user:' || OLD.user_id::text)), TG_ARGV[0], OLD.id, workspace, project, OLD.user_id);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION sync_next_seq();
+
+-- A user who loses access to projects, by leaving or being removed from a
+-- workspace or project, gets tombstones for the projects and everything in
+-- them, shown to them alone.
+CREATE FUNCTION sync_access_lost(member UUID, lost UUID[]) RETURNS VOID AS $$
+    INSERT INTO sync_tombstones (sync_seq, entity_type, entity_id, user_id)
+    SELECT sync_next_seq('user:' || member::text), item.entity_type, item.entity_id, member
+    FROM (
+        SELECT 'project' AS entity_type, p.id AS entity_id FROM projects p WHERE p.id = ANY(lost)
+        UNION ALL
+        SELECT 'task', t.id FROM tasks t WHERE t.project_id = ANY(lost)
+        UNION ALL
+        SELECT 'log_entry', l.id FROM log_entries l WHERE l.project_id = ANY(lost)
+    ) item;
+$$ LANGUAGE sql;
+
+CREATE FUNCTION sync_workspace_member_removed() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM sync_access_lost(OLD.user_id, ARRAY(
+        SELECT p.id FROM projects p
+        WHERE p.workspace_id = OLD.workspace_id
+            AND p.id NOT IN (SELECT pm.project_id FROM project_members pm WHERE pm.user_id = OLD.user_id)
+    ));
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION sync_project_member_removed() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM sync_access_lost(OLD.user_id, ARRAY(
+        SELECT p.id FROM projects p
+        WHERE p.id = OLD.project_id
+            AND p.workspace_id NOT IN (SELECT wm.workspace_id FROM workspace_members wm WHERE wm.user_id = OLD.user_id)
+    ));
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER workspace_members_sync_access_lost AFTER DELETE ON workspace_members
+    FOR EACH ROW EXECUTE FUNCTION sync_workspace_member_removed();
+CREATE TRIGGER project_members_sync_access_lost AFTER DELETE ON project_members
+    FOR EACH ROW EXECUTE FUNCTION sync_project_member_removed();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS project_members_sync_access_lost ON project_members;
+DROP TRIGGER IF EXISTS workspace_members_sync_access_lost ON workspace_members;
+DROP FUNCTION IF EXISTS sync_project_member_removed();
+DROP FUNCTION IF EXISTS sync_workspace_member_removed();
+DROP FUNCTION IF EXISTS sync_access_lost(UUID, UUID[]);
+
+CREATE FUNCTION sync_next_seq() RETURNS BIGINT AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock_shared(hashtext('sync_seq'));
+    RETURN nextval('sync_seq');
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION sync_touch() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.sync_seq := sync_next_seq();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER tasks_sync_touch ON tasks;
+DROP TRIGGER log_entries_sync_touch ON log_entries;
+CREATE TRIGGER tasks_sync_touch BEFORE INSERT OR UPDATE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION sync_touch();
+CREATE TRIGGER log_entries_sync_touch BEFORE INSERT OR UPDATE ON log_entries
+    FOR EACH ROW EXECUTE FUNCTION sync_touch();
+
+CREATE OR REPLACE FUNCTION sync_tombstone() RETURNS TRIGGER AS $$
+DECLARE
+    workspace UUID;
+BEGIN
+    IF TG_TABLE_NAME = 'projects' THEN
+        workspace := OLD.workspace_id;
+    ELSE
+        SELECT p.workspace_id INTO workspace FROM projects p WHERE p.id = OLD.project_id;
+    END IF;
+    INSERT INTO sync_tombstones (sync_seq, entity_type, entity_id, workspace_id, user_id)
+    VALUES (sync_next_seq(), TG_ARGV[0], OLD.id, workspace, OLD.user_id);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_sync_tombstones_project_id;
+ALTER TABLE sync_tombstones DROP COLUMN IF EXISTS project_id;
+
+DROP FUNCTION IF EXISTS sync_scope(UUID, UUID);
+DROP FUNCTION IF EXISTS sync_wait(TEXT);
+DROP FUNCTION IF EXISTS sync_next_seq(TEXT);
+-- +goose StatementEnd